@@ -2,20 +2,32 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"os/exec"
+	"time"
 )
 
+// commandWaitDelay bounds how long a killed command may hold its output pipes open.
+const commandWaitDelay = 5 * time.Second
+
 // Commander interface for command execution
 type Commander interface {
-	Execute(name string, args ...string) ([]byte, error)
+	Execute(ctx context.Context, name string, args ...string) ([]byte, error)
 }
 
 // RealCommander executes actual system commands
 type RealCommander struct{}
 
-func (c RealCommander) Execute(name string, args ...string) ([]byte, error) {
-	cmd := exec.Command(name, args...)
-	return cmd.Output()
+// Execute runs the command, killing it when ctx is cancelled or its deadline passes.
+func (c RealCommander) Execute(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = commandWaitDelay
+	output, err := cmd.Output()
+	if err != nil && ctx.Err() != nil {
+		return output, fmt.Errorf("%s interrupted: %w", name, ctx.Err())
+	}
+	return output, err
 }
 
 // Default commander instance
@@ -20,10 +20,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"path/filepath"
-	"sync"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 )
@@ -33,6 +34,7 @@ var (
 	outputDir   string // Directory to store analysis results
 	maxCores    int
 	compareFlag bool
+	gdbBackend  string // GDB interface used for analysis: cli or mi
 )
 
 // coreCmd represents the core analysis command
@@ -47,6 +49,24 @@ It can analyze a single core file or multiple core files in a directory:
   cbtoolbox core /path/to/core.1234
   cbtoolbox core /var/lib/postgres/cores/ --max-cores=5
 
+Directories are searched recursively; cores can be selected by time, size and name:
+  cbtoolbox core /data/cores --since 24h --min-size 1MB --exclude 'archive'
+  cbtoolbox core /data/cores --pattern 'postgres.core.*' --since 2026-10-01 --until 2026-10-08
+
+Compressed cores (gzip, zstd, lz4, xz) are decompressed into a scratch directory and removed after analysis:
+  cbtoolbox core /var/lib/systemd/coredump/core.postgres.1000.1a2b.4242.1760612345000000.zst --scratch-dir /data/scratch
+
+Cores are analyzed on a bounded worker pool, each under its own deadline:
+  cbtoolbox core /var/lib/postgres/cores/ --jobs=8 --timeout=10m
+
+The postgres executable is detected from each core (NT_FILE mappings and the
+executable's build-id) and matched against $GPHOME and any --gphome candidates:
+  cbtoolbox core core.1234 --gphome /usr/local/cloudberry-1.6 --gphome /usr/local/cloudberry-2.0
+  cbtoolbox core core.1234 --binary /opt/debug/bin/postgres
+
+Results can be streamed to stdout as newline-delimited JSON:
+  cbtoolbox core /var/lib/postgres/cores/ --output - | jq -c 'select(.type == "analysis")'
+
 Features:
 - Stack trace analysis
 - Thread inspection
@@ -58,22 +78,26 @@ Features:
 		if len(args) != 1 {
 			return fmt.Errorf("please specify a core file or directory")
 		}
-		return runCoreAnalysis(args[0])
+		// Ctrl-C cancels the run and kills any gdb processes still running
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return runCoreAnalysis(ctx, args[0])
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(coreCmd)
-	coreCmd.Flags().StringVar(&outputDir, "output-dir", "/var/log/postgres_cores", "Directory to store analysis results")
-	coreCmd.Flags().IntVar(&maxCores, "max-cores", 0, "Maximum number of core files to analyze")
+	coreCmd.PersistentFlags().StringVar(&outputDir, "output-dir", "/var/log/postgres_cores", "Directory to store analysis results")
+	coreCmd.Flags().IntVar(&maxCores, "max-cores", 0, "Maximum number of core files to analyze, most recent first")
 	coreCmd.Flags().BoolVar(&compareFlag, "compare", false, "Compare core files and identify patterns")
+	coreCmd.PersistentFlags().StringVar(&gdbBackend, "gdb-backend", gdbBackendCLI, "GDB interface to use: cli (batch output) or mi (GDB/MI machine interface)")
 }
 
 // runCoreAnalysis is the main entry point for core file analysis
 // Validates inputs, processes core files, and optionally compares results.
 // runCoreAnalysis validates input, analyzes core files, and optionally compares results.
-func runCoreAnalysis(path string) error {
-    if err := validateFormat(formatFlag); err != nil {
+func runCoreAnalysis(ctx context.Context, path string) error {
+    if err := validateCoreFlags(); err != nil {
         return err
     }
 
@@ -81,14 +105,16 @@ func runCoreAnalysis(path string) error {
         return fmt.Errorf("failed to create output directory: %w", err)
     }
 
-    // Find PostgreSQL binary
-    gphome := os.Getenv("GPHOME")
-    if gphome == "" {
-        return fmt.Errorf("GPHOME environment variable must be set")
+    // Only NDJSON records go to stdout while streaming
+    if streamingOutput() {
+        defer startStream()()
     }
 
+    // $GPHOME is only the default candidate; each core's binary is detected from the core itself
+    gphome := os.Getenv("GPHOME")
+
     // Find core files
-    coreFiles, err := findCoreFiles(path)
+    coreFiles, err := discoverCoreFiles(path)
     if err != nil {
         return err
     }
@@ -102,83 +128,83 @@ func runCoreAnalysis(path string) error {
         coreFiles = coreFiles[:maxCores]
     }
 
-    var analyses []CoreAnalysis
-    var mu sync.Mutex
-    var wg sync.WaitGroup
+    analyses, manifest := analyzeAndRecord(ctx, coreFiles, gphome)
 
-    // Process each core file
-    for _, coreFile := range coreFiles {
-        wg.Add(1)
-        go func(cf string) {
-            defer wg.Done()
-            analysis, err := analyzeCoreFile(cf, gphome)
-            if err != nil {
-                fmt.Printf("Error analyzing %s: %v\n", cf, err)
-                return
+    // Compare core files if requested
+    var comparison *CoreComparison
+    if compareFlag && len(analyses) > 1 && ctx.Err() == nil {
+        result := compareCores(analyses)
+        comparison = &result
+        if !streamingOutput() {
+            if err := saveComparison(result); err != nil {
+                fmt.Printf("Error saving comparison results: %v\n", err)
             }
+        }
+    }
 
-            // Incorporate basic_info dynamically into analysis
-            basicInfo := parseBasicInfo(analysis.FileInfo.FileOutput)
-            analysis.BasicInfo = basicInfo
-
-            mu.Lock()
-            analyses = append(analyses, analysis)
-            mu.Unlock()
-
-            // Use new saveOrPrintAnalysis function
-            if err := saveOrPrintAnalysis(analysis); err != nil {
-                fmt.Printf("Error outputting analysis for %s: %v\n", cf, err)
-            }
-        }(coreFile)
+    // The summary record ends the stream, including for cancelled or failed runs
+    if streamingOutput() {
+        if err := streamSummary(manifest, comparison); err != nil {
+            return err
+        }
     }
 
-    wg.Wait()
+    if ctx.Err() != nil {
+        return fmt.Errorf("core analysis cancelled: %w", ctx.Err())
+    }
 
     if len(analyses) == 0 {
         return fmt.Errorf("no core files were analyzed successfully")
     }
 
-    // Compare core files if requested
-    if compareFlag && len(analyses) > 1 {
-        comparison := compareCores(analyses)
-        if err := saveComparison(comparison); err != nil {
-            fmt.Printf("Error saving comparison results: %v\n", err)
+    return nil
+}
+
+// validateCoreFlags checks the flags shared by every analysis entry point.
+// Returns:
+// - An error if the output format, gdb backend or worker pool flags are invalid.
+func validateCoreFlags() error {
+    if err := validateFormat(formatFlag); err != nil {
+        return err
+    }
+
+    if gdbBackend != gdbBackendCLI && gdbBackend != gdbBackendMI {
+        return fmt.Errorf("invalid gdb backend: %s. Valid options are 'cli' or 'mi'", gdbBackend)
+    }
+
+    if sourceContextLines < 0 {
+        return fmt.Errorf("invalid source context: %d. Must not be negative", sourceContextLines)
+    }
+    if scratchDir != "" && !dirExists(scratchDir) {
+        return fmt.Errorf("scratch directory not found: %s", scratchDir)
+    }
+
+    for _, root := range sourceRoots {
+        if !dirExists(root) {
+            return fmt.Errorf("source root not found: %s", root)
         }
     }
 
-    return nil
-}
+    if err := validateClusterFlags(); err != nil {
+        return err
+    }
 
-// findCoreFiles locates core files in the specified path
-// Supports multiple filename patterns for flexibility.
-// findCoreFiles locates core files matching known patterns in the given path.
-func findCoreFiles(path string) ([]string, error) {
-	fileInfo, err := os.Stat(path)
-	if err != nil {
-		return nil, err
-	}
-
-	if !fileInfo.IsDir() {
-		return []string{path}, nil
-	}
-
-	var coreFiles []string
-	patterns := []string{
-		"core.*",
-		"*.core",
-		"core",
-		"core-*",
-		"**/core.*",
-		"**/core-*",
-	}
-
-	for _, pattern := range patterns {
-		matches, err := filepath.Glob(filepath.Join(path, pattern))
-		if err != nil {
-			continue
-		}
-		coreFiles = append(coreFiles, matches...)
-	}
+    if err := validateDiscoveryFlags(); err != nil {
+        return err
+    }
+
+    if err := validateOutputFlag(); err != nil {
+        return err
+    }
+
+    // A broken rules file fails the run before any core is analyzed
+    if err := loadCrashRules(); err != nil {
+        return err
+    }
+
+    if err := loadRedactionPolicy(); err != nil {
+        return err
+    }
 
-	return coreFiles, nil
+    return validateRunFlags()
 }
@@ -0,0 +1,319 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_abort.go
+// Purpose: Explains why a backend aborted. Assert failures are recognized by their ExceptionalCondition
+// frame, whose arguments carry the condition, file and line. The elog/ereport error stack
+// (errordata[0..errordata_stack_depth]) is read by a gdb script to recover the message, detail,
+// SQLSTATE and call site of PANIC/FATAL errors.
+// Dependencies: Uses the gdb value decoders in core_session.go.
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	errorDataBegin = "@@cbtoolbox-errordata-begin"
+	errorDataEnd   = "@@cbtoolbox-errordata-end"
+	errorDataLine  = "@@errordata "
+)
+
+// errorDataFields are the ErrorData members printed for each error stack entry.
+var errorDataFields = []struct {
+	name   string
+	format string
+}{
+	{"elevel", "%d"},
+	{"sqlerrcode", "%d"},
+	{"filename", "%s"},
+	{"lineno", "%d"},
+	{"funcname", "%s"},
+	{"message", "%s"},
+	{"detail", "%s"},
+	{"hint", "%s"},
+}
+
+// errorDataScript prints every entry of the elog error stack, one field per line:
+// @@errordata <index> <field>=<value>
+var errorDataScript = func() string {
+	var b strings.Builder
+	b.WriteString("echo " + errorDataBegin + `\n` + "\n")
+	b.WriteString("set $cbt_e = 0\n")
+	b.WriteString("while $cbt_e <= errordata_stack_depth && $cbt_e < sizeof(errordata) / sizeof(errordata[0])\n")
+	for _, f := range errorDataFields {
+		fmt.Fprintf(&b, "  printf \"%s%%d %s=%s\\n\", $cbt_e, errordata[$cbt_e].%s\n", errorDataLine, f.name, f.format, f.name)
+	}
+	b.WriteString("  set $cbt_e = $cbt_e + 1\nend\n")
+	b.WriteString("echo " + errorDataEnd + `\n` + "\n")
+	return b.String()
+}()
+
+// elevelNames maps elog levels (PostgreSQL 14 numbering, as used by Cloudberry) to names.
+var elevelNames = map[int]string{
+	10: "DEBUG5", 11: "DEBUG4", 12: "DEBUG3", 13: "DEBUG2", 14: "DEBUG1",
+	15: "LOG", 16: "LOG_SERVER_ONLY", 17: "INFO", 18: "NOTICE", 19: "WARNING",
+	20: "WARNING_CLIENT_ONLY", 21: "ERROR", 22: "FATAL", 23: "PANIC",
+}
+
+// elevelNumber returns the numeric elog level for a level name, or 0 if unknown.
+func elevelNumber(name string) int {
+	for n, level := range elevelNames {
+		if level == name {
+			return n
+		}
+	}
+	return 0
+}
+
+// unpackSQLState decodes a sqlerrcode packed by MAKE_SQLSTATE.
+func unpackSQLState(code int) string {
+	var buf [5]byte
+	for i := range buf {
+		buf[i] = byte(code&0x3F) + '0'
+		code >>= 6
+	}
+	return string(buf[:])
+}
+
+// parseErrorData extracts the elog error stack from gdb output.
+// Continuation lines belong to the previous field, since messages and details may span lines.
+// Parameters:
+// - output: The gdb output containing the errordata script's output.
+// Returns:
+// - An AbortInfo holding the error stack, or nil if no error was being reported.
+func parseErrorData(output string) *AbortInfo {
+	start := strings.Index(output, errorDataBegin)
+	if start < 0 {
+		return nil
+	}
+	block := output[start+len(errorDataBegin):]
+	if end := strings.Index(block, errorDataEnd); end >= 0 {
+		block = block[:end]
+	}
+
+	lineRE := regexp.MustCompile(`^` + regexp.QuoteMeta(errorDataLine) + `(\d+) (\w+)=(.*)$`)
+	var entries []map[string]string
+	var last map[string]string
+	var lastKey string
+	for _, line := range strings.Split(block, "\n") {
+		m := lineRE.FindStringSubmatch(line)
+		if m == nil {
+			if last != nil && line != "" {
+				last[lastKey] += "\n" + line
+			}
+			continue
+		}
+		idx, _ := strconv.Atoi(m[1])
+		for len(entries) <= idx {
+			entries = append(entries, make(map[string]string))
+		}
+		last, lastKey = entries[idx], m[2]
+		last[lastKey] = m[3]
+	}
+
+	info := &AbortInfo{}
+	for _, e := range entries {
+		report := ErrorReport{
+			Message:  nullString(e["message"]),
+			Detail:   nullString(e["detail"]),
+			Hint:     nullString(e["hint"]),
+			File:     nullString(e["filename"]),
+			Function: nullString(e["funcname"]),
+		}
+		if level, err := strconv.Atoi(e["elevel"]); err == nil {
+			report.Level = elevelNames[level]
+			if report.Level == "" {
+				report.Level = strconv.Itoa(level)
+			}
+		}
+		if code, err := strconv.Atoi(e["sqlerrcode"]); err == nil && code != 0 {
+			report.SQLState = unpackSQLState(code)
+		}
+		report.Line, _ = strconv.Atoi(e["lineno"])
+		if report.Level != "" || report.Message != "" {
+			info.Errors = append(info.Errors, report)
+		}
+	}
+	if len(info.Errors) == 0 {
+		return nil
+	}
+	return info
+}
+
+// nullString maps gdb's rendering of a NULL char * to an empty string.
+func nullString(s string) string {
+	if s == "(null)" {
+		return ""
+	}
+	return s
+}
+
+// parseFrameArguments splits gdb frame arguments into name/value pairs. Commas and parentheses
+// inside quoted strings and nested values are respected, and an unmatched ')' ends the list.
+// Parameters:
+// - args: The argument text, e.g. `conditionName=0x55d5 "x(y)", lineNumber=12`.
+// Returns:
+// - The values keyed by argument name.
+func parseFrameArguments(args string) map[string]string {
+	values := make(map[string]string)
+	add := func(arg string) {
+		if name, value, ok := strings.Cut(strings.TrimSpace(arg), "="); ok {
+			values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+
+	depth, inQuote, start := 0, false, 0
+	for i := 0; i < len(args); i++ {
+		switch c := args[i]; {
+		case inQuote && c == '\\':
+			i++
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '(' || c == '{' || c == '[':
+			depth++
+		case c == ')' || c == '}' || c == ']':
+			if depth == 0 {
+				add(args[start:i])
+				return values
+			}
+			depth--
+		case c == ',' && depth == 0:
+			add(args[start:i])
+			start = i + 1
+		}
+	}
+	add(args[start:])
+	return values
+}
+
+// assertArgsRE finds the arguments of an ExceptionalCondition frame in raw gdb backtrace output.
+var assertArgsRE = regexp.MustCompile(`(?m)(?:^#\d+\s+|\sin\s)ExceptionalCondition\s*\((.*)$`)
+
+// parseAssertFromOutput reads the Assert failure from raw gdb output. The CLI frame parser stops
+// at the first ')', which truncates conditions such as "TransactionIdIsValid(xid)".
+// Parameters:
+// - output: The raw output from GDB.
+// - info: The AbortInfo to fill.
+// Returns:
+// - True if an ExceptionalCondition frame was found.
+func parseAssertFromOutput(output string, info *AbortInfo) bool {
+	m := assertArgsRE.FindStringSubmatch(output)
+	if m == nil {
+		return false
+	}
+	applyAssertArguments(parseFrameArguments(m[1]), info)
+	return true
+}
+
+// applyAssertArguments fills the assert fields from ExceptionalCondition's arguments.
+func applyAssertArguments(args map[string]string, info *AbortInfo) {
+	info.Kind = "assert"
+	if s, ok := gdbStringValue(args["conditionName"]); ok {
+		info.Condition = s
+	}
+	if s, ok := gdbStringValue(args["errorType"]); ok {
+		info.ErrorType = s
+	}
+	if s, ok := gdbStringValue(args["fileName"]); ok {
+		info.File = s
+	}
+	if n, ok := gdbIntValue(args["lineNumber"]); ok {
+		info.Line = n
+	}
+}
+
+// detectAbortInfo completes the abort explanation once the stack has been parsed.
+// Parameters:
+// - analysis: The CoreAnalysis object to update.
+func detectAbortInfo(analysis *CoreAnalysis) {
+	info := analysis.AbortInfo
+	if info == nil {
+		info = &AbortInfo{}
+	}
+
+	if info.Kind != "assert" {
+		threads := append([]ThreadInfo{}, analysis.Threads...)
+		threads = append(threads, ThreadInfo{Backtrace: analysis.StackTrace})
+	search:
+		for _, thread := range threads {
+			for _, frame := range thread.Backtrace {
+				if frame.Function == "ExceptionalCondition" {
+					applyAssertArguments(parseFrameArguments(frame.Arguments), info)
+					break search
+				}
+			}
+		}
+	}
+	if info.Kind == "" && len(info.Errors) > 0 {
+		info.Kind = "elog"
+	}
+	if info.Kind == "" {
+		analysis.AbortInfo = nil
+		return
+	}
+
+	info.Summary = abortSummary(info)
+	analysis.AbortInfo = info
+}
+
+// primaryError returns the most severe error on the stack, preferring the most recent.
+func primaryError(errors []ErrorReport) *ErrorReport {
+	var best *ErrorReport
+	bestLevel := -1
+	for i := range errors {
+		if level := elevelNumber(errors[i].Level); level >= bestLevel {
+			best, bestLevel = &errors[i], level
+		}
+	}
+	return best
+}
+
+// abortSummary builds the one-line explanation of an abort.
+func abortSummary(info *AbortInfo) string {
+	if info.Kind == "assert" {
+		kind := "Assert"
+		if info.ErrorType != "" && info.ErrorType != "FailedAssertion" {
+			kind = info.ErrorType
+		}
+		condition := info.Condition
+		if condition == "" {
+			condition = "?"
+		}
+		summary := fmt.Sprintf("%s(%s) failed", kind, condition)
+		if info.File != "" {
+			summary += fmt.Sprintf(" at %s:%d", info.File, info.Line)
+		}
+		return summary
+	}
+
+	e := primaryError(info.Errors)
+	summary := e.Message
+	if e.Level != "" {
+		summary = e.Level + ": " + summary
+	}
+	if e.SQLState != "" {
+		summary += fmt.Sprintf(" (SQLSTATE %s)", e.SQLState)
+	}
+	if e.File != "" {
+		summary += fmt.Sprintf(" at %s:%d", e.File, e.Line)
+	}
+	if e.Function != "" {
+		summary += " in " + e.Function
+	}
+	return summary
+}
@@ -0,0 +1,131 @@
+// File: cmd/core_abort_test.go
+package cmd
+
+import (
+	"testing"
+)
+
+func TestUnpackSQLState(t *testing.T) {
+	// MAKE_SQLSTATE('X','X','0','0','0') and MAKE_SQLSTATE('5','3','2','0','0')
+	pack := func(s string) int {
+		code := 0
+		for i := len(s) - 1; i >= 0; i-- {
+			code = code<<6 | int(s[i]-'0')&0x3F
+		}
+		return code
+	}
+	for _, state := range []string{"XX000", "53200", "22012"} {
+		if got := unpackSQLState(pack(state)); got != state {
+			t.Errorf("unpackSQLState(%d) = %q, want %q", pack(state), got, state)
+		}
+	}
+}
+
+func TestParseFrameArguments(t *testing.T) {
+	args := parseFrameArguments(`conditionName=0x55d5c0 "TransactionIdIsValid(xid)", errorType=0x55d5d0 "FailedAssertion", fileName=0x55d5e0 "procarray.c", lineNumber=1234) at assert.c:44`)
+	want := map[string]string{
+		"conditionName": `0x55d5c0 "TransactionIdIsValid(xid)"`,
+		"errorType":     `0x55d5d0 "FailedAssertion"`,
+		"fileName":      `0x55d5e0 "procarray.c"`,
+		"lineNumber":    "1234",
+	}
+	for k, v := range want {
+		if args[k] != v {
+			t.Errorf("args[%s] = %q, want %q", k, args[k], v)
+		}
+	}
+	if len(args) != len(want) {
+		t.Errorf("args = %v, want %d entries", args, len(want))
+	}
+}
+
+func TestParseAssertFromOutput(t *testing.T) {
+	output := `Thread 1 (Thread 0x7f1c2b6c8880 (LWP 4242)):
+#0  __GI_raise (sig=sig@entry=6) at ../sysdeps/unix/sysv/linux/raise.c:50
+#1  0x00007f1c2b8a4921 in __GI_abort () at abort.c:79
+#2  0x000055d5c0912345 in ExceptionalCondition (conditionName=0x55d5c0 "TransactionIdIsValid(xid)", errorType=0x55d5d0 "FailedAssertion", fileName=0x55d5e0 "procarray.c", lineNumber=1234) at assert.c:44
+#3  0x000055d5c0812345 in ProcArrayEndTransaction (proc=0x7f1c) at procarray.c:1234
+`
+	info := &AbortInfo{}
+	if !parseAssertFromOutput(output, info) {
+		t.Fatal("ExceptionalCondition frame not found")
+	}
+	if info.Condition != "TransactionIdIsValid(xid)" || info.File != "procarray.c" || info.Line != 1234 {
+		t.Errorf("info = %+v", info)
+	}
+	if got, want := abortSummary(info), "Assert(TransactionIdIsValid(xid)) failed at procarray.c:1234"; got != want {
+		t.Errorf("abortSummary() = %q, want %q", got, want)
+	}
+
+	if parseAssertFromOutput("#0  raise () from /lib64/libc.so.6\n", &AbortInfo{}) {
+		t.Error("assert detected without ExceptionalCondition")
+	}
+}
+
+func TestParseErrorData(t *testing.T) {
+	output := `@@cbtoolbox-errordata-begin
+@@errordata 0 elevel=21
+@@errordata 0 sqlerrcode=2600
+@@errordata 0 filename=heapam.c
+@@errordata 0 lineno=512
+@@errordata 0 funcname=heap_fetch
+@@errordata 0 message=could not read block 10 in file "base/16384/16385"
+@@errordata 0 detail=(null)
+@@errordata 0 hint=(null)
+@@errordata 1 elevel=23
+@@errordata 1 sqlerrcode=2600
+@@errordata 1 filename=xlog.c
+@@errordata 1 lineno=2870
+@@errordata 1 funcname=XLogFlush
+@@errordata 1 message=xlog flush request 0/5000000 is not satisfied
+@@errordata 1 detail=first line
+second line
+@@errordata 1 hint=(null)
+@@cbtoolbox-errordata-end
+`
+	info := parseErrorData(output)
+	if info == nil || len(info.Errors) != 2 {
+		t.Fatalf("parseErrorData() = %+v, want 2 errors", info)
+	}
+	first := info.Errors[0]
+	if first.Level != "ERROR" || first.SQLState != unpackSQLState(2600) || first.Detail != "" || first.Line != 512 {
+		t.Errorf("errordata[0] = %+v", first)
+	}
+	if detail := info.Errors[1].Detail; detail != "first line\nsecond line" {
+		t.Errorf("multi-line detail = %q", detail)
+	}
+
+	analysis := CoreAnalysis{AbortInfo: info}
+	detectAbortInfo(&analysis)
+	if analysis.AbortInfo.Kind != "elog" {
+		t.Errorf("Kind = %q, want elog", analysis.AbortInfo.Kind)
+	}
+	want := "PANIC: xlog flush request 0/5000000 is not satisfied (SQLSTATE " + unpackSQLState(2600) + ") at xlog.c:2870 in XLogFlush"
+	if analysis.AbortInfo.Summary != want {
+		t.Errorf("Summary = %q, want %q", analysis.AbortInfo.Summary, want)
+	}
+
+	if got := parseErrorData("@@cbtoolbox-errordata-begin\n@@cbtoolbox-errordata-end\n"); got != nil {
+		t.Errorf("parseErrorData(empty stack) = %+v, want nil", got)
+	}
+}
+
+func TestDetectAbortInfoFromFrames(t *testing.T) {
+	// The MI backend keeps full argument values on the frame
+	analysis := CoreAnalysis{
+		Threads: []ThreadInfo{{IsCrashed: true, Backtrace: []StackFrame{
+			{Function: "raise"},
+			{Function: "ExceptionalCondition", Arguments: `conditionName=0x1 "!(ptr != NULL)", errorType=0x2 "BadArgument", fileName=0x3 "mcxt.c", lineNumber=900`},
+		}}},
+	}
+	detectAbortInfo(&analysis)
+	if analysis.AbortInfo == nil || analysis.AbortInfo.Summary != "BadArgument(!(ptr != NULL)) failed at mcxt.c:900" {
+		t.Errorf("AbortInfo = %+v", analysis.AbortInfo)
+	}
+
+	plain := CoreAnalysis{Threads: []ThreadInfo{{Backtrace: []StackFrame{{Function: "main"}}}}}
+	detectAbortInfo(&plain)
+	if plain.AbortInfo != nil {
+		t.Errorf("AbortInfo = %+v, want nil", plain.AbortInfo)
+	}
+}
@@ -0,0 +1,329 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_binary.go
+// Purpose: Determines which postgres executable produced a core file.
+// The executable path and GNU build-id are recovered from the core itself (NT_FILE, the auxiliary
+// vector and the ELF header captured in the first page of the executable mapping) and matched
+// against candidate Cloudberry installations, so that cores are never debugged against the
+// wrong binary on hosts with several installations side by side.
+// Dependencies: Builds on the native ELF core reader in core_elf.go.
+
+package cmd
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Auxiliary vector entries used to locate the executable mapping.
+const (
+	atPHDR  = 3 // AT_PHDR: address of the program headers
+	atEntry = 9 // AT_ENTRY: entry point of the executable
+)
+
+// ntGNUBuildID is the note type of a GNU build-id note.
+const ntGNUBuildID = 3
+
+var (
+	binaryOverride string   // Explicit postgres binary, bypassing detection
+	gphomeFlags    []string // Additional candidate GPHOME directories
+)
+
+func init() {
+	coreCmd.PersistentFlags().StringVar(&binaryOverride, "binary", "", "Postgres binary to debug against (overrides detection from the core)")
+	coreCmd.PersistentFlags().StringSliceVar(&gphomeFlags, "gphome", nil, "Candidate GPHOME directories to match against the core's build-id (repeatable)")
+}
+
+// binarySelection describes the postgres binary chosen for a core.
+type binarySelection struct {
+	Path           string // Binary to debug against
+	GPHOME         string // Installation the binary belongs to
+	Reason         string // Why this binary was chosen
+	CoreExecutable string // Executable path recorded in the core
+	CoreBuildID    string // Build-id of the executable captured in the core
+	BuildID        string // Build-id of the chosen binary
+}
+
+// coreExecutableMapping finds the file mapping of the executable that produced the core.
+// Parameters:
+// - notes: The decoded core notes.
+// Returns:
+// - The executable's first mapping (file offset 0), or nil if it cannot be determined.
+func coreExecutableMapping(notes *coreNotes) *coreMappedFile {
+	exePath := ""
+	for _, key := range []uint64{atEntry, atPHDR} {
+		addr, ok := notes.Auxv[key]
+		if !ok {
+			continue
+		}
+		for _, f := range notes.Files {
+			if addr >= f.Start && addr < f.End {
+				exePath = f.Path
+				break
+			}
+		}
+		if exePath != "" {
+			break
+		}
+	}
+
+	// Fall back to the command name, which the kernel truncates to 15 characters.
+	if exePath == "" && notes.Process != nil && notes.Process.FName != "" {
+		for _, f := range notes.Files {
+			if strings.HasPrefix(filepath.Base(f.Path), notes.Process.FName) {
+				exePath = f.Path
+				break
+			}
+		}
+	}
+
+	for i := range notes.Files {
+		if notes.Files[i].Path == exePath && notes.Files[i].Offset == 0 {
+			return &notes.Files[i]
+		}
+	}
+	return nil
+}
+
+// parseBuildIDNotes extracts a GNU build-id from raw ELF note data.
+// Parameters:
+// - data: The contents of a PT_NOTE segment or .note section.
+// Returns:
+// - The build-id as a lowercase hex string, or an empty string.
+func parseBuildIDNotes(data []byte) string {
+	le := binary.LittleEndian
+	for len(data) >= 12 {
+		namesz := int(le.Uint32(data[0:]))
+		descsz := int(le.Uint32(data[4:]))
+		noteType := le.Uint32(data[8:])
+		nameEnd := 12 + align4(namesz)
+		if nameEnd+descsz > len(data) {
+			return ""
+		}
+		if noteType == ntGNUBuildID && strings.TrimRight(string(data[12:12+namesz]), "\x00") == "GNU" {
+			return hex.EncodeToString(data[nameEnd : nameEnd+descsz])
+		}
+		next := nameEnd + align4(descsz)
+		if next > len(data) {
+			return ""
+		}
+		data = data[next:]
+	}
+	return ""
+}
+
+// readBuildIDFromCore reads the build-id of a mapped ELF object from the core's memory.
+// The kernel dumps the first page of file-backed ELF mappings, which holds the ELF header,
+// the program headers and, in practice, the build-id note.
+// Parameters:
+// - notes: The decoded core notes.
+// - start: Start address of the object's mapping at file offset 0.
+// Returns:
+// - The build-id as a hex string, or an empty string if it was not captured.
+func readBuildIDFromCore(notes *coreNotes, start uint64) string {
+	page, err := notes.readMemory(start, 4096)
+	if err != nil || len(page) < 64 || string(page[:4]) != elf.ELFMAG {
+		return ""
+	}
+	if elf.Class(page[elf.EI_CLASS]) != elf.ELFCLASS64 || elf.Data(page[elf.EI_DATA]) != elf.ELFDATA2LSB {
+		return ""
+	}
+
+	le := binary.LittleEndian
+	phoff := le.Uint64(page[32:])
+	phentsize := uint64(le.Uint16(page[54:]))
+	phnum := uint64(le.Uint16(page[56:]))
+	for i := uint64(0); i < phnum; i++ {
+		off := phoff + i*phentsize
+		if off+56 > uint64(len(page)) {
+			break
+		}
+		if elf.ProgType(le.Uint32(page[off:])) != elf.PT_NOTE {
+			continue
+		}
+		noteOff := le.Uint64(page[off+8:])
+		noteSize := le.Uint64(page[off+32:])
+		data, err := notes.readMemory(start+noteOff, int(noteSize))
+		if err != nil {
+			continue
+		}
+		if id := parseBuildIDNotes(data); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// readBuildIDFromFile reads the GNU build-id of an ELF file on disk.
+// Parameters:
+// - path: Path to the ELF executable or shared library.
+// Returns:
+// - The build-id as a hex string, or an empty string if none is present.
+func readBuildIDFromFile(path string) string {
+	f, err := elf.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	if section := f.Section(".note.gnu.build-id"); section != nil {
+		if data, err := section.Data(); err == nil {
+			if id := parseBuildIDNotes(data); id != "" {
+				return id
+			}
+		}
+	}
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_NOTE {
+			continue
+		}
+		data, err := io.ReadAll(prog.Open())
+		if err != nil {
+			continue
+		}
+		if id := parseBuildIDNotes(data); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// candidateBinaries lists postgres binaries that may match a core, in order of preference.
+// Parameters:
+// - coreExe: Executable path recorded in the core, if any.
+// - gphome: The GPHOME environment variable, if set.
+// Returns:
+// - A slice of candidate binaries paired with their GPHOME.
+func candidateBinaries(coreExe, gphome string) [][2]string {
+	var homes []string
+	if coreExe != "" {
+		homes = append(homes, filepath.Dir(filepath.Dir(coreExe)))
+	}
+	homes = append(homes, gphomeFlags...)
+	if gphome != "" {
+		homes = append(homes, gphome)
+	}
+
+	seen := make(map[string]bool)
+	var candidates [][2]string
+	add := func(path, home string) {
+		if path == "" || seen[path] {
+			return
+		}
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			return
+		}
+		seen[path] = true
+		candidates = append(candidates, [2]string{path, home})
+	}
+
+	add(coreExe, filepath.Dir(filepath.Dir(coreExe)))
+	for _, home := range homes {
+		add(filepath.Join(home, "bin", "postgres"), home)
+	}
+	return candidates
+}
+
+// selectPostgresBinary chooses the postgres binary to debug a core against.
+// Parameters:
+// - notes: The decoded core notes, or nil if the core could not be read natively.
+// - gphome: The GPHOME environment variable, if set.
+// Returns:
+// - The selected binary and the reason it was chosen.
+// - An error if no usable binary could be found.
+func selectPostgresBinary(notes *coreNotes, gphome string) (binarySelection, error) {
+	selection := binarySelection{}
+
+	if notes != nil {
+		if mapping := coreExecutableMapping(notes); mapping != nil {
+			selection.CoreExecutable = mapping.Path
+			selection.CoreBuildID = readBuildIDFromCore(notes, mapping.Start)
+		}
+	}
+
+	if binaryOverride != "" {
+		if _, err := os.Stat(binaryOverride); err != nil {
+			return selection, fmt.Errorf("postgres binary not found at %s", binaryOverride)
+		}
+		selection.Path = binaryOverride
+		selection.GPHOME = filepath.Dir(filepath.Dir(binaryOverride))
+		selection.BuildID = readBuildIDFromFile(binaryOverride)
+		selection.Reason = "explicit --binary override"
+		if selection.CoreBuildID != "" && selection.BuildID != selection.CoreBuildID {
+			selection.Reason += fmt.Sprintf(" (build-id %s does not match core build-id %s)",
+				selection.BuildID, selection.CoreBuildID)
+		}
+		return selection, nil
+	}
+
+	candidates := candidateBinaries(selection.CoreExecutable, gphome)
+	if len(candidates) == 0 {
+		return selection, fmt.Errorf("no postgres binary found for %s: set GPHOME, --gphome or --binary", coreName(notes))
+	}
+
+	if selection.CoreBuildID != "" {
+		for _, c := range candidates {
+			if id := readBuildIDFromFile(c[0]); id == selection.CoreBuildID {
+				selection.Path, selection.GPHOME, selection.BuildID = c[0], c[1], id
+				if c[0] == selection.CoreExecutable {
+					selection.Reason = "executable recorded in core; build-id matches"
+				} else {
+					selection.Reason = fmt.Sprintf("build-id matches installation in %s", c[1])
+				}
+				return selection, nil
+			}
+		}
+	}
+
+	// No verifiable match: prefer the executable recorded in the core, then $GPHOME.
+	selection.Path, selection.GPHOME = candidates[0][0], candidates[0][1]
+	selection.BuildID = readBuildIDFromFile(selection.Path)
+	switch {
+	case selection.CoreBuildID != "":
+		selection.Reason = fmt.Sprintf("no candidate matches core build-id %s; symbols may not match", selection.CoreBuildID)
+	case selection.Path == selection.CoreExecutable:
+		selection.Reason = "executable recorded in core (no build-id captured to verify)"
+	case selection.CoreExecutable != "":
+		selection.Reason = fmt.Sprintf("executable recorded in core (%s) not found; using %s", selection.CoreExecutable, selection.GPHOME)
+	default:
+		selection.Reason = "executable could not be determined from the core; using GPHOME"
+	}
+	return selection, nil
+}
+
+// coreName returns the core path for error messages.
+func coreName(notes *coreNotes) string {
+	if notes == nil {
+		return "core"
+	}
+	return notes.Path
+}
+
+// applyBinarySelection records the binary selection in the analysis.
+// Parameters:
+// - info: A pointer to the PostgresInfo object to update.
+// - selection: The selected binary.
+func applyBinarySelection(info *PostgresInfo, selection binarySelection) {
+	info.BinaryPath = selection.Path
+	info.GPHOME = selection.GPHOME
+	info.BinarySelection = selection.Reason
+	info.CoreExecutable = selection.CoreExecutable
+	info.CoreBuildID = selection.CoreBuildID
+	info.BuildID = selection.BuildID
+}
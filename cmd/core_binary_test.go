@@ -0,0 +1,152 @@
+// File: cmd/core_binary_test.go
+package cmd
+
+import (
+	"debug/elf"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// buildIDNote builds a GNU build-id note from a hex string.
+func buildIDNote(t *testing.T, id string) testNote {
+	desc, err := hex.DecodeString(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testNote{name: "GNU", typ: ntGNUBuildID, desc: desc}
+}
+
+// writeTestExecutable writes an ELF executable carrying the given build-id and returns its contents.
+func writeTestExecutable(t *testing.T, path, buildID string) []byte {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestELF(t, path, elf.ET_DYN, []testNote{buildIDNote(t, buildID)}, nil)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// writeCoreForExecutable writes a core whose executable mapping holds exeImage.
+func writeCoreForExecutable(t *testing.T, corePath, exePath string, exeImage []byte) {
+	t.Helper()
+	const exeBase = 0x555555554000
+	writeTestCore(t, corePath, []testNote{
+		prstatusNote(4242, 100, 11, nil),
+		prpsinfoNote(4242, 100, 1000, 1000, "postgres", "postgres:  7000, con1 seg0"),
+		fileNote([]coreMappedFile{
+			{Start: exeBase, End: exeBase + 0x2000, Offset: 0, Path: exePath},
+			{Start: 0x7ffff7dd7000, End: 0x7ffff7dd8000, Offset: 0, Path: "/lib64/libc.so.6"},
+		}),
+		auxvNote(map[uint64]uint64{atEntry: exeBase + 0x100}),
+	}, []testLoad{
+		{vaddr: exeBase, data: exeImage, memsz: 0x1000, flags: elf.PF_R | elf.PF_X},
+	})
+}
+
+func TestParseBuildIDNotes(t *testing.T) {
+	id := "0123456789abcdef0123456789abcdef01234567"
+	dir := t.TempDir()
+	writeTestExecutable(t, filepath.Join(dir, "postgres"), id)
+
+	if got := readBuildIDFromFile(filepath.Join(dir, "postgres")); got != id {
+		t.Errorf("readBuildIDFromFile() = %q, want %q", got, id)
+	}
+	if got := parseBuildIDNotes([]byte("short")); got != "" {
+		t.Errorf("parseBuildIDNotes(short) = %q, want empty", got)
+	}
+}
+
+func TestSelectPostgresBinary(t *testing.T) {
+	dir := t.TempDir()
+	oldID := "1111111111111111111111111111111111111111"
+	newID := "2222222222222222222222222222222222222222"
+
+	oldHome := filepath.Join(dir, "cloudberry-1.6")
+	newHome := filepath.Join(dir, "cloudberry-2.0")
+	writeTestExecutable(t, filepath.Join(oldHome, "bin", "postgres"), oldID)
+	newImage := writeTestExecutable(t, filepath.Join(newHome, "bin", "postgres"), newID)
+
+	// The core was produced by the 2.0 build, installed under a path that no longer exists.
+	corePath := filepath.Join(dir, "core.4242")
+	writeCoreForExecutable(t, corePath, "/usr/local/cloudberry-db/bin/postgres", newImage)
+
+	notes, err := readCoreNotes(corePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	origFlags, origOverride := gphomeFlags, binaryOverride
+	defer func() { gphomeFlags, binaryOverride = origFlags, origOverride }()
+
+	tests := []struct {
+		name       string
+		gphome     string
+		gphomes    []string
+		override   string
+		wantPath   string
+		wantReason string
+		wantErr    bool
+	}{
+		{
+			name:       "build-id selects matching installation",
+			gphome:     oldHome,
+			gphomes:    []string{newHome},
+			wantPath:   filepath.Join(newHome, "bin", "postgres"),
+			wantReason: "build-id matches installation in " + newHome,
+		},
+		{
+			name:       "no matching build-id falls back to GPHOME",
+			gphome:     oldHome,
+			wantPath:   filepath.Join(oldHome, "bin", "postgres"),
+			wantReason: "no candidate matches core build-id " + newID,
+		},
+		{
+			name:       "explicit override",
+			gphome:     newHome,
+			override:   filepath.Join(oldHome, "bin", "postgres"),
+			wantPath:   filepath.Join(oldHome, "bin", "postgres"),
+			wantReason: "explicit --binary override",
+		},
+		{
+			name:    "no candidates",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gphomeFlags = tt.gphomes
+			binaryOverride = tt.override
+
+			selection, err := selectPostgresBinary(notes, tt.gphome)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if selection.Path != tt.wantPath {
+				t.Errorf("Path = %s, want %s", selection.Path, tt.wantPath)
+			}
+			if !strings.HasPrefix(selection.Reason, tt.wantReason) {
+				t.Errorf("Reason = %q, want prefix %q", selection.Reason, tt.wantReason)
+			}
+			if selection.CoreExecutable != "/usr/local/cloudberry-db/bin/postgres" {
+				t.Errorf("CoreExecutable = %s", selection.CoreExecutable)
+			}
+			if selection.CoreBuildID != newID {
+				t.Errorf("CoreBuildID = %s, want %s", selection.CoreBuildID, newID)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_cluster.go
+// Purpose: Groups cores by stack similarity for `core --compare`. Frames are normalized (compiler
+// clone suffixes, template and parameter lists, addresses) and stacks are compared with one of three
+// algorithms: exact match of the top frames, shared prefix, or edit distance over the frame sequence.
+// Each cluster reports a representative core, its members and the frames that vary between them.
+// Dependencies: Uses the frame normalization of core_signatures.go.
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Clustering algorithms accepted by --cluster.
+const (
+	clusterExact        = "exact"
+	clusterPrefix       = "prefix"
+	clusterEditDistance = "edit-distance"
+)
+
+const clusterFrames = 10 // Normalized frames compared per core
+
+var (
+	clusterAlgorithm string  // Clustering algorithm; empty disables clustering
+	clusterThreshold float64 // Minimum similarity for a core to join a cluster
+)
+
+func init() {
+	coreCmd.Flags().StringVar(&clusterAlgorithm, "cluster", "", "Cluster compared cores by stack similarity: exact, prefix or edit-distance")
+	coreCmd.Flags().Float64Var(&clusterThreshold, "cluster-threshold", 0.7, "Minimum stack similarity (0-1] for cores to share a cluster")
+}
+
+var (
+	addressRE     = regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`)
+	cloneMarkerRE = regexp.MustCompile(`\s*\[clone [^\]]*\]`)
+)
+
+// validateClusterFlags checks the clustering flags.
+// Returns:
+// - An error if the algorithm or threshold is invalid.
+func validateClusterFlags() error {
+	switch clusterAlgorithm {
+	case "", clusterExact, clusterPrefix, clusterEditDistance:
+	default:
+		return fmt.Errorf("invalid cluster algorithm: %s. Valid options are 'exact', 'prefix' or 'edit-distance'", clusterAlgorithm)
+	}
+	if clusterThreshold <= 0 || clusterThreshold > 1 {
+		return fmt.Errorf("invalid cluster threshold: %g. Must be greater than 0 and at most 1", clusterThreshold)
+	}
+	return nil
+}
+
+// stripBracketed removes every balanced open...close group, including nested ones.
+func stripBracketed(s string, open, close byte) string {
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == open:
+			depth++
+		case s[i] == close && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// clusterFrameName normalizes a frame's function for similarity comparison, so that
+// `ExecHashJoin.isra.0`, `foo<int>(bar*)` and `foo<long>(baz*) [clone .cold]` compare by name.
+// Parameters:
+// - name: A function name from a stack frame.
+// Returns:
+// - The normalized name.
+func clusterFrameName(name string) string {
+	name = cloneMarkerRE.ReplaceAllString(name, "")
+	name = addressRE.ReplaceAllString(name, "")
+	if !strings.HasPrefix(name, "operator") {
+		name = stripBracketed(name, '<', '>')
+	}
+	if i := strings.Index(name, "("); i > 0 {
+		name = name[:i]
+	}
+	return normalizeFrameFunction(name)
+}
+
+// clusterStack returns the normalized frames of an analysis used for clustering.
+// Signal handler, system and unresolved frames are skipped.
+func clusterStack(analysis CoreAnalysis) []string {
+	var frames []string
+	for _, frame := range analysis.StackTrace {
+		name := clusterFrameName(frame.Function)
+		if name == "" || name == "??" || isSignalHandlerFrame(name) || isSystemFunction(name) {
+			continue
+		}
+		frames = append(frames, name)
+		if len(frames) == clusterFrames {
+			break
+		}
+	}
+	return frames
+}
+
+// stackSimilarity scores how alike two normalized stacks are.
+// Parameters:
+// - a, b: The normalized frames, innermost first.
+// - algorithm: One of clusterExact, clusterPrefix or clusterEditDistance.
+// Returns:
+// - A similarity between 0 (unrelated) and 1 (identical).
+func stackSimilarity(a, b []string, algorithm string) float64 {
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+	if longest == 0 {
+		return 1
+	}
+
+	switch algorithm {
+	case clusterPrefix:
+		common := 0
+		for common < len(a) && common < len(b) && a[common] == b[common] {
+			common++
+		}
+		return float64(common) / float64(longest)
+	case clusterEditDistance:
+		return 1 - float64(frameEditDistance(a, b))/float64(longest)
+	}
+
+	n := fingerprintFrames
+	if len(a) < n || len(b) < n {
+		n = longest
+	}
+	if len(a) < n || len(b) < n {
+		return 0
+	}
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return 0
+		}
+	}
+	return 1
+}
+
+// frameEditDistance is the Levenshtein distance between two frame sequences.
+func frameEditDistance(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// clusterCores groups cores whose stacks are at least --cluster-threshold similar.
+// Each core joins the most similar existing cluster of the same signal, compared against the
+// cluster's first core; the representative is then the member most similar to all others.
+// Parameters:
+// - analyses: The analyses to cluster.
+// - algorithm: One of clusterExact, clusterPrefix or clusterEditDistance.
+// - threshold: The minimum similarity for a core to join a cluster.
+// Returns:
+// - The clusters, largest first.
+func clusterCores(analyses []CoreAnalysis, algorithm string, threshold float64) []CrashCluster {
+	type member struct {
+		core   string
+		signal string
+		frames []string
+	}
+	members := make([]member, len(analyses))
+	for i, analysis := range analyses {
+		members[i] = member{analysis.CoreFile, analysis.SignalInfo.SignalName, clusterStack(analysis)}
+	}
+	sort.SliceStable(members, func(i, j int) bool { return members[i].core < members[j].core })
+
+	var groups [][]member
+	for _, m := range members {
+		best, bestScore := -1, 0.0
+		for i, group := range groups {
+			if group[0].signal != m.signal {
+				continue
+			}
+			if score := stackSimilarity(group[0].frames, m.frames, algorithm); score >= threshold && score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			groups = append(groups, []member{m})
+			continue
+		}
+		groups[best] = append(groups[best], m)
+	}
+
+	clusters := make([]CrashCluster, 0, len(groups))
+	for _, group := range groups {
+		// The representative (medoid) is the member with the highest total similarity
+		rep, repScore := 0, -1.0
+		for i := range group {
+			total := 0.0
+			for j := range group {
+				total += stackSimilarity(group[i].frames, group[j].frames, algorithm)
+			}
+			if total > repScore {
+				rep, repScore = i, total
+			}
+		}
+
+		cluster := CrashCluster{
+			Signal:         group[rep].signal,
+			Representative: group[rep].core,
+			Frames:         group[rep].frames,
+			Size:           len(group),
+		}
+		for _, m := range group {
+			cluster.Members = append(cluster.Members, ClusterMember{
+				CoreFile:   m.core,
+				Similarity: stackSimilarity(group[rep].frames, m.frames, algorithm),
+			})
+		}
+		stacks := make([][]string, len(group))
+		for i, m := range group {
+			stacks[i] = m.frames
+		}
+		cluster.VaryingFrames = varyingFrames(group[rep].frames, stacks)
+		clusters = append(clusters, cluster)
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool { return clusters[i].Size > clusters[j].Size })
+	for i := range clusters {
+		clusters[i].ID = i + 1
+	}
+	return clusters
+}
+
+// varyingFrames lists the frames that appear in some but not all of a cluster's stacks.
+// Parameters:
+// - representative: The representative's frames, which order the result.
+// - stacks: Every member's frames.
+// Returns:
+// - The varying frames: the representative's first, then the others alphabetically.
+func varyingFrames(representative []string, stacks [][]string) []string {
+	counts := make(map[string]int)
+	for _, frames := range stacks {
+		seen := make(map[string]bool)
+		for _, f := range frames {
+			if !seen[f] {
+				seen[f] = true
+				counts[f]++
+			}
+		}
+	}
+	members := len(stacks)
+
+	var varying, others []string
+	listed := make(map[string]bool)
+	for _, f := range representative {
+		if counts[f] < members && !listed[f] {
+			varying = append(varying, f)
+			listed[f] = true
+		}
+	}
+	for f, n := range counts {
+		if n < members && !listed[f] {
+			others = append(others, f)
+		}
+	}
+	sort.Strings(others)
+	return append(varying, others...)
+}
@@ -0,0 +1,117 @@
+// File: cmd/core_cluster_test.go
+package cmd
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClusterFrameName(t *testing.T) {
+	tests := map[string]string{
+		"ExecHashJoin.isra.0":                              "ExecHashJoin",
+		"ExecHashJoinImpl.constprop.3":                     "ExecHashJoinImpl",
+		"gpopt::CMemoryPool<int>::Allocate(unsigned long)": "gpopt::CMemoryPool::Allocate",
+		"foo<std::pair<int, long> >(bar*) [clone .cold]":   "foo",
+		"operator<<":         "operator<<",
+		"0x00007f1c2b8a4921": "",
+	}
+	for in, want := range tests {
+		if got := clusterFrameName(in); got != want {
+			t.Errorf("clusterFrameName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestStackSimilarity(t *testing.T) {
+	a := []string{"heap_fetch", "index_fetch_heap", "IndexNext", "ExecScan", "ExecProcNode", "ExecutePlan"}
+	inlined := []string{"heap_fetch", "heapam_index_fetch_tuple", "index_fetch_heap", "IndexNext", "ExecScan", "ExecProcNode", "ExecutePlan"}
+	deeper := []string{"heap_fetch", "index_fetch_heap", "IndexNext", "ExecScan", "ExecProcNode", "ExecHashJoin"}
+
+	if got := stackSimilarity(a, a, clusterExact); got != 1 {
+		t.Errorf("exact(a, a) = %v", got)
+	}
+	if got := stackSimilarity(a, inlined, clusterExact); got != 0 {
+		t.Errorf("exact(a, inlined) = %v", got)
+	}
+	if got := stackSimilarity(a, deeper, clusterExact); got != 1 {
+		t.Errorf("exact(a, deeper) = %v, want 1: only the top frames count", got)
+	}
+	if got := stackSimilarity(a, inlined, clusterPrefix); got != 1.0/7 {
+		t.Errorf("prefix(a, inlined) = %v", got)
+	}
+	if got := stackSimilarity(a, deeper, clusterPrefix); got != 5.0/6 {
+		t.Errorf("prefix(a, deeper) = %v", got)
+	}
+	if got := stackSimilarity(a, inlined, clusterEditDistance); math.Abs(got-6.0/7) > 1e-9 {
+		t.Errorf("edit-distance(a, inlined) = %v", got)
+	}
+	if got := frameEditDistance([]string{"a", "b", "c"}, []string{"b", "c", "d"}); got != 2 {
+		t.Errorf("frameEditDistance = %d, want 2", got)
+	}
+}
+
+func TestClusterCores(t *testing.T) {
+	analyses := []CoreAnalysis{
+		newAnalysis("core.1", "SIGSEGV", testFrames("raise", "heap_fetch", "index_fetch_heap", "IndexNext", "ExecScan", "ExecutePlan")),
+		newAnalysis("core.2", "SIGSEGV", testFrames("heap_fetch.isra.0", "heapam_index_fetch_tuple", "index_fetch_heap", "IndexNext", "ExecScan", "ExecutePlan")),
+		newAnalysis("core.3", "SIGSEGV", testFrames("heap_fetch", "index_fetch_heap", "IndexNext", "ExecScan", "ExecutePlan")),
+		newAnalysis("core.4", "SIGABRT", testFrames("heap_fetch", "index_fetch_heap", "IndexNext", "ExecScan", "ExecutePlan")),
+		newAnalysis("core.5", "SIGSEGV", testFrames("AllocSetAlloc", "palloc", "MemoryContextStrdup", "set_ps_display")),
+	}
+
+	clusters := clusterCores(analyses, clusterEditDistance, 0.7)
+	if len(clusters) != 3 {
+		t.Fatalf("clusters = %+v, want 3", clusters)
+	}
+	top := clusters[0]
+	if top.ID != 1 || top.Size != 3 || top.Signal != "SIGSEGV" {
+		t.Errorf("top cluster = %+v", top)
+	}
+	if top.Representative != "core.1" {
+		t.Errorf("Representative = %q, want core.1", top.Representative)
+	}
+	if !reflect.DeepEqual(top.VaryingFrames, []string{"heapam_index_fetch_tuple"}) {
+		t.Errorf("VaryingFrames = %v", top.VaryingFrames)
+	}
+	for _, m := range top.Members {
+		if m.CoreFile == "core.2" && (m.Similarity >= 1 || m.Similarity < 0.7) {
+			t.Errorf("core.2 similarity = %v", m.Similarity)
+		}
+	}
+
+	// Exact matching keeps the inlined variant apart
+	if clusters := clusterCores(analyses, clusterExact, 1); len(clusters) != 4 {
+		t.Errorf("exact clusters = %d, want 4", len(clusters))
+	}
+}
+
+func TestCompareCoresClusters(t *testing.T) {
+	oldAlgorithm, oldThreshold := clusterAlgorithm, clusterThreshold
+	defer func() { clusterAlgorithm, clusterThreshold = oldAlgorithm, oldThreshold }()
+
+	analyses := []CoreAnalysis{
+		newAnalysis("core.1", "SIGSEGV", testFrames("heap_fetch", "IndexNext")),
+		newAnalysis("core.2", "SIGSEGV", testFrames("heap_fetch.isra.0", "IndexNext")),
+	}
+	clusterAlgorithm = ""
+	if got := compareCores(analyses); got.Clusters != nil {
+		t.Errorf("clusters without --cluster: %+v", got.Clusters)
+	}
+
+	clusterAlgorithm, clusterThreshold = clusterPrefix, 0.5
+	got := compareCores(analyses)
+	if len(got.Clusters) != 1 || got.Clusters[0].Size != 2 || !strings.HasPrefix(got.ClusterMethod, "prefix") {
+		t.Errorf("comparison = %+v", got)
+	}
+
+	clusterAlgorithm = "fuzzy"
+	if err := validateClusterFlags(); err == nil || !strings.Contains(err.Error(), "invalid cluster algorithm") {
+		t.Errorf("validateClusterFlags() = %v", err)
+	}
+	clusterAlgorithm, clusterThreshold = clusterExact, 0
+	if err := validateClusterFlags(); err == nil {
+		t.Error("threshold 0 accepted")
+	}
+}
@@ -5,6 +5,8 @@ import (
    "path/filepath"
    "strings"
    "testing"
+
+   "gopkg.in/yaml.v2"
 )
 
 func TestCoreCommand(t *testing.T) {
@@ -85,6 +87,7 @@ _sigfault = {si_addr = 0x0}`,                         // gdb output
        format      string
        expectError bool
        errorMsg    string
+       coreError   string // Error recorded for the core in the run manifest
    }{
        {
            name: "no args",
@@ -98,7 +101,7 @@ _sigfault = {si_addr = 0x0}`,                         // gdb output
            errorMsg: "please specify a core file or directory",
        },
        {
-           name: "missing GPHOME",
+           name: "missing GPHOME and no binary in core",
            args: []string{"core", mockCorePath},
            envVars: map[string]string{
                "GPHOME": "",
@@ -106,7 +109,8 @@ _sigfault = {si_addr = 0x0}`,                         // gdb output
            outputDir: mockOutputDir,
            format: "yaml",
            expectError: true, 
-           errorMsg: "GPHOME environment variable must be set",
+           errorMsg: "no core files were analyzed successfully",
+           coreError: "no postgres binary found for core: set GPHOME, --gphome or --binary", // The mock core has no notes to name it by
        },
        {
            name: "valid args with GPHOME",
@@ -184,6 +188,9 @@ _sigfault = {si_addr = 0x0}`,                         // gdb output
                } else if !strings.Contains(err.Error(), tt.errorMsg) {
                    t.Errorf("error = %q, want %q", err.Error(), tt.errorMsg)
                }
+               if tt.coreError != "" {
+                   checkManifestCoreError(t, tt.outputDir, mockCorePath, tt.coreError)
+               }
                return
            }
 
@@ -203,3 +210,30 @@ _sigfault = {si_addr = 0x0}`,                         // gdb output
        })
    }
 }
+
+// checkManifestCoreError verifies that a run manifest in dir records why a core failed.
+func checkManifestCoreError(t *testing.T, dir, corePath, want string) {
+   t.Helper()
+   manifests, _ := filepath.Glob(filepath.Join(dir, "run_manifest_*.yaml"))
+   var errs []string
+   for _, path := range manifests {
+       data, err := os.ReadFile(path)
+       if err != nil {
+           t.Fatal(err)
+       }
+       var manifest RunManifest
+       if err := yaml.Unmarshal(data, &manifest); err != nil {
+           t.Fatalf("parsing %s: %v", path, err)
+       }
+       for _, core := range manifest.Cores {
+           if core.CoreFile != corePath {
+               continue
+           }
+           if strings.Contains(core.Error, want) {
+               return
+           }
+           errs = append(errs, core.Error)
+       }
+   }
+   t.Errorf("core errors in run manifests = %q, want %q", errs, want)
+}
@@ -0,0 +1,388 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_compress.go
+// Purpose: Analyzes compressed cores, such as the zstd cores systemd-coredump stores and gzipped
+// archives. A compressed core is recognized by its magic bytes and streamed into a private
+// directory of the scratch area (--scratch-dir), which is checked for free space before and while
+// decompressing. The uncompressed copy is removed as soon as its analysis ends; copies left by a
+// run that was killed are removed by the next run.
+// Dependencies: gzip is decompressed with Go's standard library; zstd, lz4 and xz cores need the
+// zstd, lz4 or xz command.
+
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var scratchDir string // Directory compressed cores are decompressed into; empty means the system temporary directory
+
+func init() {
+	coreCmd.PersistentFlags().StringVar(&scratchDir, "scratch-dir", "", "Directory to decompress compressed cores into (default the system temporary directory)")
+}
+
+const (
+	// scratchPrefix starts the name of every scratch directory, followed by the owning process ID.
+	scratchPrefix = "cbtoolbox-core-"
+
+	// scratchReserve is the free space left in the scratch area for everything else on its file system.
+	scratchReserve = 512 << 20
+
+	// scratchCheckInterval is how much is written between free-space checks while decompressing.
+	scratchCheckInterval = 64 << 20
+
+	// compressionHeaderSize is the part of a file read to recognize its compression and content size.
+	compressionHeaderSize = 18
+)
+
+// compressionFormat is a compression format a core can be stored in.
+type compressionFormat struct {
+	name  string
+	magic []byte
+	ext   string   // Conventional file name extension
+	tool  []string // Command decompressing a file to stdout; nil if decompressed in-process
+}
+
+// compressionFormats are the recognized compression formats.
+var compressionFormats = []compressionFormat{
+	{name: "gzip", magic: []byte{0x1f, 0x8b}, ext: ".gz"},
+	{name: "zstd", magic: []byte{0x28, 0xb5, 0x2f, 0xfd}, ext: ".zst", tool: []string{"zstd", "-d", "-c", "-q"}},
+	{name: "lz4", magic: []byte{0x04, 0x22, 0x4d, 0x18}, ext: ".lz4", tool: []string{"lz4", "-d", "-c", "-q"}},
+	{name: "xz", magic: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, ext: ".xz", tool: []string{"xz", "-d", "-c", "-q"}},
+}
+
+// scratchFreeSpace returns the free space of the scratch area. It is a variable so tests can
+// simulate a full disk.
+var scratchFreeSpace = diskFreeSpace
+
+var (
+	sweptScratchMu   sync.Mutex
+	sweptScratchDirs = make(map[string]bool) // Scratch areas already swept by this process
+)
+
+// detectCompression recognizes a compressed core by its magic bytes.
+// Parameters:
+// - path: The file to check.
+// Returns:
+// - The compression format, or nil if the file is not compressed in a recognized format.
+// - The uncompressed size recorded in the header, or 0 if the header does not record it.
+func detectCompression(path string) (*compressionFormat, int64) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0
+	}
+	defer f.Close()
+
+	header := make([]byte, compressionHeaderSize)
+	n, _ := io.ReadFull(f, header)
+	header = header[:n]
+	for i := range compressionFormats {
+		if format := &compressionFormats[i]; bytes.HasPrefix(header, format.magic) {
+			return format, contentSize(format.name, header)
+		}
+	}
+	return nil, 0
+}
+
+// contentSize reads the uncompressed size from the first frame header of a zstd or lz4 stream.
+// gzip records the size only modulo 4 GiB and xz only in its index at the end, so neither is read.
+// Parameters:
+// - format: The compression format name.
+// - header: The start of the file.
+// Returns:
+// - The uncompressed size, or 0 if unknown.
+func contentSize(format string, header []byte) int64 {
+	switch format {
+	case "zstd":
+		// Frame_Header_Descriptor: Frame_Content_Size_flag (2 bits), Single_Segment_flag,
+		// unused, reserved, Content_Checksum_flag, Dictionary_ID_flag (2 bits)
+		if len(header) < 5 {
+			return 0
+		}
+		fhd := header[4]
+		singleSegment := fhd&0x20 != 0
+		offset := 5
+		if !singleSegment {
+			offset++ // Window_Descriptor
+		}
+		offset += []int{0, 1, 2, 4}[fhd&0x03]
+		size := []int{0, 2, 4, 8}[fhd>>6]
+		if size == 0 && singleSegment {
+			size = 1
+		}
+		if size == 0 || len(header) < offset+size {
+			return 0
+		}
+		field := header[offset : offset+size]
+		switch size {
+		case 1:
+			return int64(field[0])
+		case 2:
+			return int64(binary.LittleEndian.Uint16(field)) + 256
+		case 4:
+			return int64(binary.LittleEndian.Uint32(field))
+		default:
+			return int64(binary.LittleEndian.Uint64(field))
+		}
+	case "lz4":
+		// FLG bit 3 is the Content Size flag; the size follows the BD byte
+		if len(header) < 14 || header[4]&0x08 == 0 {
+			return 0
+		}
+		return int64(binary.LittleEndian.Uint64(header[6:14]))
+	}
+	return 0
+}
+
+// analyzeCompressedCore decompresses a core into the scratch area, analyzes the copy and removes it.
+// The analysis reports the compressed file as the core, with its compressed and uncompressed sizes.
+// Parameters:
+// - ctx: Context bounding the analysis; cancelling it stops decompression.
+// - corePath: Path to the compressed core.
+// - format: The core's compression format.
+// - size: The uncompressed size recorded in the header, or 0 if unknown.
+// - gphome: Path to the default PostgreSQL/Cloudberry installation ($GPHOME); may be empty.
+// Returns:
+// - The analysis of the uncompressed core.
+// - An error if the core cannot be decompressed or analyzed.
+func analyzeCompressedCore(ctx context.Context, corePath string, format *compressionFormat, size int64, gphome string) (CoreAnalysis, error) {
+	analysis := CoreAnalysis{
+		Timestamp: time.Now().Format(time.RFC3339),
+		CoreFile:  corePath,
+	}
+	fileInfo, err := os.Stat(corePath)
+	if err != nil {
+		return analysis, err
+	}
+
+	uncompressed, cleanup, err := decompressCore(ctx, corePath, format, size)
+	if err != nil {
+		return analysis, err
+	}
+	defer cleanup()
+
+	analysis, err = analyzeCoreFile(ctx, uncompressed, gphome)
+	analysis.CoreFile = corePath
+	analysis.FileInfo.FileOutput = strings.ReplaceAll(analysis.FileInfo.FileOutput, uncompressed, corePath)
+	analysis.FileInfo.Created = fileInfo.ModTime().Format(time.RFC3339)
+	analysis.FileInfo.Compression = format.name
+	analysis.FileInfo.CompressedSize = fileInfo.Size()
+	return analysis, err
+}
+
+// decompressCore streams a compressed core into a new directory of the scratch area.
+// Parameters:
+// - ctx: Context bounding the decompression.
+// - corePath: Path to the compressed core.
+// - format: The core's compression format.
+// - size: The uncompressed size recorded in the header, or 0 if unknown.
+// Returns:
+// - The path of the uncompressed copy.
+// - A function removing the copy.
+// - An error if the scratch area lacks space, the decompressor is missing or decompression fails.
+func decompressCore(ctx context.Context, corePath string, format *compressionFormat, size int64) (string, func(), error) {
+	dir := scratchDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	sweepStaleScratch(dir)
+
+	if free, err := scratchFreeSpace(dir); err == nil && free < uint64(size)+scratchReserve {
+		need := fmt.Sprintf("%s plus %s reserve", formatBytes(uint64(size)), formatBytes(scratchReserve))
+		if size == 0 {
+			need = formatBytes(scratchReserve) + " reserve"
+		}
+		return "", nil, fmt.Errorf("not enough space in %s to decompress %s: %s free, need %s; use --scratch-dir",
+			dir, corePath, formatBytes(free), need)
+	}
+
+	tmpDir, err := os.MkdirTemp(dir, fmt.Sprintf("%s%d-*", scratchPrefix, os.Getpid()))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
+
+	name := filepath.Base(corePath)
+	if trimmed := strings.TrimSuffix(name, format.ext); trimmed != "" {
+		name = trimmed
+	}
+	uncompressed := filepath.Join(tmpDir, name)
+	out, err := os.OpenFile(uncompressed, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to create scratch file: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := &scratchWriter{file: out, dir: dir, abort: cancel}
+	if format.tool == nil {
+		err = decompressInProcess(ctx, corePath, w)
+	} else {
+		err = decompressWithTool(ctx, corePath, format, w)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to decompress %s core %s: %w", format.name, corePath, err)
+	}
+	return uncompressed, cleanup, nil
+}
+
+// decompressInProcess decompresses a gzip core, including multi-member files.
+// Parameters:
+// - ctx: Context checked between reads.
+// - corePath: Path to the compressed core.
+// - w: The scratch file.
+// Returns:
+// - An error if reading, decompressing or writing fails.
+func decompressInProcess(ctx context.Context, corePath string, w io.Writer) error {
+	in, err := os.Open(corePath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	_, err = io.Copy(w, contextReader{ctx: ctx, r: zr})
+	return err
+}
+
+// decompressWithTool decompresses a core by streaming a decompressor's stdout to the scratch file.
+// Parameters:
+// - ctx: Context bounding the decompressor; cancelling it kills the process.
+// - corePath: Path to the compressed core.
+// - format: The core's compression format.
+// - w: The scratch file; it kills the decompressor when the scratch area fills up.
+// Returns:
+// - An error if the decompressor is missing or fails.
+func decompressWithTool(ctx context.Context, corePath string, format *compressionFormat, w *scratchWriter) error {
+	args := append(append([]string(nil), format.tool[1:]...), "--", corePath)
+	cmd := exec.CommandContext(ctx, format.tool[0], args...)
+	var stderr bytes.Buffer
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	switch {
+	case w.err != nil:
+		return w.err
+	case errors.Is(err, exec.ErrNotFound):
+		return fmt.Errorf("%s is not installed: %w", format.tool[0], err)
+	case err != nil && stderr.Len() > 0:
+		return fmt.Errorf("%s: %w: %s", format.tool[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
+
+// scratchWriter writes an uncompressed core to the scratch area, stopping before it fills the
+// file system: every scratchCheckInterval bytes the free space is checked against scratchReserve.
+type scratchWriter struct {
+	file    *os.File
+	dir     string
+	abort   func() // Stops the decompressor
+	written int64
+	checked int64 // Bytes written at the last free-space check
+	err     error
+}
+
+// Write writes to the scratch file, failing once the scratch area is nearly full.
+func (w *scratchWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.written-w.checked >= scratchCheckInterval {
+		w.checked = w.written
+		if free, err := scratchFreeSpace(w.dir); err == nil && free < scratchReserve {
+			w.err = fmt.Errorf("scratch area %s is nearly full (%s free) after writing %s; use --scratch-dir",
+				w.dir, formatBytes(free), formatBytes(uint64(w.written)))
+			w.abort()
+			return 0, w.err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.written += int64(n)
+	if err != nil {
+		w.err = err
+		w.abort()
+	}
+	return n, err
+}
+
+// contextReader stops reading once its context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read reads from the underlying reader unless the context is done.
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// sweepStaleScratch removes scratch directories left by runs that are no longer alive, such as
+// runs killed before they could clean up. Each scratch area is swept once per process.
+// Parameters:
+// - dir: The scratch area.
+func sweepStaleScratch(dir string) {
+	sweptScratchMu.Lock()
+	defer sweptScratchMu.Unlock()
+	if sweptScratchDirs[dir] {
+		return
+	}
+	sweptScratchDirs[dir] = true
+
+	matches, _ := filepath.Glob(filepath.Join(dir, scratchPrefix+"*"))
+	for _, match := range matches {
+		owner, _, ok := strings.Cut(strings.TrimPrefix(filepath.Base(match), scratchPrefix), "-")
+		pid, err := strconv.Atoi(owner)
+		if !ok || err != nil || pid <= 0 || pid == os.Getpid() || processAlive(pid) {
+			continue
+		}
+		os.RemoveAll(match)
+	}
+}
+
+// compressionSummary describes how a core was stored.
+// Parameters:
+// - info: The core's file information.
+// Returns:
+// - The format and sizes, or an empty string for an uncompressed core.
+func compressionSummary(info FileInfo) string {
+	if info.Compression == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s, %s (%s uncompressed)", info.Compression,
+		formatBytes(uint64(info.CompressedSize)), formatBytes(uint64(info.Size)))
+}
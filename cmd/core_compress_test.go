@@ -0,0 +1,223 @@
+// File: cmd/core_compress_test.go
+package cmd
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// useScratchDir points the scratch area at a fresh directory for the duration of a test.
+func useScratchDir(t *testing.T) string {
+	t.Helper()
+	old := scratchDir
+	t.Cleanup(func() { scratchDir = old })
+	scratchDir = t.TempDir()
+	return scratchDir
+}
+
+// compressTestFile compresses a file with the named format.
+func compressTestFile(t *testing.T, format, src, dst string) {
+	t.Helper()
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	if format == "gzip" {
+		zw := gzip.NewWriter(out)
+		if _, err := zw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if _, err := exec.LookPath(format); err != nil {
+		t.Skipf("%s is not installed", format)
+	}
+	cmd := exec.Command(format, "-c", "-q", src)
+	cmd.Stdout = out
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("%s: %v", format, err)
+	}
+}
+
+func TestDetectCompression(t *testing.T) {
+	le := binary.LittleEndian
+	zstd4 := append([]byte{0x28, 0xb5, 0x2f, 0xfd, 0xa4}, le.AppendUint32(nil, 70000)...)
+	lz4 := append([]byte{0x04, 0x22, 0x4d, 0x18, 0x6c, 0x70}, le.AppendUint64(nil, 1<<33)...)
+	tests := []struct {
+		name   string
+		header []byte
+		format string
+		size   int64
+	}{
+		{"gzip", []byte{0x1f, 0x8b, 0x08, 0x00}, "gzip", 0},
+		{"zstd with 4-byte content size", zstd4, "zstd", 70000},
+		{"zstd with 2-byte content size", []byte{0x28, 0xb5, 0x2f, 0xfd, 0x64, 0x2c, 0x00}, "zstd", 300},
+		{"zstd with 1-byte content size", []byte{0x28, 0xb5, 0x2f, 0xfd, 0x24, 0x80}, "zstd", 128},
+		{"zstd from a pipe", []byte{0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x58, 0x81}, "zstd", 0},
+		{"lz4 with content size", lz4, "lz4", 1 << 33},
+		{"lz4 without content size", []byte{0x04, 0x22, 0x4d, 0x18, 0x64, 0x40, 0xa7}, "lz4", 0},
+		{"xz", []byte{0xfd, '7', 'z', 'X', 'Z', 0x00, 0x00, 0x04}, "xz", 0},
+		{"ELF core", []byte("\x7fELF\x02\x01\x01"), "", 0},
+		{"truncated magic", []byte{0x28, 0xb5}, "", 0},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_"))
+			if err := os.WriteFile(path, tt.header, 0644); err != nil {
+				t.Fatal(err)
+			}
+			format, size := detectCompression(path)
+			name := ""
+			if format != nil {
+				name = format.name
+			}
+			if name != tt.format {
+				t.Errorf("format = %q, want %q", name, tt.format)
+			}
+			if size != tt.size {
+				t.Errorf("size = %d, want %d", size, tt.size)
+			}
+		})
+	}
+}
+
+func TestAnalyzeCompressedCore(t *testing.T) {
+	mock := &MockCommander{}
+	oldCmdExecutor := cmdExecutor
+	SetCommander(mock)
+	defer SetCommander(oldCmdExecutor)
+
+	for _, format := range []string{"gzip", "zstd", "lz4", "xz"} {
+		t.Run(format, func(t *testing.T) {
+			scratch := useScratchDir(t)
+			tmpDir := t.TempDir()
+			raw := filepath.Join(tmpDir, "core.4242")
+			writeTestCore(t, raw, []testNote{
+				prstatusNote(4242, 100, 11, map[string]uint64{"rip": 0x555555555000}),
+				siginfoNote(11, 1, 0x8),
+			}, nil)
+			corePath := raw + ".compressed"
+			compressTestFile(t, format, raw, corePath)
+			rawInfo, _ := os.Stat(raw)
+			coreInfo, _ := os.Stat(corePath)
+
+			analysis, err := analyzeCoreFile(context.Background(), corePath, filepath.Join(tmpDir, "missing-gphome"))
+			if err != nil {
+				t.Fatalf("analyzeCoreFile() error = %v", err)
+			}
+			if analysis.CoreFile != corePath {
+				t.Errorf("CoreFile = %q, want %q", analysis.CoreFile, corePath)
+			}
+			fi := analysis.FileInfo
+			if fi.Compression != format || fi.Size != rawInfo.Size() || fi.CompressedSize != coreInfo.Size() {
+				t.Errorf("FileInfo = %+v, want %s, %d uncompressed, %d compressed", fi, format, rawInfo.Size(), coreInfo.Size())
+			}
+			if analysis.SignalInfo.SignalNumber != 11 || analysis.SignalInfo.FaultAddress != "0x8" {
+				t.Errorf("unexpected signal info: %+v", analysis.SignalInfo)
+			}
+			if entries, _ := os.ReadDir(scratch); len(entries) != 0 {
+				t.Errorf("scratch area not cleaned up: %v", entries)
+			}
+		})
+	}
+}
+
+func TestDecompressCoreScratchSpace(t *testing.T) {
+	scratch := useScratchDir(t)
+	corePath := filepath.Join(t.TempDir(), "core.1.gz")
+	if err := os.WriteFile(corePath+".raw", []byte("core contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	compressTestFile(t, "gzip", corePath+".raw", corePath)
+	format, _ := detectCompression(corePath)
+
+	old := scratchFreeSpace
+	defer func() { scratchFreeSpace = old }()
+
+	// Too little space for the declared size plus the reserve
+	scratchFreeSpace = func(string) (uint64, error) { return scratchReserve + 100, nil }
+	if _, _, err := decompressCore(context.Background(), corePath, format, 1000); err == nil || !strings.Contains(err.Error(), "not enough space") {
+		t.Errorf("decompressCore() error = %v, want not enough space", err)
+	}
+
+	// The same space suffices for a smaller core
+	path, cleanup, err := decompressCore(context.Background(), corePath, format, 13)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "core contents" || filepath.Base(path) != "core.1" {
+		t.Errorf("decompressed %s = %q", path, data)
+	}
+	cleanup()
+	if entries, _ := os.ReadDir(scratch); len(entries) != 0 {
+		t.Errorf("scratch area not cleaned up: %v", entries)
+	}
+
+	// The scratch area filling up while decompressing stops the decompressor
+	scratchFreeSpace = func(string) (uint64, error) { return scratchReserve - 1, nil }
+	aborted := false
+	w := &scratchWriter{file: os.Stdout, dir: scratch, abort: func() { aborted = true }, checked: -scratchCheckInterval}
+	if _, err := w.Write([]byte("x")); err == nil || !aborted {
+		t.Errorf("Write() error = %v, aborted %v", err, aborted)
+	}
+}
+
+func TestSweepStaleScratch(t *testing.T) {
+	dir := t.TempDir()
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Skip("true is not available")
+	}
+	stale := filepath.Join(dir, scratchPrefix+strconv.Itoa(exited.ProcessState.Pid())+"-1")
+	own := filepath.Join(dir, scratchPrefix+strconv.Itoa(os.Getpid())+"-1")
+	other := filepath.Join(dir, "unrelated")
+	for _, d := range []string{stale, own, other} {
+		if err := os.Mkdir(d, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sweepStaleScratch(dir)
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("stale scratch directory was not removed")
+	}
+	for _, d := range []string{own, other} {
+		if _, err := os.Stat(d); err != nil {
+			t.Errorf("%s was removed", d)
+		}
+	}
+}
+
+func TestDiscoverCompressedCores(t *testing.T) {
+	useDiscoveryFlags(t, "", "", "", nil, nil)
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "raw")
+	writeTestCore(t, raw, nil, nil)
+	compressTestFile(t, "gzip", raw, filepath.Join(dir, "core.postgres.4242.gz"))
+	if err := os.WriteFile(filepath.Join(dir, "core.txt"), []byte("not a core"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := discoverCoreFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := relativePaths(t, dir, files); len(got) != 1 || got[0] != "core.postgres.4242.gz" {
+		t.Errorf("discoverCoreFiles() = %v", got)
+	}
+}
@@ -0,0 +1,89 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_demangle.go
+// Purpose: Demangles C++ symbols (Itanium C++ ABI, as emitted by GCC and Clang) so GPORCA frames read
+// like the source: _ZN5gpopt11CExpression5ArityEv becomes gpopt::CExpression::Arity(). The work is
+// left to c++filt from GNU Binutils, which is installed wherever gdb is; the symbols of an analysis
+// are demangled in one run. Without c++filt, symbols are left mangled.
+// Dependencies: Runs c++filt through cmdExecutor.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const cxxFiltCommand = "c++filt"
+
+// demangledSymbol is a symbol as c++filt prints it.
+type demangledSymbol struct {
+	full string // With parameter types and clone suffixes: gpopt::CEngine::Optimize() [clone .isra.0]
+	name string // The qualified name alone, followed by any clone suffix: gpopt::CEngine::Optimize.isra.0
+}
+
+// demangleSymbols demangles Itanium C++ ABI symbols, including GCC clone suffixes such as .isra.0.
+// Parameters:
+// - ctx: Context for c++filt.
+// - symbols: The mangled symbols, each starting with _Z.
+// Returns:
+// - The demangled symbols by mangled symbol, leaving out those c++filt cannot demangle (all if it cannot run).
+func demangleSymbols(ctx context.Context, symbols []string) map[string]demangledSymbol {
+	demangled := make(map[string]demangledSymbol)
+	if len(symbols) == 0 {
+		return demangled
+	}
+	full, err := runCxxFilt(ctx, nil, symbols)
+	if err != nil {
+		return demangled
+	}
+	names, err := runCxxFilt(ctx, []string{"-p"}, symbols)
+	if err != nil {
+		return demangled
+	}
+
+	for i, symbol := range symbols {
+		// c++filt prints what it cannot demangle unchanged
+		if full[i] == symbol {
+			continue
+		}
+		suffix := ""
+		if j := strings.IndexByte(symbol, '.'); j >= 0 {
+			suffix = symbol[j:]
+		}
+		demangled[symbol] = demangledSymbol{full: full[i], name: names[i] + suffix}
+	}
+	return demangled
+}
+
+// runCxxFilt runs c++filt on a list of symbols.
+// Parameters:
+// - ctx: Context for c++filt.
+// - flags: Options for c++filt; -p leaves out parameter types and clone suffixes.
+// - symbols: The symbols to demangle.
+// Returns:
+// - One line of output per symbol.
+// - An error if c++filt fails or prints a different number of lines.
+func runCxxFilt(ctx context.Context, flags, symbols []string) ([]string, error) {
+	args := append(append(append([]string(nil), flags...), "--"), symbols...)
+	output, err := cmdExecutor.Execute(ctx, cxxFiltCommand, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", cxxFiltCommand, err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+	if len(lines) != len(symbols) {
+		return nil, fmt.Errorf("%s printed %d lines for %d symbols", cxxFiltCommand, len(lines), len(symbols))
+	}
+	return lines, nil
+}
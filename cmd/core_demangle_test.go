@@ -0,0 +1,186 @@
+// File: cmd/core_demangle_test.go
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestDemangleSymbol(t *testing.T) {
+	// Expected values are c++filt's output
+	tests := []struct {
+		symbol, full, name string
+	}{
+		{"_ZN5gpopt11CExpression5ArityEv", "gpopt::CExpression::Arity()", "gpopt::CExpression::Arity"},
+		{"_ZNK5gpopt11CExpression5ArityEv", "gpopt::CExpression::Arity() const", "gpopt::CExpression::Arity"},
+		{"_ZN5gpopt11CXformUtils28FProcessGPDBAntiSemiHashJoinEPN4gpos11CMemoryPoolEPNS_11CExpressionES5_",
+			"gpopt::CXformUtils::FProcessGPDBAntiSemiHashJoin(gpos::CMemoryPool*, gpopt::CExpression*, gpopt::CExpression*)",
+			"gpopt::CXformUtils::FProcessGPDBAntiSemiHashJoin"},
+		{"_ZN4gpos10CException5RaiseEPKcjjjz",
+			"gpos::CException::Raise(char const*, unsigned int, unsigned int, unsigned int, ...)", "gpos::CException::Raise"},
+		{"_ZN5gpopt9CMemoTreeD2Ev", "gpopt::CMemoTree::~CMemoTree()", "gpopt::CMemoTree::~CMemoTree"},
+		{"_ZN4gpos16CDynamicPtrArrayIN5gpopt11CExpressionEXadL_ZNS_14CleanupReleaseIS2_EEvPT_EEE6AppendEPS2_",
+			"gpos::CDynamicPtrArray<gpopt::CExpression, &(void gpos::CleanupRelease<gpopt::CExpression>(gpopt::CExpression*))>::Append(gpopt::CExpression*)",
+			"gpos::CDynamicPtrArray<gpopt::CExpression, &(void gpos::CleanupRelease<gpopt::CExpression>(gpopt::CExpression*))>::Append"},
+		{"_ZNSt6vectorIiSaIiEE9push_backERKi", "std::vector<int, std::allocator<int> >::push_back(int const&)",
+			"std::vector<int, std::allocator<int> >::push_back"},
+		{"_ZN5gpopt7CEngine8OptimizeEv.constprop.0.isra.0",
+			"gpopt::CEngine::Optimize() [clone .constprop.0] [clone .isra.0]", "gpopt::CEngine::Optimize.constprop.0.isra.0"},
+		{"_ZTVN5gpopt7CEngineE", "vtable for gpopt::CEngine", "vtable for gpopt::CEngine"},
+		{"_ZN12_GLOBAL__N_13fooEv", "(anonymous namespace)::foo()", "(anonymous namespace)::foo"},
+		{"_Z3barPFviE", "bar(void (*)(int))", "bar"},
+		{"_Z3bazPA10_i", "baz(int (*) [10])", "baz"},
+		{"_ZZN5gpopt3Foo3barEvENKUlvE_clEv", "gpopt::Foo::bar()::{lambda()#1}::operator()() const",
+			"gpopt::Foo::bar()::{lambda()#1}::operator()"},
+		{"_ZStlsISt11char_traitsIcEERSt13basic_ostreamIcT_ES5_PKc",
+			"std::basic_ostream<char, std::char_traits<char> >& std::operator<< <std::char_traits<char> >(std::basic_ostream<char, std::char_traits<char> >&, char const*)",
+			"std::operator<< <std::char_traits<char> >"},
+		{"_ZN4llvm12is_containedIRA41_KjjEEbOT_RKT0_",
+			"bool llvm::is_contained<unsigned int const (&) [41], unsigned int>(unsigned int const (&) [41], unsigned int const&)",
+			"llvm::is_contained<unsigned int const (&) [41], unsigned int>"},
+		{"_ZN4llvm12hash_combineIJPvjjEEENS_9hash_codeEDpRKT_",
+			"llvm::hash_code llvm::hash_combine<void*, unsigned int, unsigned int>(void* const&, unsigned int const&, unsigned int const&)",
+			"llvm::hash_combine<void*, unsigned int, unsigned int>"},
+		{"_ZNSt8ios_base7failureB5cxx11C1EPKcRKSt10error_code",
+			"std::ios_base::failure[abi:cxx11]::failure(char const*, std::error_code const&)",
+			"std::ios_base::failure[abi:cxx11]::failure"},
+
+		// An empty pack leaves no ", " at the end of a list, but does elsewhere, and then no
+		// space goes between the closing brackets
+		{"_ZN4llvm10NewGVNPass3runERNS_8FunctionERNS_15AnalysisManagerIS1_JEEE",
+			"llvm::NewGVNPass::run(llvm::Function&, llvm::AnalysisManager<llvm::Function>&)", "llvm::NewGVNPass::run"},
+		{"_Z1fIJEEviDpT_i", "void f<>(int, , int)", "f<>"},
+		{"_Z1fIJEEvDpT_i", "void f<>(, int)", "f<>"},
+		{"_ZN1AIN1BIiEEJEE1gEv", "A<B<int>>::g()", "A<B<int>>::g"},
+
+		// A qualifier the template argument already has is printed once
+		{"_ZN4llvm22containsIrreducibleCFGIPKNS_10BasicBlockENS_13LoopBlocksRPOENS_8LoopInfoENS_11GraphTraitsIS3_EEEEbRT0_RKT1_",
+			"bool llvm::containsIrreducibleCFG<llvm::BasicBlock const*, llvm::LoopBlocksRPO, llvm::LoopInfo, llvm::GraphTraits<llvm::BasicBlock const*> >(llvm::LoopBlocksRPO&, llvm::LoopInfo const&)",
+			"llvm::containsIrreducibleCFG<llvm::BasicBlock const*, llvm::LoopBlocksRPO, llvm::LoopInfo, llvm::GraphTraits<llvm::BasicBlock const*> >"},
+		{"_Z1fIKiEvPKT_", "void f<int const>(int const*)", "f<int const>"},
+
+		// A lambda is a substitution candidate only with the scope it is local to
+		{"_ZN4llvm12handleErrorsIJZNKS_6object13ELFObjectFileINS1_7ELFTypeILNS_7support10endiannessE1ELb0EEEE15getSectionIndexENS1_11DataRefImplEEUlRKNS_13ErrorInfoBaseEE_EEENS_5ErrorESD_DpOT_",
+			"llvm::Error llvm::handleErrors<llvm::object::ELFObjectFile<llvm::object::ELFType<(llvm::support::endianness)1, false> >::getSectionIndex(llvm::object::DataRefImpl) const::{lambda(llvm::ErrorInfoBase const&)#1}>(llvm::Error, llvm::object::ELFObjectFile<llvm::object::ELFType<(llvm::support::endianness)1, false> >::getSectionIndex(llvm::object::DataRefImpl) const::{lambda(llvm::ErrorInfoBase const&)#1}&&)",
+			"llvm::handleErrors<llvm::object::ELFObjectFile<llvm::object::ELFType<(llvm::support::endianness)1, false> >::getSectionIndex(llvm::object::DataRefImpl) const::{lambda(llvm::ErrorInfoBase const&)#1}>"},
+
+		// The address of a qualified function drops its parameters
+		{"_Z1fIXadL_ZN1A1gEvEEEvv", "void f<&A::g>()", "f<&A::g>"},
+		{"_Z1fIXadL_Z1gvEEEvv", "void f<&(g())>()", "f<&(g())>"},
+
+		{"_Z1fPFPFilEcE", "f(int (*(*)(char))(long))", "f"},
+		{"_ZN1AUt_D2Ev", "A::{unnamed type#1}::~A()", "A::{unnamed type#1}::~A"},
+		{"_Z1fIM1AKFvvEEvT_S1_", "void f<void (A::*)() const>(void (A::*)() const, void () const)", "f<void (A::*)() const>"},
+	}
+	symbols := make([]string, len(tests))
+	for i, tt := range tests {
+		symbols[i] = tt.symbol
+	}
+	// C names, truncated and malformed symbols are left alone
+	undecodable := []string{"_Z", "_ZN5gpopt", "_ZN5gpopt11CExpressionE9Ari", "_Z1fS9_", "_ZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZZ"}
+
+	requireCxxFilt(t)
+	demangled := demangleSymbols(context.Background(), append(symbols, undecodable...))
+	for _, tt := range tests {
+		got, ok := demangled[tt.symbol]
+		if !ok || got.full != tt.full || got.name != tt.name {
+			t.Errorf("demangleSymbols(%s) = %q, %q, %v\nwant %q, %q", tt.symbol, got.full, got.name, ok, tt.full, tt.name)
+		}
+	}
+	for _, symbol := range undecodable {
+		if got, ok := demangled[symbol]; ok {
+			t.Errorf("demangleSymbols(%s) = %q, want it left mangled", symbol, got.full)
+		}
+	}
+}
+
+func TestDemangleSymbolsWithoutCxxFilt(t *testing.T) {
+	oldExecutor := cmdExecutor
+	defer SetCommander(oldExecutor)
+	symbols := []string{"_ZN5gpopt11CExpression5ArityEv", "_ZN5gpopt7CEngine8OptimizeEv.isra.0"}
+
+	// c++filt missing or printing something unexpected leaves every symbol mangled
+	for name, mock := range map[string]*MockCommander{
+		"not installed": {Outputs: []string{""}, Errors: []error{exec.ErrNotFound}},
+		"short output":  {Outputs: []string{"gpopt::CExpression::Arity()\n"}, Errors: []error{nil}},
+		"no -p support": {Outputs: []string{"a()\nb()\n", ""}, Errors: []error{nil, errors.New("exit status 1")}},
+	} {
+		SetCommander(mock)
+		if demangled := demangleSymbols(context.Background(), symbols); len(demangled) != 0 {
+			t.Errorf("%s: demangleSymbols() = %v, want none", name, demangled)
+		}
+	}
+
+	// The clone suffix c++filt -p drops is put back on the name
+	mock := &MockCommander{
+		Outputs: []string{
+			"gpopt::CExpression::Arity()\ngpopt::CEngine::Optimize() [clone .isra.0]\n",
+			"gpopt::CExpression::Arity\ngpopt::CEngine::Optimize\n",
+		},
+		Errors: []error{nil, nil},
+	}
+	SetCommander(mock)
+	demangled := demangleSymbols(context.Background(), symbols)
+	if got := demangled[symbols[1]]; got.full != "gpopt::CEngine::Optimize() [clone .isra.0]" || got.name != "gpopt::CEngine::Optimize.isra.0" {
+		t.Errorf("demangleSymbols(%s) = %+v", symbols[1], got)
+	}
+	if cmds := mock.GetCommands(); len(cmds) != 2 || !strings.HasPrefix(cmds[1], "c++filt -p -- ") {
+		t.Errorf("commands = %v, want c++filt then c++filt -p", cmds)
+	}
+}
+
+// requireCxxFilt skips a test that needs c++filt where it is not installed.
+func requireCxxFilt(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath(cxxFiltCommand); err != nil {
+		t.Skip("c++filt not installed")
+	}
+}
+
+// TestDemangleCorpus checks demangling against c++filt's recorded output on symbols sampled from
+// system libraries, so a c++filt that is run wrongly or prints something else is noticed.
+func TestDemangleCorpus(t *testing.T) {
+	requireCxxFilt(t)
+	file, err := os.Open("testdata/demangle_corpus.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var symbols, wants []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		symbol, want, found := strings.Cut(line, "\t")
+		if !found {
+			t.Fatalf("malformed corpus line %q", line)
+		}
+		symbols = append(symbols, symbol)
+		wants = append(wants, want)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(symbols) == 0 {
+		t.Fatal("empty corpus")
+	}
+
+	demangled := demangleSymbols(context.Background(), symbols)
+	for i, symbol := range symbols {
+		got, ok := demangled[symbol]
+		switch {
+		case wants[i] == symbol && ok:
+			t.Errorf("demangleSymbols(%s) = %q, want it left mangled", symbol, got.full)
+		case wants[i] != symbol && got.full != wants[i]:
+			t.Errorf("demangleSymbols(%s)\n got %s\nwant %s", symbol, got.full, wants[i])
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_discovery.go
+// Purpose: Finds the core files to analyze under a directory. The tree is walked recursively,
+// candidates are matched against file name patterns (the defaults or --pattern) and --exclude,
+// deduplicated, filtered by modification time (--since, --until) and size (--min-size), checked to
+// be ELF core files or compressed, and ordered most recent first so --max-cores keeps the newest cores.
+// Dependencies: Uses Go's standard libraries for directory walking and file inspection.
+
+package cmd
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	sinceFlag    string   // Only cores modified at or after this time
+	untilFlag    string   // Only cores modified before this time
+	minSizeFlag  string   // Only cores at least this large
+	patternFlags []string // File name patterns replacing coreFileNamePatterns
+	excludeFlags []string // Patterns of files and directories to skip
+
+	// Parsed by validateDiscoveryFlags
+	discoverySince   time.Time
+	discoveryUntil   time.Time
+	discoveryMinSize int64
+)
+
+func init() {
+	// core watch searches its directories the same way
+	for _, c := range []*cobra.Command{coreCmd, coreWatchCmd} {
+		c.Flags().StringVar(&sinceFlag, "since", "", "Only analyze cores modified at or after this time (RFC 3339, YYYY-MM-DD[ HH:MM] or a duration such as 24h)")
+		c.Flags().StringVar(&untilFlag, "until", "", "Only analyze cores modified before this time (same formats as --since)")
+		c.Flags().StringVar(&minSizeFlag, "min-size", "", "Only analyze cores at least this large (e.g. 4096, 512K, 10MB)")
+		c.Flags().StringSliceVar(&patternFlags, "pattern", nil, "Core file name pattern to search for, replacing the defaults (repeatable)")
+		c.Flags().StringSliceVar(&excludeFlags, "exclude", nil, "Pattern of file or directory names, or paths relative to the search directory, to skip (repeatable)")
+	}
+}
+
+// elfHeaderSize is the part of the ELF header read to recognize a core file.
+const elfHeaderSize = 18
+
+// validateDiscoveryFlags parses the discovery filters.
+// Returns:
+// - An error if a time, size or pattern is invalid.
+func validateDiscoveryFlags() error {
+	var err error
+	if discoverySince, err = parseDiscoveryTime(sinceFlag, time.Now()); err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	if discoveryUntil, err = parseDiscoveryTime(untilFlag, time.Now()); err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+	if !discoverySince.IsZero() && !discoveryUntil.IsZero() && !discoveryUntil.After(discoverySince) {
+		return fmt.Errorf("invalid time range: --until %s is not after --since %s", untilFlag, sinceFlag)
+	}
+	if discoveryMinSize, err = parseByteSize(minSizeFlag); err != nil {
+		return fmt.Errorf("invalid --min-size: %w", err)
+	}
+	for _, pattern := range append(append([]string(nil), patternFlags...), excludeFlags...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// parseDiscoveryTime parses a --since or --until value.
+// Parameters:
+// - value: An RFC 3339 time, a local date or date and time, or a duration before now.
+// - now: The time durations count back from.
+// Returns:
+// - The time, or the zero time if value is empty.
+// - An error if the value is in none of the accepted formats.
+func parseDiscoveryTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a time, date or duration", value)
+}
+
+// parseByteSize parses a size such as 4096, 512K, 10MB or 1GiB; units are powers of 1024.
+// Parameters:
+// - value: The size; empty means no limit.
+// Returns:
+// - The size in bytes.
+// - An error if the value is not a size.
+func parseByteSize(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	s := strings.ToUpper(strings.TrimSpace(value))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	multiplier := int64(1)
+	if n := len(s); n > 0 {
+		if shift := strings.IndexByte("KMGT", s[n-1]); shift >= 0 {
+			multiplier = 1 << (10 * (shift + 1))
+			s = s[:n-1]
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a size", value)
+	}
+	return n * multiplier, nil
+}
+
+// coreCandidate is a file found during discovery.
+type coreCandidate struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// discoveredCores is the outcome of a directory search.
+type discoveredCores struct {
+	cores      []string // ELF core files and compressed files, most recent first
+	incomplete []string // Files too short to hold an ELF header, which may still be being written
+	skipped    int      // Files that are neither ELF core files nor compressed
+}
+
+// discoverCoreFiles locates the core files to analyze and applies the --since, --until and
+// --min-size filters and the ELF core check to directory searches. Compressed files are kept
+// without decompressing them; --min-size applies to their compressed size.
+// Parameters:
+// - path: A core file or a directory to search.
+// Returns:
+// - The core files, most recent first.
+// - An error if path does not exist.
+func discoverCoreFiles(path string) ([]string, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	// A core named on the command line is analyzed whatever it looks like
+	if !fileInfo.IsDir() {
+		return []string{path}, nil
+	}
+
+	found := searchCoreFiles(path)
+	if skipped := found.skipped + len(found.incomplete); skipped > 0 {
+		fmt.Printf("Skipping %d file(s) that are neither ELF core files nor compressed\n", skipped)
+	}
+	return found.cores, nil
+}
+
+// searchCoreFiles searches a directory for core files passing the discovery filters and sorts
+// them by their header. A file too short to hold an ELF header is reported apart, so a watcher
+// can wait for it to be written rather than dismiss it.
+// Parameters:
+// - root: The directory to search.
+// Returns:
+// - The core files found, incomplete files and the number of files skipped.
+func searchCoreFiles(root string) discoveredCores {
+	var found discoveredCores
+	for _, c := range walkCoreCandidates(root) {
+		switch {
+		case !matchesDiscoveryFilters(c):
+			continue
+		case c.size < elfHeaderSize:
+			found.incomplete = append(found.incomplete, c.path)
+		case isCoreOrCompressed(c.path):
+			found.cores = append(found.cores, c.path)
+		default:
+			found.skipped++
+		}
+	}
+	return found
+}
+
+// matchesDiscoveryFilters reports whether a candidate passes --since, --until and --min-size.
+// Parameters:
+// - c: The candidate.
+// Returns:
+// - True if the candidate is within the time window and large enough.
+func matchesDiscoveryFilters(c coreCandidate) bool {
+	return (discoverySince.IsZero() || !c.modTime.Before(discoverySince)) &&
+		(discoveryUntil.IsZero() || c.modTime.Before(discoveryUntil)) &&
+		c.size >= discoveryMinSize
+}
+
+// isCoreOrCompressed reports whether a file is an ELF core file or a compressed file.
+// Parameters:
+// - path: The file to check.
+// Returns:
+// - True if the file's header is that of an ELF core file or a supported compression format.
+func isCoreOrCompressed(path string) bool {
+	if format, _ := detectCompression(path); format != nil {
+		return true
+	}
+	return isELFCore(path)
+}
+
+// coreNamePatterns returns the file name patterns searched for: --pattern, or the defaults.
+func coreNamePatterns() []string {
+	if len(patternFlags) > 0 {
+		return patternFlags
+	}
+	return coreFileNamePatterns
+}
+
+// walkCoreCandidates walks a directory tree for files matching the core file name patterns.
+// Unreadable directories are skipped. Symbolic links to files are followed, and a file reached
+// through several names is returned once.
+// Parameters:
+// - root: The directory to search.
+// Returns:
+// - The candidates, most recently modified first, then by path.
+func walkCoreCandidates(root string) []coreCandidate {
+	patterns := coreNamePatterns()
+
+	var candidates []coreCandidate
+	seen := make(map[string]bool)
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			fmt.Printf("Skipping %s: %v\n", path, err)
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		if path != root && matchesAny(excludeFlags, d.Name(), rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !matchesAny(patterns, d.Name(), rel) {
+			return nil
+		}
+
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		key := path
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			key = resolved
+		}
+		if seen[key] {
+			return nil
+		}
+		seen[key] = true
+		candidates = append(candidates, coreCandidate{path: path, modTime: info.ModTime(), size: info.Size()})
+		return nil
+	})
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if !candidates[i].modTime.Equal(candidates[j].modTime) {
+			return candidates[i].modTime.After(candidates[j].modTime)
+		}
+		return candidates[i].path < candidates[j].path
+	})
+	return candidates
+}
+
+// matchesAny reports whether a file's name, or its path relative to the search directory,
+// matches one of the patterns. Patterns containing a separator are matched against the path.
+// Parameters:
+// - patterns: Glob patterns as accepted by filepath.Match.
+// - name: The file's base name.
+// - rel: The file's path relative to the search directory.
+// Returns:
+// - True if a pattern matches.
+func matchesAny(patterns []string, name, rel string) bool {
+	for _, pattern := range patterns {
+		target := name
+		if strings.ContainsRune(pattern, filepath.Separator) {
+			target = rel
+		}
+		if ok, _ := filepath.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isELFCore reports whether a file is an ELF core file, reading only its header.
+// Parameters:
+// - path: The file to check.
+// Returns:
+// - True if the file starts with an ELF header of type ET_CORE.
+func isELFCore(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	header := make([]byte, elfHeaderSize)
+	if _, err := io.ReadFull(f, header); err != nil || !bytes.HasPrefix(header, []byte("\x7fELF")) {
+		return false
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if elf.Data(header[elf.EI_DATA]) == elf.ELFDATA2MSB {
+		order = binary.BigEndian
+	}
+	return elf.Type(order.Uint16(header[16:])) == elf.ET_CORE
+}
@@ -0,0 +1,206 @@
+// File: cmd/core_discovery_test.go
+package cmd
+
+import (
+	"debug/elf"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// useDiscoveryFlags sets the discovery flags for the duration of a test.
+func useDiscoveryFlags(t *testing.T, since, until, minSize string, patterns, excludes []string) {
+	t.Helper()
+	old := []interface{}{sinceFlag, untilFlag, minSizeFlag, patternFlags, excludeFlags}
+	t.Cleanup(func() {
+		sinceFlag, untilFlag, minSizeFlag = old[0].(string), old[1].(string), old[2].(string)
+		patternFlags, excludeFlags = old[3].([]string), old[4].([]string)
+		validateDiscoveryFlags()
+	})
+	sinceFlag, untilFlag, minSizeFlag, patternFlags, excludeFlags = since, until, minSize, patterns, excludes
+	if err := validateDiscoveryFlags(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// discoveryTree creates core files of different ages, sizes and kinds under a directory.
+// It returns the directory and the base time the ages count back from.
+func discoveryTree(t *testing.T) (string, time.Time) {
+	t.Helper()
+	dir := t.TempDir()
+	now := time.Now().Truncate(time.Second)
+	files := []struct {
+		name string
+		age  time.Duration
+		kind string
+	}{
+		{"core.1", 3 * time.Hour, "core"},
+		{"seg0/core.2", time.Hour, "core"},
+		{"seg0/deep/er/core.3", 2 * time.Hour, "core"},
+		{"seg1/postgres.core", 30 * time.Minute, "core"},
+		{"archive/core.4", time.Minute, "core"},
+		{"core.notes", 10 * time.Minute, "text"},
+		{"core.exec", 20 * time.Minute, "exec"},
+		{"postgres.core.5", 5 * time.Hour, "core"},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		switch f.kind {
+		case "core":
+			writeTestCore(t, path, nil, nil)
+		case "exec":
+			writeTestELF(t, path, elf.ET_EXEC, nil, nil)
+		default:
+			if err := os.WriteFile(path, []byte("not a core"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		mtime := now.Add(-f.age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A second name for a core is not analyzed twice
+	if err := os.Symlink(filepath.Join(dir, "core.1"), filepath.Join(dir, "core.link")); err != nil {
+		t.Fatal(err)
+	}
+	return dir, now
+}
+
+// relativePaths strips the search directory from discovered paths.
+func relativePaths(t *testing.T, dir string, paths []string) []string {
+	t.Helper()
+	rel := make([]string, len(paths))
+	for i, p := range paths {
+		r, err := filepath.Rel(dir, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel[i] = filepath.ToSlash(r)
+	}
+	return rel
+}
+
+func TestDiscoverCoreFiles(t *testing.T) {
+	dir, now := discoveryTree(t)
+
+	tests := []struct {
+		name     string
+		since    string
+		until    string
+		minSize  string
+		patterns []string
+		excludes []string
+		want     []string
+	}{
+		{
+			name: "recursive, newest first, ELF cores only",
+			want: []string{"archive/core.4", "seg1/postgres.core", "seg0/core.2", "seg0/deep/er/core.3", "core.1"},
+		},
+		{
+			name:  "time window",
+			since: now.Add(-150 * time.Minute).Format(time.RFC3339),
+			until: now.Add(-30 * time.Minute).Format(time.RFC3339),
+			want:  []string{"seg0/core.2", "seg0/deep/er/core.3"},
+		},
+		{
+			name:  "since a duration ago",
+			since: "45m",
+			want:  []string{"archive/core.4", "seg1/postgres.core"},
+		},
+		{
+			name:    "minimum size",
+			minSize: "1M",
+		},
+		{
+			name:     "custom pattern",
+			patterns: []string{"postgres.core*"},
+			want:     []string{"seg1/postgres.core", "postgres.core.5"},
+		},
+		{
+			name:     "excluded directory and path",
+			excludes: []string{"archive", "seg0/deep"},
+			want:     []string{"seg1/postgres.core", "seg0/core.2", "core.1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useDiscoveryFlags(t, tt.since, tt.until, tt.minSize, tt.patterns, tt.excludes)
+			files, err := discoverCoreFiles(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := relativePaths(t, dir, files); len(got)+len(tt.want) > 0 && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("discoverCoreFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A file named on the command line is analyzed even if it does not look like a core
+	useDiscoveryFlags(t, "", "", "", nil, nil)
+	named := filepath.Join(dir, "core.notes")
+	if files, err := discoverCoreFiles(named); err != nil || len(files) != 1 || files[0] != named {
+		t.Errorf("discoverCoreFiles(file) = %v, %v", files, err)
+	}
+}
+
+func TestDiscoveryFlagParsing(t *testing.T) {
+	sizes := map[string]int64{"": 0, "4096": 4096, "512K": 512 << 10, "10MB": 10 << 20, "1GiB": 1 << 30, "2 kb": 2048}
+	for value, want := range sizes {
+		if got, err := parseByteSize(value); err != nil || got != want {
+			t.Errorf("parseByteSize(%q) = %d, %v, want %d", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"big", "-1", "1X"} {
+		if _, err := parseByteSize(value); err == nil {
+			t.Errorf("parseByteSize(%q) accepted an invalid size", value)
+		}
+	}
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	if got, _ := parseDiscoveryTime("36h", now); !got.Equal(now.Add(-36 * time.Hour)) {
+		t.Errorf("duration = %v", got)
+	}
+	if got, _ := parseDiscoveryTime("2026-10-01", now); got.Year() != 2026 || got.Day() != 1 || got.Hour() != 0 {
+		t.Errorf("date = %v", got)
+	}
+	if got, _ := parseDiscoveryTime("2026-10-01T08:30:00Z", now); !got.Equal(time.Date(2026, 10, 1, 8, 30, 0, 0, time.UTC)) {
+		t.Errorf("RFC 3339 = %v", got)
+	}
+
+	useDiscoveryFlags(t, "", "", "", nil, nil)
+	invalid := []struct{ since, until, minSize, pattern string }{
+		{since: "yesterday"},
+		{until: "10/01/2026"},
+		{since: "2026-10-08", until: "2026-10-01"},
+		{minSize: "huge"},
+		{pattern: "core.["},
+	}
+	for _, tt := range invalid {
+		sinceFlag, untilFlag, minSizeFlag, patternFlags = tt.since, tt.until, tt.minSize, nil
+		if tt.pattern != "" {
+			patternFlags = []string{tt.pattern}
+		}
+		if err := validateDiscoveryFlags(); err == nil {
+			t.Errorf("validateDiscoveryFlags accepted %+v", tt)
+		}
+	}
+}
+
+func TestIsELFCore(t *testing.T) {
+	dir := t.TempDir()
+	core, exec, text := filepath.Join(dir, "core"), filepath.Join(dir, "exec"), filepath.Join(dir, "text")
+	writeTestCore(t, core, nil, nil)
+	writeTestELF(t, exec, elf.ET_EXEC, nil, nil)
+	if err := os.WriteFile(text, []byte("\x7fELF"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !isELFCore(core) || isELFCore(exec) || isELFCore(text) || isELFCore(filepath.Join(dir, "missing")) {
+		t.Error("isELFCore misclassified a file")
+	}
+}
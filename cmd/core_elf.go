@@ -0,0 +1,521 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_elf.go
+// Purpose: Implements a pure-Go reader for Linux ELF core files.
+// It decodes the PT_NOTE segments of a core (NT_PRSTATUS, NT_PRPSINFO, NT_SIGINFO, NT_FILE
+// and NT_AUXV) and reads process memory from PT_LOAD segments, so that basic facts about a
+// crash are available even on hosts where `file` and `gdb` are not installed.
+// Dependencies: Uses Go's standard `debug/elf` and `encoding/binary` packages.
+
+package cmd
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Core note types not defined by debug/elf.
+const (
+	ntPRStatus = 1          // NT_PRSTATUS: per-thread status and registers
+	ntPRPSInfo = 3          // NT_PRPSINFO: process information
+	ntAuxv     = 6          // NT_AUXV: auxiliary vector
+	ntSigInfo  = 0x53494749 // NT_SIGINFO: siginfo_t of the fatal signal
+	ntFile     = 0x46494c45 // NT_FILE: file-backed memory mappings
+
+	atExecFn = 31 // AT_EXECFN: address of the executable's file name
+)
+
+// Register layouts of `struct user_regs_struct` as stored in NT_PRSTATUS.
+var (
+	prstatusRegsAMD64 = []string{
+		"r15", "r14", "r13", "r12", "rbp", "rbx", "r11", "r10", "r9", "r8",
+		"rax", "rcx", "rdx", "rsi", "rdi", "orig_rax", "rip", "cs", "eflags",
+		"rsp", "ss", "fs_base", "gs_base", "ds", "es", "fs", "gs",
+	}
+	prstatusRegsARM64 = func() []string {
+		regs := make([]string, 0, 34)
+		for i := 0; i <= 30; i++ {
+			regs = append(regs, fmt.Sprintf("x%d", i))
+		}
+		return append(regs, "sp", "pc", "cpsr")
+	}()
+)
+
+// Offsets into the 64-bit Linux note payloads.
+const (
+	prstatusPIDOffset  = 32  // pr_pid, followed by pr_ppid, pr_pgrp, pr_sid
+	prstatusRegsOffset = 112 // pr_reg
+	prpsinfoUIDOffset  = 16  // pr_uid, followed by pr_gid, pr_pid, pr_ppid
+	prpsinfoFNameOff   = 40  // pr_fname[16]
+	prpsinfoArgsOff    = 56  // pr_psargs[80]
+)
+
+// coreNotes holds the decoded contents of an ELF core file.
+type coreNotes struct {
+	Path     string
+	Machine  elf.Machine
+	Threads  []corePRStatus
+	Process  *corePRPSInfo
+	SigInfo  *coreSigInfo
+	Files    []coreMappedFile
+	Auxv     map[uint64]uint64
+	Segments []coreSegment
+}
+
+// corePRStatus is the per-thread state from an NT_PRSTATUS note.
+type corePRStatus struct {
+	Signal    int
+	PID       int
+	PPID      int
+	Registers map[string]uint64
+	RegOrder  []string
+}
+
+// corePRPSInfo is the process information from an NT_PRPSINFO note.
+type corePRPSInfo struct {
+	State  byte
+	UID    int
+	GID    int
+	PID    int
+	PPID   int
+	FName  string
+	PSArgs string
+	Title  string // Full process title recovered from memory, or PSArgs
+}
+
+// coreSigInfo is the siginfo_t of the fatal signal from an NT_SIGINFO note.
+type coreSigInfo struct {
+	Signo     int
+	Errno     int
+	Code      int
+	Addr      uint64
+	SenderPID int
+	SenderUID int
+}
+
+// coreMappedFile is a file-backed mapping from an NT_FILE note.
+type coreMappedFile struct {
+	Start  uint64
+	End    uint64
+	Offset uint64 // File offset in bytes
+	Path   string
+}
+
+// coreSegment is a PT_LOAD segment of the core.
+type coreSegment struct {
+	Vaddr  uint64
+	Memsz  uint64
+	Filesz uint64
+	Offset uint64
+	Flags  elf.ProgFlag
+}
+
+// readCoreNotes decodes the notes and load segments of an ELF core file.
+// Parameters:
+// - path: Path to the core file.
+// Returns:
+// - A coreNotes object with the decoded information.
+// - An error if the file is not a supported ELF core file.
+func readCoreNotes(path string) (*coreNotes, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ELF file: %w", err)
+	}
+	defer f.Close()
+
+	if f.Type != elf.ET_CORE {
+		return nil, fmt.Errorf("%s is not a core file (type %s)", path, f.Type)
+	}
+	if f.Class != elf.ELFCLASS64 || f.ByteOrder != binary.LittleEndian {
+		return nil, fmt.Errorf("unsupported core format: %s %s", f.Class, f.Data)
+	}
+
+	notes := &coreNotes{
+		Path:    path,
+		Machine: f.Machine,
+		Auxv:    make(map[uint64]uint64),
+	}
+
+	for _, prog := range f.Progs {
+		switch prog.Type {
+		case elf.PT_LOAD:
+			notes.Segments = append(notes.Segments, coreSegment{
+				Vaddr:  prog.Vaddr,
+				Memsz:  prog.Memsz,
+				Filesz: prog.Filesz,
+				Offset: prog.Off,
+				Flags:  prog.Flags,
+			})
+		case elf.PT_NOTE:
+			data, err := io.ReadAll(prog.Open())
+			if err != nil {
+				return nil, fmt.Errorf("failed to read note segment: %w", err)
+			}
+			notes.parseNoteSegment(data)
+		}
+	}
+
+	if notes.Process != nil {
+		notes.Process.Title = notes.recoverProcessTitle()
+	}
+
+	return notes, nil
+}
+
+// parseNoteSegment walks the notes of a PT_NOTE segment.
+func (n *coreNotes) parseNoteSegment(data []byte) {
+	le := binary.LittleEndian
+	for len(data) >= 12 {
+		namesz := int(le.Uint32(data[0:]))
+		descsz := int(le.Uint32(data[4:]))
+		noteType := le.Uint32(data[8:])
+
+		nameEnd := 12 + align4(namesz)
+		descEnd := nameEnd + align4(descsz)
+		if nameEnd+descsz > len(data) {
+			return
+		}
+		name := strings.TrimRight(string(data[12:12+namesz]), "\x00")
+		desc := data[nameEnd : nameEnd+descsz]
+
+		if name == "CORE" {
+			switch noteType {
+			case ntPRStatus:
+				n.parsePRStatus(desc)
+			case ntPRPSInfo:
+				n.parsePRPSInfo(desc)
+			case ntSigInfo:
+				n.parseSigInfo(desc)
+			case ntFile:
+				n.parseFileNote(desc)
+			case ntAuxv:
+				n.parseAuxv(desc)
+			}
+		}
+
+		if descEnd > len(data) {
+			return
+		}
+		data = data[descEnd:]
+	}
+}
+
+// align4 rounds n up to a multiple of four.
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// parsePRStatus decodes an NT_PRSTATUS note.
+func (n *coreNotes) parsePRStatus(desc []byte) {
+	if len(desc) < prstatusRegsOffset {
+		return
+	}
+	le := binary.LittleEndian
+	status := corePRStatus{
+		Signal:    int(le.Uint16(desc[12:])),
+		PID:       int(int32(le.Uint32(desc[prstatusPIDOffset:]))),
+		PPID:      int(int32(le.Uint32(desc[prstatusPIDOffset+4:]))),
+		Registers: make(map[string]uint64),
+	}
+
+	var layout []string
+	switch n.Machine {
+	case elf.EM_X86_64:
+		layout = prstatusRegsAMD64
+	case elf.EM_AARCH64:
+		layout = prstatusRegsARM64
+	}
+	for i, reg := range layout {
+		off := prstatusRegsOffset + i*8
+		if off+8 > len(desc) {
+			break
+		}
+		status.Registers[reg] = le.Uint64(desc[off:])
+		status.RegOrder = append(status.RegOrder, reg)
+	}
+
+	n.Threads = append(n.Threads, status)
+}
+
+// parsePRPSInfo decodes an NT_PRPSINFO note.
+func (n *coreNotes) parsePRPSInfo(desc []byte) {
+	if len(desc) < prpsinfoArgsOff+80 {
+		return
+	}
+	le := binary.LittleEndian
+	n.Process = &corePRPSInfo{
+		State:  desc[1],
+		UID:    int(le.Uint32(desc[prpsinfoUIDOffset:])),
+		GID:    int(le.Uint32(desc[prpsinfoUIDOffset+4:])),
+		PID:    int(int32(le.Uint32(desc[prpsinfoUIDOffset+8:]))),
+		PPID:   int(int32(le.Uint32(desc[prpsinfoUIDOffset+12:]))),
+		FName:  cString(desc[prpsinfoFNameOff : prpsinfoFNameOff+16]),
+		PSArgs: strings.TrimSpace(cString(desc[prpsinfoArgsOff : prpsinfoArgsOff+80])),
+	}
+}
+
+// fields converts the note to the fields decoded from gdb's $_siginfo.
+func (s *coreSigInfo) fields() siginfoFields {
+	fields := siginfoFields{Signo: s.Signo, Errno: s.Errno, Code: s.Code}
+	if s.Code <= 0 {
+		uid := s.SenderUID
+		fields.SenderPID, fields.SenderUID = s.SenderPID, &uid
+	} else {
+		fields.Fault = &SignalFault{Address: fmt.Sprintf("0x%x", s.Addr)}
+	}
+	return fields
+}
+
+// parseSigInfo decodes an NT_SIGINFO note.
+func (n *coreNotes) parseSigInfo(desc []byte) {
+	if info := decodeSigInfo(desc); info != nil {
+		n.SigInfo = info
+	}
+}
+
+// decodeSigInfo decodes a siginfo_t as laid out in memory and in NT_SIGINFO.
+// Returns:
+// - The decoded fields, or nil if desc is too short.
+func decodeSigInfo(desc []byte) *coreSigInfo {
+	if len(desc) < 24 {
+		return nil
+	}
+	le := binary.LittleEndian
+	info := &coreSigInfo{
+		Signo: int(int32(le.Uint32(desc[0:]))),
+		Errno: int(int32(le.Uint32(desc[4:]))),
+		Code:  int(int32(le.Uint32(desc[8:]))),
+	}
+	if info.Code <= 0 {
+		// Sent by a process: _kill = {si_pid, si_uid}
+		info.SenderPID = int(int32(le.Uint32(desc[16:])))
+		info.SenderUID = int(le.Uint32(desc[20:]))
+	} else {
+		// Raised by the kernel: _sigfault = {si_addr}
+		info.Addr = le.Uint64(desc[16:])
+	}
+	return info
+}
+
+// parseFileNote decodes an NT_FILE note.
+func (n *coreNotes) parseFileNote(desc []byte) {
+	if len(desc) < 16 {
+		return
+	}
+	le := binary.LittleEndian
+	count := le.Uint64(desc[0:])
+	pageSize := le.Uint64(desc[8:])
+	entries := desc[16:]
+	if count > uint64(len(entries))/24 {
+		return
+	}
+
+	names := bytes.Split(entries[count*24:], []byte{0})
+	for i := uint64(0); i < count; i++ {
+		entry := entries[i*24:]
+		file := coreMappedFile{
+			Start:  le.Uint64(entry[0:]),
+			End:    le.Uint64(entry[8:]),
+			Offset: le.Uint64(entry[16:]) * pageSize,
+		}
+		if int(i) < len(names) {
+			file.Path = string(names[i])
+		}
+		n.Files = append(n.Files, file)
+	}
+}
+
+// parseAuxv decodes an NT_AUXV note.
+func (n *coreNotes) parseAuxv(desc []byte) {
+	le := binary.LittleEndian
+	for off := 0; off+16 <= len(desc); off += 16 {
+		key := le.Uint64(desc[off:])
+		if key == 0 {
+			break
+		}
+		n.Auxv[key] = le.Uint64(desc[off+8:])
+	}
+}
+
+// cString returns the bytes up to the first NUL as a string.
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// readMemory reads process memory captured in the core's load segments.
+// Parameters:
+// - addr: The virtual address to read from.
+// - size: The number of bytes to read.
+// Returns:
+// - The bytes read; shorter than size if the range leaves the captured memory.
+// - An error if the address is not captured in the core.
+func (n *coreNotes) readMemory(addr uint64, size int) ([]byte, error) {
+	for _, seg := range n.Segments {
+		if addr < seg.Vaddr || addr >= seg.Vaddr+seg.Memsz {
+			continue
+		}
+		rel := addr - seg.Vaddr
+		if rel >= seg.Filesz {
+			return nil, fmt.Errorf("address 0x%x was not dumped", addr)
+		}
+		if avail := seg.Filesz - rel; uint64(size) > avail {
+			size = int(avail)
+		}
+
+		f, err := os.Open(n.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		buf := make([]byte, size)
+		read, err := f.ReadAt(buf, int64(seg.Offset+rel))
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return buf[:read], nil
+	}
+	return nil, fmt.Errorf("address 0x%x is not mapped in the core", addr)
+}
+
+// segmentFor returns the load segment containing the given address, if any.
+func (n *coreNotes) segmentFor(addr uint64) *coreSegment {
+	for i := range n.Segments {
+		seg := &n.Segments[i]
+		if addr >= seg.Vaddr && addr < seg.Vaddr+seg.Memsz {
+			return seg
+		}
+	}
+	return nil
+}
+
+// recoverProcessTitle returns the full process title of the crashed process.
+// NT_PRPSINFO only keeps the first 80 bytes of the command line, but postgres rewrites its
+// title in place over the original argv area at the top of the stack. This locates that area
+// near AT_EXECFN and reads the complete NUL-terminated title from the core's memory.
+// Returns:
+// - The full title, or the truncated pr_psargs if it cannot be recovered.
+func (n *coreNotes) recoverProcessTitle() string {
+	psargs := n.Process.PSArgs
+	execfn, ok := n.Auxv[atExecFn]
+	if !ok || psargs == "" {
+		return psargs
+	}
+	seg := n.segmentFor(execfn)
+	if seg == nil || execfn-seg.Vaddr >= seg.Filesz {
+		return psargs
+	}
+
+	data, err := n.readMemory(seg.Vaddr, int(execfn-seg.Vaddr))
+	if err != nil {
+		return psargs
+	}
+
+	prefix := psargs
+	if len(prefix) > 40 {
+		prefix = prefix[:40]
+	}
+	idx := bytes.LastIndex(data, []byte(prefix))
+	if idx < 0 {
+		return psargs
+	}
+	title := strings.TrimSpace(cString(data[idx:]))
+	if len(title) > len(psargs) && strings.HasPrefix(title, psargs) {
+		return title
+	}
+	return psargs
+}
+
+// describe returns a `file`-style description of the core file.
+func (n *coreNotes) describe() string {
+	desc := fmt.Sprintf("ELF 64-bit LSB core file, %s", machineName(n.Machine))
+	if n.Process != nil {
+		desc += fmt.Sprintf(", from '%s'", n.Process.Title)
+		desc += fmt.Sprintf(", real uid: %d, real gid: %d", n.Process.UID, n.Process.GID)
+		if n.Process.FName != "" {
+			desc += fmt.Sprintf(", execfn: '%s'", n.Process.FName)
+		}
+	}
+	return desc
+}
+
+// machineName returns the `file`-style name of an ELF machine.
+func machineName(m elf.Machine) string {
+	switch m {
+	case elf.EM_X86_64:
+		return "x86-64"
+	case elf.EM_AARCH64:
+		return "ARM aarch64"
+	}
+	return strings.TrimPrefix(m.String(), "EM_")
+}
+
+// applyCoreNotes fills the analysis from decoded core notes.
+// Fields already populated by gdb are kept; the notes only fill the gaps, except for
+// process identity in BasicInfo, which the notes know more reliably than `file`.
+// Parameters:
+// - analysis: A pointer to the CoreAnalysis object to update.
+// - notes: The decoded core notes.
+func applyCoreNotes(analysis *CoreAnalysis, notes *coreNotes) {
+	if analysis.BasicInfo == nil {
+		analysis.BasicInfo = make(map[string]string)
+	}
+	info := analysis.BasicInfo
+
+	if p := notes.Process; p != nil {
+		info["pid"] = strconv.Itoa(p.PID)
+		info["ppid"] = strconv.Itoa(p.PPID)
+		info["real_uid"] = strconv.Itoa(p.UID)
+		info["real_gid"] = strconv.Itoa(p.GID)
+		info["executable"] = p.FName
+		info["cmdline"] = p.Title
+		extractProcessInfo(p.Title, info)
+	}
+
+	if s := notes.SigInfo; s != nil && analysis.SignalInfo.SignalNumber == 0 {
+		dispositions := analysis.SignalInfo.Dispositions
+		analysis.SignalInfo = s.fields().signalInfo()
+		applySignalDispositions(&analysis.SignalInfo, dispositions)
+	}
+	if s := notes.SigInfo; s != nil && s.Code <= 0 && analysis.SignalInfo.SenderPID == 0 {
+		uid := s.SenderUID
+		analysis.SignalInfo.SenderPID, analysis.SignalInfo.SenderUID = s.SenderPID, &uid
+	}
+
+	if len(analysis.Threads) == 0 {
+		for i, status := range notes.Threads {
+			analysis.Threads = append(analysis.Threads, ThreadInfo{
+				ThreadID:  strconv.Itoa(i + 1),
+				LWPID:     strconv.Itoa(status.PID),
+				State:     "stopped",
+				IsCrashed: i == 0, // The kernel writes the signalled thread first
+			})
+		}
+	}
+
+	if len(analysis.Registers) == 0 && len(notes.Threads) > 0 {
+		analysis.Registers = make(map[string]string)
+		for reg, value := range notes.Threads[0].Registers {
+			analysis.Registers[reg] = fmt.Sprintf("0x%x", value)
+		}
+	}
+}
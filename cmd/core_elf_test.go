@@ -0,0 +1,315 @@
+// File: cmd/core_elf_test.go
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"debug/elf"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testNote is a single note to embed in a synthetic core file.
+type testNote struct {
+	name string
+	typ  uint32
+	desc []byte
+}
+
+// testLoad is a PT_LOAD segment to embed in a synthetic core file.
+type testLoad struct {
+	vaddr uint64
+	data  []byte
+	memsz uint64 // Defaults to len(data)
+	flags elf.ProgFlag
+}
+
+// writeTestCore writes a minimal x86-64 ELF core file with the given notes and segments.
+func writeTestCore(t *testing.T, path string, notes []testNote, loads []testLoad) {
+	t.Helper()
+	writeTestELF(t, path, elf.ET_CORE, notes, loads)
+}
+
+// writeTestELF writes a minimal x86-64 ELF file of the given type with one PT_NOTE
+// segment holding the notes, followed by the load segments.
+func writeTestELF(t *testing.T, path string, typ elf.Type, notes []testNote, loads []testLoad) {
+	t.Helper()
+	le := binary.LittleEndian
+
+	var noteData bytes.Buffer
+	for _, n := range notes {
+		name := append([]byte(n.name), 0)
+		binary.Write(&noteData, le, uint32(len(name)))
+		binary.Write(&noteData, le, uint32(len(n.desc)))
+		binary.Write(&noteData, le, n.typ)
+		noteData.Write(name)
+		noteData.Write(make([]byte, align4(len(name))-len(name)))
+		noteData.Write(n.desc)
+		noteData.Write(make([]byte, align4(len(n.desc))-len(n.desc)))
+	}
+
+	phnum := 1 + len(loads)
+	offset := uint64(64 + 56*phnum)
+
+	var buf bytes.Buffer
+	ident := [16]byte{0x7f, 'E', 'L', 'F', 2, 1, 1}
+	buf.Write(ident[:])
+	binary.Write(&buf, le, uint16(typ))
+	binary.Write(&buf, le, uint16(elf.EM_X86_64))
+	binary.Write(&buf, le, uint32(1))
+	binary.Write(&buf, le, uint64(0))  // e_entry
+	binary.Write(&buf, le, uint64(64)) // e_phoff
+	binary.Write(&buf, le, uint64(0))  // e_shoff
+	binary.Write(&buf, le, uint32(0))  // e_flags
+	binary.Write(&buf, le, uint16(64)) // e_ehsize
+	binary.Write(&buf, le, uint16(56)) // e_phentsize
+	binary.Write(&buf, le, uint16(phnum))
+	binary.Write(&buf, le, uint16(64)) // e_shentsize
+	binary.Write(&buf, le, uint16(0))  // e_shnum
+	binary.Write(&buf, le, uint16(0))  // e_shstrndx
+
+	writePhdr := func(typ elf.ProgType, flags elf.ProgFlag, off, vaddr, filesz, memsz uint64) {
+		binary.Write(&buf, le, uint32(typ))
+		binary.Write(&buf, le, uint32(flags))
+		binary.Write(&buf, le, off)
+		binary.Write(&buf, le, vaddr)
+		binary.Write(&buf, le, uint64(0))
+		binary.Write(&buf, le, filesz)
+		binary.Write(&buf, le, memsz)
+		binary.Write(&buf, le, uint64(1))
+	}
+
+	writePhdr(elf.PT_NOTE, 0, offset, 0, uint64(noteData.Len()), 0)
+	offset += uint64(noteData.Len())
+	for _, l := range loads {
+		memsz := l.memsz
+		if memsz == 0 {
+			memsz = uint64(len(l.data))
+		}
+		writePhdr(elf.PT_LOAD, l.flags, offset, l.vaddr, uint64(len(l.data)), memsz)
+		offset += uint64(len(l.data))
+	}
+
+	buf.Write(noteData.Bytes())
+	for _, l := range loads {
+		buf.Write(l.data)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// prstatusNote builds an NT_PRSTATUS note for an x86-64 thread.
+func prstatusNote(pid, ppid int, signal int, regs map[string]uint64) testNote {
+	desc := make([]byte, prstatusRegsOffset+27*8+8)
+	le := binary.LittleEndian
+	le.PutUint16(desc[12:], uint16(signal))
+	le.PutUint32(desc[prstatusPIDOffset:], uint32(pid))
+	le.PutUint32(desc[prstatusPIDOffset+4:], uint32(ppid))
+	for i, reg := range prstatusRegsAMD64 {
+		le.PutUint64(desc[prstatusRegsOffset+i*8:], regs[reg])
+	}
+	return testNote{name: "CORE", typ: ntPRStatus, desc: desc}
+}
+
+// prpsinfoNote builds an NT_PRPSINFO note.
+func prpsinfoNote(pid, ppid, uid, gid int, fname, psargs string) testNote {
+	desc := make([]byte, prpsinfoArgsOff+80)
+	le := binary.LittleEndian
+	le.PutUint32(desc[prpsinfoUIDOffset:], uint32(uid))
+	le.PutUint32(desc[prpsinfoUIDOffset+4:], uint32(gid))
+	le.PutUint32(desc[prpsinfoUIDOffset+8:], uint32(pid))
+	le.PutUint32(desc[prpsinfoUIDOffset+12:], uint32(ppid))
+	copy(desc[prpsinfoFNameOff:prpsinfoFNameOff+15], fname)
+	copy(desc[prpsinfoArgsOff:prpsinfoArgsOff+79], psargs)
+	return testNote{name: "CORE", typ: ntPRPSInfo, desc: desc}
+}
+
+// siginfoNote builds an NT_SIGINFO note. For code <= 0 the value is the sender pid,
+// otherwise it is the fault address.
+func siginfoNote(signo, code int, value uint64) testNote {
+	desc := make([]byte, 128)
+	le := binary.LittleEndian
+	le.PutUint32(desc[0:], uint32(signo))
+	le.PutUint32(desc[8:], uint32(int32(code)))
+	le.PutUint64(desc[16:], value)
+	return testNote{name: "CORE", typ: ntSigInfo, desc: desc}
+}
+
+// fileNote builds an NT_FILE note from mapped files.
+func fileNote(files []coreMappedFile) testNote {
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	binary.Write(&buf, le, uint64(len(files)))
+	binary.Write(&buf, le, uint64(4096))
+	for _, f := range files {
+		binary.Write(&buf, le, f.Start)
+		binary.Write(&buf, le, f.End)
+		binary.Write(&buf, le, f.Offset/4096)
+	}
+	for _, f := range files {
+		buf.WriteString(f.Path)
+		buf.WriteByte(0)
+	}
+	return testNote{name: "CORE", typ: ntFile, desc: buf.Bytes()}
+}
+
+// auxvNote builds an NT_AUXV note.
+func auxvNote(entries map[uint64]uint64) testNote {
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	for k, v := range entries {
+		binary.Write(&buf, le, k)
+		binary.Write(&buf, le, v)
+	}
+	binary.Write(&buf, le, uint64(0))
+	binary.Write(&buf, le, uint64(0))
+	return testNote{name: "CORE", typ: ntAuxv, desc: buf.Bytes()}
+}
+
+func TestReadCoreNotes(t *testing.T) {
+	tmpDir := t.TempDir()
+	corePath := filepath.Join(tmpDir, "core.4242")
+
+	title := "postgres:  7000, gpadmin testdb 10.0.0.5(51234) con17 seg3 cmd4 slice2 MPPEXEC SELECT with a very long title"
+	stackBase := uint64(0x7ffc00000000)
+	stack := make([]byte, 4096)
+	copy(stack[1024:], title)
+	copy(stack[2048:], "/usr/local/cloudberry/bin/postgres")
+
+	writeTestCore(t, corePath, []testNote{
+		prstatusNote(4242, 100, 11, map[string]uint64{"rip": 0x555555555000, "rsp": 0x7ffc00000800}),
+		prstatusNote(4243, 100, 0, map[string]uint64{"rip": 0x7fff00001000}),
+		prpsinfoNote(4242, 100, 1000, 1001, "postgres", title),
+		siginfoNote(11, 1, 0x10),
+		fileNote([]coreMappedFile{
+			{Start: 0x555555554000, End: 0x555555556000, Offset: 0, Path: "/usr/local/cloudberry/bin/postgres"},
+			{Start: 0x7ffff7dd7000, End: 0x7ffff7dd8000, Offset: 4096, Path: "/lib64/libc.so.6"},
+		}),
+		auxvNote(map[uint64]uint64{atExecFn: stackBase + 2048}),
+	}, []testLoad{
+		{vaddr: stackBase, data: stack, flags: elf.PF_R | elf.PF_W},
+	})
+
+	notes, err := readCoreNotes(corePath)
+	if err != nil {
+		t.Fatalf("readCoreNotes() error = %v", err)
+	}
+
+	if len(notes.Threads) != 2 || notes.Threads[0].PID != 4242 || notes.Threads[1].PID != 4243 {
+		t.Fatalf("unexpected threads: %+v", notes.Threads)
+	}
+	if notes.Threads[0].Registers["rip"] != 0x555555555000 || notes.Threads[0].Registers["rsp"] != 0x7ffc00000800 {
+		t.Errorf("unexpected registers: %v", notes.Threads[0].Registers)
+	}
+	if notes.Process == nil || notes.Process.UID != 1000 || notes.Process.GID != 1001 || notes.Process.PPID != 100 {
+		t.Fatalf("unexpected process info: %+v", notes.Process)
+	}
+	if len(notes.Process.PSArgs) >= len(title) || !strings.HasPrefix(title, notes.Process.PSArgs) {
+		t.Errorf("PSArgs = %q, want a truncated prefix of the title", notes.Process.PSArgs)
+	}
+	if notes.Process.Title != title {
+		t.Errorf("Title = %q, want %q", notes.Process.Title, title)
+	}
+	if notes.SigInfo == nil || notes.SigInfo.Signo != 11 || notes.SigInfo.Code != 1 || notes.SigInfo.Addr != 0x10 {
+		t.Errorf("unexpected siginfo: %+v", notes.SigInfo)
+	}
+	if len(notes.Files) != 2 || notes.Files[1].Path != "/lib64/libc.so.6" || notes.Files[1].Offset != 4096 {
+		t.Errorf("unexpected mapped files: %+v", notes.Files)
+	}
+
+	data, err := notes.readMemory(stackBase+2048, 8)
+	if err != nil || string(data) != "/usr/loc" {
+		t.Errorf("readMemory() = %q, %v", data, err)
+	}
+	if _, err := notes.readMemory(0x1000, 8); err == nil {
+		t.Error("expected error reading unmapped memory")
+	}
+}
+
+func TestReadCoreNotesNotCore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "core.1")
+	if err := os.WriteFile(path, []byte("not an elf file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readCoreNotes(path); err == nil {
+		t.Error("expected error for non-ELF file")
+	}
+}
+
+func TestApplyCoreNotes(t *testing.T) {
+	notes := &coreNotes{
+		Machine: elf.EM_X86_64,
+		Threads: []corePRStatus{
+			{PID: 4242, Registers: map[string]uint64{"rip": 0x1234}},
+			{PID: 4243, Registers: map[string]uint64{}},
+		},
+		Process: &corePRPSInfo{
+			PID: 4242, PPID: 100, UID: 1000, GID: 1000, FName: "postgres",
+			Title: "postgres:  7000, gpadmin testdb 10.0.0.5(51234) con17 seg3 cmd4 slice2 MPPEXEC SELECT",
+		},
+		SigInfo: &coreSigInfo{Signo: 6, Code: -6, SenderPID: 4242},
+	}
+
+	analysis := CoreAnalysis{BasicInfo: parseBasicInfo(notes.describe())}
+	applyCoreNotes(&analysis, notes)
+
+	if analysis.BasicInfo["segment_id"] != "3" || analysis.BasicInfo["connection_id"] != "17" {
+		t.Errorf("unexpected basic info: %v", analysis.BasicInfo)
+	}
+	if analysis.BasicInfo["ppid"] != "100" || analysis.BasicInfo["client_address"] != "10.0.0.5" {
+		t.Errorf("unexpected basic info: %v", analysis.BasicInfo)
+	}
+	if analysis.SignalInfo.SignalName != "SIGABRT" || analysis.SignalInfo.SenderPID != 4242 {
+		t.Errorf("unexpected signal info: %+v", analysis.SignalInfo)
+	}
+	if analysis.SignalInfo.FaultAddress != "" {
+		t.Errorf("FaultAddress = %q, want empty for a sent signal", analysis.SignalInfo.FaultAddress)
+	}
+	if len(analysis.Threads) != 2 || !analysis.Threads[0].IsCrashed || analysis.Threads[1].LWPID != "4243" {
+		t.Errorf("unexpected threads: %+v", analysis.Threads)
+	}
+	if analysis.Registers["rip"] != "0x1234" {
+		t.Errorf("rip = %q, want 0x1234", analysis.Registers["rip"])
+	}
+	if !strings.Contains(notes.describe(), "core file, x86-64") {
+		t.Errorf("describe() = %q", notes.describe())
+	}
+}
+
+func TestAnalyzeCoreFileWithoutGDB(t *testing.T) {
+	tmpDir := t.TempDir()
+	corePath := filepath.Join(tmpDir, "core.4242")
+	writeTestCore(t, corePath, []testNote{
+		prstatusNote(4242, 100, 11, map[string]uint64{"rip": 0x555555555000}),
+		prpsinfoNote(4242, 100, 1000, 1000, "postgres", "postgres:  7000, gpadmin testdb con17 seg3 cmd4"),
+		siginfoNote(11, 1, 0x8),
+	}, nil)
+
+	// Neither `file` nor a postgres binary is available.
+	mock := &MockCommander{}
+	oldCmdExecutor := cmdExecutor
+	SetCommander(mock)
+	defer SetCommander(oldCmdExecutor)
+
+	analysis, err := analyzeCoreFile(context.Background(), corePath, filepath.Join(tmpDir, "missing-gphome"))
+	if err != nil {
+		t.Fatalf("analyzeCoreFile() error = %v", err)
+	}
+	if analysis.AnalysisBackend != "elf-notes" {
+		t.Errorf("AnalysisBackend = %q, want elf-notes", analysis.AnalysisBackend)
+	}
+	if analysis.SignalInfo.SignalNumber != 11 || analysis.SignalInfo.FaultAddress != "0x8" {
+		t.Errorf("unexpected signal info: %+v", analysis.SignalInfo)
+	}
+	if analysis.BasicInfo["segment_id"] != "3" {
+		t.Errorf("segment_id = %q, want 3", analysis.BasicInfo["segment_id"])
+	}
+	if len(analysis.Threads) != 1 || analysis.Threads[0].LWPID != "4242" {
+		t.Errorf("unexpected threads: %+v", analysis.Threads)
+	}
+}
@@ -20,8 +20,11 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -29,12 +32,18 @@ import (
 
 // analyzeCoreFile performs a comprehensive analysis of a core dump file.
 // Parameters:
+// - ctx: Context bounding the analysis; cancelling it kills any running external tools.
 // - corePath: Path to the core dump file.
-// - gphome: Path to the PostgreSQL/Cloudberry installation.
+// - gphome: Path to the default PostgreSQL/Cloudberry installation ($GPHOME); may be empty.
 // Returns:
 // - A `CoreAnalysis` object containing parsed details from the core dump.
 // - An error if the analysis fails at any step.
-func analyzeCoreFile(corePath string, gphome string) (CoreAnalysis, error) {
+func analyzeCoreFile(ctx context.Context, corePath string, gphome string) (CoreAnalysis, error) {
+	// A compressed core is analyzed from an uncompressed copy in the scratch area
+	if format, size := detectCompression(corePath); format != nil {
+		return analyzeCompressedCore(ctx, corePath, format, size, gphome)
+	}
+
 	analysis := CoreAnalysis{
 		Timestamp: time.Now().Format(time.RFC3339),
 		CoreFile:  corePath,
@@ -51,46 +60,125 @@ func analyzeCoreFile(corePath string, gphome string) (CoreAnalysis, error) {
 		Created: fileInfo.ModTime().Format(time.RFC3339),
 	}
 
+	// Decode the core's own notes; this works without any external tools
+	notes, notesErr := readCoreNotes(corePath)
+
 	// Get file type information
-	output, err := cmdExecutor.Execute("file", corePath)
+	output, err := cmdExecutor.Execute(ctx, "file", corePath)
 	if err != nil {
-		return analysis, fmt.Errorf("failed to get file info: %w", err)
+		if notesErr != nil || ctx.Err() != nil {
+			return analysis, fmt.Errorf("failed to get file info: %w", err)
+		}
+		output = []byte(notes.describe())
 	}
 	analysis.FileInfo.FileOutput = strings.TrimSpace(string(output))
 
 	// Parse basic info BEFORE GDB analysis
 	analysis.BasicInfo = parseBasicInfo(analysis.FileInfo.FileOutput)
+	if notes != nil {
+		applyCoreNotes(&analysis, notes)
+	}
 
-	// Find PostgreSQL binary
-	postgresPath := filepath.Join(gphome, "bin", "postgres")
-	if _, err := os.Stat(postgresPath); err != nil {
-		return analysis, fmt.Errorf("postgres binary not found at %s", postgresPath)
+	// Find the PostgreSQL binary that produced this core
+	selection, err := selectPostgresBinary(notes, gphome)
+	applyBinarySelection(&analysis.PostgresInfo, selection)
+	if err != nil {
+		if notes != nil {
+			analysis.MemoryMap = buildMemoryMap("", notes, stackPointer(&analysis))
+			return nativeAnalysis(analysis, err.Error()), nil
+		}
+		return analysis, err
 	}
+	postgresPath := selection.Path
 
 	// Get PostgreSQL information
-	pgInfo, err := getPostgresInfo(postgresPath)
+	pgInfo, err := getPostgresInfo(ctx, postgresPath)
 	if err != nil {
 		return analysis, err
 	}
+	applyBinarySelection(&pgInfo, selection)
 	analysis.PostgresInfo = pgInfo
 
-	// Run GDB analysis
-	if err := gdbAnalysis(&analysis, postgresPath); err != nil {
+	// Locate separate debug symbols by build-id before gdb loads the modules
+	analysis.Modules = resolveDebugSymbols(ctx, notes, selection)
+
+	// Run GDB analysis through the selected interface
+	if gdbBackend == gdbBackendMI {
+		analysis.AnalysisBackend = "gdb-mi"
+		err = gdbMIAnalysis(ctx, &analysis, postgresPath)
+	} else {
+		analysis.AnalysisBackend = "gdb-cli"
+		err = gdbAnalysis(ctx, &analysis, postgresPath)
+	}
+	if err != nil {
+		if notes != nil && errors.Is(err, exec.ErrNotFound) {
+			return nativeAnalysis(analysis, "gdb is not installed"), nil
+		}
 		return analysis, err
 	}
 
+	// Fill anything gdb could not determine from the core notes
+	if notes != nil {
+		applyCoreNotes(&analysis, notes)
+	}
+
+	// Prefer the backend's own session globals over the process title
+	applySessionInfo(&analysis)
+
+	// Tell a signal sent by the process itself or its parent from one sent by someone else
+	attributeSignalSender(&analysis)
+	recoverReraisedFault(&analysis, notes)
+
+	// The core's own segments give exact permissions; classify the fault address against them
+	if notes != nil {
+		analysis.MemoryMap = buildMemoryMap("", notes, stackPointer(&analysis))
+	}
+	addFaultAddressContext(&analysis.SignalInfo, &analysis)
+
+	// Demangle C++ frames so GPORCA functions read like the source
+	demangleFrames(ctx, &analysis)
+
+	// Report runaway recursion before deduplication hides it
+	detectStackRecursion(&analysis)
+
 	// Deduplicate stack trace
 	analysis.StackTrace = deduplicateStackTrace(analysis.StackTrace)
 
 	// Enhance signal info from stack
 	detectSignalFromStack(&analysis)
 
+	// Explain Assert failures and elog aborts
+	detectAbortInfo(&analysis)
+	detectOrcaException(&analysis)
+
 	// Enhance basic info with thread and signal context
 	enhanceProcessInfo(analysis.BasicInfo, &analysis)
 
+	// Show the code around the crashing frames
+	attachSourceContext(&analysis)
+
+	// Name the known issues this crash matches
+	attachKnownIssues(&analysis)
+
 	return analysis, nil
 }
 
+// nativeAnalysis finalizes an analysis built only from the core's ELF notes.
+// Parameters:
+// - analysis: The CoreAnalysis object populated by applyCoreNotes.
+// - reason: Why gdb analysis was skipped.
+// Returns:
+// - The finalized CoreAnalysis object.
+func nativeAnalysis(analysis CoreAnalysis, reason string) CoreAnalysis {
+	fmt.Printf("Using native core reader for %s: %s\n", analysis.CoreFile, reason)
+	analysis.AnalysisBackend = "elf-notes"
+	enhanceProcessInfo(analysis.BasicInfo, &analysis)
+	attributeSignalSender(&analysis)
+	addFaultAddressContext(&analysis.SignalInfo, &analysis)
+	attachKnownIssues(&analysis)
+	return analysis
+}
+
 // deduplicateStackTrace removes duplicate stack frames from the analysis.
 // Parameters:
 // - frames: A slice of `StackFrame` objects representing the stack trace.
@@ -113,30 +201,31 @@ func deduplicateStackTrace(frames []StackFrame) []StackFrame {
 
 // getPostgresInfo collects PostgreSQL binary information such as version and build options.
 // Parameters:
+// - ctx: Context bounding the external commands.
 // - binaryPath: Path to the PostgreSQL binary.
 // Returns:
 // - A `PostgresInfo` object containing version and configuration details.
 // - An error if the information cannot be retrieved.
-func getPostgresInfo(binaryPath string) (PostgresInfo, error) {
+func getPostgresInfo(ctx context.Context, binaryPath string) (PostgresInfo, error) {
 	info := PostgresInfo{
 		BinaryPath: binaryPath,
 	}
 
 	// Get PostgreSQL version
-	output, err := cmdExecutor.Execute(binaryPath, "--version")
+	output, err := cmdExecutor.Execute(ctx, binaryPath, "--version")
 	if err == nil {
 		info.Version = strings.TrimSpace(string(output))
 	}
 
 	// Get Cloudberry version
-	output, err = cmdExecutor.Execute(binaryPath, "--gp-version")
+	output, err = cmdExecutor.Execute(ctx, binaryPath, "--gp-version")
 	if err == nil {
 		info.GPVersion = strings.TrimSpace(string(output))
 	}
 
 	// Get build options
 	pgConfigPath := filepath.Join(filepath.Dir(binaryPath), "pg_config")
-	output, err = cmdExecutor.Execute(pgConfigPath, "--configure")
+	output, err = cmdExecutor.Execute(ctx, pgConfigPath, "--configure")
 	if err == nil {
 		// Clean up the configure options
 		options := strings.Fields(strings.TrimSpace(string(output)))
@@ -160,13 +249,46 @@ func dirExists(path string) bool {
 	return err == nil && info.IsDir()
 }
 
+// gdbScripts are the gdb command scripts run at the end of every analysis. MI token
+// gdbScriptMIToken+i runs the i-th script.
+var gdbScripts = []struct {
+	name   string
+	script string
+}{
+	{"memctx", memoryContextScript},
+	{"errordata", errorDataScript},
+}
+
+const gdbScriptMIToken = 200
+
+// writeGDBScript writes a gdb command script to a temporary file.
+// Parameters:
+// - name: A short name for the script, used in the file name.
+// - script: The gdb commands.
+// Returns:
+// - The path of the script; the caller removes it.
+// - An error if the file cannot be written.
+func writeGDBScript(name, script string) (string, error) {
+	f, err := os.CreateTemp("", "cbtoolbox-"+name+"-*.gdb")
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s script: %w", name, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(script); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write %s script: %w", name, err)
+	}
+	return f.Name(), nil
+}
+
 // gdbAnalysis performs detailed analysis using GDB commands.
 // Parameters:
+// - ctx: Context bounding the gdb run.
 // - analysis: A pointer to the `CoreAnalysis` object to update with GDB results.
 // - binaryPath: Path to the PostgreSQL binary.
 // Returns:
 // - An error if the GDB commands fail.
-func gdbAnalysis(analysis *CoreAnalysis, binaryPath string) error {
+func gdbAnalysis(ctx context.Context, analysis *CoreAnalysis, binaryPath string) error {
   gdbCmds := []string{
       "set pagination off",
       "set print pretty on",
@@ -174,9 +296,6 @@ func gdbAnalysis(analysis *CoreAnalysis, binaryPath string) error {
       "info threads",
       "thread apply all bt full",
       "info registers all",
-      "info signal SIGABRT",
-      "info signal SIGSEGV",
-      "info signal SIGBUS",
       "print $_siginfo",
       "info sharedlibrary",
       "x/1i $pc",
@@ -188,21 +307,32 @@ func gdbAnalysis(analysis *CoreAnalysis, binaryPath string) error {
       "bt full",
       "print $_siginfo.si_code",  // Add signal code information
       "maintenance info sections", // Add memory section information
-      "quit",
   }
+  gdbCmds = append(gdbCmds, sessionGDBCommands()...) // Running query and session identity
+  gdbCmds = append(gdbCmds, signalDispositionCommands()...) // How gdb handles the fatal signals
 
 	// Add source directory info for better line numbers
 	if srcDir := filepath.Join(filepath.Dir(binaryPath), "../src"); dirExists(srcDir) {
 		gdbCmds = append([]string{"directory " + srcDir}, gdbCmds...)
 	}
+	for _, root := range sourceRoots {
+		gdbCmds = append([]string{"directory " + root}, gdbCmds...)
+	}
 
-	args := []string{"-nx", "--batch"}
+	args := append([]string{"-nx", "--batch"}, gdbDebugArgs()...)
 	for _, cmd := range gdbCmds {
 		args = append(args, "-ex", cmd)
 	}
-	args = append(args, binaryPath, analysis.CoreFile)
+	// Scripts run last, each on its own; an error stops only the script it occurs in
+	for _, s := range gdbScripts {
+		if script, err := writeGDBScript(s.name, s.script); err == nil {
+			defer os.Remove(script)
+			args = append(args, "-x", script)
+		}
+	}
+	args = append(args, "-ex", "quit", binaryPath, analysis.CoreFile)
 
-  output, err := cmdExecutor.Execute("gdb", args...)
+  output, err := cmdExecutor.Execute(ctx, "gdb", args...)
 	if err != nil {
 		return fmt.Errorf("GDB analysis failed: %w", err)
 	}
@@ -231,4 +361,23 @@ func parseGDBOutput(output string, analysis *CoreAnalysis) {
 
 	// Parse shared libraries
 	analysis.Libraries = parseSharedLibraries(output)
+
+	// Parse session globals
+	analysis.SessionInfo = parseSessionOutput(output)
+
+	// Parse the memory-context tree
+	analysis.MemoryContexts = parseMemoryContexts(output)
+
+	// Parse the memory map
+	analysis.MemoryMap = buildMemoryMap(output, nil, stackPointer(analysis))
+
+	// Parse the elog error stack and any Assert failure
+	analysis.AbortInfo = parseErrorData(output)
+	assert := &AbortInfo{}
+	if parseAssertFromOutput(output, assert) {
+		if analysis.AbortInfo != nil {
+			assert.Errors = analysis.AbortInfo.Errors
+		}
+		analysis.AbortInfo = assert
+	}
 }
@@ -0,0 +1,590 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_gdb_mi.go
+// Purpose: Implements a GDB/MI (machine interface) backend for core file analysis.
+// Instead of scraping the human-oriented output of `gdb --batch`, this backend issues
+// MI commands such as `-thread-info` and `-stack-list-frames` and maps their structured
+// result records directly into `StackFrame`, `ThreadInfo` and register data.
+// Dependencies: Relies on an external GDB that supports the `mi3` interpreter.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported GDB backends for the --gdb-backend flag.
+const (
+	gdbBackendCLI = "cli"
+	gdbBackendMI  = "mi"
+)
+
+// miLocalsFrames is the number of innermost frames per thread for which
+// local variables are requested with -stack-list-variables.
+const miLocalsFrames = 5
+
+// miRecord is a single MI result record such as `12^done,threads=[...]`.
+type miRecord struct {
+	Token   string                 // Numeric token used to correlate the record with its command
+	Class   string                 // Result class: done, error, running, connected or exit
+	Results map[string]interface{} // Parsed results; values are string, map or []interface{}
+	Console string                 // Console stream output emitted before the result record
+}
+
+// miTokenCommand formats an MI command so that it can be passed through
+// `gdb -ex` using the `interpreter-exec` CLI command.
+// Parameters:
+// - token: The numeric token to prefix the command with.
+// - command: The MI command, including its leading dash.
+// Returns:
+// - A CLI command string that executes the MI command.
+func miTokenCommand(token int, command string) string {
+	escaped := strings.ReplaceAll(command, `\`, `\\`)
+	escaped = strings.ReplaceAll(escaped, `"`, `\"`)
+	return fmt.Sprintf(`interpreter-exec mi3 "%d%s"`, token, escaped)
+}
+
+// runGDBMI runs gdb with the MI interpreter and the given tokenized commands.
+// Parameters:
+// - ctx: Context bounding the gdb run.
+// - binaryPath: Path to the PostgreSQL binary.
+// - corePath: Path to the core file.
+// - commands: MI commands keyed by token.
+// Returns:
+// - The parsed result records keyed by token.
+// - An error if gdb could not be run.
+func runGDBMI(ctx context.Context, binaryPath, corePath string, commands map[int]string) (map[string]miRecord, error) {
+	tokens := make([]int, 0, len(commands))
+	for token := range commands {
+		tokens = append(tokens, token)
+	}
+	sort.Ints(tokens)
+
+	args := append([]string{"-nx", "--batch", "--interpreter=mi3"}, gdbDebugArgs()...)
+	args = append(args, "-ex", "set pagination off")
+	for _, root := range sourceRoots {
+		args = append(args, "-ex", "directory "+root)
+	}
+	for _, token := range tokens {
+		args = append(args, "-ex", miTokenCommand(token, commands[token]))
+	}
+	args = append(args, binaryPath, corePath)
+
+	output, err := cmdExecutor.Execute(ctx, "gdb", args...)
+	if err != nil {
+		return nil, fmt.Errorf("GDB/MI analysis failed: %w", err)
+	}
+	return parseMIOutput(string(output)), nil
+}
+
+// gdbMIAnalysis performs core file analysis using the GDB/MI interface.
+// Parameters:
+// - ctx: Context bounding the gdb runs.
+// - analysis: A pointer to the `CoreAnalysis` object to update with GDB results.
+// - binaryPath: Path to the PostgreSQL binary.
+// Returns:
+// - An error if the GDB commands fail.
+func gdbMIAnalysis(ctx context.Context, analysis *CoreAnalysis, binaryPath string) error {
+	// First pass: process-wide information and the list of threads.
+	commands := map[int]string{
+		1: "-thread-info",
+		2: "-data-list-register-names",
+		3: "-data-list-register-values --skip-unavailable x",
+		4: "-file-list-shared-libraries",
+		5: "-data-evaluate-expression $_siginfo",
+		6: "-data-disassemble -s $pc -e $pc+1 -- 0",
+	}
+	sessionMICommands(commands)
+	signalDispositionMICommands(commands)
+	for i, s := range gdbScripts {
+		if script, err := writeGDBScript(s.name, s.script); err == nil {
+			defer os.Remove(script)
+			commands[gdbScriptMIToken+i] = fmt.Sprintf("-interpreter-exec console %q", "source "+script)
+		}
+	}
+	records, err := runGDBMI(ctx, binaryPath, analysis.CoreFile, commands)
+	if err != nil {
+		return err
+	}
+
+	analysis.Threads = miThreads(records["1"])
+	analysis.Registers = miRegisters(records["2"], records["3"])
+	analysis.Libraries = miSharedLibraries(records["4"])
+	analysis.SignalInfo = miSignalInfo(records["5"])
+	var dispositions strings.Builder
+	for i := range dispositionSignals {
+		dispositions.WriteString(records[strconv.Itoa(signalDispositionMIToken+i)].Console)
+	}
+	applySignalDispositions(&analysis.SignalInfo, parseSignalDispositions(dispositions.String()))
+	analysis.CurrentInstruction = miCurrentInstruction(records["6"])
+	analysis.SessionInfo = miSessionInfo(records)
+	var scriptOutput strings.Builder
+	for i := range gdbScripts {
+		scriptOutput.WriteString(records[strconv.Itoa(gdbScriptMIToken+i)].Console)
+	}
+	analysis.MemoryContexts = parseMemoryContexts(scriptOutput.String())
+	analysis.AbortInfo = parseErrorData(scriptOutput.String())
+
+	if len(analysis.Threads) == 0 {
+		return nil
+	}
+
+	// Second pass: per-thread stacks, arguments and locals.
+	commands = make(map[int]string)
+	for i, thread := range analysis.Threads {
+		base := (i + 1) * 1000
+		commands[base+1] = fmt.Sprintf("-stack-list-frames --thread %s", thread.ThreadID)
+		commands[base+2] = fmt.Sprintf("-stack-list-arguments --thread %s 1", thread.ThreadID)
+		for frame := 0; frame < miLocalsFrames; frame++ {
+			commands[base+10+frame] = fmt.Sprintf(
+				"-stack-list-variables --thread %s --frame %d --all-values", thread.ThreadID, frame)
+		}
+	}
+
+	records, err = runGDBMI(ctx, binaryPath, analysis.CoreFile, commands)
+	if err != nil {
+		return err
+	}
+
+	analysis.StackTrace = nil
+	for i := range analysis.Threads {
+		base := (i + 1) * 1000
+		frames := miStackFrames(records[strconv.Itoa(base+1)])
+		miApplyArguments(frames, records[strconv.Itoa(base+2)])
+		for frame := 0; frame < miLocalsFrames && frame < len(frames); frame++ {
+			frames[frame].Locals = miVariables(records[strconv.Itoa(base+10+frame)])
+		}
+		analysis.Threads[i].Backtrace = frames
+		analysis.Threads[i].Name = determineThreadRole(frames)
+	}
+
+	// Keep the crashed thread's frames first, mirroring `bt` output order.
+	for _, thread := range analysis.Threads {
+		if thread.IsCrashed {
+			analysis.StackTrace = append(analysis.StackTrace, thread.Backtrace...)
+		}
+	}
+	for _, thread := range analysis.Threads {
+		if !thread.IsCrashed {
+			analysis.StackTrace = append(analysis.StackTrace, thread.Backtrace...)
+		}
+	}
+
+	return nil
+}
+
+// parseMIOutput extracts result records from raw GDB/MI output.
+// Console stream records (`~"..."`) are attached to the result record that follows them.
+// Parameters:
+// - output: The raw output of a gdb run with `--interpreter=mi3`.
+// Returns:
+// - A map of result records keyed by token. Untokenized records are keyed by "".
+func parseMIOutput(output string) map[string]miRecord {
+	records := make(map[string]miRecord)
+	resultRE := regexp.MustCompile(`^(\d*)\^(done|error|running|connected|exit)(?:,(.*))?$`)
+
+	var console strings.Builder
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, `~"`) {
+			if text, _, err := parseMICString(line[1:]); err == nil {
+				console.WriteString(text)
+			}
+			continue
+		}
+
+		matches := resultRE.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		record := miRecord{
+			Token:   matches[1],
+			Class:   matches[2],
+			Results: map[string]interface{}{},
+			Console: console.String(),
+		}
+		console.Reset()
+		if matches[3] != "" {
+			if results, err := parseMIResults(matches[3]); err == nil {
+				record.Results = results
+			}
+		}
+		records[record.Token] = record
+	}
+
+	return records
+}
+
+// parseMIResults parses a comma-separated list of `name=value` results.
+// Parameters:
+// - s: The result list text following the result class.
+// Returns:
+// - A map of result names to parsed values.
+// - An error if the text is not well-formed MI.
+func parseMIResults(s string) (map[string]interface{}, error) {
+	results := make(map[string]interface{})
+	for len(s) > 0 {
+		name, value, rest, err := parseMIResult(s)
+		if err != nil {
+			return results, err
+		}
+		results[name] = value
+		s = strings.TrimPrefix(rest, ",")
+	}
+	return results, nil
+}
+
+// parseMIResult parses a single `name=value` pair.
+func parseMIResult(s string) (string, interface{}, string, error) {
+	eq := strings.IndexByte(s, '=')
+	if eq <= 0 {
+		return "", nil, s, fmt.Errorf("malformed MI result: %q", s)
+	}
+	value, rest, err := parseMIValue(s[eq+1:])
+	return s[:eq], value, rest, err
+}
+
+// parseMIValue parses an MI value: a C string, a tuple or a list.
+// Parameters:
+// - s: Text starting with the value.
+// Returns:
+// - The parsed value (string, map[string]interface{} or []interface{}).
+// - The remaining unparsed text.
+// - An error if the value is malformed.
+func parseMIValue(s string) (interface{}, string, error) {
+	if s == "" {
+		return nil, s, fmt.Errorf("unexpected end of MI value")
+	}
+
+	switch s[0] {
+	case '"':
+		return parseMICString(s)
+	case '{':
+		tuple := make(map[string]interface{})
+		s = s[1:]
+		for {
+			if strings.HasPrefix(s, "}") {
+				return tuple, s[1:], nil
+			}
+			name, value, rest, err := parseMIResult(s)
+			if err != nil {
+				return tuple, rest, err
+			}
+			tuple[name] = value
+			s = strings.TrimPrefix(rest, ",")
+		}
+	case '[':
+		var list []interface{}
+		s = s[1:]
+		for {
+			if strings.HasPrefix(s, "]") {
+				return list, s[1:], nil
+			}
+			var value interface{}
+			var err error
+			if s != "" && s[0] != '"' && s[0] != '{' && s[0] != '[' {
+				// Lists may hold named results, e.g. stack=[frame={...},frame={...}].
+				_, value, s, err = parseMIResult(s)
+			} else {
+				value, s, err = parseMIValue(s)
+			}
+			if err != nil {
+				return list, s, err
+			}
+			list = append(list, value)
+			s = strings.TrimPrefix(s, ",")
+		}
+	}
+
+	return nil, s, fmt.Errorf("unexpected character %q in MI value", s[0])
+}
+
+// parseMICString parses a C-style quoted string as used by GDB/MI.
+// Parameters:
+// - s: Text starting with the opening double quote.
+// Returns:
+// - The unescaped string contents.
+// - The text following the closing quote.
+// - An error if the string is not terminated.
+func parseMICString(s string) (string, string, error) {
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return sb.String(), s[i+1:], nil
+		case '\\':
+			if i+1 >= len(s) {
+				return sb.String(), "", fmt.Errorf("unterminated escape in MI string")
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case '0', '1', '2', '3':
+				// Octal escape such as \303.
+				if i+2 < len(s) {
+					if n, err := strconv.ParseUint(s[i:i+3], 8, 8); err == nil {
+						sb.WriteByte(byte(n))
+						i += 2
+						continue
+					}
+				}
+				sb.WriteByte(s[i])
+			default:
+				sb.WriteByte(s[i])
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), "", fmt.Errorf("unterminated MI string")
+}
+
+// miString returns a string-valued field from an MI tuple.
+func miString(tuple map[string]interface{}, key string) string {
+	if v, ok := tuple[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// miList returns a list-valued field from an MI tuple.
+func miList(tuple map[string]interface{}, key string) []interface{} {
+	if v, ok := tuple[key].([]interface{}); ok {
+		return v
+	}
+	return nil
+}
+
+// miThreads maps a `-thread-info` result into ThreadInfo objects.
+// Parameters:
+// - record: The result record of `-thread-info`.
+// Returns:
+// - A slice of ThreadInfo objects without backtraces.
+func miThreads(record miRecord) []ThreadInfo {
+	var threads []ThreadInfo
+	lwpRE := regexp.MustCompile(`LWP (\d+)`)
+	addrRE := regexp.MustCompile(`Thread (0x[0-9a-fA-F]+)`)
+	current := miString(record.Results, "current-thread-id")
+
+	for _, item := range miList(record.Results, "threads") {
+		tuple, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		targetID := miString(tuple, "target-id")
+		thread := ThreadInfo{
+			ThreadID:  miString(tuple, "id"),
+			State:     miString(tuple, "state"),
+			IsCrashed: current != "" && miString(tuple, "id") == current,
+		}
+		if matches := lwpRE.FindStringSubmatch(targetID); matches != nil {
+			thread.LWPID = matches[1]
+		}
+		if matches := addrRE.FindStringSubmatch(targetID); matches != nil {
+			thread.ThreadAddr = matches[1]
+		}
+		threads = append(threads, thread)
+	}
+
+	return deduplicateThreads(threads)
+}
+
+// miStackFrames maps a `-stack-list-frames` result into StackFrame objects.
+// Parameters:
+// - record: The result record of `-stack-list-frames`.
+// Returns:
+// - A slice of StackFrame objects ordered from innermost to outermost.
+func miStackFrames(record miRecord) []StackFrame {
+	var frames []StackFrame
+	for _, item := range miList(record.Results, "stack") {
+		tuple, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		frame := StackFrame{
+			FrameNum:   miString(tuple, "level"),
+			Location:   miString(tuple, "addr"),
+			Function:   miString(tuple, "func"),
+			SourceFile: miString(tuple, "file"),
+			LineNumber: parseInt(miString(tuple, "line")),
+		}
+		if frame.Function == "" {
+			frame.Function = "??"
+		}
+		if from := miString(tuple, "from"); from != "" {
+			frame.Module = filepath.Base(from)
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// miApplyArguments fills frame arguments from a `-stack-list-arguments` result.
+// Parameters:
+// - frames: The frames to update, indexed by level.
+// - record: The result record of `-stack-list-arguments`.
+func miApplyArguments(frames []StackFrame, record miRecord) {
+	byLevel := make(map[string]int, len(frames))
+	for i, frame := range frames {
+		byLevel[frame.FrameNum] = i
+	}
+
+	for _, item := range miList(record.Results, "stack-args") {
+		tuple, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		idx, ok := byLevel[miString(tuple, "level")]
+		if !ok {
+			continue
+		}
+		var args []string
+		for _, arg := range miList(tuple, "args") {
+			if a, ok := arg.(map[string]interface{}); ok {
+				args = append(args, fmt.Sprintf("%s=%s", miString(a, "name"), miString(a, "value")))
+			}
+		}
+		frames[idx].Arguments = strings.Join(args, ", ")
+	}
+}
+
+// miVariables maps a `-stack-list-variables` result into a map of locals.
+// Parameters:
+// - record: The result record of `-stack-list-variables`.
+// Returns:
+// - A map of variable names to values, or nil if there are none.
+func miVariables(record miRecord) map[string]string {
+	var locals map[string]string
+	for _, item := range miList(record.Results, "variables") {
+		tuple, ok := item.(map[string]interface{})
+		if !ok || miString(tuple, "arg") == "1" {
+			continue
+		}
+		if locals == nil {
+			locals = make(map[string]string)
+		}
+		locals[miString(tuple, "name")] = miString(tuple, "value")
+	}
+	return locals
+}
+
+// miRegisters combines register names and values into a register map.
+// Parameters:
+// - names: The result record of `-data-list-register-names`.
+// - values: The result record of `-data-list-register-values`.
+// Returns:
+// - A map of register names to their hexadecimal values.
+func miRegisters(names, values miRecord) map[string]string {
+	registers := make(map[string]string)
+	nameList := miList(names.Results, "register-names")
+
+	for _, item := range miList(values.Results, "register-values") {
+		tuple, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(miString(tuple, "number"))
+		if err != nil || n < 0 || n >= len(nameList) {
+			continue
+		}
+		if name, ok := nameList[n].(string); ok && name != "" {
+			registers[name] = miString(tuple, "value")
+		}
+	}
+	return registers
+}
+
+// miSharedLibraries maps a `-file-list-shared-libraries` result into LibraryInfo objects.
+// Parameters:
+// - record: The result record of `-file-list-shared-libraries`.
+// Returns:
+// - A slice of LibraryInfo objects.
+func miSharedLibraries(record miRecord) []LibraryInfo {
+	var libraries []LibraryInfo
+	for _, item := range miList(record.Results, "shared-libraries") {
+		tuple, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name := miString(tuple, "host-name")
+		if name == "" {
+			name = miString(tuple, "id")
+		}
+		library := LibraryInfo{
+			Name:     name,
+			Version:  getLibraryVersion(name),
+			Type:     categorizeLibrary(name),
+			IsLoaded: miString(tuple, "symbols-loaded") == "1",
+		}
+		if ranges := miList(tuple, "ranges"); len(ranges) > 0 {
+			if r, ok := ranges[0].(map[string]interface{}); ok {
+				library.StartAddr = miString(r, "from")
+				library.EndAddr = miString(r, "to")
+			}
+		} else {
+			library.StartAddr = miString(tuple, "from")
+			library.EndAddr = miString(tuple, "to")
+		}
+		library.TextStart = library.StartAddr
+		library.TextEnd = library.EndAddr
+		libraries = append(libraries, library)
+	}
+	return libraries
+}
+
+// miSignalInfo decodes the value of `$_siginfo` into a SignalInfo object.
+// Parameters:
+// - record: The result record of `-data-evaluate-expression $_siginfo`.
+// Returns:
+// - A SignalInfo object; empty if the expression could not be evaluated.
+func miSignalInfo(record miRecord) SignalInfo {
+	fields, ok := parseSiginfoFields(miString(record.Results, "value"))
+	if !ok {
+		return SignalInfo{}
+	}
+	return fields.signalInfo()
+}
+
+// miCurrentInstruction formats the instruction at $pc from a `-data-disassemble` result.
+// Parameters:
+// - record: The result record of `-data-disassemble`.
+// Returns:
+// - The instruction in `x/1i $pc` style, or an empty string.
+func miCurrentInstruction(record miRecord) string {
+	insns := miList(record.Results, "asm_insns")
+	if len(insns) == 0 {
+		return ""
+	}
+	tuple, ok := insns[0].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s <%s+%s>:\t%s",
+		miString(tuple, "address"),
+		miString(tuple, "func-name"),
+		miString(tuple, "offset"),
+		miString(tuple, "inst"))
+}
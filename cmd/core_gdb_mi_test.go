@@ -0,0 +1,169 @@
+// File: cmd/core_gdb_mi_test.go
+package cmd
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMIValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected interface{}
+		rest     string
+	}{
+		{
+			name:     "c string with escapes",
+			input:    `"say \"hi\"\n",x`,
+			expected: "say \"hi\"\n",
+			rest:     ",x",
+		},
+		{
+			name:  "tuple",
+			input: `{level="0",func="raise"}`,
+			expected: map[string]interface{}{
+				"level": "0",
+				"func":  "raise",
+			},
+		},
+		{
+			name:  "list of named results",
+			input: `[frame={level="0"},frame={level="1"}]`,
+			expected: []interface{}{
+				map[string]interface{}{"level": "0"},
+				map[string]interface{}{"level": "1"},
+			},
+		},
+		{
+			name:     "list of values",
+			input:    `["rax","rbx",""]`,
+			expected: []interface{}{"rax", "rbx", ""},
+		},
+		{
+			name:     "empty list",
+			input:    `[]`,
+			expected: []interface{}(nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, rest, err := parseMIValue(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(value, tt.expected) {
+				t.Errorf("parseMIValue(%q) = %#v, want %#v", tt.input, value, tt.expected)
+			}
+			if rest != tt.rest {
+				t.Errorf("rest = %q, want %q", rest, tt.rest)
+			}
+		})
+	}
+}
+
+func TestParseMIOutput(t *testing.T) {
+	output := `=thread-group-added,id="i1"
+~"[New LWP 1234]\n"
+1^done,threads=[{id="1",target-id="LWP 1234",state="stopped"}],current-thread-id="1"
+(gdb)
+2^error,msg="No registers."
+`
+	records := parseMIOutput(output)
+
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	if records["1"].Class != "done" {
+		t.Errorf("record 1 class = %s, want done", records["1"].Class)
+	}
+	if records["1"].Console != "[New LWP 1234]\n" {
+		t.Errorf("record 1 console = %q", records["1"].Console)
+	}
+	if msg := miString(records["2"].Results, "msg"); msg != "No registers." {
+		t.Errorf("record 2 msg = %q, want %q", msg, "No registers.")
+	}
+}
+
+func TestGDBMIAnalysis(t *testing.T) {
+	firstPass := `1^done,threads=[{id="1",target-id="Thread 0x7f8b4c000700 (LWP 1234)",state="stopped"},{id="2",target-id="LWP 1235",state="stopped"}],current-thread-id="1"
+2^done,register-names=["rax","rbx","rip"]
+3^done,register-values=[{number="0",value="0x0"},{number="2",value="0x555555555000"}]
+4^done,shared-libraries=[{id="/lib64/libc.so.6",host-name="/lib64/libc.so.6",symbols-loaded="1",ranges=[{from="0x00007ffff7dd7000",to="0x00007ffff7dd8000"}]}]
+5^done,value="{si_signo = 11, si_errno = 0, si_code = 1, _sifields = {_sigfault = {si_addr = 0x0}}}"
+6^done,asm_insns=[{address="0x0000555555555000",func-name="ExecInterpExpr",offset="42",inst="mov (%rax),%rdx"}]`
+
+	secondPass := `1001^done,stack=[frame={level="0",addr="0x0000555555555000",func="ExecInterpExpr",file="execExprInterp.c",line="512"},frame={level="1",addr="0x00007ffff7dd7100",func="__libc_start_main",from="/lib64/libc.so.6"}]
+1002^done,stack-args=[frame={level="0",args=[{name="state",value="0x5555"},{name="isnull",value="0x0"}]},frame={level="1",args=[]}]
+1010^done,variables=[{name="op",value="0x0"},{name="state",arg="1",value="0x5555"}]
+1011^error,msg="Frame level 1 has no locals"
+2001^done,stack=[frame={level="0",addr="0x00007ffff7dd7200",func="rxThreadFunc",from="/usr/local/cloudberry/lib/postgresql/interconnect.so"}]
+2002^done,stack-args=[frame={level="0",args=[]}]`
+
+	mock := &MockCommander{
+		Outputs: []string{firstPass, secondPass},
+		Errors:  []error{nil, nil},
+	}
+	oldCmdExecutor := cmdExecutor
+	SetCommander(mock)
+	defer SetCommander(oldCmdExecutor)
+
+	analysis := &CoreAnalysis{CoreFile: "/tmp/core.1234"}
+	if err := gdbMIAnalysis(context.Background(), analysis, "/mock/path/postgres"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	commands := mock.GetCommands()
+	if len(commands) != 2 || !strings.Contains(commands[0], "--interpreter=mi3") {
+		t.Fatalf("unexpected gdb invocations: %v", commands)
+	}
+	if !strings.Contains(commands[1], `interpreter-exec mi3 "1001-stack-list-frames --thread 1"`) {
+		t.Errorf("second pass missing per-thread stack command: %s", commands[1])
+	}
+
+	if len(analysis.Threads) != 2 {
+		t.Fatalf("got %d threads, want 2", len(analysis.Threads))
+	}
+	crashed := analysis.Threads[0]
+	if !crashed.IsCrashed || crashed.LWPID != "1234" || crashed.ThreadAddr != "0x7f8b4c000700" {
+		t.Errorf("unexpected crashed thread: %+v", crashed)
+	}
+	if len(crashed.Backtrace) != 2 {
+		t.Fatalf("got %d frames, want 2", len(crashed.Backtrace))
+	}
+	top := crashed.Backtrace[0]
+	if top.Function != "ExecInterpExpr" || top.SourceFile != "execExprInterp.c" || top.LineNumber != 512 {
+		t.Errorf("unexpected top frame: %+v", top)
+	}
+	if top.Arguments != "state=0x5555, isnull=0x0" {
+		t.Errorf("Arguments = %q", top.Arguments)
+	}
+	if !reflect.DeepEqual(top.Locals, map[string]string{"op": "0x0"}) {
+		t.Errorf("Locals = %v", top.Locals)
+	}
+	if crashed.Backtrace[1].Module != "libc.so.6" {
+		t.Errorf("Module = %q, want libc.so.6", crashed.Backtrace[1].Module)
+	}
+	if analysis.Threads[1].Name != "Interconnect RX" {
+		t.Errorf("thread 2 name = %q, want Interconnect RX", analysis.Threads[1].Name)
+	}
+
+	if len(analysis.StackTrace) != 3 || analysis.StackTrace[0].Function != "ExecInterpExpr" {
+		t.Errorf("unexpected stack trace: %+v", analysis.StackTrace)
+	}
+	if analysis.Registers["rip"] != "0x555555555000" || analysis.Registers["rax"] != "0x0" {
+		t.Errorf("unexpected registers: %v", analysis.Registers)
+	}
+	if analysis.SignalInfo.SignalNumber != 11 || analysis.SignalInfo.SignalCode != 1 || analysis.SignalInfo.FaultAddress != "0x0" {
+		t.Errorf("unexpected signal info: %+v", analysis.SignalInfo)
+	}
+	if len(analysis.Libraries) != 1 || !analysis.Libraries[0].IsLoaded || analysis.Libraries[0].StartAddr != "0x00007ffff7dd7000" {
+		t.Errorf("unexpected libraries: %+v", analysis.Libraries)
+	}
+	if analysis.CurrentInstruction != "0x0000555555555000 <ExecInterpExpr+42>:\tmov (%rax),%rdx" {
+		t.Errorf("CurrentInstruction = %q", analysis.CurrentInstruction)
+	}
+}
@@ -2,10 +2,12 @@
 package cmd
 
 import (
+	"context"
   "fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -15,9 +17,13 @@ type MockCommander struct {
 	Errors  []error
 	index   int
 	cmds    []string
+	mu      sync.Mutex
 }
 
-func (m *MockCommander) Execute(name string, args ...string) ([]byte, error) {
+func (m *MockCommander) Execute(ctx context.Context, name string, args ...string) ([]byte, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
     // Record the command
     m.cmds = append(m.cmds, name+" "+strings.Join(args, " "))
 
@@ -122,7 +128,7 @@ _sigfault = {si_addr = 0x0}`,
 			defer SetCommander(oldCmdExecutor)
 
 			// Run analysis
-			result, err := analyzeCoreFile(corePath, gphome)
+			result, err := analyzeCoreFile(context.Background(), corePath, gphome)
 
 			// Check error
 			if tt.expectedError && err == nil {
@@ -161,6 +167,61 @@ _sigfault = {si_addr = 0x0}`,
 	}
 }
 
+func TestAnalyzeCoreFileFaultVerdictWithSignalHandler(t *testing.T) {
+	tmpDir := t.TempDir()
+	corePath := filepath.Join(tmpDir, "core.1234")
+	if err := os.WriteFile(corePath, []byte("mock core file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gphome := filepath.Join(tmpDir, "gphome")
+	if err := os.MkdirAll(filepath.Join(gphome, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(gphome, "bin", "postgres"), []byte("mock postgres binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The usual Cloudberry crash: the fault goes through the backend's handler
+	mock := &MockCommander{
+		Outputs: []string{
+			"core file from 'postgres' (signal 11)",
+			"postgres (PostgreSQL) 14.4",
+			"postgres (Cloudberry Database) 2.0.0",
+			"--with-openssl",
+			`Thread 1 (LWP 1234):
+#0  0x00007f8b4c37c425 in raise () from /lib64/libc.so.6
+#1  0x0000555555a01234 in StandardHandlerForSigillSigsegvSigbus_OnMainThread (processName=0x5555 "postgres", postgres_signal_arg=11) at elog.c:4500
+#2  <signal handler called>
+#3  0x0000555555601234 in ExecHashJoinImpl (pstate=0x0) at nodeHashjoin.c:300
+#4  0x0000555555602345 in ExecProcNode (node=0x0) at execProcnode.c:200
+
+si_signo = 11
+si_code = 1
+_sigfault = {si_addr = 0x18}`,
+		},
+		Errors: []error{nil, nil, nil, nil, nil},
+	}
+	oldCmdExecutor := cmdExecutor
+	SetCommander(mock)
+	defer SetCommander(oldCmdExecutor)
+
+	analysis, err := analyzeCoreFile(context.Background(), corePath, gphome)
+	if err != nil {
+		t.Fatalf("analyzeCoreFile() error = %v", err)
+	}
+	sig := analysis.SignalInfo
+	if !strings.HasPrefix(sig.SignalDescription, "Segmentation fault") {
+		t.Errorf("SignalDescription = %q, want the handler's description", sig.SignalDescription)
+	}
+	if sig.FaultClass != faultNullDereference || sig.FaultVerdict != "NULL pointer dereference (offset 0x18)" {
+		t.Errorf("FaultClass/FaultVerdict = %q/%q, want the NULL dereference verdict", sig.FaultClass, sig.FaultVerdict)
+	}
+	out := captureOutput(func() { printGDBStyle(analysis) })
+	if !strings.Contains(out, "Fault: NULL pointer dereference (offset 0x18)") {
+		t.Errorf("--gdb-style output lacks the verdict:\n%s", out)
+	}
+}
+
 func TestGDBAnalysis(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -210,7 +271,7 @@ _sigfault = {si_addr = 0x0}
 			analysis := &CoreAnalysis{}
 
 			// Run GDB analysis
-			err := gdbAnalysis(analysis, "/mock/path/postgres")
+			err := gdbAnalysis(context.Background(), analysis, "/mock/path/postgres")
 
 			// Check error
 			if tt.expectedError && err == nil {
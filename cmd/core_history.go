@@ -0,0 +1,167 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_history.go
+// Purpose: Implements the `core history` subcommands for querying the crash-signature store.
+// `list` shows every known signature, `show` prints the full record of one signature and
+// `search` finds signatures by function, signal, host, segment, version or core file.
+// Dependencies: Reads the store maintained by core_signatures.go.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var historyLimit int // Maximum number of signatures listed
+
+// coreHistoryCmd groups the crash history subcommands
+var coreHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Query previously seen crash signatures",
+	Long: `Query the crash-signature store kept in --output-dir.
+Each analyzed core is recorded under a normalized crash fingerprint, so recurring
+crashes can be recognized without re-running old analyses:
+  cbtoolbox core history list
+  cbtoolbox core history show 3f2a9c
+  cbtoolbox core history search ExecHashJoin`,
+}
+
+var coreHistoryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List known crash signatures, most recent first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openCrashStore(outputDir)
+		if err != nil {
+			return err
+		}
+		printSignatureTable(store.list())
+		return nil
+	},
+}
+
+var coreHistoryShowCmd = &cobra.Command{
+	Use:   "show <signature-id>",
+	Short: "Show the full record of a crash signature",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateDataFormat(formatFlag); err != nil {
+			return err
+		}
+		store, err := openCrashStore(outputDir)
+		if err != nil {
+			return err
+		}
+		sig, err := store.lookup(args[0])
+		if err != nil {
+			return err
+		}
+
+		var data []byte
+		if formatFlag == "json" {
+			data, err = json.MarshalIndent(sig, "", "  ")
+		} else {
+			data, err = yaml.Marshal(sig)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to marshal crash signature: %w", err)
+		}
+		fmt.Println(strings.TrimRight(string(data), "\n"))
+		return nil
+	},
+}
+
+var coreHistorySearchCmd = &cobra.Command{
+	Use:   "search <term>",
+	Short: "Find crash signatures by function, signal, host, segment, version or core file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openCrashStore(outputDir)
+		if err != nil {
+			return err
+		}
+		printSignatureTable(searchSignatures(store.list(), args[0]))
+		return nil
+	},
+}
+
+func init() {
+	coreCmd.AddCommand(coreHistoryCmd)
+	coreHistoryCmd.AddCommand(coreHistoryListCmd, coreHistoryShowCmd, coreHistorySearchCmd)
+	coreHistoryCmd.PersistentFlags().IntVar(&historyLimit, "limit", 0, "Maximum number of signatures to list (0 lists all)")
+}
+
+// searchSignatures filters signatures by a case-insensitive search term.
+// Parameters:
+// - sigs: The signatures to search.
+// - term: The text to look for.
+// Returns:
+// - The matching signatures, in their original order.
+func searchSignatures(sigs []*CrashSignature, term string) []*CrashSignature {
+	term = strings.ToLower(term)
+	var matches []*CrashSignature
+	for _, sig := range sigs {
+		fields := []string{sig.ID, sig.Signal}
+		fields = append(fields, sig.Frames...)
+		fields = append(fields, sig.Hosts...)
+		fields = append(fields, sig.Segments...)
+		for _, v := range sig.Versions {
+			fields = append(fields, v.Version)
+		}
+		for _, occ := range sig.Occurrences {
+			fields = append(fields, occ.CoreFile)
+		}
+		for _, field := range fields {
+			if strings.Contains(strings.ToLower(field), term) {
+				matches = append(matches, sig)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// printSignatureTable prints a one-line summary per crash signature.
+// Parameters:
+// - sigs: The signatures to print.
+func printSignatureTable(sigs []*CrashSignature) {
+	if len(sigs) == 0 {
+		fmt.Println("No crash signatures found")
+		return
+	}
+	if historyLimit > 0 && len(sigs) > historyLimit {
+		sigs = sigs[:historyLimit]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCOUNT\tFIRST SEEN\tLAST SEEN\tFIRST BUILD\tSIGNAL\tTOP FRAME")
+	for _, sig := range sigs {
+		topFrame, firstBuild := "??", "unknown"
+		if len(sig.Frames) > 0 {
+			topFrame = sig.Frames[0]
+		}
+		if len(sig.Versions) > 0 {
+			firstBuild = sig.Versions[0].Version
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			sig.ID, sig.Count, sig.FirstSeen, sig.LastSeen, firstBuild, sig.Signal, topFrame)
+	}
+	w.Flush()
+}
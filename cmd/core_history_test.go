@@ -0,0 +1,78 @@
+// File: cmd/core_history_test.go
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+// historyTestStore builds a store with two signatures.
+func historyTestStore() *crashStore {
+	store := &crashStore{signatures: make(map[string]*CrashSignature)}
+	store.record(newAnalysis("/cores/core.1", "SIGSEGV", testFrames("ExecHashJoinImpl", "ExecProcNode"), coreOrigin("2026-10-01T10:00:00Z", "1.6.0", "0")), "sdw1")
+	store.record(newAnalysis("/cores/core.2", "SIGABRT", testFrames("ExceptionalCondition", "heap_insert"), coreOrigin("2026-10-05T10:00:00Z", "2.0.0", "2")), "sdw2")
+	return store
+}
+
+func TestSearchSignatures(t *testing.T) {
+	sigs := historyTestStore().list()
+
+	tests := []struct {
+		term string
+		want int
+	}{
+		{"hashjoin", 1},
+		{"SIGABRT", 1},
+		{"sdw", 2},
+		{"2.0.0", 1},
+		{"core.1", 1},
+		{"nothing-matches", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			if got := searchSignatures(sigs, tt.term); len(got) != tt.want {
+				t.Errorf("searchSignatures(%q) returned %d, want %d", tt.term, len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestCrashStoreLookup(t *testing.T) {
+	store := historyTestStore()
+	sigs := store.list()
+
+	if sigs[0].Signal != "SIGABRT" {
+		t.Errorf("list() should return the most recent signature first, got %s", sigs[0].Signal)
+	}
+
+	sig, err := store.lookup(sigs[1].ID[:8])
+	if err != nil {
+		t.Fatalf("lookup() error = %v", err)
+	}
+	if sig.ID != sigs[1].ID {
+		t.Errorf("lookup() = %s, want %s", sig.ID, sigs[1].ID)
+	}
+	if _, err := store.lookup("zzzz"); err == nil {
+		t.Error("expected error for unknown signature")
+	}
+	if _, err := store.lookup(""); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("expected ambiguous prefix error, got %v", err)
+	}
+}
+
+func TestPrintSignatureTable(t *testing.T) {
+	output := capturePrinterOutput(func() {
+		printSignatureTable(historyTestStore().list())
+	})
+	for _, want := range []string{"FIRST BUILD", "ExecHashJoinImpl", "ExceptionalCondition", "1.6.0"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+
+	output = capturePrinterOutput(func() { printSignatureTable(nil) })
+	if !strings.Contains(output, "No crash signatures found") {
+		t.Errorf("unexpected output for empty store: %s", output)
+	}
+}
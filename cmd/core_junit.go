@@ -0,0 +1,277 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_junit.go
+// Purpose: Renders core analyses as JUnit XML (--format junit) so CI servers report cores as failed
+// tests. Each core is a failed testcase named by its crash signature, so CI test history follows a
+// recurring crash across builds; the body is the crashed thread's backtrace, and the core file, host
+// and segment are testcase properties. Testsuites group the cores of one crash pattern.
+// Dependencies: Uses encoding/xml and the crash fingerprint of core_signatures.go.
+
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const junitClassName = "cbtoolbox.core" // Testcase class name for analyzed cores
+
+// junitTestSuites is the document root of a JUnit report.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite groups the testcases of one crash pattern.
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Hostname  string          `xml:"hostname,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is one analyzed core.
+type junitTestCase struct {
+	Name       string          `xml:"name,attr"`
+	ClassName  string          `xml:"classname,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Failure    *junitFailure   `xml:"failure,omitempty"`
+}
+
+// junitProperty is a name/value pair attached to a testcase.
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// junitFailure marks a testcase as failed.
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// junitHostname returns the host the cores were analyzed on.
+func junitHostname() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return pseudonymizeHostname(host)
+}
+
+// junitSignature describes a crash for a failure message, e.g.
+// "SIGSEGV in heap_fetch ← index_fetch_heap (fingerprint 1a2b3c4d5e6f7a8b)".
+func junitSignature(analysis CoreAnalysis) string {
+	id, frames := crashFingerprint(analysis)
+	signal := analysis.SignalInfo.SignalName
+	if signal == "" {
+		signal = "crash"
+	}
+	if len(frames) == 0 {
+		return fmt.Sprintf("%s (fingerprint %s)", signal, id)
+	}
+	return fmt.Sprintf("%s in %s (fingerprint %s)", signal, strings.Join(frames, " ← "), id)
+}
+
+// junitFailureBody is the crash explanation followed by the crashed thread's backtrace.
+func junitFailureBody(analysis CoreAnalysis) string {
+	var lines []string
+	if info := analysis.AbortInfo; info != nil {
+		lines = append(lines, info.Summary)
+	}
+	if verdict := analysis.SignalInfo.FaultVerdict; verdict != "" {
+		lines = append(lines, "Fault: "+verdict)
+	}
+	if usage := analysis.StackUsage; usage != nil && usage.Exhausted {
+		lines = append(lines, "Stack: "+usage.Verdict)
+	}
+	for _, issue := range analysis.KnownIssues {
+		lines = append(lines, fmt.Sprintf("Known issue %s: %s", issue.ID, issue.Title))
+	}
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+
+	threads := reportThreads(analysis)
+	if len(threads) > 0 && threads[0].Crashed {
+		lines = append(lines, threads[0].Title+":")
+		lines = append(lines, markdownBacktrace(threads[0])...)
+	} else {
+		for _, frame := range analysis.StackTrace {
+			lines = append(lines, reportFrameLine(frame))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// junitTestCaseFor converts an analysis into a failed testcase.
+// Parameters:
+// - analysis: The analyzed core.
+// - host: The host the core was analyzed on.
+// Returns:
+// - The testcase.
+func junitTestCaseFor(analysis CoreAnalysis, host string) junitTestCase {
+	props := []junitProperty{
+		{"core_file", analysis.CoreFile},
+		{"host", host},
+	}
+	segment := analysis.BasicInfo["segment_id"]
+	if session := analysis.SessionInfo; session != nil && session.SegmentID != nil {
+		segment = strconv.Itoa(*session.SegmentID)
+	}
+	if segment != "" && segment != "N/A" {
+		props = append(props, junitProperty{"segment", segment})
+	}
+	if analysis.Fingerprint != "" {
+		props = append(props, junitProperty{"fingerprint", analysis.Fingerprint})
+	}
+	if analysis.PostgresInfo.GPVersion != "" {
+		props = append(props, junitProperty{"version", analysis.PostgresInfo.GPVersion})
+	}
+	for _, issue := range analysis.KnownIssues {
+		props = append(props, junitProperty{"known_issue", issue.ID})
+	}
+
+	// Core file names carry a PID and timestamp; the signature stays the same from build to build
+	return junitTestCase{
+		Name:       junitSignature(analysis),
+		ClassName:  junitClassName,
+		Time:       "0",
+		Properties: props,
+		Failure: &junitFailure{
+			Message: junitSignature(analysis),
+			Type:    analysis.SignalInfo.SignalName,
+			Body:    junitFailureBody(analysis),
+		},
+	}
+}
+
+// marshalJUnit serializes a JUnit report with an XML declaration.
+func marshalJUnit(report junitTestSuites) ([]byte, error) {
+	for _, suite := range report.Suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+	}
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to render JUnit report: %w", err)
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// renderAnalysisJUnit renders a single core analysis as a JUnit report with one failed testcase.
+// Parameters:
+// - analysis: The CoreAnalysis object to render.
+// Returns:
+// - The JUnit XML document.
+// - An error if rendering fails.
+func renderAnalysisJUnit(analysis CoreAnalysis) ([]byte, error) {
+	host := junitHostname()
+	suite := junitTestSuite{
+		Name:      junitPatternName(crashPatternSignature(analysis)),
+		Tests:     1,
+		Failures:  1,
+		Timestamp: analysis.Timestamp,
+		Hostname:  host,
+		Cases:     []junitTestCase{junitTestCaseFor(analysis, host)},
+	}
+	return marshalJUnit(junitTestSuites{Name: "cbtoolbox core", Suites: []junitTestSuite{suite}})
+}
+
+// junitPatternName names the testsuite of a crash pattern signature, e.g. "SIGSEGV: heap_fetch ← IndexNext".
+func junitPatternName(signature string) string {
+	parts := strings.Split(signature, "|")
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return parts[0] + ": " + strings.Join(parts[1:], " ← ")
+}
+
+// numberDuplicateCases keeps testcase names unique within a suite, as CI servers key results by
+// name: the second core with a signature becomes "<signature> #2", the third "<signature> #3".
+// The first keeps the bare signature, so a crash seen once is named the same from build to build.
+// Parameters:
+// - cases: The testcases of one suite, renamed in place.
+func numberDuplicateCases(cases []junitTestCase) {
+	seen := make(map[string]int)
+	for i := range cases {
+		seen[cases[i].Name]++
+		if n := seen[cases[i].Name]; n > 1 {
+			cases[i].Name = fmt.Sprintf("%s #%d", cases[i].Name, n)
+		}
+	}
+}
+
+// renderComparisonJUnit renders a comparison as one testsuite per crash pattern. Every compared
+// core is a testcase; cores that match no other core form a pattern of their own.
+// Parameters:
+// - comparison: The CoreComparison object to render.
+// Returns:
+// - The JUnit XML document.
+// - An error if rendering fails.
+func renderComparisonJUnit(comparison CoreComparison) ([]byte, error) {
+	host := junitHostname()
+	groups := make(map[string][]junitTestCase)
+	if len(comparison.analyses) > 0 {
+		for _, analysis := range comparison.analyses {
+			signature := crashPatternSignature(analysis)
+			groups[signature] = append(groups[signature], junitTestCaseFor(analysis, host))
+		}
+	} else {
+		// Without the analyses only the recurring patterns and their core files are known
+		for _, pattern := range comparison.CrashPatterns {
+			signature := strings.Join(append([]string{pattern.Signal}, pattern.StackSignature...), "|")
+			for _, core := range pattern.AffectedCoreFiles {
+				groups[signature] = append(groups[signature], junitTestCase{
+					Name:       junitPatternName(signature),
+					ClassName:  junitClassName,
+					Time:       "0",
+					Properties: []junitProperty{{"core_file", core}},
+					Failure:    &junitFailure{Message: junitPatternName(signature), Type: pattern.Signal},
+				})
+			}
+		}
+	}
+
+	var suites []junitTestSuite
+	for signature, cases := range groups {
+		numberDuplicateCases(cases)
+		suites = append(suites, junitTestSuite{
+			Name:      junitPatternName(signature),
+			Tests:     len(cases),
+			Failures:  len(cases),
+			Timestamp: comparison.TimeRange["last"],
+			Hostname:  host,
+			Cases:     cases,
+		})
+	}
+	sort.Slice(suites, func(i, j int) bool {
+		if suites[i].Tests != suites[j].Tests {
+			return suites[i].Tests > suites[j].Tests
+		}
+		return suites[i].Name < suites[j].Name
+	})
+	return marshalJUnit(junitTestSuites{Name: "cbtoolbox core --compare", Suites: suites})
+}
@@ -0,0 +1,116 @@
+// File: cmd/core_junit_test.go
+package cmd
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+// parseJUnit decodes a rendered JUnit report.
+func parseJUnit(t *testing.T, data []byte) junitTestSuites {
+	t.Helper()
+	if !strings.HasPrefix(string(data), "<?xml") {
+		t.Errorf("report does not start with an XML declaration")
+	}
+	var report junitTestSuites
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatalf("invalid JUnit XML: %v\n%s", err, data)
+	}
+	return report
+}
+
+func TestRenderAnalysisJUnit(t *testing.T) {
+	analysis := reportAnalysis()
+	segment := 1
+	analysis.SessionInfo.SegmentID = &segment
+	analysis.StackTrace = analysis.Threads[1].Backtrace
+	var frames []string
+	analysis.Fingerprint, frames = crashFingerprint(analysis)
+	if len(frames) == 0 || frames[0] != "pg_detoast_datum" {
+		t.Fatalf("fingerprint frames = %v", frames)
+	}
+
+	data, err := renderAnalysisJUnit(analysis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := parseJUnit(t, data)
+	if report.Tests != 1 || report.Failures != 1 || len(report.Suites) != 1 || len(report.Suites[0].Cases) != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	// The testcase is named by the crash, not the core file, so CI history follows it across builds
+	tc := report.Suites[0].Cases[0]
+	want := "SIGSEGV in " + strings.Join(frames, " ← ") + " (fingerprint " + analysis.Fingerprint + ")"
+	if tc.Name != want || tc.ClassName != junitClassName {
+		t.Errorf("testcase = %s/%s, want name %q", tc.ClassName, tc.Name, want)
+	}
+	if strings.Contains(report.Suites[0].Name, "core.4242") {
+		t.Errorf("suite name %q depends on the core file", report.Suites[0].Name)
+	}
+	if tc.Failure == nil || tc.Failure.Type != "SIGSEGV" {
+		t.Fatalf("failure = %+v", tc.Failure)
+	}
+	if tc.Failure.Message != want {
+		t.Errorf("message = %q, want %q", tc.Failure.Message, want)
+	}
+	for _, want := range []string{"PANIC: could not write to file", "Fault: NULL pointer dereference", "Thread 1 [LWP 4242]:", "#0   pg_detoast_datum at detoast.c:120", "repeated 20 times"} {
+		if !strings.Contains(tc.Failure.Body, want) {
+			t.Errorf("failure body missing %q:\n%s", want, tc.Failure.Body)
+		}
+	}
+
+	props := make(map[string]string)
+	for _, p := range tc.Properties {
+		props[p.Name] = p.Value
+	}
+	if props["core_file"] != "/var/cores/core.4242" || props["segment"] != "1" || props["host"] == "" || props["fingerprint"] != analysis.Fingerprint {
+		t.Errorf("properties = %v", props)
+	}
+}
+
+func TestRenderComparisonJUnit(t *testing.T) {
+	analyses := []CoreAnalysis{
+		newAnalysis("core.1", "SIGSEGV", testFrames("heap_fetch", "IndexNext", "ExecScan")),
+		newAnalysis("core.2", "SIGSEGV", testFrames("heap_fetch", "IndexNext", "ExecScan")),
+		newAnalysis("core.3", "SIGABRT", testFrames("ExceptionalCondition", "ProcArrayEndTransaction")),
+	}
+	data, err := renderComparisonJUnit(compareCores(analyses))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := parseJUnit(t, data)
+	if report.Tests != 3 || report.Failures != 3 || len(report.Suites) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	if s := report.Suites[0]; s.Name != "SIGSEGV: heap_fetch ← IndexNext ← ExecScan" || s.Tests != 2 {
+		t.Errorf("suites[0] = %s (%d tests)", s.Name, s.Tests)
+	}
+	if s := report.Suites[1]; s.Name != "SIGABRT: ExceptionalCondition ← ProcArrayEndTransaction" || s.Cases[0].Properties[0].Value != "core.3" {
+		t.Errorf("suites[1] = %+v", s)
+	}
+	// Cores with the same crash are named by the crash, numbered to keep names unique in the suite
+	if cases := report.Suites[0].Cases; cases[0].Name != junitSignature(analyses[0]) || cases[1].Name != cases[0].Name+" #2" ||
+		strings.Contains(cases[0].Name, "core.") {
+		t.Errorf("testcase names = %q, %q", cases[0].Name, cases[1].Name)
+	}
+
+	// A comparison read back from disk only knows the recurring patterns
+	saved := compareCores(analyses)
+	saved.analyses = nil
+	data, err = renderComparisonJUnit(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report = parseJUnit(t, data)
+	if len(report.Suites) != 1 || report.Tests != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	if tc := report.Suites[0].Cases[0]; tc.Name != report.Suites[0].Name || tc.Properties[0].Name != "core_file" {
+		t.Errorf("testcase = %+v", tc)
+	}
+	if cases := report.Suites[0].Cases; cases[1].Name != report.Suites[0].Name+" #2" {
+		t.Errorf("testcase names = %q, %q", cases[0].Name, cases[1].Name)
+	}
+}
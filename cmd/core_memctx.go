@@ -0,0 +1,300 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_memctx.go
+// Purpose: Reconstructs the crashed backend's memory-context tree from the core. Functions cannot be
+// called in a core, so MemoryContextStats is unavailable; instead a gdb script walks TopMemoryContext
+// through the firstchild/nextchild/parent links and, for AllocSet contexts, sums the block list and
+// freelists the same way AllocSetStats does. The result is stored as a tree with per-context totals
+// and the largest contexts, and rendered in MemoryContextStats' log format.
+// Dependencies: The script runs in the same gdb invocation as the rest of the analysis.
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var memoryContextTop int // Number of largest contexts listed in the report
+
+func init() {
+	coreCmd.PersistentFlags().IntVar(&memoryContextTop, "memory-top", 10, "Number of largest memory contexts to list")
+}
+
+const (
+	memoryContextBegin = "@@cbtoolbox-memctx-begin"
+	memoryContextEnd   = "@@cbtoolbox-memctx-end"
+	memoryContextLine  = "@@memctx "
+
+	memoryContextLimit       = 100000 // Stop walking after this many contexts
+	memoryContextMaxChildren = 100    // Children printed per parent, as MemoryContextStats does
+	memoryContextIdentLen    = 100    // Ident characters printed per context
+)
+
+// memoryContextScript walks the memory-context tree without recursion, using the parent links to
+// climb back up. One line is printed per context:
+// @@memctx depth|address|type|total|blocks|free|free chunks|name|ident
+var memoryContextScript = `set $cbt_n = 0
+set $cbt_depth = 0
+set $cbt_c = TopMemoryContext
+echo ` + memoryContextBegin + `\n
+while $cbt_c != 0 && $cbt_n < ` + strconv.Itoa(memoryContextLimit) + `
+  set $cbt_n = $cbt_n + 1
+  set $cbt_total = $cbt_c->mem_allocated
+  set $cbt_blocks = 0
+  set $cbt_free = 0
+  set $cbt_chunks = 0
+  if $cbt_c->type == T_AllocSetContext
+    set $cbt_set = (AllocSet) $cbt_c
+    set $cbt_total = 0
+    set $cbt_b = $cbt_set->blocks
+    while $cbt_b != 0
+      set $cbt_blocks = $cbt_blocks + 1
+      set $cbt_total = $cbt_total + ($cbt_b->endptr - (char *) $cbt_b)
+      set $cbt_free = $cbt_free + ($cbt_b->endptr - $cbt_b->freeptr)
+      set $cbt_b = $cbt_b->next
+    end
+    set $cbt_i = 0
+    while $cbt_i < sizeof($cbt_set->freelist) / sizeof($cbt_set->freelist[0])
+      set $cbt_ch = $cbt_set->freelist[$cbt_i]
+      while $cbt_ch != 0
+        set $cbt_chunks = $cbt_chunks + 1
+        set $cbt_free = $cbt_free + $cbt_ch->size + sizeof(AllocChunkData)
+        set $cbt_ch = (AllocChunk) $cbt_ch->aset
+      end
+      set $cbt_i = $cbt_i + 1
+    end
+  end
+  printf "` + memoryContextLine + `%d|%p|", $cbt_depth, $cbt_c
+  output $cbt_c->type
+  printf "|%lu|%lu|%lu|%lu|%s|", $cbt_total, $cbt_blocks, $cbt_free, $cbt_chunks, $cbt_c->name
+  if $cbt_c->ident != 0
+    printf "%s", $cbt_c->ident
+  end
+  echo \n
+  if $cbt_c->firstchild != 0
+    set $cbt_c = $cbt_c->firstchild
+    set $cbt_depth = $cbt_depth + 1
+  else
+    while $cbt_c != 0 && $cbt_c->nextchild == 0
+      set $cbt_c = $cbt_c->parent
+      set $cbt_depth = $cbt_depth - 1
+    end
+    if $cbt_c != 0
+      set $cbt_c = $cbt_c->nextchild
+    end
+  end
+end
+echo ` + memoryContextEnd + `\n
+`
+
+// parseMemoryContexts rebuilds the memory-context tree from the walk's output.
+// Lines that do not start a context continue the previous context's ident, since idents
+// (query strings, for example) may span lines.
+// Parameters:
+// - output: The gdb output containing the walk.
+// Returns:
+// - The memory-context report, or nil if the walk produced no contexts.
+func parseMemoryContexts(output string) *MemoryContextReport {
+	start := strings.Index(output, memoryContextBegin)
+	if start < 0 {
+		return nil
+	}
+	block := output[start+len(memoryContextBegin):]
+	complete := false
+	if end := strings.Index(block, memoryContextEnd); end >= 0 {
+		block, complete = block[:end], true
+	}
+
+	report := &MemoryContextReport{}
+	var stack []*MemoryContextNode
+	var last *MemoryContextNode
+	for _, line := range strings.Split(block, "\n") {
+		if !strings.HasPrefix(line, memoryContextLine) {
+			if last != nil && line != "" {
+				last.Ident += "\n" + line
+			}
+			continue
+		}
+		node, depth, ok := parseMemoryContextLine(strings.TrimPrefix(line, memoryContextLine))
+		if !ok || depth > len(stack) || (depth == 0 && report.Root != nil) {
+			last = nil
+			continue
+		}
+		stack = stack[:depth]
+		if depth == 0 {
+			report.Root = node
+		} else {
+			parent := stack[depth-1]
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, node)
+		last = node
+	}
+	if report.Root == nil {
+		return nil
+	}
+
+	report.Truncated = !complete
+	summarizeMemoryContexts(report)
+	return report
+}
+
+// parseMemoryContextLine decodes one context line of the walk.
+// Returns:
+// - The context, its depth in the tree, and false if the line is malformed.
+func parseMemoryContextLine(line string) (*MemoryContextNode, int, bool) {
+	fields := strings.SplitN(line, "|", 9)
+	if len(fields) != 9 {
+		return nil, 0, false
+	}
+	depth, err := strconv.Atoi(fields[0])
+	if err != nil || depth < 0 {
+		return nil, 0, false
+	}
+	var nums [4]uint64
+	for i := range nums {
+		if nums[i], err = strconv.ParseUint(fields[3+i], 10, 64); err != nil {
+			return nil, 0, false
+		}
+	}
+
+	node := &MemoryContextNode{
+		Name:       fields[7],
+		Ident:      fields[8],
+		Address:    fields[1],
+		Type:       strings.TrimPrefix(strings.TrimSuffix(fields[2], "Context"), "T_"),
+		TotalBytes: nums[0],
+		Blocks:     nums[1],
+		FreeBytes:  nums[2],
+		FreeChunks: nums[3],
+	}
+	if node.FreeBytes <= node.TotalBytes {
+		node.UsedBytes = node.TotalBytes - node.FreeBytes
+	}
+	return node, depth, true
+}
+
+// summarizeMemoryContexts fills the subtree and grand totals and the largest contexts.
+// Parameters:
+// - report: The report whose tree has been built; updated in place.
+func summarizeMemoryContexts(report *MemoryContextReport) {
+	type ranked struct {
+		node *MemoryContextNode
+		path string
+	}
+	var all []ranked
+
+	var walk func(node *MemoryContextNode, path string) uint64
+	walk = func(node *MemoryContextNode, path string) uint64 {
+		if path != "" {
+			path += "/"
+		}
+		path += node.Name
+		all = append(all, ranked{node, path})
+
+		report.ContextCount++
+		report.TotalBytes += node.TotalBytes
+		report.FreeBytes += node.FreeBytes
+		report.UsedBytes += node.UsedBytes
+		report.Blocks += node.Blocks
+		report.FreeChunks += node.FreeChunks
+
+		node.SubtreeTotalBytes = node.TotalBytes
+		for _, child := range node.Children {
+			node.SubtreeTotalBytes += walk(child, path)
+		}
+		return node.SubtreeTotalBytes
+	}
+	walk(report.Root, "")
+
+	sort.SliceStable(all, func(i, j int) bool { return all[i].node.TotalBytes > all[j].node.TotalBytes })
+	for i := 0; i < len(all) && i < memoryContextTop; i++ {
+		n := all[i].node
+		report.Largest = append(report.Largest, MemoryContextSummary{
+			Path:       all[i].path,
+			Ident:      n.Ident,
+			TotalBytes: n.TotalBytes,
+			UsedBytes:  n.UsedBytes,
+		})
+	}
+}
+
+// memoryContextStatsLine formats totals the way AllocSetStats does.
+func memoryContextStatsLine(total, blocks, free, chunks, used uint64) string {
+	return fmt.Sprintf("%d total in %d blocks; %d free (%d chunks); %d used", total, blocks, free, chunks, used)
+}
+
+// identWhitespaceRE matches the line breaks and indentation in multi-line idents.
+var identWhitespaceRE = regexp.MustCompile(`\s+`)
+
+// displayIdent shortens an ident for a single log line.
+func displayIdent(ident string) string {
+	ident = identWhitespaceRE.ReplaceAllString(ident, " ")
+	if len(ident) > memoryContextIdentLen {
+		ident = ident[:memoryContextIdentLen]
+	}
+	return ident
+}
+
+// formatMemoryContexts renders the tree in MemoryContextStats' server log format.
+// Parameters:
+// - report: The memory-context report.
+// Returns:
+// - The formatted lines.
+func formatMemoryContexts(report *MemoryContextReport) []string {
+	var lines []string
+	var walk func(node *MemoryContextNode, level int)
+	walk = func(node *MemoryContextNode, level int) {
+		line := fmt.Sprintf("%s%s: %s", strings.Repeat("  ", level), node.Name,
+			memoryContextStatsLine(node.TotalBytes, node.Blocks, node.FreeBytes, node.FreeChunks, node.UsedBytes))
+		if node.Ident != "" {
+			line += ": " + displayIdent(node.Ident)
+		}
+		lines = append(lines, line)
+
+		for i, child := range node.Children {
+			if i == memoryContextMaxChildren {
+				var rest MemoryContextReport
+				for _, c := range node.Children[i:] {
+					sumMemoryContext(c, &rest)
+				}
+				lines = append(lines, fmt.Sprintf("%s%d more child contexts containing %s",
+					strings.Repeat("  ", level+1), len(node.Children)-i,
+					memoryContextStatsLine(rest.TotalBytes, rest.Blocks, rest.FreeBytes, rest.FreeChunks, rest.UsedBytes)))
+				break
+			}
+			walk(child, level+1)
+		}
+	}
+	walk(report.Root, 0)
+
+	lines = append(lines, fmt.Sprintf("Grand total: %d bytes in %d blocks; %d free (%d chunks); %d used",
+		report.TotalBytes, report.Blocks, report.FreeBytes, report.FreeChunks, report.UsedBytes))
+	return lines
+}
+
+// sumMemoryContext adds the totals of a context and its descendants to acc.
+func sumMemoryContext(node *MemoryContextNode, acc *MemoryContextReport) {
+	acc.TotalBytes += node.TotalBytes
+	acc.FreeBytes += node.FreeBytes
+	acc.UsedBytes += node.UsedBytes
+	acc.Blocks += node.Blocks
+	acc.FreeChunks += node.FreeChunks
+	for _, child := range node.Children {
+		sumMemoryContext(child, acc)
+	}
+}
@@ -0,0 +1,127 @@
+// File: cmd/core_memctx_test.go
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// memctxLine builds one line of memory-context walk output.
+func memctxLine(depth int, typ string, total, blocks, free, chunks uint64, name, ident string) string {
+	return fmt.Sprintf("@@memctx %d|0x%x|%s|%d|%d|%d|%d|%s|%s",
+		depth, 0x1000+depth, typ, total, blocks, free, chunks, name, ident)
+}
+
+const sampleMemoryContextOutput = `Thread 1 "postgres" received signal SIGABRT
+@@cbtoolbox-memctx-begin
+@@memctx 0|0x55d5c0a00000|T_AllocSetContext|97664|6|14040|20|TopMemoryContext|
+@@memctx 1|0x55d5c0a10000|T_AllocSetContext|8192|1|7928|0|TopTransactionContext|
+@@memctx 1|0x55d5c0a20000|T_AllocSetContext|1073750016|130|1024|2|MessageContext|
+@@memctx 2|0x55d5c0a30000|T_AllocSetContext|4096|3|1504|0|CachedPlanSource|select *
+  from big_table
+@@memctx 2|0x55d5c0a40000|T_GenerationContext|32768|0|0|0|ReorderBufferChange|
+@@memctx 1|0x55d5c0a50000|T_AllocSetContext|8192|1|7720|1|PortalContext|<unnamed>
+@@cbtoolbox-memctx-end
+`
+
+func TestParseMemoryContexts(t *testing.T) {
+	oldTop := memoryContextTop
+	defer func() { memoryContextTop = oldTop }()
+	memoryContextTop = 2
+
+	report := parseMemoryContexts(sampleMemoryContextOutput)
+	if report == nil {
+		t.Fatal("parseMemoryContexts() = nil")
+	}
+	if report.ContextCount != 6 || report.Truncated {
+		t.Errorf("ContextCount = %d, Truncated = %v", report.ContextCount, report.Truncated)
+	}
+
+	root := report.Root
+	if root.Name != "TopMemoryContext" || len(root.Children) != 3 {
+		t.Fatalf("root = %+v", root)
+	}
+	message := root.Children[1]
+	if message.Name != "MessageContext" || len(message.Children) != 2 || message.UsedBytes != 1073750016-1024 {
+		t.Errorf("MessageContext = %+v", message)
+	}
+	if want := uint64(1073750016 + 4096 + 32768); message.SubtreeTotalBytes != want {
+		t.Errorf("SubtreeTotalBytes = %d, want %d", message.SubtreeTotalBytes, want)
+	}
+	if plan := message.Children[0]; plan.Ident != "select *\n  from big_table" {
+		t.Errorf("multi-line ident = %q", plan.Ident)
+	}
+	if gen := message.Children[1]; gen.Type != "Generation" || gen.TotalBytes != 32768 {
+		t.Errorf("generation context = %+v", gen)
+	}
+
+	if want := uint64(97664 + 8192 + 1073750016 + 4096 + 32768 + 8192); report.TotalBytes != want {
+		t.Errorf("TotalBytes = %d, want %d", report.TotalBytes, want)
+	}
+	if len(report.Largest) != 2 || report.Largest[0].Path != "TopMemoryContext/MessageContext" {
+		t.Errorf("Largest = %+v", report.Largest)
+	}
+}
+
+func TestParseMemoryContextsIncomplete(t *testing.T) {
+	if got := parseMemoryContexts("no walk output"); got != nil {
+		t.Errorf("parseMemoryContexts(no walk) = %+v, want nil", got)
+	}
+	// gdb stops a sourced script at the first error, e.g. when TopMemoryContext has no type
+	if got := parseMemoryContexts("@@cbtoolbox-memctx-begin\n"); got != nil {
+		t.Errorf("parseMemoryContexts(empty walk) = %+v, want nil", got)
+	}
+
+	output := "@@cbtoolbox-memctx-begin\n" +
+		memctxLine(0, "T_AllocSetContext", 8192, 1, 100, 0, "TopMemoryContext", "") + "\n" +
+		memctxLine(1, "T_AllocSetContext", 8192, 1, 100, 0, "ErrorContext", "") + "\n"
+	report := parseMemoryContexts(output)
+	if report == nil || !report.Truncated || report.ContextCount != 2 {
+		t.Errorf("report = %+v, want 2 contexts, truncated", report)
+	}
+}
+
+func TestFormatMemoryContexts(t *testing.T) {
+	lines := []string{"@@cbtoolbox-memctx-begin",
+		memctxLine(0, "T_AllocSetContext", 8192, 1, 1000, 2, "TopMemoryContext", ""),
+	}
+	for i := 0; i < memoryContextMaxChildren+3; i++ {
+		lines = append(lines, memctxLine(1, "T_AllocSetContext", 1024, 1, 24, 0, fmt.Sprintf("child%d", i), ""))
+	}
+	lines = append(lines, "@@cbtoolbox-memctx-end")
+	report := parseMemoryContexts(strings.Join(lines, "\n"))
+
+	formatted := formatMemoryContexts(report)
+	if formatted[0] != "TopMemoryContext: 8192 total in 1 blocks; 1000 free (2 chunks); 7192 used" {
+		t.Errorf("root line = %q", formatted[0])
+	}
+	if formatted[1] != "  child0: 1024 total in 1 blocks; 24 free (0 chunks); 1000 used" {
+		t.Errorf("child line = %q", formatted[1])
+	}
+	if want := "  3 more child contexts containing 3072 total in 3 blocks; 72 free (0 chunks); 3000 used"; formatted[len(formatted)-2] != want {
+		t.Errorf("summary line = %q, want %q", formatted[len(formatted)-2], want)
+	}
+	if !strings.HasPrefix(formatted[len(formatted)-1], "Grand total: ") {
+		t.Errorf("last line = %q, want grand total", formatted[len(formatted)-1])
+	}
+}
+
+func TestWriteGDBScript(t *testing.T) {
+	path, err := writeGDBScript("memctx", memoryContextScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := string(data)
+	for _, want := range []string{"TopMemoryContext", "firstchild", "nextchild", "freelist", memoryContextEnd} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_memmap.go
+// Purpose: Builds the process memory map of a core and classifies the fault address against it.
+// Regions and their permissions come from the core's PT_LOAD segments (or, without native notes,
+// from gdb's `maintenance info sections`), and file names from NT_FILE or `info proc mappings`.
+// The fault address is classified as a NULL dereference, stack overflow, wild pointer, write to a
+// read-only mapping, execution of a non-executable page or a shared-memory access.
+// Dependencies: Uses the ELF core reader in core_elf.go.
+
+package cmd
+
+import (
+	"debug/elf"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Fault address classes reported in SignalInfo.FaultClass.
+const (
+	faultNullDereference = "null-dereference"
+	faultStackOverflow   = "stack-overflow"
+	faultWildPointer     = "wild-pointer"
+	faultReadOnlyWrite   = "write-to-read-only"
+	faultNonExecutable   = "execute-non-executable"
+	faultSharedMemory    = "shared-memory"
+	faultGuardPage       = "guard-page"
+	faultMapped          = "mapped-access"
+)
+
+const (
+	nullPageLimit     = 0x10000  // Addresses below vm.mmap_min_addr are never mapped
+	stackOverflowSlop = 0x100000 // Faults this far below the stack pointer count as stack overflow
+)
+
+// Memory region kinds.
+const (
+	regionFile   = "file"
+	regionAnon   = "anon"
+	regionShared = "shared-memory"
+	regionStack  = "stack"
+	regionGuard  = "guard"
+)
+
+// regionBounds returns a mapping's start and end addresses.
+func regionBounds(m MemoryMapping) (uint64, uint64) {
+	start, _ := parseHexAddress(m.Start)
+	end, _ := parseHexAddress(m.End)
+	return start, end
+}
+
+// parseHexAddress parses a gdb address such as "0x7ffd5c3a1b20", ignoring anything after it.
+func parseHexAddress(s string) (uint64, bool) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(strings.TrimPrefix(fields[0], "0x"), 16, 64)
+	return v, err == nil
+}
+
+// procMappingRE matches a row of `info proc mappings`; newer gdb versions include a Perms column.
+var procMappingRE = regexp.MustCompile(`^\s*(0x[0-9a-fA-F]+)\s+(0x[0-9a-fA-F]+)\s+0x[0-9a-fA-F]+\s+(0x[0-9a-fA-F]+)(?:\s+([r-][w-][x-][sp-]))?\s*(.*)$`)
+
+// parseProcMappings parses the output of `info proc mappings`.
+// Parameters:
+// - output: The raw GDB output.
+// Returns:
+// - The mappings, in address order.
+func parseProcMappings(output string) []MemoryMapping {
+	var mappings []MemoryMapping
+	for _, line := range strings.Split(output, "\n") {
+		m := procMappingRE.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		mapping := MemoryMapping{Start: m[1], End: m[2], Offset: m[3], Path: strings.TrimSpace(m[5])}
+		if m[4] != "" {
+			mapping.Perms = m[4][:3]
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings
+}
+
+// coreSectionRE matches a core load section of `maintenance info sections`.
+var coreSectionRE = regexp.MustCompile(`\[\d+\]\s+(0x[0-9a-fA-F]+)->(0x[0-9a-fA-F]+)\s+at\s+0x[0-9a-fA-F]+:\s+load\w*\s+(.*)$`)
+
+// parseCoreSections parses the core load sections of `maintenance info sections`.
+// BFD marks sections without PF_W as READONLY and those with PF_X as CODE.
+// Parameters:
+// - output: The raw GDB output.
+// Returns:
+// - The mappings, with permissions but without file names.
+func parseCoreSections(output string) []MemoryMapping {
+	var mappings []MemoryMapping
+	for _, line := range strings.Split(output, "\n") {
+		m := coreSectionRE.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		flags := strings.Fields(m[3])
+		perms := []byte("rw-")
+		for _, flag := range flags {
+			switch flag {
+			case "READONLY":
+				perms[1] = '-'
+			case "CODE":
+				perms[2] = 'x'
+			}
+		}
+		mappings = append(mappings, MemoryMapping{Start: m[1], End: m[2], Perms: string(perms)})
+	}
+	return mappings
+}
+
+// segmentMappings builds mappings from a core's PT_LOAD segments, which carry exact permissions.
+func segmentMappings(notes *coreNotes) []MemoryMapping {
+	var mappings []MemoryMapping
+	for _, seg := range notes.Segments {
+		perms := []byte("---")
+		if seg.Flags&elf.PF_R != 0 {
+			perms[0] = 'r'
+		}
+		if seg.Flags&elf.PF_W != 0 {
+			perms[1] = 'w'
+		}
+		if seg.Flags&elf.PF_X != 0 {
+			perms[2] = 'x'
+		}
+		mappings = append(mappings, MemoryMapping{
+			Start: fmt.Sprintf("0x%x", seg.Vaddr),
+			End:   fmt.Sprintf("0x%x", seg.Vaddr+seg.Memsz),
+			Perms: string(perms),
+		})
+	}
+	return mappings
+}
+
+// isSharedMemoryPath reports whether a mapping's file is a shared-memory segment: postgres' main
+// shared memory (an anonymous shared mapping, shown as /dev/zero), SysV shm or POSIX DSM segments.
+func isSharedMemoryPath(path string) bool {
+	for _, prefix := range []string{"/dev/zero", "/SYSV", "/dev/shm/", "/memfd:"} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildMemoryMap combines the available sources into the process memory map.
+// Parameters:
+// - output: The raw GDB output; may be empty.
+// - notes: The decoded core notes, or nil.
+// - sp: The crashed thread's stack pointer, or 0 if unknown.
+// Returns:
+// - The memory map, in address order.
+func buildMemoryMap(output string, notes *coreNotes, sp uint64) []MemoryMapping {
+	named := parseProcMappings(output)
+	var regions []MemoryMapping
+	if notes != nil && len(notes.Segments) > 0 {
+		regions = segmentMappings(notes)
+		if len(named) == 0 {
+			for _, f := range notes.Files {
+				named = append(named, MemoryMapping{
+					Start:  fmt.Sprintf("0x%x", f.Start),
+					End:    fmt.Sprintf("0x%x", f.End),
+					Offset: fmt.Sprintf("0x%x", f.Offset),
+					Path:   f.Path,
+				})
+			}
+		}
+	} else {
+		regions = parseCoreSections(output)
+	}
+	if len(regions) == 0 {
+		regions = named
+	}
+
+	// Name each region after the file mapped at its start
+	for i := range regions {
+		start, _ := regionBounds(regions[i])
+		for _, n := range named {
+			if ns, ne := regionBounds(n); start >= ns && start < ne {
+				if regions[i].Path == "" {
+					regions[i].Path = n.Path
+				}
+				if off, ok := parseHexAddress(n.Offset); ok && regions[i].Offset == "" {
+					regions[i].Offset = fmt.Sprintf("0x%x", off+start-ns)
+				}
+				if regions[i].Perms == "" {
+					regions[i].Perms = n.Perms
+				}
+				break
+			}
+		}
+	}
+
+	sort.Slice(regions, func(i, j int) bool {
+		a, _ := regionBounds(regions[i])
+		b, _ := regionBounds(regions[j])
+		return a < b
+	})
+
+	for i := range regions {
+		start, end := regionBounds(regions[i])
+		regions[i].Size = end - start
+		switch path := regions[i].Path; {
+		case sp != 0 && sp >= start && sp < end, path == "[stack]":
+			regions[i].Kind = regionStack
+		case regions[i].Perms == "---":
+			regions[i].Kind = regionGuard
+		case isSharedMemoryPath(path):
+			regions[i].Kind = regionShared
+		case path == "" || strings.HasPrefix(path, "["):
+			regions[i].Kind = regionAnon
+		default:
+			regions[i].Kind = regionFile
+		}
+	}
+	return regions
+}
+
+// findMapping returns the index of the mapping containing addr, or -1.
+func findMapping(mappings []MemoryMapping, addr uint64) int {
+	for i, m := range mappings {
+		if start, end := regionBounds(m); addr >= start && addr < end {
+			return i
+		}
+	}
+	return -1
+}
+
+// Names of the stack pointer and program counter on x86-64 and aarch64, as gdb and the core
+// notes report them. A thread's registers hold one name of each pair.
+var (
+	stackPointerRegisters   = []string{"rsp", "sp"}
+	programCounterRegisters = []string{"rip", "pc"}
+)
+
+// registerValue returns a register of the crashed thread as a number.
+func registerValue(analysis *CoreAnalysis, name string) uint64 {
+	v, _ := parseHexAddress(analysis.Registers[name])
+	return v
+}
+
+// archRegisterValue returns the first of a set of per-architecture register names the crashed
+// thread has, as a number.
+// Parameters:
+// - analysis: The CoreAnalysis object with Registers populated.
+// - names: The register's name on each architecture.
+// Returns:
+// - The register's value, or 0 if the thread has none of the names.
+func archRegisterValue(analysis *CoreAnalysis, names []string) uint64 {
+	for _, name := range names {
+		if _, ok := analysis.Registers[name]; ok {
+			return registerValue(analysis, name)
+		}
+	}
+	return 0
+}
+
+// stackPointer returns the crashed thread's stack pointer: rsp on x86-64, sp on aarch64.
+func stackPointer(analysis *CoreAnalysis) uint64 {
+	return archRegisterValue(analysis, stackPointerRegisters)
+}
+
+// programCounter returns the crashed thread's program counter: rip on x86-64, pc on aarch64.
+func programCounter(analysis *CoreAnalysis) uint64 {
+	return archRegisterValue(analysis, programCounterRegisters)
+}
+
+// classifyFaultAddress decides what kind of access caused a SIGSEGV or SIGBUS.
+// Parameters:
+// - analysis: The CoreAnalysis object with SignalInfo, Registers and MemoryMap populated.
+// Returns:
+// - The fault class and a human-readable verdict, or empty strings if there is no fault address.
+func classifyFaultAddress(analysis *CoreAnalysis) (string, string) {
+	info := analysis.SignalInfo
+	if info.SignalNumber != 11 && info.SignalNumber != 7 {
+		return "", ""
+	}
+	addr, ok := parseHexAddress(info.FaultAddress)
+	if !ok {
+		return "", ""
+	}
+
+	if addr < nullPageLimit {
+		if addr == 0 {
+			return faultNullDereference, "NULL pointer dereference"
+		}
+		return faultNullDereference, fmt.Sprintf("NULL pointer dereference (offset 0x%x)", addr)
+	}
+
+	sp := stackPointer(analysis)
+	pc := programCounter(analysis)
+	maps := analysis.MemoryMap
+	idx := findMapping(maps, addr)
+
+	// Stack overflow: the fault lies just below the stack, in its guard page or close under sp
+	stack := -1
+	for i := range maps {
+		if maps[i].Kind == regionStack {
+			stack = i
+			break
+		}
+	}
+	if stack >= 0 && (idx < 0 || maps[idx].Kind == regionGuard) {
+		stackStart, _ := regionBounds(maps[stack])
+		if addr < stackStart && stackStart-addr <= stackOverflowSlop {
+			if idx >= 0 {
+				return faultStackOverflow, fmt.Sprintf("stack overflow: fault in the stack guard page %s-%s", maps[idx].Start, maps[idx].End)
+			}
+			return faultStackOverflow, fmt.Sprintf("stack overflow: fault 0x%x bytes below the stack", stackStart-addr)
+		}
+	}
+	if sp != 0 && addr < sp && sp-addr <= stackOverflowSlop && (idx < 0 || maps[idx].Kind == regionGuard) {
+		return faultStackOverflow, fmt.Sprintf("stack overflow: fault 0x%x bytes below the stack pointer", sp-addr)
+	}
+
+	if idx < 0 {
+		if len(maps) == 0 {
+			return "", ""
+		}
+		return faultWildPointer, fmt.Sprintf("wild pointer: 0x%x is not mapped", addr)
+	}
+
+	m := maps[idx]
+	where := m.Path
+	if where == "" {
+		where = "anonymous memory"
+	}
+	switch {
+	case m.Kind == regionShared:
+		start, _ := regionBounds(m)
+		return faultSharedMemory, fmt.Sprintf("shared memory segment access (%s, offset 0x%x)", where, addr-start)
+	case addr == pc && !strings.Contains(m.Perms, "x"):
+		return faultNonExecutable, fmt.Sprintf("execute of non-executable page in %s", where)
+	case m.Kind == regionGuard:
+		return faultGuardPage, fmt.Sprintf("access to inaccessible (PROT_NONE) page %s-%s", m.Start, m.End)
+	case !strings.Contains(m.Perms, "w") && info.SignalCode == 2:
+		return faultReadOnlyWrite, fmt.Sprintf("write to read-only mapping %s (%s)", where, m.Perms)
+	}
+	return faultMapped, fmt.Sprintf("fault in mapped memory %s (%s)", where, m.Perms)
+}
@@ -0,0 +1,147 @@
+// File: cmd/core_memmap_test.go
+package cmd
+
+import (
+	"debug/elf"
+	"strings"
+	"testing"
+)
+
+// testMemoryNotes describes a small postgres address space:
+// executable text and data, shared memory, a heap, a guard page and the stack.
+func testMemoryNotes() *coreNotes {
+	return &coreNotes{
+		Segments: []coreSegment{
+			{Vaddr: 0x555555554000, Memsz: 0x1000, Flags: elf.PF_R | elf.PF_X},
+			{Vaddr: 0x555555755000, Memsz: 0x1000, Flags: elf.PF_R},
+			{Vaddr: 0x555555756000, Memsz: 0x1000, Flags: elf.PF_R | elf.PF_W},
+			{Vaddr: 0x7f0000000000, Memsz: 0x100000, Flags: elf.PF_R | elf.PF_W},
+			{Vaddr: 0x7f1000000000, Memsz: 0x10000, Flags: elf.PF_R | elf.PF_W},
+			{Vaddr: 0x7ffd00000000, Memsz: 0x1000, Flags: 0},
+			{Vaddr: 0x7ffd00001000, Memsz: 0x21000, Flags: elf.PF_R | elf.PF_W},
+		},
+		Files: []coreMappedFile{
+			{Start: 0x555555554000, End: 0x555555555000, Offset: 0, Path: "/usr/local/cloudberry/bin/postgres"},
+			{Start: 0x555555755000, End: 0x555555757000, Offset: 0x1000, Path: "/usr/local/cloudberry/bin/postgres"},
+			{Start: 0x7f0000000000, End: 0x7f0000100000, Offset: 0, Path: "/dev/zero (deleted)"},
+		},
+	}
+}
+
+func TestParseProcMappings(t *testing.T) {
+	output := `Mapped address spaces:
+
+          Start Addr           End Addr       Size     Offset  Perms  objfile
+      0x555555554000     0x555555556000     0x2000        0x0  r-xp   /usr/local/cloudberry/bin/postgres
+      0x7ffff7dd7000     0x7ffff7dfc000    0x25000        0x0  r--p   /lib64/ld-linux-x86-64.so.2
+      0x7ffffffde000     0x7ffffffff000    0x21000        0x0  rw-p   [stack]
+`
+	mappings := parseProcMappings(output)
+	if len(mappings) != 3 {
+		t.Fatalf("mappings = %+v, want 3", mappings)
+	}
+	if m := mappings[0]; m.Perms != "r-x" || m.Path != "/usr/local/cloudberry/bin/postgres" {
+		t.Errorf("mappings[0] = %+v", m)
+	}
+
+	// Core files list mappings without permissions
+	old := "      0x555555554000     0x555555556000     0x2000        0x0 /usr/local/cloudberry/bin/postgres\n"
+	if m := parseProcMappings(old); len(m) != 1 || m[0].Perms != "" || m[0].Path != "/usr/local/cloudberry/bin/postgres" {
+		t.Errorf("parseProcMappings(no perms) = %+v", m)
+	}
+}
+
+func TestParseCoreSections(t *testing.T) {
+	output := `Core file: ` + "`" + `/tmp/core.1', file type elf64-x86-64.
+ [0]      0x00000000->0x00000a54 at 0x00000388: note0 READONLY HAS_CONTENTS
+ [3]      0x555555554000->0x555555555000 at 0x00002000: load1 ALLOC LOAD READONLY CODE HAS_CONTENTS
+ [4]      0x555555756000->0x555555757000 at 0x00003000: load2 ALLOC LOAD HAS_CONTENTS
+`
+	sections := parseCoreSections(output)
+	if len(sections) != 2 || sections[0].Perms != "r-x" || sections[1].Perms != "rw-" {
+		t.Errorf("sections = %+v", sections)
+	}
+}
+
+func TestBuildMemoryMap(t *testing.T) {
+	sp := uint64(0x7ffd00010000)
+	maps := buildMemoryMap("", testMemoryNotes(), sp)
+	if len(maps) != 7 {
+		t.Fatalf("memory map = %+v, want 7 regions", maps)
+	}
+
+	want := []struct{ perms, kind, path, offset string }{
+		{"r-x", regionFile, "/usr/local/cloudberry/bin/postgres", "0x0"},
+		{"r--", regionFile, "/usr/local/cloudberry/bin/postgres", "0x1000"},
+		{"rw-", regionFile, "/usr/local/cloudberry/bin/postgres", "0x2000"},
+		{"rw-", regionShared, "/dev/zero (deleted)", "0x0"},
+		{"rw-", regionAnon, "", ""},
+		{"---", regionGuard, "", ""},
+		{"rw-", regionStack, "", ""},
+	}
+	for i, w := range want {
+		m := maps[i]
+		if m.Perms != w.perms || m.Kind != w.kind || m.Path != w.path || m.Offset != w.offset {
+			t.Errorf("maps[%d] = %+v, want %+v", i, m, w)
+		}
+	}
+}
+
+func TestClassifyFaultAddress(t *testing.T) {
+	maps := buildMemoryMap("", testMemoryNotes(), 0x7ffd00010000)
+	tests := []struct {
+		name  string
+		addr  string
+		code  int
+		rip   string
+		want  string
+		match string
+	}{
+		{"NULL", "0x0", 1, "", faultNullDereference, "NULL pointer dereference"},
+		{"near NULL", "0x18", 1, "", faultNullDereference, "offset 0x18"},
+		{"stack guard page", "0x7ffd00000ff8", 2, "", faultStackOverflow, "guard page"},
+		{"below the stack pointer", "0x7ffcfffff000", 1, "", faultStackOverflow, "below the stack"},
+		{"wild pointer", "0x4141414141414141", 1, "", faultWildPointer, "not mapped"},
+		{"read-only write", "0x555555755010", 2, "", faultReadOnlyWrite, "read-only mapping /usr/local/cloudberry/bin/postgres"},
+		{"non-executable", "0x7f1000000100", 2, "0x7f1000000100", faultNonExecutable, "non-executable"},
+		{"shared memory", "0x7f0000080000", 2, "", faultSharedMemory, "offset 0x80000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// x86-64 and aarch64 name the stack pointer and program counter differently
+			for _, regs := range [][2]string{{"rsp", "rip"}, {"sp", "pc"}} {
+				analysis := CoreAnalysis{
+					SignalInfo: SignalInfo{SignalNumber: 11, SignalCode: tt.code, FaultAddress: tt.addr},
+					Registers:  map[string]string{regs[0]: "0x7ffd00010000", regs[1]: tt.rip},
+					MemoryMap:  maps,
+				}
+				class, verdict := classifyFaultAddress(&analysis)
+				if class != tt.want || !strings.Contains(verdict, tt.match) {
+					t.Errorf("classifyFaultAddress(%s) with %s = %q, %q; want %q containing %q", tt.addr, regs[0], class, verdict, tt.want, tt.match)
+				}
+			}
+		})
+	}
+
+	// Only memory faults are classified
+	abort := CoreAnalysis{SignalInfo: SignalInfo{SignalNumber: 6, FaultAddress: "0x0"}, MemoryMap: maps}
+	if class, _ := classifyFaultAddress(&abort); class != "" {
+		t.Errorf("SIGABRT classified as %q", class)
+	}
+}
+
+func TestAddFaultAddressContext(t *testing.T) {
+	analysis := CoreAnalysis{
+		SignalInfo: SignalInfo{SignalNumber: 11, SignalCode: 1, SignalDescription: "Segmentation fault", FaultAddress: "0x8"},
+	}
+	addFaultAddressContext(&analysis.SignalInfo, &analysis)
+	if analysis.SignalInfo.FaultClass != faultNullDereference {
+		t.Errorf("FaultClass = %q", analysis.SignalInfo.FaultClass)
+	}
+	if want := "NULL pointer dereference (offset 0x8)"; analysis.SignalInfo.FaultVerdict != want {
+		t.Errorf("FaultVerdict = %q, want %q", analysis.SignalInfo.FaultVerdict, want)
+	}
+	if analysis.SignalInfo.SignalDescription != "Segmentation fault" {
+		t.Errorf("SignalDescription = %q, want it unchanged", analysis.SignalInfo.SignalDescription)
+	}
+}
@@ -0,0 +1,226 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_orca.go
+// Purpose: Handles C++ frames, which come mostly from the GPORCA optimizer. Mangled frame names are
+// demangled, and frames are classified as ORCA (gpopt, gpdxl, gpmd, gpnaucrates and the
+// translator), gpos (ORCA's OS layer) or the C++ standard library. When ORCA raised a
+// gpos::CException, its major/minor code and the file and line it was raised from are recovered
+// from the Raise frame's arguments.
+// Dependencies: Uses c++filt through core_demangle.go.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Frame components of C++ code.
+const (
+	componentORCA   = "orca"
+	componentGPOS   = "gpos"
+	componentCXXStd = "c++std"
+)
+
+// orcaPrefixes name the optimizer proper and the code translating between it and the executor.
+var orcaPrefixes = []string{
+	"gpopt::", "gpdxl::", "gpmd::", "gpnaucrates::", "gpdbcost::", "gpdb::",
+	"CGPOptimizer::", "COptTasks::", "CTranslator",
+}
+
+// cxxStdPrefixes name the C++ standard library and runtime.
+var cxxStdPrefixes = []string{
+	"std::", "__gnu_cxx::", "__cxxabiv1::", "__cxa_", "__gxx_personality", "_Unwind_",
+}
+
+// gposExceptionMajors names the major codes of gpos::CException (gpos/error/CException.h,
+// gpopt/exception.h and naucrates/exception.h).
+var gposExceptionMajors = map[int]string{
+	0: "ExmaInvalid", 1: "ExmaSystem", 2: "ExmaSQL", 3: "ExmaUnhandled",
+	100: "ExmaGPOPT", 200: "ExmaDXL",
+}
+
+// gposSystemMinors names the minor codes of ExmaSystem exceptions.
+var gposSystemMinors = map[int]string{
+	0: "ExmiInvalid", 1: "ExmiAbort", 2: "ExmiAssert", 3: "ExmiOOM", 4: "ExmiOutOfStack",
+	5: "ExmiAbortTimeout", 6: "ExmiIOError", 7: "ExmiNetError", 8: "ExmiOverflow",
+}
+
+// orcaExceptionFieldREs extract the exception from the arguments of Raise (filename, line, major,
+// minor) or from a CException value passed to Reraise (m_filename, m_line, m_major, m_minor).
+var orcaExceptionFieldREs = map[string]*regexp.Regexp{
+	"major":    regexp.MustCompile(`\b(?:m_)?major\s*=\s*(\d+)`),
+	"minor":    regexp.MustCompile(`\b(?:m_)?minor\s*=\s*(\d+)`),
+	"filename": regexp.MustCompile(`\b(?:m_)?filename\s*=\s*(?:0x[0-9a-fA-F]+\s+)?"([^"]*)"`),
+	"line":     regexp.MustCompile(`\b(?:m_)?line\s*=\s*(\d+)`),
+}
+
+// frameComponent classifies the C++ code a frame belongs to.
+// Parameters:
+// - function: The (demangled) function name.
+// Returns:
+// - componentORCA, componentGPOS or componentCXXStd, or "" for C code and unknown frames.
+func frameComponent(function string) string {
+	for _, prefix := range orcaPrefixes {
+		if strings.HasPrefix(function, prefix) {
+			return componentORCA
+		}
+	}
+	if strings.HasPrefix(function, "gpos::") {
+		return componentGPOS
+	}
+	for _, prefix := range cxxStdPrefixes {
+		if strings.HasPrefix(function, prefix) {
+			return componentCXXStd
+		}
+	}
+	return ""
+}
+
+// isORCAExceptionFrame reports whether a frame belongs to the gpos exception machinery, which
+// raises and propagates errors rather than causing them.
+func isORCAExceptionFrame(function string) bool {
+	return strings.HasPrefix(function, "gpos::CException::") || strings.HasPrefix(function, "gpos::CErrorHandler")
+}
+
+// demangleFrame demangles a frame's function name and classifies the frame.
+// The demangled name without parameters becomes the function name, so signatures,
+// fingerprints and rules match the source; the mangled and full demangled names are kept.
+// Parameters:
+// - frame: The frame to update in place.
+// - demangled: Demangled symbols by mangled symbol.
+func demangleFrame(frame *StackFrame, demangled map[string]demangledSymbol) {
+	if symbol, ok := demangled[frame.Function]; ok {
+		frame.MangledName = frame.Function
+		frame.DemangledName = symbol.full
+		frame.Function = symbol.name
+	}
+	frame.Component = frameComponent(frame.Function)
+}
+
+// demangleFrames demangles and classifies every frame of the stack trace and the threads.
+// Names gdb has already demangled are kept; the mangled ones are demangled in a single c++filt run.
+// Parameters:
+// - ctx: Context for c++filt.
+// - analysis: The CoreAnalysis object to update in place.
+func demangleFrames(ctx context.Context, analysis *CoreAnalysis) {
+	frames := []*StackFrame{}
+	for i := range analysis.StackTrace {
+		frames = append(frames, &analysis.StackTrace[i])
+	}
+	for t := range analysis.Threads {
+		for i := range analysis.Threads[t].Backtrace {
+			frames = append(frames, &analysis.Threads[t].Backtrace[i])
+		}
+	}
+
+	var symbols []string
+	seen := make(map[string]bool)
+	for _, frame := range frames {
+		if strings.HasPrefix(frame.Function, "_Z") && !seen[frame.Function] {
+			seen[frame.Function] = true
+			symbols = append(symbols, frame.Function)
+		}
+	}
+	demangled := demangleSymbols(ctx, symbols)
+	for _, frame := range frames {
+		demangleFrame(frame, demangled)
+	}
+}
+
+// detectOrcaException recovers the gpos::CException an ORCA crash was raised with.
+// Parameters:
+// - analysis: The CoreAnalysis object; OrcaException is set if a Raise frame carries the exception.
+func detectOrcaException(analysis *CoreAnalysis) {
+	frames := analysis.StackTrace
+	for _, thread := range analysis.Threads {
+		if thread.IsCrashed && len(frames) == 0 {
+			frames = thread.Backtrace
+		}
+	}
+
+	for i, frame := range frames {
+		if !strings.HasPrefix(frame.Function, "gpos::CException::") {
+			continue
+		}
+		fields := frame.Arguments
+		for _, value := range frame.Locals {
+			fields += ", " + value
+		}
+		exc := parseOrcaException(fields)
+		if exc == nil {
+			continue
+		}
+
+		// The exception was raised by the first caller outside the exception machinery
+		for _, caller := range frames[i+1:] {
+			if !isORCAExceptionFrame(caller.Function) && caller.Function != "??" {
+				exc.RaisedIn = caller.Function
+				break
+			}
+		}
+		exc.Summary = orcaExceptionSummary(*exc)
+		analysis.OrcaException = exc
+		return
+	}
+}
+
+// parseOrcaException extracts an exception from gdb's rendering of Raise arguments or a CException.
+// Parameters:
+// - fields: The frame's arguments and locals.
+// Returns:
+// - The exception, or nil if no major code was found.
+func parseOrcaException(fields string) *OrcaException {
+	values := make(map[string]string)
+	for name, re := range orcaExceptionFieldREs {
+		if m := re.FindStringSubmatch(fields); m != nil {
+			values[name] = m[1]
+		}
+	}
+	if values["major"] == "" {
+		return nil
+	}
+
+	exc := &OrcaException{File: values["filename"]}
+	exc.Major, _ = strconv.Atoi(values["major"])
+	exc.Minor, _ = strconv.Atoi(values["minor"])
+	exc.Line, _ = strconv.Atoi(values["line"])
+	exc.MajorName = gposExceptionMajors[exc.Major]
+	if exc.Major == 1 {
+		exc.MinorName = gposSystemMinors[exc.Minor]
+	}
+	return exc
+}
+
+// orcaExceptionSummary describes an exception in one line.
+func orcaExceptionSummary(exc OrcaException) string {
+	summary := fmt.Sprintf("GPORCA exception %d.%d", exc.Major, exc.Minor)
+	if exc.MajorName != "" {
+		name := exc.MajorName
+		if exc.MinorName != "" {
+			name += "/" + exc.MinorName
+		}
+		summary += " (" + name + ")"
+	}
+	if exc.File != "" {
+		summary += fmt.Sprintf(" raised at %s:%d", filepath.Base(exc.File), exc.Line)
+	}
+	if exc.RaisedIn != "" {
+		summary += " in " + exc.RaisedIn
+	}
+	return summary
+}
@@ -0,0 +1,108 @@
+// File: cmd/core_orca_test.go
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// orcaFrames is the stack of an assertion failure raised inside the optimizer, as gdb reports it
+// with mangled names.
+func orcaFrames(raiseArgs string) []StackFrame {
+	frames := testFrames(
+		"__cxa_throw",
+		"_ZN4gpos10CException5RaiseEPKcjjjz",
+		"_ZN5gpopt11CXformUtils28FProcessGPDBAntiSemiHashJoinEPN4gpos11CMemoryPoolEPNS_11CExpressionES5_",
+		"_ZN5gpopt7CEngine8OptimizeEv.constprop.0.isra.0",
+		"standard_planner",
+	)
+	frames[1].Arguments = raiseArgs
+	return frames
+}
+
+func TestFrameComponent(t *testing.T) {
+	tests := map[string]string{
+		"gpopt::CEngine::Optimize":         componentORCA,
+		"gpdxl::CTranslatorDXLToPlStmt::X": componentORCA,
+		"CGPOptimizer::GPOPTOptimizedPlan": componentORCA,
+		"gpos::CException::Raise":          componentGPOS,
+		"std::vector<int>::push_back":      componentCXXStd,
+		"__cxa_throw":                      componentCXXStd,
+		"ExecScan":                         "",
+	}
+	for function, want := range tests {
+		if got := frameComponent(function); got != want {
+			t.Errorf("frameComponent(%s) = %q, want %q", function, got, want)
+		}
+	}
+}
+
+func TestDemangleFrames(t *testing.T) {
+	requireCxxFilt(t)
+	analysis := newAnalysis("", "SIGABRT", orcaFrames(""), crashedThread())
+	demangleFrames(context.Background(), &analysis)
+
+	frame := analysis.StackTrace[3]
+	if frame.Function != "gpopt::CEngine::Optimize.constprop.0.isra.0" ||
+		frame.MangledName != "_ZN5gpopt7CEngine8OptimizeEv.constprop.0.isra.0" ||
+		frame.DemangledName != "gpopt::CEngine::Optimize() [clone .constprop.0] [clone .isra.0]" ||
+		frame.Component != componentORCA {
+		t.Errorf("frame = %+v", frame)
+	}
+	if got := analysis.Threads[0].Backtrace[1]; got.Function != "gpos::CException::Raise" || got.Component != componentGPOS {
+		t.Errorf("thread frame = %+v", got)
+	}
+	// C frames keep their names
+	if got := analysis.StackTrace[4]; got.Function != "standard_planner" || got.MangledName != "" || got.Component != "" {
+		t.Errorf("C frame = %+v", got)
+	}
+
+	// The optimizer frame, not the exception machinery, is the key function
+	if got := findKeyFunction(analysis.StackTrace); got != "gpopt::CXformUtils::FProcessGPDBAntiSemiHashJoin" {
+		t.Errorf("findKeyFunction = %q", got)
+	}
+	if got := crashPatternSignature(analysis); got != "SIGABRT|gpos::CException::Raise|gpopt::CXformUtils::FProcessGPDBAntiSemiHashJoin" {
+		t.Errorf("crashPatternSignature = %q", got)
+	}
+}
+
+func TestDetectOrcaException(t *testing.T) {
+	requireCxxFilt(t)
+	analysis := newAnalysis("", "SIGABRT", orcaFrames(`filename=0x7f12a0 "../libgpopt/src/xforms/CXformUtils.cpp", line=123, major=1, minor=2`), crashedThread())
+	demangleFrames(context.Background(), &analysis)
+	detectOrcaException(&analysis)
+
+	exc := analysis.OrcaException
+	if exc == nil {
+		t.Fatal("no exception detected")
+	}
+	if exc.Major != 1 || exc.Minor != 2 || exc.MajorName != "ExmaSystem" || exc.MinorName != "ExmiAssert" ||
+		exc.Line != 123 || exc.RaisedIn != "gpopt::CXformUtils::FProcessGPDBAntiSemiHashJoin" {
+		t.Errorf("exception = %+v", exc)
+	}
+	if want := "GPORCA exception 1.2 (ExmaSystem/ExmiAssert) raised at CXformUtils.cpp:123 in gpopt::CXformUtils::FProcessGPDBAntiSemiHashJoin"; exc.Summary != want {
+		t.Errorf("summary = %q, want %q", exc.Summary, want)
+	}
+
+	// A rethrown exception carries its fields in a CException local; only the crashed thread has frames
+	analysis = newAnalysis("", "SIGABRT", orcaFrames(""), crashedThread())
+	analysis.StackTrace = nil
+	analysis.Threads[0].Backtrace[1].Function = "_ZN4gpos10CException7ReraiseES0_b"
+	analysis.Threads[0].Backtrace[1].Locals = map[string]string{
+		"exc": `{m_major = 100, m_minor = 3, m_filename = 0x55d0 "CEngine.cpp", m_line = 45}`,
+	}
+	demangleFrames(context.Background(), &analysis)
+	detectOrcaException(&analysis)
+	if exc := analysis.OrcaException; exc == nil || exc.MajorName != "ExmaGPOPT" || exc.MinorName != "" ||
+		!strings.Contains(exc.Summary, "(ExmaGPOPT) raised at CEngine.cpp:45") {
+		t.Errorf("exception = %+v", exc)
+	}
+
+	// Frames without the exception leave the analysis alone
+	analysis = newAnalysis("", "SIGABRT", orcaFrames(""), crashedThread())
+	detectOrcaException(&analysis)
+	if analysis.OrcaException != nil {
+		t.Errorf("exception = %+v", analysis.OrcaException)
+	}
+}
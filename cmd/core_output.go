@@ -0,0 +1,251 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_output.go
+// Purpose: Decides where analysis results go. With `--output -` every analysis is streamed to stdout
+// as one JSON object per line (NDJSON), followed by a summary record, and progress messages move to
+// stderr. Otherwise each core's analysis is written to --output-dir under a name derived from the
+// core's path, and index.json links every analyzed core to its analysis files.
+// Dependencies: Uses encoding/json and the analysis types of core_types.go.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	outputStdout          = "-"               // --output value that streams NDJSON to stdout
+	analysisIndexFile     = "index.json"      // Core-to-analysis index, relative to --output-dir
+	analysisIndexLockFile = "index.json.lock" // Lock serializing concurrent index writers
+)
+
+// Record types written by `--output -`.
+const (
+	streamRecordAnalysis = "analysis"
+	streamRecordSummary  = "summary"
+)
+
+var (
+	outputFlag string // "-" streams results to stdout; empty writes files to --output-dir
+
+	streamOut io.Writer  // The real stdout while streaming
+	streamMu  sync.Mutex // Serializes records written to streamOut
+)
+
+func init() {
+	coreCmd.PersistentFlags().StringVar(&outputFlag, "output", "", "Write results to '-' (stdout, newline-delimited JSON) instead of files in --output-dir")
+}
+
+// streamAnalysisRecord is one analyzed core in the NDJSON stream.
+type streamAnalysisRecord struct {
+	Type string `json:"type"`
+	CoreAnalysis
+}
+
+// streamSummaryRecord ends the NDJSON stream of a run with the status of every core.
+type streamSummaryRecord struct {
+	Type string `json:"type"`
+	RunManifest
+	Comparison *CoreComparison `json:"comparison,omitempty"`
+}
+
+// validateOutputFlag checks that --output can be honoured with the other output flags.
+// Returns:
+// - An error if --output is not '-', or is combined with --gdb-style or a report format.
+func validateOutputFlag() error {
+	switch {
+	case outputFlag == "":
+		return nil
+	case outputFlag != outputStdout:
+		return fmt.Errorf("invalid output: %s. Only '-' (stdout) is supported; use --output-dir for files", outputFlag)
+	case gdbStyleOutput:
+		return fmt.Errorf("--output - cannot be combined with --gdb-style")
+	case isReportFormat(formatFlag):
+		return fmt.Errorf("--output - streams JSON and cannot be combined with --format %s", formatFlag)
+	}
+	return nil
+}
+
+// streamingOutput reports whether results are streamed to stdout.
+func streamingOutput() bool {
+	return outputFlag == outputStdout
+}
+
+// startStream routes stdout to the NDJSON stream and every other message to stderr.
+// Returns:
+// - A function restoring stdout, to be deferred by the caller.
+func startStream() func() {
+	stdout := os.Stdout
+	streamOut = stdout
+	os.Stdout = os.Stderr
+	return func() {
+		os.Stdout = stdout
+		streamOut = nil
+	}
+}
+
+// writeStreamRecord writes a record as a single line of the NDJSON stream.
+// Parameters:
+// - record: The record to write.
+// Returns:
+// - An error if the record cannot be encoded or written.
+func writeStreamRecord(record interface{}) error {
+	streamMu.Lock()
+	defer streamMu.Unlock()
+
+	out := streamOut
+	if out == nil {
+		out = os.Stdout
+	}
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(record); err != nil {
+		return fmt.Errorf("failed to stream record: %w", err)
+	}
+	return nil
+}
+
+// streamAnalysis writes an analysis to the NDJSON stream.
+// Parameters:
+// - analysis: The CoreAnalysis object to stream.
+// Returns:
+// - An error if the record cannot be written.
+func streamAnalysis(analysis CoreAnalysis) error {
+	return writeStreamRecord(streamAnalysisRecord{Type: streamRecordAnalysis, CoreAnalysis: prepareAnalysis(analysis)})
+}
+
+// streamSummary writes the final summary record of a run to the NDJSON stream.
+// Parameters:
+// - manifest: The run manifest with the status of every core.
+// - comparison: The --compare results, or nil.
+// Returns:
+// - An error if the record cannot be written.
+func streamSummary(manifest RunManifest, comparison *CoreComparison) error {
+	return writeStreamRecord(streamSummaryRecord{Type: streamRecordSummary, RunManifest: manifest, Comparison: comparison})
+}
+
+// analysisFileName returns the file name of a core's analysis. The name is derived from the core's
+// absolute path, so re-analyzing a core replaces its previous analysis and no two cores share a file.
+// Parameters:
+// - coreFile: Path to the core file.
+// - format: The output format.
+// Returns:
+// - A file name such as "core_analysis_core.1234_1a2b3c4d.json".
+func analysisFileName(coreFile, format string) string {
+	path, err := filepath.Abs(coreFile)
+	if err != nil {
+		path = coreFile
+	}
+	sum := sha256.Sum256([]byte(path))
+
+	base := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, filepath.Base(path))
+
+	return fmt.Sprintf("core_analysis_%s_%s.%s", base, hex.EncodeToString(sum[:4]), formatExtension(format))
+}
+
+// readAnalysisIndex loads index.json; a missing index is empty.
+// Parameters:
+// - dir: The directory holding the index (normally --output-dir).
+// Returns:
+// - The loaded index.
+// - An error if the index exists but cannot be read.
+func readAnalysisIndex(dir string) (AnalysisIndex, error) {
+	var index AnalysisIndex
+	data, err := os.ReadFile(filepath.Join(dir, analysisIndexFile))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return index, fmt.Errorf("failed to read analysis index: %w", err)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return index, fmt.Errorf("failed to parse analysis index: %w", err)
+	}
+	return index, nil
+}
+
+// updateAnalysisIndex records an analysis file in index.json, holding an exclusive lock so that
+// concurrent runs writing to the same directory do not lose each other's entries. Locking is
+// Unix-only; see lockFile.
+// Parameters:
+// - dir: The directory holding the index and the analysis file.
+// - analysis: The analysis that was written.
+// - format: The format it was written in.
+// - file: The analysis file name, relative to dir.
+// Returns:
+// - An error if the index cannot be locked, read or written.
+func updateAnalysisIndex(dir string, analysis CoreAnalysis, format, file string) error {
+	lock, err := os.OpenFile(filepath.Join(dir, analysisIndexLockFile), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open analysis index lock: %w", err)
+	}
+	defer lock.Close()
+	if err := lockFile(lock); err != nil {
+		return fmt.Errorf("failed to lock analysis index: %w", err)
+	}
+	defer unlockFile(lock)
+
+	index, err := readAnalysisIndex(dir)
+	if err != nil {
+		return err
+	}
+
+	coreFile, err := filepath.Abs(analysis.CoreFile)
+	if err != nil {
+		coreFile = analysis.CoreFile
+	}
+	now := time.Now().Format(time.RFC3339)
+	i := sort.Search(len(index.Cores), func(i int) bool { return index.Cores[i].CoreFile >= coreFile })
+	if i == len(index.Cores) || index.Cores[i].CoreFile != coreFile {
+		index.Cores = append(index.Cores, AnalysisIndexEntry{})
+		copy(index.Cores[i+1:], index.Cores[i:])
+		index.Cores[i] = AnalysisIndexEntry{CoreFile: coreFile, Files: make(map[string]string)}
+	}
+	entry := &index.Cores[i]
+	if entry.Files == nil {
+		entry.Files = make(map[string]string)
+	}
+	entry.Files[format] = file
+	entry.Fingerprint = analysis.Fingerprint
+	entry.AnalyzedAt = now
+	index.Updated = now
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal analysis index: %w", err)
+	}
+	path := filepath.Join(dir, analysisIndexFile)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to write analysis index: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write analysis index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// File: cmd/core_output_test.go
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnalysisFileName(t *testing.T) {
+	a := analysisFileName("/var/cores/core.1234", formatJSON)
+	if a != analysisFileName("/var/cores/../cores/core.1234", formatJSON) {
+		t.Errorf("name is not derived from the cleaned path: %s", a)
+	}
+	if !strings.HasPrefix(a, "core_analysis_core.1234_") || !strings.HasSuffix(a, ".json") {
+		t.Errorf("name = %s", a)
+	}
+	if b := analysisFileName("/other/cores/core.1234", formatJSON); b == a {
+		t.Errorf("cores with the same name in different directories share %s", a)
+	}
+	if md := analysisFileName("/var/cores/core.1234", formatMarkdown); strings.TrimSuffix(md, ".md") != strings.TrimSuffix(a, ".json") {
+		t.Errorf("markdown name = %s", md)
+	}
+	if got := analysisFileName("/var/cores/core 1:2", formatYAML); !strings.HasPrefix(got, "core_analysis_core_1_2_") {
+		t.Errorf("name not sanitized: %s", got)
+	}
+}
+
+func TestSaveAnalysisIndex(t *testing.T) {
+	oldDir, oldFormat := outputDir, formatFlag
+	defer func() { outputDir, formatFlag = oldDir, oldFormat }()
+	outputDir = t.TempDir()
+
+	first := CoreAnalysis{CoreFile: "/cores/a/core.1", Fingerprint: "1111"}
+	second := CoreAnalysis{CoreFile: "/cores/b/core.1", Fingerprint: "2222"}
+	for _, format := range []string{formatJSON, formatJSON, formatMarkdown} {
+		formatFlag = format
+		if err := saveAnalysis(first); err != nil {
+			t.Fatal(err)
+		}
+	}
+	formatFlag = formatJSON
+	if err := saveAnalysis(second); err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-analysis replaces the core's file; cores with the same name do not collide
+	matches, _ := filepath.Glob(filepath.Join(outputDir, "core_analysis_*"))
+	if len(matches) != 3 {
+		t.Errorf("analysis files = %v, want 3", matches)
+	}
+
+	index, err := readAnalysisIndex(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Cores) != 2 || index.Cores[0].CoreFile != "/cores/a/core.1" || index.Cores[1].CoreFile != "/cores/b/core.1" {
+		t.Fatalf("index = %+v", index.Cores)
+	}
+	entry := index.Cores[0]
+	if entry.Fingerprint != "1111" || entry.Files[formatJSON] != analysisFileName(first.CoreFile, formatJSON) || entry.Files[formatMarkdown] == "" {
+		t.Errorf("entry = %+v", entry)
+	}
+	for _, file := range entry.Files {
+		if _, err := os.Stat(filepath.Join(outputDir, file)); err != nil {
+			t.Errorf("indexed file missing: %v", err)
+		}
+	}
+}
+
+func TestStreamOutput(t *testing.T) {
+	oldOutput, oldStream := outputFlag, streamOut
+	defer func() { outputFlag, streamOut = oldOutput, oldStream }()
+	outputFlag = outputStdout
+	var buf bytes.Buffer
+	streamOut = &buf
+
+	for _, core := range []string{"/cores/core.1", "/cores/core.2"} {
+		if err := saveOrPrintAnalysis(CoreAnalysis{CoreFile: core, SignalInfo: SignalInfo{SignalName: "SIGSEGV"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifest := RunManifest{Jobs: 1, Summary: map[string]int{coreStatusOK: 2}}
+	if err := streamSummary(manifest, &CoreComparison{TotalCores: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	var records []map[string]interface{}
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 3 {
+		t.Fatalf("records = %d, want 3", len(records))
+	}
+	if records[0]["type"] != streamRecordAnalysis || records[1]["core_file"] != "/cores/core.2" {
+		t.Errorf("analysis records = %v", records[:2])
+	}
+	if summary := records[2]; summary["type"] != streamRecordSummary || summary["jobs"] != 1.0 || summary["comparison"] == nil {
+		t.Errorf("summary record = %v", summary)
+	}
+}
+
+func TestValidateOutputFlag(t *testing.T) {
+	oldOutput, oldFormat, oldGDB := outputFlag, formatFlag, gdbStyleOutput
+	defer func() { outputFlag, formatFlag, gdbStyleOutput = oldOutput, oldFormat, oldGDB }()
+
+	tests := []struct {
+		output, format string
+		gdbStyle       bool
+		wantErr        bool
+	}{
+		{"", formatHTML, true, false},
+		{"-", formatJSON, false, false},
+		{"-", formatYAML, false, false},
+		{"-", formatJSON, true, true},
+		{"-", formatJUnit, false, true},
+		{"results.json", formatJSON, false, true},
+	}
+	for _, tt := range tests {
+		outputFlag, formatFlag, gdbStyleOutput = tt.output, tt.format, tt.gdbStyle
+		if err := validateOutputFlag(); (err != nil) != tt.wantErr {
+			t.Errorf("validateOutputFlag(%q, %s, gdb=%v) error = %v, wantErr %v", tt.output, tt.format, tt.gdbStyle, err, tt.wantErr)
+		}
+	}
+}
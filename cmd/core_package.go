@@ -0,0 +1,443 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_package.go
+// Purpose: Implements `core package`, which builds a self-contained crash bundle for offline analysis.
+// The bundle is a tar.gz holding the core, the exact postgres binary and every shared library the
+// process had loaded (laid out under sysroot/ by their original paths), the analysis, `sysinfo`
+// output, a gdbinit that reproduces the gdb session, and a manifest with SHA-256 checksums.
+// A compressed core is bundled decompressed, since gdb cannot open it otherwise. The core cannot be
+// redacted, so --redact needs --include-unredacted-core.
+// Dependencies: Uses analyzeCoreFile for library resolution, decompressCore from core_compress.go
+// and gatherSysInfo from sysinfo.go.
+
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// Roles of the files stored in a crash bundle.
+const (
+	bundleRoleCore     = "core"
+	bundleRoleBinary   = "binary"
+	bundleRoleLibrary  = "library"
+	bundleRoleAnalysis = "analysis"
+	bundleRoleSysinfo  = "sysinfo"
+	bundleRoleGDBInit  = "gdbinit"
+)
+
+var (
+	packageBundlePath     string // Path of the bundle to create
+	packageUnredactedCore bool   // Bundle the core even though --redact cannot scrub it
+)
+
+// corePackageCmd represents the core package command
+var corePackageCmd = &cobra.Command{
+	Use:   "package <core_file>",
+	Short: "Create a self-contained crash bundle for offline analysis",
+	Long: `Create a tar.gz bundle holding everything needed to debug a core on another machine:
+the core, the exact postgres binary, every shared library the process had loaded,
+the analysis, sysinfo output and a manifest with SHA-256 checksums.
+
+--redact scrubs the analysis, sysinfo and manifest but not the core, which holds
+the process's memory; it is refused unless --include-unredacted-core is given.
+
+The bundle includes a gdbinit that reproduces the gdb session:
+  cbtoolbox core package /var/lib/postgres/cores/core.1234
+  tar xzf core.1234_bundle_*.tar.gz && cd core.1234_bundle_* && gdb -nx -x gdbinit`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return runCorePackage(ctx, args[0])
+	},
+}
+
+func init() {
+	coreCmd.AddCommand(corePackageCmd)
+	corePackageCmd.Flags().StringVar(&packageBundlePath, "bundle", "", "Path of the bundle to create (default <output-dir>/<core>_bundle_<timestamp>.tar.gz)")
+	corePackageCmd.Flags().BoolVar(&packageUnredactedCore, "include-unredacted-core", false, "With --redact, bundle the core file even though it cannot be redacted")
+}
+
+// runCorePackage analyzes a core file and writes its crash bundle.
+// Parameters:
+// - ctx: Context for the analysis; cancelling it stops gdb.
+// - corePath: Path to the core file.
+// Returns:
+// - An error if the core cannot be analyzed or the bundle cannot be written.
+func runCorePackage(ctx context.Context, corePath string) error {
+	if err := validateCoreFlags(); err != nil {
+		return err
+	}
+	// The core holds the process's memory, queries and data included, which redaction cannot reach
+	if redaction != nil && !packageUnredactedCore {
+		return fmt.Errorf("--redact cannot redact the core file, which holds the process's memory; pass --include-unredacted-core to bundle it anyway")
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	analysis, result := runOneCore(ctx, corePath, os.Getenv("GPHOME"))
+	if result.Status != coreStatusOK {
+		return fmt.Errorf("failed to analyze %s (%s): %s", corePath, result.Status, result.Error)
+	}
+
+	// gdb cannot open a compressed core, so the bundle holds a decompressed copy
+	bundledCore := analysis.CoreFile
+	if format, size := detectCompression(analysis.CoreFile); format != nil {
+		uncompressed, cleanup, err := decompressCore(ctx, analysis.CoreFile, format, size)
+		if err != nil {
+			return fmt.Errorf("failed to decompress %s for the bundle: %w", corePath, err)
+		}
+		defer cleanup()
+		bundledCore = uncompressed
+	}
+
+	info, errs, gphomeErrs := gatherSysInfo()
+	var notes []string
+	for _, err := range append(errs, gphomeErrs...) {
+		notes = append(notes, "sysinfo: "+err.Error())
+	}
+
+	bundlePath := packageBundlePath
+	if bundlePath == "" {
+		timestamp := time.Now().Format("20060102_150405")
+		bundlePath = filepath.Join(outputDir, fmt.Sprintf("%s_bundle_%s.tar.gz", filepath.Base(corePath), timestamp))
+	}
+
+	manifest, err := writeCoreBundle(bundlePath, bundledCore, analysis, info, notes)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Bundle saved to: %s (%d files", bundlePath, len(manifest.Files))
+	if len(manifest.Missing) > 0 {
+		fmt.Printf(", %d missing: %s", len(manifest.Missing), strings.Join(manifest.Missing, ", "))
+	}
+	fmt.Println(")")
+	return nil
+}
+
+// bundleWriter streams files into a crash bundle while recording their checksums.
+type bundleWriter struct {
+	tw       *tar.Writer
+	root     string
+	manifest *BundleManifest
+}
+
+// addFile copies a file from disk into the bundle.
+// Parameters:
+// - src: Path of the file on disk.
+// - dst: Path of the file within the bundle.
+// - role: The file's role, recorded in the manifest.
+// Returns:
+// - An error if the bundle cannot be written; unreadable sources are recorded as missing.
+func (b *bundleWriter) addFile(src, dst, role string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		b.manifest.Missing = append(b.manifest.Missing, src)
+		return nil
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		b.manifest.Missing = append(b.manifest.Missing, src)
+		return nil
+	}
+
+	header := &tar.Header{
+		Name:    path.Join(b.root, dst),
+		Mode:    int64(info.Mode().Perm()),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if err := b.tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write bundle entry %s: %w", dst, err)
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(b.tw, hash), f)
+	if err != nil {
+		return fmt.Errorf("failed to write bundle entry %s: %w", dst, err)
+	}
+
+	b.manifest.Files = append(b.manifest.Files, BundleFile{
+		Path:   dst,
+		Source: src,
+		Role:   role,
+		Size:   n,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+	})
+	return nil
+}
+
+// addData writes generated content into the bundle.
+// Parameters:
+// - dst: Path of the file within the bundle.
+// - role: The file's role, recorded in the manifest; empty for the manifest itself.
+// - data: The file content.
+// Returns:
+// - An error if the bundle cannot be written.
+func (b *bundleWriter) addData(dst, role string, data []byte) error {
+	header := &tar.Header{
+		Name:    path.Join(b.root, dst),
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := b.tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write bundle entry %s: %w", dst, err)
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write bundle entry %s: %w", dst, err)
+	}
+
+	if role != "" {
+		sum := sha256.Sum256(data)
+		b.manifest.Files = append(b.manifest.Files, BundleFile{
+			Path:   dst,
+			Role:   role,
+			Size:   int64(len(data)),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+	return nil
+}
+
+// sysrootPath maps an absolute path on this host to its location in the bundle's sysroot.
+func sysrootPath(src string) string {
+	if abs, err := filepath.Abs(src); err == nil {
+		src = abs
+	}
+	return path.Join("sysroot", filepath.ToSlash(filepath.Clean(src)))
+}
+
+// bundleLibraries lists the shared libraries to include in a bundle.
+// Libraries resolved by gdb are used when available; otherwise the file mappings recorded in
+// the core are used.
+// Parameters:
+// - analysis: The CoreAnalysis object for the core.
+// - corePath: The uncompressed core, whose notes list the mapped files.
+// Returns:
+// - Absolute library paths, without duplicates.
+func bundleLibraries(analysis CoreAnalysis, corePath string) []string {
+	seen := make(map[string]bool)
+	var libs []string
+	add := func(lib string) {
+		if !filepath.IsAbs(lib) || seen[lib] || lib == analysis.PostgresInfo.BinaryPath {
+			return
+		}
+		seen[lib] = true
+		libs = append(libs, lib)
+	}
+
+	for _, lib := range analysis.Libraries {
+		add(lib.Name)
+	}
+	if len(libs) == 0 {
+		if notes, err := readCoreNotes(corePath); err == nil {
+			for _, f := range notes.Files {
+				if f.Offset == 0 && strings.Contains(filepath.Base(f.Path), ".so") {
+					add(f.Path)
+				}
+			}
+		}
+	}
+	return libs
+}
+
+// bundleGDBInit builds the gdb script that reproduces the session from an extracted bundle.
+// Parameters:
+// - binary: Path of the postgres binary within the bundle; empty if the bundle has none.
+// - core: Path of the core within the bundle.
+// Returns:
+// - The gdbinit content.
+func bundleGDBInit(binary, core string) string {
+	var sb strings.Builder
+	sb.WriteString("# Reproduce the gdb session for this core. Run from the extracted bundle directory:\n")
+	sb.WriteString("#   gdb -nx -x gdbinit\n")
+	sb.WriteString("set pagination off\n")
+	sb.WriteString("set sysroot sysroot\n")
+	sb.WriteString("set solib-search-path sysroot\n")
+	if binary != "" {
+		sb.WriteString("file " + binary + "\n")
+	} else {
+		sb.WriteString("# The postgres binary is not in this bundle; load the matching one with `file <path>`\n")
+	}
+	sb.WriteString("core-file " + core + "\n")
+	return sb.String()
+}
+
+// marshalFormat marshals a value in the --format output format; bundles hold JSON for report formats.
+func marshalFormat(v interface{}) ([]byte, error) {
+	if dataFormat() == formatJSON {
+		return json.MarshalIndent(v, "", "  ")
+	}
+	return yaml.Marshal(v)
+}
+
+// writeCoreBundle writes a crash bundle for an analyzed core.
+// Parameters:
+// - bundlePath: Path of the tar.gz to create.
+// - corePath: The core to bundle: analysis.CoreFile, or its decompressed copy if it is compressed.
+// - analysis: The CoreAnalysis object for the core.
+// - info: The sysinfo output for this host.
+// - notes: Remarks to record in the manifest.
+// Returns:
+// - The bundle manifest.
+// - An error if the bundle cannot be written, in which case no partial bundle is left behind.
+func writeCoreBundle(bundlePath, corePath string, analysis CoreAnalysis, info SysInfo, notes []string) (_ BundleManifest, err error) {
+	host, _ := os.Hostname()
+	if redaction != nil {
+		// Only the generated files can be redacted; the core and binaries are copied as they are
+		host = pseudonymizeHostname(host)
+		info.Hostname = pseudonymizeHostname(info.Hostname)
+		notes = append(notes, fmt.Sprintf("analysis and sysinfo redacted with policy %s; the core file and binaries are not redacted", redaction.Name))
+	}
+	manifest := BundleManifest{
+		CreatedAt:   time.Now().Format(time.RFC3339),
+		Host:        host,
+		CoreFile:    analysis.CoreFile,
+		Binary:      analysis.PostgresInfo.BinaryPath,
+		BuildID:     analysis.PostgresInfo.BuildID,
+		CoreBuildID: analysis.PostgresInfo.CoreBuildID,
+		Compression: analysis.FileInfo.Compression,
+		GDBCommand:  "gdb -nx -x gdbinit",
+		Notes:       notes,
+	}
+	if manifest.Compression != "" {
+		manifest.Notes = append(manifest.Notes, fmt.Sprintf("the core was %s-compressed; the bundle holds it decompressed", manifest.Compression))
+	}
+
+	// The bundle is written beside its final path and only renamed into place once complete
+	tmpPath := bundlePath + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	b := &bundleWriter{
+		tw:       tw,
+		root:     strings.TrimSuffix(filepath.Base(bundlePath), ".tar.gz"),
+		manifest: &manifest,
+	}
+
+	analysisData, err := marshalFormat(analysis)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to marshal analysis: %w", err)
+	}
+	sysinfoData, err := marshalFormat(info)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to marshal sysinfo: %w", err)
+	}
+
+	coreDst := path.Join("core", filepath.Base(corePath))
+	binaryDst := ""
+	if analysis.PostgresInfo.BinaryPath != "" {
+		binaryDst = sysrootPath(analysis.PostgresInfo.BinaryPath)
+	} else {
+		manifest.Notes = append(manifest.Notes, "no postgres binary was identified for this core; gdbinit does not load one")
+	}
+
+	steps := []func() error{
+		func() error { return b.addData("analysis."+dataFormat(), bundleRoleAnalysis, analysisData) },
+		func() error { return b.addData("sysinfo."+dataFormat(), bundleRoleSysinfo, sysinfoData) },
+		func() error {
+			if err := b.addFile(corePath, coreDst, bundleRoleCore); err != nil || corePath == analysis.CoreFile {
+				return err
+			}
+			// Name the original core, not the scratch copy, as the source
+			for i := range manifest.Files {
+				if manifest.Files[i].Role == bundleRoleCore {
+					manifest.Files[i].Source = analysis.CoreFile
+				}
+			}
+			return nil
+		},
+		func() error {
+			if analysis.PostgresInfo.BinaryPath == "" {
+				manifest.Missing = append(manifest.Missing, "postgres binary (not determined)")
+				return nil
+			}
+			return b.addFile(analysis.PostgresInfo.BinaryPath, binaryDst, bundleRoleBinary)
+		},
+	}
+	for _, lib := range bundleLibraries(analysis, corePath) {
+		lib := lib
+		steps = append(steps, func() error { return b.addFile(lib, sysrootPath(lib), bundleRoleLibrary) })
+	}
+	steps = append(steps, func() error {
+		// Only a binary that made it into the bundle can be loaded
+		binary := ""
+		for _, f := range manifest.Files {
+			if f.Role == bundleRoleBinary {
+				binary = f.Path
+			}
+		}
+		return b.addData("gdbinit", bundleRoleGDBInit, []byte(bundleGDBInit(binary, coreDst)))
+	})
+
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return manifest, err
+		}
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, fmt.Errorf("failed to marshal bundle manifest: %w", err)
+	}
+	if err := b.addData("MANIFEST.json", "", manifestData); err != nil {
+		return manifest, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return manifest, fmt.Errorf("failed to finish bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return manifest, fmt.Errorf("failed to finish bundle: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return manifest, fmt.Errorf("failed to finish bundle: %w", err)
+	}
+	if err := os.Rename(tmpPath, bundlePath); err != nil {
+		return manifest, fmt.Errorf("failed to finish bundle: %w", err)
+	}
+	return manifest, nil
+}
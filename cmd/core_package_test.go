@@ -0,0 +1,276 @@
+// File: cmd/core_package_test.go
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readBundle returns the contents of every entry in a bundle, keyed by path below the bundle root.
+func readBundle(t *testing.T, bundlePath string) map[string][]byte {
+	t.Helper()
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+
+	root := strings.TrimSuffix(filepath.Base(bundlePath), ".tar.gz")
+	entries := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(header.Name, root+"/") {
+			t.Errorf("entry %s outside bundle root %s", header.Name, root)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[strings.TrimPrefix(header.Name, root+"/")] = data
+	}
+	return entries
+}
+
+func TestWriteCoreBundle(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"core.1234":           "core contents",
+		"gphome/bin/postgres": "postgres binary",
+		"lib64/libc.so.6":     "libc",
+		"gphome/lib/libpq.so": "libpq",
+	}
+	for name, content := range files {
+		p := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldFormat := formatFlag
+	defer func() { formatFlag = oldFormat }()
+	formatFlag = "yaml"
+
+	binary := filepath.Join(tmpDir, "gphome/bin/postgres")
+	analysis := CoreAnalysis{
+		CoreFile:     filepath.Join(tmpDir, "core.1234"),
+		PostgresInfo: PostgresInfo{BinaryPath: binary, BuildID: "abcd"},
+		Libraries: []LibraryInfo{
+			{Name: filepath.Join(tmpDir, "lib64/libc.so.6")},
+			{Name: filepath.Join(tmpDir, "gphome/lib/libpq.so")},
+			{Name: filepath.Join(tmpDir, "lib64/libc.so.6")},
+			{Name: filepath.Join(tmpDir, "missing/libgone.so")},
+			{Name: "linux-vdso.so.1"},
+		},
+	}
+
+	bundlePath := filepath.Join(tmpDir, "core.1234_bundle.tar.gz")
+	manifest, err := writeCoreBundle(bundlePath, analysis.CoreFile, analysis, SysInfo{Hostname: "sdw1"}, nil)
+	if err != nil {
+		t.Fatalf("writeCoreBundle() error = %v", err)
+	}
+
+	entries := readBundle(t, bundlePath)
+	binaryDst := sysrootPath(binary)
+	for _, want := range []string{"core/core.1234", binaryDst, "analysis.yaml", "sysinfo.yaml", "gdbinit", "MANIFEST.json",
+		sysrootPath(filepath.Join(tmpDir, "lib64/libc.so.6")), sysrootPath(filepath.Join(tmpDir, "gphome/lib/libpq.so"))} {
+		if _, ok := entries[want]; !ok {
+			t.Errorf("bundle missing %s", want)
+		}
+	}
+	if string(entries[binaryDst]) != "postgres binary" {
+		t.Errorf("binary content = %q", entries[binaryDst])
+	}
+	if !strings.Contains(string(entries["gdbinit"]), "file "+binaryDst) ||
+		!strings.Contains(string(entries["gdbinit"]), "core-file core/core.1234") {
+		t.Errorf("gdbinit does not load the bundled binary and core:\n%s", entries["gdbinit"])
+	}
+
+	var stored BundleManifest
+	if err := json.Unmarshal(entries["MANIFEST.json"], &stored); err != nil {
+		t.Fatalf("invalid manifest: %v", err)
+	}
+	if len(stored.Files) != len(manifest.Files) || len(stored.Files) != 7 {
+		t.Errorf("manifest lists %d files, want 7", len(stored.Files))
+	}
+	for _, f := range stored.Files {
+		sum := sha256.Sum256(entries[f.Path])
+		if hex.EncodeToString(sum[:]) != f.SHA256 {
+			t.Errorf("checksum mismatch for %s", f.Path)
+		}
+	}
+	if len(stored.Missing) != 1 || !strings.HasSuffix(stored.Missing[0], "libgone.so") {
+		t.Errorf("Missing = %v, want the unreadable library", stored.Missing)
+	}
+}
+
+func TestWriteCoreBundleWithoutBinary(t *testing.T) {
+	tmpDir := t.TempDir()
+	corePath := filepath.Join(tmpDir, "core.1234")
+	if err := os.WriteFile(corePath, []byte("core contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, binary := range map[string]string{"not identified": "", "unreadable": filepath.Join(tmpDir, "missing/postgres")} {
+		t.Run(name, func(t *testing.T) {
+			bundlePath := filepath.Join(t.TempDir(), "core.1234_bundle.tar.gz")
+			analysis := CoreAnalysis{CoreFile: corePath, PostgresInfo: PostgresInfo{BinaryPath: binary}}
+			manifest, err := writeCoreBundle(bundlePath, analysis.CoreFile, analysis, SysInfo{}, nil)
+			if err != nil {
+				t.Fatalf("writeCoreBundle() error = %v", err)
+			}
+			gdbinit := string(readBundle(t, bundlePath)["gdbinit"])
+			if strings.Contains(gdbinit, "\nfile ") || !strings.Contains(gdbinit, "core-file core/core.1234") {
+				t.Errorf("gdbinit should load only the core:\n%s", gdbinit)
+			}
+			noted := false
+			for _, note := range manifest.Notes {
+				noted = noted || strings.Contains(note, "no postgres binary was identified")
+			}
+			if noted != (binary == "") {
+				t.Errorf("Notes = %v", manifest.Notes)
+			}
+		})
+	}
+}
+
+func TestWriteCoreBundleFailureLeavesNoBundle(t *testing.T) {
+	// /proc files report a size of zero but have contents, so copying one overruns its tar entry
+	const unstable = "/proc/self/status"
+	if _, err := os.Stat(unstable); err != nil {
+		t.Skip("no /proc filesystem")
+	}
+	tmpDir := t.TempDir()
+	corePath := filepath.Join(tmpDir, "core.1234")
+	if err := os.WriteFile(corePath, []byte("core contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	bundlePath := filepath.Join(tmpDir, "core.1234_bundle.tar.gz")
+	analysis := CoreAnalysis{CoreFile: corePath, PostgresInfo: PostgresInfo{BinaryPath: unstable}}
+	if _, err := writeCoreBundle(bundlePath, corePath, analysis, SysInfo{}, nil); err == nil {
+		t.Fatal("writeCoreBundle() succeeded, want an error")
+	}
+	for _, path := range []string{bundlePath, bundlePath + ".tmp"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s left behind after a failed write", filepath.Base(path))
+		}
+	}
+}
+
+func TestBundleLibrariesFromCoreNotes(t *testing.T) {
+	dir := t.TempDir()
+	corePath := filepath.Join(dir, "core.4242")
+	writeTestCore(t, corePath, []testNote{
+		fileNote([]coreMappedFile{
+			{Start: 0x1000, End: 0x2000, Offset: 0, Path: "/usr/local/cloudberry/bin/postgres"},
+			{Start: 0x3000, End: 0x4000, Offset: 0, Path: "/lib64/libc.so.6"},
+			{Start: 0x4000, End: 0x5000, Offset: 0x1000, Path: "/lib64/libc.so.6"},
+			{Start: 0x6000, End: 0x7000, Offset: 0, Path: "/usr/lib/locale/locale-archive"},
+		}),
+	}, nil)
+
+	libs := bundleLibraries(CoreAnalysis{CoreFile: corePath}, corePath)
+	if len(libs) != 1 || libs[0] != "/lib64/libc.so.6" {
+		t.Errorf("bundleLibraries() = %v, want [/lib64/libc.so.6]", libs)
+	}
+}
+
+func TestRunCorePackage(t *testing.T) {
+	coreDir, _ := setupWatchEnv(t)
+	corePath := filepath.Join(coreDir, "core.555")
+	if err := os.WriteFile(corePath, []byte("mock core file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldBundle := packageBundlePath
+	defer func() { packageBundlePath = oldBundle }()
+	packageBundlePath = ""
+
+	if err := runCorePackage(context.Background(), corePath); err != nil {
+		t.Fatalf("runCorePackage() error = %v", err)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(outputDir, "core.555_bundle_*.tar.gz"))
+	if len(matches) != 1 {
+		t.Fatalf("expected one bundle in %s, got %v", outputDir, matches)
+	}
+	entries := readBundle(t, matches[0])
+	if string(entries[path.Join("core", "core.555")]) != "mock core file" {
+		t.Error("bundle does not contain the core")
+	}
+	if _, ok := entries["analysis.json"]; !ok {
+		t.Error("bundle does not contain the analysis")
+	}
+
+	// A compressed core is bundled decompressed, so the gdbinit can load it
+	useScratchDir(t)
+	compressTestFile(t, "gzip", corePath, corePath+".gz")
+	packageBundlePath = filepath.Join(t.TempDir(), "compressed.tar.gz")
+	if err := runCorePackage(context.Background(), corePath+".gz"); err != nil {
+		t.Fatalf("runCorePackage() error = %v", err)
+	}
+	entries = readBundle(t, packageBundlePath)
+	if string(entries[path.Join("core", "core.555")]) != "mock core file" {
+		t.Errorf("bundle does not contain the decompressed core: %v", entries)
+	}
+	if gdbinit := string(entries["gdbinit"]); !strings.Contains(gdbinit, "core-file core/core.555\n") {
+		t.Errorf("gdbinit does not load the decompressed core:\n%s", gdbinit)
+	}
+	var manifest BundleManifest
+	if err := json.Unmarshal(entries["MANIFEST.json"], &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Compression != "gzip" || manifest.CoreFile != corePath+".gz" {
+		t.Errorf("manifest = %+v", manifest)
+	}
+	for _, f := range manifest.Files {
+		if f.Role == bundleRoleCore && f.Source != corePath+".gz" {
+			t.Errorf("core source = %q", f.Source)
+		}
+	}
+	packageBundlePath = ""
+
+	// --redact cannot scrub the core, so bundling it takes an explicit opt-in
+	useRedaction(t, "basic")
+	oldUnredacted := packageUnredactedCore
+	defer func() { packageUnredactedCore = oldUnredacted }()
+	packageUnredactedCore = false
+	if err := runCorePackage(context.Background(), corePath); err == nil || !strings.Contains(err.Error(), "--include-unredacted-core") {
+		t.Errorf("runCorePackage(--redact) error = %v, want a refusal", err)
+	}
+	packageUnredactedCore = true
+	if err := runCorePackage(context.Background(), corePath); err != nil {
+		t.Errorf("runCorePackage(--redact --include-unredacted-core) error = %v", err)
+	}
+
+	// A core that cannot be analyzed produces no bundle
+	withRunFlags(t, 1, time.Millisecond, blockingCommander{})
+	if err := runCorePackage(context.Background(), corePath); err == nil {
+		t.Error("expected error when analysis times out")
+	}
+}
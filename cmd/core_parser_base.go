@@ -167,6 +167,10 @@ func findKeyFunction(backtrace []StackFrame) string {
     }
 
     for _, frame := range backtrace {
+	// The C++ runtime and gpos exception handling only propagate errors raised by their callers
+	if frameComponent(frame.Function) == componentCXXStd || isORCAExceptionFrame(frame.Function) {
+	    continue
+	}
 	if !skipFuncs[frame.Function] && frame.Function != "??" {
 	    return frame.Function
 	}
@@ -183,7 +187,7 @@ func isSystemFunction(funcName string) bool {
     systemPrefixes := []string{
 	"std::",     // C++ standard library
 	"__",        // Internal/compiler functions
-	"_Z",        // Mangled names
+	"_Z",        // Mangled names the demangler could not decode
 	"pthread_",  // Threading functions
     }
 
@@ -205,6 +209,11 @@ func isSystemFunction(funcName string) bool {
 	return true
     }
 
+    // GPORCA and gpos frames are Cloudberry code; the C++ runtime is not
+    if frameComponent(funcName) == componentCXXStd {
+	return true
+    }
+
     for _, prefix := range systemPrefixes {
 	if strings.HasPrefix(funcName, prefix) {
 	    return true
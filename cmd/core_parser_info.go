@@ -57,13 +57,20 @@ func parseBasicInfo(fileOutput string) map[string]string {
 	info["core_time"] = "N/A"
     }
 
-    // Additional derived fields
-    if _, ok := info["database_id"]; ok {
-	info["description"] = fmt.Sprintf(
-	    "Coordinator Write (Read-Only Mode), Database %s, Segment %s, Connection %s, Client %s (PID %s)",
-	    info["database_id"], info["segment_id"], info["connection_id"], info["client_address"], info["client_pid"],
-	)
-	info["process_type"] = "Coordinator Write (Read-Only Mode)"
+    // Additional derived fields; the process type is only known from the process title or session globals
+    var desc []string
+    for _, field := range []struct{ label, key string }{
+	{"Database", "database_id"},
+	{"Segment", "segment_id"},
+	{"Connection", "connection_id"},
+	{"Client", "client_address"},
+    } {
+	if value := info[field.key]; value != "N/A" {
+	    desc = append(desc, fmt.Sprintf("%s %s", field.label, value))
+	}
+    }
+    if len(desc) > 0 {
+	info["description"] = strings.Join(desc, ", ")
     }
 
     return info
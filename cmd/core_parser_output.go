@@ -12,8 +12,8 @@
 
 // File: cmd/core_parser_output.go
 // Purpose: Implements utilities for saving and comparing core dump analysis results.
-// Includes functions to serialize analysis data in JSON or YAML format and
-// to identify common patterns across multiple core files.
+// Includes functions to serialize analysis data in JSON or YAML format, or render it as an HTML,
+// Markdown or JUnit report, and to identify common patterns across multiple core files.
 // Dependencies: Uses Go standard libraries for JSON, YAML, file handling, and string manipulation.
 
 package cmd
@@ -36,29 +36,21 @@ import (
 // Returns:
 // - An error if the operation fails, or nil otherwise.
 func saveAnalysis(analysis CoreAnalysis) error {
-	timestamp := time.Now().Format("20060102_150405")
-	filename := filepath.Join(outputDir, fmt.Sprintf("core_analysis_%s.%s", timestamp, formatFlag))
-
-	// Deduplicate threads and parse basic info
-	analysis.Threads = deduplicateThreads(analysis.Threads)
-	analysis.BasicInfo = parseBasicInfo(analysis.FileInfo.FileOutput)
-
-	// Mark crashed threads and enhance thread info
-	for i := range analysis.Threads {
-		for _, frame := range analysis.Threads[i].Backtrace {
-			if strings.Contains(frame.Function, "SigillSigsegvSigbus") {
-				analysis.Threads[i].IsCrashed = true
-				break
-			}
-		}
-		analysis.Threads[i].Name = determineThreadRole(analysis.Threads[i].Backtrace)
-	}
+	filename := filepath.Join(outputDir, analysisFileName(analysis.CoreFile, formatFlag))
+	analysis = prepareAnalysis(analysis)
 
 	var data []byte
 	var err error
-	if formatFlag == "json" {
+	switch formatFlag {
+	case formatJSON:
 		data, err = json.MarshalIndent(analysis, "", "  ")
-	} else {
+	case formatHTML:
+		data, err = renderAnalysisHTML(analysis)
+	case formatMarkdown:
+		data = renderAnalysisMarkdown(analysis)
+	case formatJUnit:
+		data, err = renderAnalysisJUnit(analysis)
+	default:
 		data, err = yaml.Marshal(analysis)
 	}
 	if err != nil {
@@ -68,11 +60,36 @@ func saveAnalysis(analysis CoreAnalysis) error {
 	if err := os.WriteFile(filename, data, 0644); err != nil {
 		return fmt.Errorf("failed to write analysis file: %w", err)
 	}
+	if err := updateAnalysisIndex(outputDir, analysis, formatFlag, filepath.Base(filename)); err != nil {
+		return err
+	}
 
 	fmt.Printf("Analysis saved to: %s\n", filename)
 	return nil
 }
 
+// prepareAnalysis deduplicates threads and names each thread's role before an analysis is output.
+// Parameters:
+// - analysis: The CoreAnalysis object to prepare.
+// Returns:
+// - The prepared analysis.
+func prepareAnalysis(analysis CoreAnalysis) CoreAnalysis {
+	// Deduplicate threads
+	analysis.Threads = deduplicateThreads(analysis.Threads)
+
+	// Mark crashed threads and enhance thread info
+	for i := range analysis.Threads {
+		for _, frame := range analysis.Threads[i].Backtrace {
+			if strings.Contains(frame.Function, "SigillSigsegvSigbus") {
+				analysis.Threads[i].IsCrashed = true
+				break
+			}
+		}
+		analysis.Threads[i].Name = determineThreadRole(analysis.Threads[i].Backtrace)
+	}
+	return analysis
+}
+
 // compareCores analyzes multiple core files to identify patterns.
 // Parameters:
 // - analyses: A slice of CoreAnalysis objects representing individual core dump analyses.
@@ -80,6 +97,7 @@ func saveAnalysis(analysis CoreAnalysis) error {
 // - A CoreComparison object summarizing common patterns and statistics.
 func compareCores(analyses []CoreAnalysis) CoreComparison {
 	comparison := CoreComparison{
+		analyses:        analyses,
 		TotalCores:      len(analyses),
 		CommonSignals:   make(map[string]int),
 		CommonFunctions: make(map[string]int),
@@ -113,15 +131,9 @@ func compareCores(analyses []CoreAnalysis) CoreComparison {
 			}
 		}
 
-		// Create crash signature
-		var signature strings.Builder
-		signature.WriteString(signal)
-		for i, frame := range analysis.StackTrace {
-			if i < 3 && !isSystemFunction(frame.Function) {
-				signature.WriteString("|" + frame.Function)
-			}
-		}
-		crashGroups[signature.String()] = append(crashGroups[signature.String()], analysis)
+		// Group by crash signature
+		signature := crashPatternSignature(analysis)
+		crashGroups[signature] = append(crashGroups[signature], analysis)
 	}
 
 	// Generate crash patterns
@@ -146,9 +158,35 @@ func compareCores(analyses []CoreAnalysis) CoreComparison {
 		return comparison.CrashPatterns[i].OccurrenceCount > comparison.CrashPatterns[j].OccurrenceCount
 	})
 
+	// Group near-identical stacks that exact signatures split apart
+	if clusterAlgorithm != "" {
+		comparison.ClusterMethod = fmt.Sprintf("%s (threshold %g)", clusterAlgorithm, clusterThreshold)
+		comparison.Clusters = clusterCores(analyses, clusterAlgorithm, clusterThreshold)
+	}
+
+	// Tell known crashes from new ones
+	markKnownCrashes(&comparison, analyses)
+
 	return comparison
 }
 
+// crashPatternSignature builds the signature compareCores groups cores by:
+// the signal plus the non-system frames among the top three, separated by "|".
+// Parameters:
+// - analysis: The CoreAnalysis object to sign.
+// Returns:
+// - The crash pattern signature.
+func crashPatternSignature(analysis CoreAnalysis) string {
+	var signature strings.Builder
+	signature.WriteString(analysis.SignalInfo.SignalName)
+	for i, frame := range analysis.StackTrace {
+		if i < 3 && !isSystemFunction(frame.Function) {
+			signature.WriteString("|" + frame.Function)
+		}
+	}
+	return signature.String()
+}
+
 // saveComparison saves comparison results to a file.
 // Parameters:
 // - comparison: The CoreComparison object summarizing core file patterns.
@@ -156,13 +194,20 @@ func compareCores(analyses []CoreAnalysis) CoreComparison {
 // - An error if the operation fails, or nil otherwise.
 func saveComparison(comparison CoreComparison) error {
 	timestamp := time.Now().Format("20060102_150405")
-	filename := filepath.Join(outputDir, fmt.Sprintf("core_comparison_%s.%s", timestamp, formatFlag))
+	filename := filepath.Join(outputDir, fmt.Sprintf("core_comparison_%s.%s", timestamp, formatExtension(formatFlag)))
 
 	var data []byte
 	var err error
-	if formatFlag == "json" {
+	switch formatFlag {
+	case formatJSON:
 		data, err = json.MarshalIndent(comparison, "", "  ")
-	} else {
+	case formatHTML:
+		data, err = renderComparisonHTML(comparison)
+	case formatMarkdown:
+		data = renderComparisonMarkdown(comparison)
+	case formatJUnit:
+		data, err = renderComparisonJUnit(comparison)
+	default:
 		data, err = yaml.Marshal(comparison)
 	}
 	if err != nil {
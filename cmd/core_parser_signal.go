@@ -20,7 +20,6 @@ package cmd
 
 import (
     "fmt"
-    "strconv"
     "strings"
     "regexp"
 )
@@ -31,6 +30,7 @@ var signalMap = map[int]string{
     2:  "SIGINT",   // Terminal interrupt
     3:  "SIGQUIT",  // Terminal quit
     4:  "SIGILL",   // Illegal instruction
+    5:  "SIGTRAP",  // Trace/breakpoint trap
     6:  "SIGABRT",  // Process abort
     7:  "SIGBUS",   // Bus error
     8:  "SIGFPE",   // Floating point exception
@@ -39,6 +39,9 @@ var signalMap = map[int]string{
     13: "SIGPIPE",  // Broken pipe
     14: "SIGALRM",  // Timer signal
     15: "SIGTERM",  // Termination
+    24: "SIGXCPU",  // CPU time limit exceeded
+    25: "SIGXFSZ",  // File size limit exceeded
+    31: "SIGSYS",   // Bad system call
 }
 
 // signalCodeMap maps signal-specific codes to descriptions.
@@ -48,11 +51,38 @@ var signalCodeMap = map[int]map[int]string{
 	2: "SEGV_ACCERR (Invalid permissions for mapped object)",
 	3: "SEGV_BNDERR (Failed address bound checks)",
 	4: "SEGV_PKUERR (Access denied by memory protection keys)",
+	5: "SEGV_ACCADI (ADI not enabled for mapped object)",
+	6: "SEGV_ADIDERR (Disrupting MCD error)",
+	7: "SEGV_ADIPERR (Precise MCD exception)",
+	8: "SEGV_MTEAERR (Asynchronous ARM MTE error)",
+	9: "SEGV_MTESERR (Synchronous ARM MTE exception)",
+	10: "SEGV_CPERR (Control protection fault)",
     },
     7: { // SIGBUS codes
 	1: "BUS_ADRALN (Invalid address alignment)",
 	2: "BUS_ADRERR (Nonexistent physical address)",
 	3: "BUS_OBJERR (Object-specific hardware error)",
+	4: "BUS_MCEERR_AR (Hardware memory error consumed on a machine check)",
+	5: "BUS_MCEERR_AO (Hardware memory error detected in process but not consumed)",
+    },
+    4: { // SIGILL codes
+	1: "ILL_ILLOPC (Illegal opcode)",
+	2: "ILL_ILLOPN (Illegal operand)",
+	3: "ILL_ILLADR (Illegal addressing mode)",
+	4: "ILL_ILLTRP (Illegal trap)",
+	5: "ILL_PRVOPC (Privileged opcode)",
+	6: "ILL_PRVREG (Privileged register)",
+	7: "ILL_COPROC (Coprocessor error)",
+	8: "ILL_BADSTK (Internal stack error)",
+	9: "ILL_BADIADDR (Unimplemented instruction address)",
+    },
+    5: { // SIGTRAP codes
+	1: "TRAP_BRKPT (Process breakpoint)",
+	2: "TRAP_TRACE (Process trace trap)",
+	3: "TRAP_BRANCH (Process taken branch trap)",
+	4: "TRAP_HWBKPT (Hardware breakpoint or watchpoint)",
+	5: "TRAP_UNK (Undiagnosed trap)",
+	6: "TRAP_PERF (Perf event with sigtrap=1)",
     },
     8: { // SIGFPE codes
 	1: "FPE_INTDIV (Integer divide by zero)",
@@ -63,9 +93,25 @@ var signalCodeMap = map[int]map[int]string{
 	6: "FPE_FLTRES (Floating point inexact result)",
 	7: "FPE_FLTINV (Invalid floating point operation)",
 	8: "FPE_FLTSUB (Subscript out of range)",
+	14: "FPE_FLTUNK (Undiagnosed floating-point exception)",
+	15: "FPE_CONDTRAP (Trap on condition)",
     },
 }
 
+// genericSignalCodeMap describes the si_codes shared by all signals: who sent the signal.
+var genericSignalCodeMap = map[int]string{
+    siUser:   "SI_USER (Sent by kill)",
+    siKernel: "SI_KERNEL (Sent by the kernel)",
+    -1:       "SI_QUEUE (Sent by sigqueue)",
+    -2:       "SI_TIMER (POSIX timer expired)",
+    -3:       "SI_MESGQ (POSIX message queue state changed)",
+    -4:       "SI_ASYNCIO (Asynchronous I/O completed)",
+    -5:       "SI_SIGIO (Queued SIGIO)",
+    siTkill:  "SI_TKILL (Sent by tkill or tgkill, e.g. from raise or abort)",
+    -7:       "SI_DETHREAD (Sent by execve killing subsidiary threads)",
+    -60:      "SI_ASYNCNL (Asynchronous name lookup completed)",
+}
+
 // parseSignalInfo extracts signal information from GDB output.
 // Parameters:
 // - output: The raw GDB output containing signal information.
@@ -74,21 +120,13 @@ var signalCodeMap = map[int]map[int]string{
 func parseSignalInfo(output string) SignalInfo {
     info := SignalInfo{}
 
-    // Extract signal number and code
-    siginfoRE := regexp.MustCompile(`si_signo\s*=\s*(\d+)[\s,]*si_code\s*=\s*(\d+)`)
-    if matches := siginfoRE.FindStringSubmatch(output); matches != nil {
-        info.SignalNumber = parseInt(matches[1])
-        info.SignalCode = parseInt(matches[2])
-        info.SignalName = getSignalName(info.SignalNumber)
-        info.SignalDescription = getSignalDescription(info.SignalNumber, info.SignalCode)
+    // Decode $_siginfo: signal, code, and the fault address or sender the code selects
+    if fields, ok := parseSiginfoFields(output); ok {
+        info = fields.signalInfo()
     }
 
-    // Parse fault address from direct si_addr or _sigfault
-    faultAddrRE := regexp.MustCompile(`(?:si_addr|_sigfault = {si_addr) = (0x[0-9a-fA-F]+)`)
-    if matches := faultAddrRE.FindStringSubmatch(output); matches != nil {
-        info.FaultInfo = &SignalFault{Address: matches[1]}
-        info.FaultAddress = matches[1]
-    }
+    // How gdb handles the signals a backend dumps core on
+    applySignalDispositions(&info, parseSignalDispositions(output))
 
     return info
 }
@@ -123,21 +161,48 @@ func getSignalDescription(signo, code int) string {
 	desc.WriteString("Bus error")
     case 8:
 	desc.WriteString("Floating point exception")
+    case 4:
+	desc.WriteString("Illegal instruction")
+    case 5:
+	desc.WriteString("Trace/breakpoint trap")
     default:
 	desc.WriteString(fmt.Sprintf("Signal %d", signo))
     }
 
-    if codes, ok := signalCodeMap[signo]; ok {
-	if codeDesc, ok := codes[code]; ok {
-	    desc.WriteString(fmt.Sprintf(" - %s", codeDesc))
-	} else if code != 0 {
-	    desc.WriteString(fmt.Sprintf(" (code %d)", code))
-	}
+    if codeDesc := signalCodeDescription(signo, code); codeDesc != "" {
+	desc.WriteString(fmt.Sprintf(" - %s", codeDesc))
+    } else if _, ok := signalCodeMap[signo]; ok && code != 0 {
+	desc.WriteString(fmt.Sprintf(" (code %d)", code))
     }
 
     return desc.String()
 }
 
+// signalCodeDescription looks up the description of a signal's si_code.
+// Positive codes other than SI_KERNEL are specific to the signal; the others say who sent it.
+// Parameters:
+// - signo: The signal number.
+// - code: The signal code.
+// Returns:
+// - The description, e.g. "SEGV_MAPERR (Address not mapped to object)", or "" if unknown.
+func signalCodeDescription(signo, code int) string {
+    if code > 0 && code != siKernel {
+	return signalCodeMap[signo][code]
+    }
+    return genericSignalCodeMap[code]
+}
+
+// getSignalCodeName returns the symbolic name of a signal's si_code.
+// Parameters:
+// - signo: The signal number.
+// - code: The signal code.
+// Returns:
+// - The name, e.g. "SEGV_MAPERR" or "SI_TKILL", or "" if unknown.
+func getSignalCodeName(signo, code int) string {
+    name, _, _ := strings.Cut(signalCodeDescription(signo, code), " ")
+    return name
+}
+
 // enhanceSignalInfo adds additional context to signal information.
 // Parameters:
 // - info: A pointer to the SignalInfo object to enhance.
@@ -179,28 +244,19 @@ func enhanceSignalInfo(info *SignalInfo, analysis *CoreAnalysis) {
     addCloudberryContext(info, analysis)
 }
 
-// addFaultAddressContext adds fault address-related details to the signal description.
+// addFaultAddressContext classifies the fault address against the memory map and records the verdict.
+// The verdict is kept in FaultVerdict rather than the description, which detectSignalFromStack
+// rewrites when the crash went through the backend's signal handler.
 // Parameters:
 // - info: A pointer to the SignalInfo object.
-// - analysis: A CoreAnalysis object containing library mappings.
+// - analysis: A CoreAnalysis object containing the memory map and registers.
 func addFaultAddressContext(info *SignalInfo, analysis *CoreAnalysis) {
-    addr, err := strconv.ParseUint(strings.TrimPrefix(info.FaultAddress, "0x"), 16, 64)
-    if err != nil {
+    class, verdict := classifyFaultAddress(analysis)
+    if class == "" {
 	return
     }
-
-    for _, lib := range analysis.Libraries {
-	start, _ := strconv.ParseUint(strings.TrimPrefix(lib.TextStart, "0x"), 16, 64)
-	end, _ := strconv.ParseUint(strings.TrimPrefix(lib.TextEnd, "0x"), 16, 64)
-	if addr >= start && addr <= end {
-	    info.SignalDescription += fmt.Sprintf(" (fault address in %s)", lib.Name)
-	    return
-	}
-    }
-
-    if info.SignalDescription != "" {
-	info.SignalDescription += " (fault address not in mapped memory)"
-    }
+    info.FaultClass = class
+    info.FaultVerdict = verdict
 }
 
 // addCloudberryContext adds context specific to Cloudberry processes.
@@ -29,18 +29,15 @@ si_addr = 0x0000000000000000`,
            },
        },
        {
-           name: "SIGABRT with fault info",
+           // SI_USER: the union holds the sender, so si_addr is not a fault address
+           name: "SIGABRT sent by kill",
            input: `si_signo = 6, si_code = 0
 _sigfault = {si_addr = 0x00007f8b4c37c425}`,
            expected: SignalInfo{
                SignalNumber: 6,
                SignalCode: 0,
                SignalName: "SIGABRT", 
-               SignalDescription: "Process abort signal (possibly assertion failure)",
-               FaultInfo: &SignalFault{
-                   Address: "0x00007f8b4c37c425",
-               },
-               FaultAddress: "0x00007f8b4c37c425",
+               SignalDescription: "Process abort signal (possibly assertion failure) - SI_USER (Sent by kill)",
            },
        },
        {
@@ -30,7 +30,7 @@ var gdbStyleOutput bool
 
 // Initialize flags for GDB-style output.
 func init() {
-    coreCmd.Flags().BoolVar(&gdbStyleOutput, "gdb-style", false, "Output in GDB-like format")
+    coreCmd.PersistentFlags().BoolVar(&gdbStyleOutput, "gdb-style", false, "Output in GDB-like format")
 }
 
 // saveOrPrintAnalysis handles output based on the specified format.
@@ -39,6 +39,9 @@ func init() {
 // Returns:
 // - An error if the operation fails, or nil on success.
 func saveOrPrintAnalysis(analysis CoreAnalysis) error {
+    if streamingOutput() {
+        return streamAnalysis(analysis)
+    }
     if gdbStyleOutput {
         return printGDBStyle(analysis)
     }
@@ -63,27 +66,49 @@ func printGDBStyle(analysis CoreAnalysis) error {
         fmt.Printf("Process: %s\n", desc)
     }
     fmt.Printf("Core: %s\n", analysis.CoreFile)
+    if compression := compressionSummary(analysis.FileInfo); compression != "" {
+        fmt.Printf("Compression: %s\n", compression)
+    }
     fmt.Printf("Time: %s\n", analysis.Timestamp)
     fmt.Printf("PostgreSQL: %s\n", analysis.PostgresInfo.Version)
     fmt.Printf("Cloudberry: %s\n", analysis.PostgresInfo.GPVersion)
-
-    fmt.Printf("\nSignal Configuration:\n")
-    fmt.Printf("%-10s  Stop    Print   Pass    Description\n", "Signal")
-    fmt.Printf("%-10s  %-7s %-7s %-7s %s\n", 
-        analysis.SignalInfo.SignalName,
-        "Yes",  // Typically "Yes" for core dumps
-        "Yes", 
-        "Yes",
-        analysis.SignalInfo.SignalDescription)
+    printAbortInfo(analysis.AbortInfo)
+    if exc := analysis.OrcaException; exc != nil {
+        fmt.Printf("\n*** %s ***\n", exc.Summary)
+    }
+    printKnownIssues(analysis.KnownIssues)
+    if r := analysis.Redaction; r != nil {
+        fmt.Printf("\nRedacted: %d value(s) pseudonymized (policy %s)\n", len(r.Changes), r.Policy)
+    }
+    printSessionInfo(analysis.SessionInfo)
+
+    if len(analysis.SignalInfo.Dispositions) > 0 {
+        fmt.Printf("\nSignal Configuration:\n")
+        fmt.Printf("%-10s  Stop    Print   Pass    Description\n", "Signal")
+        for _, d := range analysis.SignalInfo.Dispositions {
+            fmt.Printf("%-10s  %-7s %-7s %-7s %s\n",
+                d.Signal, yesNo(d.Stop), yesNo(d.Print), yesNo(d.Pass), d.Description)
+        }
+    }
 
     fmt.Printf("\nProgram received signal %s (%d), %s\n",
         analysis.SignalInfo.SignalName,
         analysis.SignalInfo.SignalNumber,
         analysis.SignalInfo.SignalDescription)
     
+    printSignalOrigin(analysis.SignalInfo)
     if analysis.SignalInfo.FaultInfo != nil {
         fmt.Printf("Fault address: %s\n", analysis.SignalInfo.FaultInfo.Address)
     }
+    if analysis.SignalInfo.FaultVerdict != "" {
+        fmt.Printf("Fault: %s\n", analysis.SignalInfo.FaultVerdict)
+    }
+    if usage := analysis.StackUsage; usage != nil {
+        fmt.Printf("Stack: %s\n", usage.Verdict)
+        for _, cycle := range usage.Recursion {
+            fmt.Printf("Recursion: %s\n", cycle.Summary)
+        }
+    }
 
     fmt.Println("\nThread Information:")
     for _, thread := range analysis.Threads {
@@ -94,6 +119,37 @@ func printGDBStyle(analysis CoreAnalysis) error {
     fmt.Println("Registers:")
     printRegistersEnhanced(analysis.Registers)
 
+    if len(analysis.Modules) > 0 {
+        found := 0
+        for _, module := range analysis.Modules {
+            if module.SymbolsFound {
+                found++
+            }
+        }
+        fmt.Printf("\nDebug Symbols: %d of %d modules\n", found, len(analysis.Modules))
+        for _, module := range analysis.Modules {
+            if !module.SymbolsFound {
+                fmt.Printf("  missing: %s (build-id %s)\n", module.Path, module.BuildID)
+            }
+        }
+    }
+
+    if report := analysis.MemoryContexts; report != nil {
+        fmt.Printf("\nMemory Contexts (%d):\n", report.ContextCount)
+        for _, line := range formatMemoryContexts(report) {
+            fmt.Println(line)
+        }
+        if report.Truncated {
+            fmt.Println("(memory context walk incomplete)")
+        }
+        if len(report.Largest) > 0 {
+            fmt.Println("\nLargest Memory Contexts:")
+            for _, ctx := range report.Largest {
+                fmt.Printf("  %12d bytes  %s\n", ctx.TotalBytes, ctx.Path)
+            }
+        }
+    }
+
     fmt.Println("\nKey Shared Libraries:")
     for _, lib := range analysis.Libraries {
         if lib.Type == "Core" || lib.Type == "Extension" {
@@ -107,6 +163,82 @@ func printGDBStyle(analysis CoreAnalysis) error {
     return nil
 }
 
+// printAbortInfo outputs why the backend aborted, followed by the elog error stack.
+// Parameters:
+// - info: The abort information; nothing is printed if nil.
+func printAbortInfo(info *AbortInfo) {
+    if info == nil {
+        return
+    }
+    fmt.Printf("\n*** %s ***\n", info.Summary)
+    for i, e := range info.Errors {
+        fmt.Printf("  errordata[%d]: %s", i, e.Level)
+        if e.SQLState != "" {
+            fmt.Printf(" %s", e.SQLState)
+        }
+        fmt.Printf(": %s\n", e.Message)
+        if e.Detail != "" {
+            fmt.Printf("    DETAIL: %s\n", e.Detail)
+        }
+        if e.Hint != "" {
+            fmt.Printf("    HINT: %s\n", e.Hint)
+        }
+        if e.File != "" {
+            fmt.Printf("    LOCATION: %s, %s:%d\n", e.Function, e.File, e.Line)
+        }
+    }
+}
+
+// printKnownIssues outputs the known issues the crash matched.
+// Parameters:
+// - issues: The matched known issues; nothing is printed if empty.
+func printKnownIssues(issues []KnownIssue) {
+    for _, issue := range issues {
+        fmt.Printf("\nKnown issue %s: %s", issue.ID, issue.Title)
+        if issue.FixedIn != "" {
+            fmt.Printf(" (fixed in %s)", issue.FixedIn)
+        }
+        fmt.Println()
+        if issue.Workaround != "" {
+            fmt.Printf("  Workaround: %s\n", issue.Workaround)
+        }
+    }
+}
+
+// printSessionInfo outputs the session identity and the query the backend was running.
+// Parameters:
+// - session: The session information; nothing is printed if nil.
+func printSessionInfo(session *SessionInfo) {
+    if session == nil {
+        return
+    }
+    fmt.Println("\nSession:")
+    if session.Role != "" {
+        fmt.Printf("  Role: %s\n", session.Role)
+    }
+    if session.PID != 0 {
+        fmt.Printf("  PID: %d\n", session.PID)
+    }
+    if session.SessionID != 0 {
+        fmt.Printf("  Session: con%d, command %d\n", session.SessionID, session.CommandCount)
+    }
+    if session.SegmentID != nil {
+        fmt.Printf("  Segment: %d\n", *session.SegmentID)
+    }
+    if session.SliceID != nil {
+        fmt.Printf("  Slice: %d\n", *session.SliceID)
+    }
+    if session.Database != "" || session.User != "" {
+        fmt.Printf("  Database: %s, User: %s\n", session.Database, session.User)
+    }
+    if session.RemoteHost != "" {
+        fmt.Printf("  Client: %s\n", session.RemoteHost)
+    }
+    if session.Query != "" {
+        fmt.Printf("  Query: %s\n", session.Query)
+    }
+}
+
 // printThreadWithLWP prints thread details along with LWP (Light Weight Process) information.
 // Parameters:
 // - thread: The ThreadInfo object containing thread details.
@@ -124,9 +256,7 @@ func printThreadWithLWP(thread ThreadInfo, crashed bool) {
     }
     fmt.Printf("%s:\n", threadHeader)
     
-    for _, frame := range thread.Backtrace {
-        printFrameDetailed(frame)
-    }
+    collapseRecursion(thread, printFrameDetailed, printRecursionCycle)
 }
 
 // printFrameDetailed outputs detailed information about a stack frame.
@@ -142,6 +272,31 @@ func printFrameDetailed(frame StackFrame) {
         frameStr += fmt.Sprintf(" from %s", frame.Module)
     }
     fmt.Println(frameStr)
+    printSourceContext(frame.Source)
+}
+
+// printRecursionCycle outputs the line that replaces the repeated frames of a recursion cycle.
+// Parameters:
+// - cycle: The recursion cycle being collapsed.
+func printRecursionCycle(cycle RecursionCycle) {
+    fmt.Printf("    ... %s (frames %d-%d) ...\n", cycle.Summary, cycle.FirstFrame, cycle.LastFrame)
+}
+
+// printSourceContext outputs the source lines around a frame, marking the frame's line.
+// Parameters:
+// - src: The frame's source context; nothing is printed if nil.
+func printSourceContext(src *SourceContext) {
+    if src == nil {
+        return
+    }
+    fmt.Printf("    %s:\n", src.File)
+    for _, line := range src.Lines {
+        marker := "  "
+        if line.Current {
+            marker = "=>"
+        }
+        fmt.Printf("    %s %5d  %s\n", marker, line.Number, line.Text)
+    }
 }
 
 // printRegistersEnhanced organizes and prints CPU register values.
@@ -208,11 +363,27 @@ func printSignalInfo(analysis CoreAnalysis) {
         analysis.SignalInfo.SignalNumber,
         analysis.SignalInfo.SignalDescription)
     
+    printSignalOrigin(analysis.SignalInfo)
     if analysis.SignalInfo.FaultAddress != "" {
         fmt.Printf("Fault address: %s\n", analysis.SignalInfo.FaultAddress)
     }
 }
 
+// printSignalOrigin outputs where the signal came from and its si_code.
+// Parameters:
+// - info: The SignalInfo object with the decoded siginfo.
+func printSignalOrigin(info SignalInfo) {
+    verdict := signalOriginVerdict(info)
+    if verdict == "" {
+        return
+    }
+    code := info.SignalCodeName
+    if code == "" {
+        code = fmt.Sprintf("si_code %d", info.SignalCode)
+    }
+    fmt.Printf("Origin: %s (%s)\n", verdict, code)
+}
+
 // printThreads outputs all thread information.
 // Parameters:
 // - analysis: The CoreAnalysis object containing thread details.
@@ -251,9 +422,7 @@ func printThread(thread ThreadInfo, crashed bool) {
     }
     fmt.Println(threadHeader)
 
-    for _, frame := range thread.Backtrace {
-        printFrame(frame)
-    }
+    collapseRecursion(thread, printFrame, printRecursionCycle)
 }
 
 // printFrame outputs detailed stack frame information.
@@ -280,6 +449,7 @@ func printFrame(frame StackFrame) {
     }
 
     fmt.Println(frameStr)
+    printSourceContext(frame.Source)
 }
 
 // printRegisters outputs register states.
@@ -0,0 +1,243 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_recursion.go
+// Purpose: Detects runaway recursion in backtraces. Repeating cycles of frames (such as
+// ExecEvalExpr → ExecInterpExpr in deep expression trees) are recorded on each thread so that
+// printed backtraces can collapse them, and the crashed thread's stack depth is compared against
+// its stack mapping and the backend's max_stack_depth to recognize true stack exhaustion.
+// Dependencies: Uses the memory map built in core_memmap.go and the globals read in core_session.go.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var recursionThreshold int // Minimum repetitions of a frame cycle reported as recursion
+
+func init() {
+	coreCmd.PersistentFlags().IntVar(&recursionThreshold, "recursion-threshold", 10, "Minimum repetitions of a frame cycle reported as recursion")
+}
+
+const (
+	maxRecursionPeriod = 16      // Longest frame cycle looked for
+	stackLimitSlop     = 0x10000 // A stack pointer this close to the end of a stack that cannot grow has run out of stack
+	defaultStackRlimit = 8 << 20 // The usual `ulimit -s`; a core does not record the process's own stack limit
+)
+
+// frameKey identifies a frame for cycle detection; arguments and addresses differ between iterations.
+func frameKey(frame StackFrame) string {
+	if frame.Function != "" {
+		return frame.Function
+	}
+	return frame.Location
+}
+
+// cycleRepeats counts how many times the period-long cycle starting at frames[start] repeats.
+func cycleRepeats(keys []string, start, period int) int {
+	repeats := 1
+	for next := start + period; next+period <= len(keys); next += period {
+		for i := 0; i < period; i++ {
+			if keys[next+i] != keys[start+i] {
+				return repeats
+			}
+		}
+		repeats++
+	}
+	return repeats
+}
+
+// detectRecursion finds repeating frame cycles in a backtrace.
+// At each frame the cycle covering the most frames is taken, preferring the shortest period.
+// Parameters:
+// - frames: The backtrace, innermost frame first.
+// Returns:
+// - The cycles repeated at least --recursion-threshold times, innermost first.
+func detectRecursion(frames []StackFrame) []RecursionCycle {
+	keys := make([]string, len(frames))
+	for i, frame := range frames {
+		keys[i] = frameKey(frame)
+	}
+
+	var cycles []RecursionCycle
+	for start := 0; start < len(keys); {
+		bestPeriod, bestRepeats := 0, 0
+		for period := 1; period <= maxRecursionPeriod && start+2*period <= len(keys); period++ {
+			repeats := cycleRepeats(keys, start, period)
+			if repeats >= recursionThreshold && repeats*period > bestRepeats*bestPeriod {
+				bestPeriod, bestRepeats = period, repeats
+			}
+		}
+		if bestPeriod == 0 {
+			start++
+			continue
+		}
+
+		// Callers come later in the backtrace; list the cycle in call order
+		functions := make([]string, bestPeriod)
+		for i := 0; i < bestPeriod; i++ {
+			functions[bestPeriod-1-i] = keys[start+i]
+		}
+		end := start + bestPeriod*bestRepeats - 1
+		cycles = append(cycles, RecursionCycle{
+			Functions:  functions,
+			Repeats:    bestRepeats,
+			FirstFrame: start,
+			LastFrame:  end,
+			Summary:    fmt.Sprintf("%s repeated %s times", strings.Join(functions, " → "), formatCount(bestRepeats)),
+		})
+		start = end + 1
+	}
+	return cycles
+}
+
+// formatCount formats a count with thousands separators, e.g. 4812 as "4,812".
+func formatCount(n int) string {
+	if n < 0 {
+		return "-" + formatCount(-n)
+	}
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// formatBytes formats a byte count for stack depth messages.
+func formatBytes(n uint64) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d bytes", n)
+	}
+	return humanizeSize(strconv.FormatUint(n/1024, 10))
+}
+
+// analyzeStackUsage measures the crashed thread's stack and decides whether it ran out.
+// The depth is measured from the backend's stack_base_ptr when it could be read, otherwise from
+// the top of the stack mapping containing the stack pointer.
+// Parameters:
+// - analysis: The CoreAnalysis object with threads, registers, memory map and signal populated.
+// Returns:
+// - The stack usage, or nil if neither the depth nor any recursion is known.
+func analyzeStackUsage(analysis *CoreAnalysis) *StackUsage {
+	usage := &StackUsage{}
+	for _, thread := range analysis.Threads {
+		if thread.IsCrashed {
+			usage.Frames = len(thread.Backtrace)
+			usage.Recursion = thread.Recursion
+		}
+	}
+	if usage.Frames == 0 {
+		usage.Frames = len(analysis.StackTrace)
+	}
+
+	sp := stackPointer(analysis)
+	var stackStart, stackEnd uint64
+	atLimit := false
+	if sp != 0 {
+		if idx := findMapping(analysis.MemoryMap, sp); idx >= 0 {
+			stackStart, stackEnd = regionBounds(analysis.MemoryMap[idx])
+			usage.StackMappingBytes = stackEnd - stackStart
+			atLimit = sp-stackStart < stackLimitSlop && stackCannotGrow(analysis.MemoryMap, idx)
+		}
+	}
+	base := stackEnd
+	if session := analysis.SessionInfo; session != nil {
+		if b, ok := parseHexAddress(session.StackBase); ok && b > sp {
+			base = b
+		}
+		usage.MaxStackDepthBytes = uint64(session.MaxStackDepthKB) * 1024
+	}
+	if sp != 0 && base > sp {
+		usage.DepthBytes = base - sp
+	}
+	if usage.DepthBytes == 0 && len(usage.Recursion) == 0 {
+		return nil
+	}
+
+	depth := fmt.Sprintf("%s across %s frames", formatBytes(usage.DepthBytes), formatCount(usage.Frames))
+	segfault := analysis.SignalInfo.SignalNumber == 11
+	beyondMax := usage.MaxStackDepthBytes > 0 && usage.DepthBytes > usage.MaxStackDepthBytes
+	switch {
+	case analysis.SignalInfo.FaultClass == faultStackOverflow, segfault && atLimit:
+		usage.Exhausted = true
+		usage.Verdict = "stack exhausted: " + depth
+	case segfault && beyondMax:
+		usage.Exhausted = true
+		usage.Verdict = fmt.Sprintf("stack exhausted: %s, beyond max_stack_depth (%dkB); the recursion does not call check_stack_depth()",
+			depth, analysis.SessionInfo.MaxStackDepthKB)
+	case beyondMax:
+		usage.Verdict = fmt.Sprintf("stack depth %s exceeds max_stack_depth (%dkB); the recursion does not call check_stack_depth()",
+			depth, analysis.SessionInfo.MaxStackDepthKB)
+	case usage.MaxStackDepthBytes > 0:
+		usage.Verdict = fmt.Sprintf("stack depth %s, %d%% of max_stack_depth (%dkB)",
+			depth, usage.DepthBytes*100/usage.MaxStackDepthBytes, analysis.SessionInfo.MaxStackDepthKB)
+	case usage.DepthBytes > 0:
+		usage.Verdict = "stack depth " + depth
+	default:
+		usage.Verdict = fmt.Sprintf("%s frames", formatCount(usage.Frames))
+	}
+	return usage
+}
+
+// stackCannotGrow reports whether a stack mapping is at its limit. The low end of the main thread's
+// [stack] mapping is only the deepest point the stack has reached so far, so it counts as a limit
+// only once the mapping is as large as the stack rlimit; a thread stack ends at its guard page.
+// Parameters:
+// - maps: The memory map.
+// - idx: Index of the mapping holding the stack pointer.
+// Returns:
+// - True if the stack cannot extend below the mapping.
+func stackCannotGrow(maps []MemoryMapping, idx int) bool {
+	start, end := regionBounds(maps[idx])
+	if end-start+stackLimitSlop >= defaultStackRlimit {
+		return true
+	}
+	for _, m := range maps {
+		if _, guardEnd := regionBounds(m); m.Kind == regionGuard && guardEnd == start {
+			return true
+		}
+	}
+	return false
+}
+
+// detectStackRecursion records recursion on every thread and the crashed thread's stack usage.
+// It must run before the crashed thread's stack trace is deduplicated.
+// Parameters:
+// - analysis: The CoreAnalysis object to update.
+func detectStackRecursion(analysis *CoreAnalysis) {
+	for i := range analysis.Threads {
+		analysis.Threads[i].Recursion = detectRecursion(analysis.Threads[i].Backtrace)
+	}
+	analysis.StackUsage = analyzeStackUsage(analysis)
+}
+
+// collapseRecursion yields the frames of a backtrace to print, replacing all but the first
+// iteration of each recursion cycle with a single summary line.
+// Parameters:
+// - thread: The thread whose backtrace is printed.
+// - frame: Called for each frame that is printed.
+// - collapsed: Called with the cycle in place of its repeated frames.
+func collapseRecursion(thread ThreadInfo, frame func(StackFrame), collapsed func(RecursionCycle)) {
+	next := 0
+	for i := 0; i < len(thread.Backtrace); i++ {
+		if next < len(thread.Recursion) && i == thread.Recursion[next].FirstFrame+len(thread.Recursion[next].Functions) {
+			collapsed(thread.Recursion[next])
+			i = thread.Recursion[next].LastFrame
+			next++
+			continue
+		}
+		frame(thread.Backtrace[i])
+	}
+}
@@ -0,0 +1,164 @@
+// File: cmd/core_recursion_test.go
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// recursiveBacktrace builds a backtrace of a crash inside deep expression evaluation:
+// two leaf frames, `repeats` iterations of ExecInterpExpr → ExecEvalExpr, then the executor entry.
+func recursiveBacktrace(repeats int) []StackFrame {
+	frames := []StackFrame{{Function: "pg_detoast_datum"}, {Function: "texteq"}}
+	for i := 0; i < repeats; i++ {
+		frames = append(frames, StackFrame{Function: "ExecEvalExpr"}, StackFrame{Function: "ExecInterpExpr"})
+	}
+	frames = append(frames, StackFrame{Function: "ExecScan"}, StackFrame{Function: "PostgresMain"})
+	for i := range frames {
+		frames[i].FrameNum = fmt.Sprint(i)
+	}
+	return frames
+}
+
+func TestDetectRecursion(t *testing.T) {
+	cycles := detectRecursion(recursiveBacktrace(4812))
+	if len(cycles) != 1 {
+		t.Fatalf("cycles = %+v, want 1", cycles)
+	}
+	c := cycles[0]
+	if c.Repeats != 4812 || c.FirstFrame != 2 || c.LastFrame != 2+2*4812-1 {
+		t.Errorf("cycle = %+v", c)
+	}
+	if want := "ExecInterpExpr → ExecEvalExpr repeated 4,812 times"; c.Summary != want {
+		t.Errorf("Summary = %q, want %q", c.Summary, want)
+	}
+
+	// Ordinary plan nesting is not recursion
+	if cycles := detectRecursion(recursiveBacktrace(3)); len(cycles) != 0 {
+		t.Errorf("cycles = %+v, want none below the threshold", cycles)
+	}
+
+	// Direct self-recursion
+	var frames []StackFrame
+	for i := 0; i < 50; i++ {
+		frames = append(frames, StackFrame{Function: "expression_tree_walker"})
+	}
+	if cycles := detectRecursion(frames); len(cycles) != 1 || len(cycles[0].Functions) != 1 || cycles[0].Repeats != 50 {
+		t.Errorf("self-recursion cycles = %+v", cycles)
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	for n, want := range map[int]string{0: "0", 999: "999", 4812: "4,812", 1234567: "1,234,567", -1000: "-1,000"} {
+		if got := formatCount(n); got != want {
+			t.Errorf("formatCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestAnalyzeStackUsage(t *testing.T) {
+	stack := []MemoryMapping{{Start: "0x7ffd00000000", End: "0x7ffd00800000", Kind: regionStack}}
+	session := buildSessionInfo(map[string]string{"max_stack_depth": "2048", "stack_base": "140724611837952"})
+	if session.MaxStackDepthKB != 2048 || session.StackBase != "0x7ffd007ff000" {
+		t.Fatalf("session = %+v", session)
+	}
+
+	tests := []struct {
+		name      string
+		sp        string
+		signal    SignalInfo
+		exhausted bool
+		verdict   string
+	}{
+		{"within max_stack_depth", "0x7ffd007f0000", SignalInfo{SignalNumber: 6}, false, "60 KiB across 44 frames, 2% of max_stack_depth"},
+		{"beyond max_stack_depth", "0x7ffd00400000", SignalInfo{SignalNumber: 6}, false, "exceeds max_stack_depth (2048kB)"},
+		{"fault below the stack", "0x7ffd00000100", SignalInfo{SignalNumber: 11, FaultClass: faultStackOverflow}, true, "stack exhausted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis := CoreAnalysis{
+				Threads:     []ThreadInfo{{IsCrashed: true, Backtrace: recursiveBacktrace(20)}},
+				Registers:   map[string]string{"rsp": tt.sp},
+				SignalInfo:  tt.signal,
+				MemoryMap:   stack,
+				SessionInfo: session,
+			}
+			detectStackRecursion(&analysis)
+			usage := analysis.StackUsage
+			if usage == nil {
+				t.Fatal("StackUsage = nil")
+			}
+			if usage.Exhausted != tt.exhausted || !strings.Contains(usage.Verdict, tt.verdict) {
+				t.Errorf("usage = %+v, want exhausted=%v, verdict containing %q", usage, tt.exhausted, tt.verdict)
+			}
+			if len(usage.Recursion) != 1 || usage.Frames != 44 {
+				t.Errorf("usage = %+v, want one cycle over 44 frames", usage)
+			}
+		})
+	}
+
+	// The low end of a stack mapping is a limit only when the stack cannot grow past it
+	grown := []MemoryMapping{{Start: "0x7ffd00700000", End: "0x7ffd00800000", Kind: regionStack}}
+	guarded := append([]MemoryMapping{{Start: "0x7ffd006ff000", End: "0x7ffd00700000", Kind: regionGuard}}, grown...)
+	deep := []MemoryMapping{{Start: "0x7ffd00400000", End: "0x7ffd00800000", Kind: regionStack}}
+	segv := SignalInfo{SignalNumber: 11}
+	limits := []struct {
+		name      string
+		sp        string
+		maps      []MemoryMapping
+		exhausted bool
+		verdict   string
+	}{
+		{"segfault at the deepest point the main stack reached", "0x7ffd00700100", grown, false, "of max_stack_depth"},
+		{"segfault at the end of a thread stack", "0x7ffd00700100", guarded, true, "stack exhausted"},
+		{"segfault beyond max_stack_depth", "0x7ffd00400100", deep, true, "beyond max_stack_depth (2048kB)"},
+	}
+	for _, tt := range limits {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis := CoreAnalysis{
+				Threads:     []ThreadInfo{{IsCrashed: true, Backtrace: recursiveBacktrace(20)}},
+				Registers:   map[string]string{"rsp": tt.sp},
+				SignalInfo:  segv,
+				MemoryMap:   tt.maps,
+				SessionInfo: session,
+			}
+			usage := analyzeStackUsage(&analysis)
+			if usage == nil || usage.Exhausted != tt.exhausted || !strings.Contains(usage.Verdict, tt.verdict) {
+				t.Errorf("usage = %+v, want exhausted=%v, verdict containing %q", usage, tt.exhausted, tt.verdict)
+			}
+		})
+	}
+
+	// aarch64 names the stack pointer sp
+	arm := CoreAnalysis{
+		Threads:     []ThreadInfo{{IsCrashed: true, Backtrace: recursiveBacktrace(20)}},
+		Registers:   map[string]string{"sp": "0x7ffd00400000", "pc": "0x555555555000"},
+		SignalInfo:  SignalInfo{SignalNumber: 6},
+		MemoryMap:   stack,
+		SessionInfo: session,
+	}
+	if usage := analyzeStackUsage(&arm); usage == nil || !strings.Contains(usage.Verdict, "exceeds max_stack_depth (2048kB)") {
+		t.Errorf("aarch64 usage = %+v, want the depth measured from sp", usage)
+	}
+
+	if usage := analyzeStackUsage(&CoreAnalysis{}); usage != nil {
+		t.Errorf("analyzeStackUsage(empty) = %+v, want nil", usage)
+	}
+}
+
+func TestPrintThreadCollapsesRecursion(t *testing.T) {
+	thread := ThreadInfo{ThreadID: "1", IsCrashed: true, Backtrace: recursiveBacktrace(100)}
+	thread.Recursion = detectRecursion(thread.Backtrace)
+
+	output := captureOutput(func() { printThread(thread, true) })
+	if n := strings.Count(output, "in ExecEvalExpr"); n != 1 {
+		t.Errorf("ExecEvalExpr printed %d times, want 1:\n%s", n, output)
+	}
+	if !strings.Contains(output, "... ExecInterpExpr → ExecEvalExpr repeated 100 times (frames 2-201) ...") {
+		t.Errorf("missing recursion summary:\n%s", output)
+	}
+	if !strings.Contains(output, "in PostgresMain") {
+		t.Errorf("frames after the cycle are missing:\n%s", output)
+	}
+}
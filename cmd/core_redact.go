@@ -0,0 +1,576 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_redact.go
+// Purpose: Implements --redact, which pseudonymizes client addresses, hostnames, user and database
+// names, query text and string literals before an analysis is output, so that it can be shared.
+// Each value is replaced by a stable keyed hash (e.g. "user-1a2b3c4d"), so the same user or client
+// gets the same pseudonym in every field and every core. The policy is a built-in preset (basic,
+// strict) or a YAML file extending one, and every change is listed in the analysis's redaction report.
+// Without a configured salt, a random per-site salt is generated once and kept in ~/.cbtoolbox, so
+// pseudonyms cannot be reversed by hashing likely values.
+// Dependencies: Uses crypto/hmac for pseudonyms and gopkg.in/yaml.v2 for policy files.
+
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Built-in redaction presets.
+const (
+	redactPresetBasic  = "basic"
+	redactPresetStrict = "strict"
+)
+
+// How --redact treats query text.
+const (
+	redactQueryKeep     = "keep"     // Only addresses, names and patterns inside the query are replaced
+	redactQueryLiterals = "literals" // String literals are replaced; the query's structure is kept
+	redactQueryFull     = "full"     // The whole query is replaced
+)
+
+// Redaction categories; each pseudonym starts with the category's prefix.
+const (
+	redactClientAddress = "client_address"
+	redactHostname      = "hostname"
+	redactUser          = "user"
+	redactDatabase      = "database"
+	redactQuery         = "query"
+	redactLiteral       = "literal"
+)
+
+// redactSaltEnv supplies the pseudonym key when the policy does not.
+const redactSaltEnv = "CBTOOLBOX_REDACT_SALT"
+
+// redactSaltFile holds the generated per-site salt, under ~/.cbtoolbox.
+const redactSaltFile = "redact_salt"
+
+var redactPrefixes = map[string]string{
+	redactClientAddress: "ip",
+	redactHostname:      "host",
+	redactUser:          "user",
+	redactDatabase:      "db",
+	redactQuery:         "query",
+	redactLiteral:       "lit",
+}
+
+// Default names that identify nothing and are left alone.
+var redactIgnoredValues = map[string]bool{"": true, "N/A": true, "postgres": true, "template0": true, "template1": true, "[local]": true}
+
+var (
+	redactFlag string           // Preset name or policy file
+	redaction  *redactionPolicy // Active policy; nil when --redact is not set
+)
+
+var (
+	ipv4Pattern       = regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}\b`)
+	sqlLiteralPattern = regexp.MustCompile(`'(?:[^']|'')*'`)
+	gdbStringPattern  = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+	titlePattern      = regexp.MustCompile(`postgres:\s*\d+,\s*(\S+)\s+(\S+)\s+(\S+?)\(\d+\)`)
+
+	// Quoted values in error messages: "relation" names and 'literal' values
+	errorLiteralPattern = regexp.MustCompile(gdbStringPattern.String() + `|` + sqlLiteralPattern.String())
+)
+
+func init() {
+	coreCmd.PersistentFlags().StringVar(&redactFlag, "redact", "", "Pseudonymize shareable output: basic, strict or a YAML redaction policy file")
+}
+
+// redactionPolicy is a resolved redaction policy.
+type redactionPolicy struct {
+	Name            string
+	Salt            string
+	ClientAddresses bool
+	Hostnames       bool
+	Users           bool
+	Databases       bool
+	Queries         string
+	FrameStrings    bool // String literals in frame arguments and locals
+	ErrorMessages   bool // Quoted values in abort summaries and error messages
+	Patterns        []redactionPattern
+}
+
+// redactionPattern is a site-specific regular expression whose matches are pseudonymized.
+type redactionPattern struct {
+	Name  string `yaml:"name"`
+	Regex string `yaml:"regex"`
+
+	re *regexp.Regexp
+}
+
+// redactionPolicyFile is the layout of a policy file; unset fields keep the preset's value.
+type redactionPolicyFile struct {
+	Extends         string             `yaml:"extends"`
+	Salt            string             `yaml:"salt"`
+	ClientAddresses *bool              `yaml:"client_addresses"`
+	Hostnames       *bool              `yaml:"hostnames"`
+	Users           *bool              `yaml:"users"`
+	Databases       *bool              `yaml:"databases"`
+	Queries         string             `yaml:"queries"`
+	FrameStrings    *bool              `yaml:"frame_strings"`
+	ErrorMessages   *bool              `yaml:"error_messages"`
+	Patterns        []redactionPattern `yaml:"patterns"`
+}
+
+// redactionPreset returns a built-in policy.
+// Parameters:
+// - name: basic or strict.
+// Returns:
+// - The policy, or nil if there is no such preset.
+func redactionPreset(name string) *redactionPolicy {
+	switch name {
+	case redactPresetBasic:
+		return &redactionPolicy{Name: name, ClientAddresses: true, Hostnames: true, Users: true, Databases: true,
+			Queries: redactQueryLiterals, FrameStrings: true}
+	case redactPresetStrict:
+		return &redactionPolicy{Name: name, ClientAddresses: true, Hostnames: true, Users: true, Databases: true,
+			Queries: redactQueryFull, FrameStrings: true, ErrorMessages: true}
+	}
+	return nil
+}
+
+// loadRedactionPolicy resolves --redact into the active policy.
+// Returns:
+// - An error if --redact names neither a preset nor a valid policy file.
+func loadRedactionPolicy() error {
+	redaction = nil
+	if redactFlag == "" {
+		return nil
+	}
+	policy := redactionPreset(redactFlag)
+	if policy == nil {
+		var err error
+		if policy, err = readRedactionPolicy(redactFlag); err != nil {
+			return err
+		}
+	}
+	if policy.Salt == "" {
+		policy.Salt = os.Getenv(redactSaltEnv)
+	}
+	if policy.Salt == "" {
+		// An empty key makes every pseudonym a plain hash of a short value, reversible by brute force
+		salt, err := siteRedactionSalt()
+		if err != nil {
+			return fmt.Errorf("--redact needs a salt: set salt in the policy or %s (%w)", redactSaltEnv, err)
+		}
+		policy.Salt = salt
+	}
+	redaction = policy
+	return nil
+}
+
+// siteRedactionSalt returns the per-site salt, generating it on first use.
+// Returns:
+// - The salt stored in ~/.cbtoolbox/redact_salt.
+// - An error if the salt can be neither read nor created.
+func siteRedactionSalt() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(home, ".cbtoolbox", redactSaltFile)
+	if data, err := os.ReadFile(path); err == nil {
+		if salt := strings.TrimSpace(string(data)); salt != "" {
+			return salt, nil
+		}
+		return "", fmt.Errorf("%s is empty", path)
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	salt := hex.EncodeToString(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		// Another run created it first; use that one so pseudonyms stay consistent
+		return siteRedactionSalt()
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(salt + "\n"); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return salt, nil
+}
+
+// readRedactionPolicy reads a YAML policy file.
+// Parameters:
+// - path: The policy file.
+// Returns:
+// - The policy: the preset it extends (basic by default) with the file's settings applied.
+// - An error if the file cannot be read or is invalid.
+func readRedactionPolicy(path string) (*redactionPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redaction policy: %s is not a preset (basic, strict) or a readable file: %w", path, err)
+	}
+	var file redactionPolicyFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction policy %s: %w", path, err)
+	}
+
+	base := file.Extends
+	if base == "" {
+		base = redactPresetBasic
+	}
+	policy := redactionPreset(base)
+	if policy == nil {
+		return nil, fmt.Errorf("redaction policy %s extends unknown preset %q", path, base)
+	}
+	policy.Name = path
+	policy.Salt = file.Salt
+	for _, field := range []struct {
+		value  *bool
+		target *bool
+	}{
+		{file.ClientAddresses, &policy.ClientAddresses},
+		{file.Hostnames, &policy.Hostnames},
+		{file.Users, &policy.Users},
+		{file.Databases, &policy.Databases},
+		{file.FrameStrings, &policy.FrameStrings},
+		{file.ErrorMessages, &policy.ErrorMessages},
+	} {
+		if field.value != nil {
+			*field.target = *field.value
+		}
+	}
+	switch file.Queries {
+	case "":
+	case redactQueryKeep, redactQueryLiterals, redactQueryFull:
+		policy.Queries = file.Queries
+	default:
+		return nil, fmt.Errorf("redaction policy %s: invalid queries setting %q. Valid options are keep, literals or full", path, file.Queries)
+	}
+	for _, p := range file.Patterns {
+		if p.Name == "" {
+			return nil, fmt.Errorf("redaction policy %s: pattern %q has no name", path, p.Regex)
+		}
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("redaction policy %s: bad pattern %s: %w", path, p.Name, err)
+		}
+		p.re = re
+		policy.Patterns = append(policy.Patterns, p)
+	}
+	return policy, nil
+}
+
+// pseudonym returns the stable replacement of a value.
+// Parameters:
+// - category: The redaction category (or custom pattern name), which sets the prefix.
+// - value: The value to replace.
+// Returns:
+// - A pseudonym such as "user-1a2b3c4d"; equal values always map to the same pseudonym.
+func (p *redactionPolicy) pseudonym(category, value string) string {
+	mac := hmac.New(sha256.New, []byte(p.Salt))
+	mac.Write([]byte(value))
+	prefix, ok := redactPrefixes[category]
+	if !ok {
+		prefix = category
+	}
+	return prefix + "-" + hex.EncodeToString(mac.Sum(nil))[:8]
+}
+
+// redactor pseudonymizes one analysis and records what it changed.
+type redactor struct {
+	policy  *redactionPolicy
+	known   []redactedValue
+	report  *RedactionReport
+	changes map[RedactionChange]bool
+}
+
+// redactedValue is a sensitive value found in the analysis, replaced wherever it occurs.
+type redactedValue struct {
+	category string
+	re       *regexp.Regexp
+}
+
+// addKnown registers a sensitive value; values are matched as whole words.
+func (r *redactor) addKnown(category, value string) {
+	value = strings.TrimSpace(value)
+	if redactIgnoredValues[value] || len(value) < 2 {
+		return
+	}
+	for _, k := range r.known {
+		if k.re.String() == redactWordPattern(value) {
+			return
+		}
+	}
+	r.known = append(r.known, redactedValue{category, regexp.MustCompile(redactWordPattern(value))})
+}
+
+// redactWordPattern matches a value not embedded in a longer name, path or address.
+func redactWordPattern(value string) string {
+	return `(^|[^\w.@/-])(` + regexp.QuoteMeta(value) + `)($|[^\w@/-])`
+}
+
+// replace pseudonymizes a value and records the change.
+func (r *redactor) replace(field, category, value string) string {
+	replacement := r.policy.pseudonym(category, value)
+	change := RedactionChange{Field: field, Category: category, Replacement: replacement}
+	if !r.changes[change] {
+		r.changes[change] = true
+		r.report.Changes = append(r.report.Changes, change)
+	}
+	return replacement
+}
+
+// text redacts a free-text field.
+// Parameters:
+// - field: The field's path in the analysis, for the report.
+// - s: The field's value.
+// - literals: The pattern of quoted literals to replace, or nil to keep them.
+// Returns:
+// - The redacted text.
+func (r *redactor) text(field, s string, literals *regexp.Regexp) string {
+	// Quoted literals go first so that each is replaced as a whole
+	if literals != nil {
+		s = literals.ReplaceAllStringFunc(s, func(m string) string {
+			quote := m[:1]
+			inner := m[1 : len(m)-1]
+			if inner == "" || strings.HasPrefix(inner, redactPrefixes[redactLiteral]+"-") {
+				return m
+			}
+			return quote + r.replace(field, redactLiteral, inner) + quote
+		})
+	}
+	for _, p := range r.policy.Patterns {
+		s = p.re.ReplaceAllStringFunc(s, func(m string) string { return r.replace(field, p.Name, m) })
+	}
+	if r.policy.ClientAddresses {
+		s = ipv4Pattern.ReplaceAllStringFunc(s, func(m string) string { return r.replace(field, redactClientAddress, m) })
+	}
+	for _, k := range r.known {
+		s = k.re.ReplaceAllStringFunc(s, func(m string) string {
+			parts := k.re.FindStringSubmatch(m)
+			return parts[1] + r.replace(field, k.category, parts[2]) + parts[3]
+		})
+	}
+	return s
+}
+
+// value redacts a field that holds only a sensitive value.
+func (r *redactor) value(field, category string, enabled bool, s string) string {
+	if !enabled || redactIgnoredValues[s] {
+		return r.text(field, s, nil)
+	}
+	return r.replace(field, category, s)
+}
+
+// frames redacts the arguments and locals of a backtrace.
+func (r *redactor) frames(field string, frames []StackFrame) {
+	var literals *regexp.Regexp
+	if r.policy.FrameStrings {
+		literals = gdbStringPattern
+	}
+	for i := range frames {
+		f := &frames[i]
+		path := fmt.Sprintf("%s[%d]", field, i)
+		f.Arguments = r.text(path+".args", f.Arguments, literals)
+		if len(f.Locals) > 0 {
+			locals := make(map[string]string, len(f.Locals))
+			for name, v := range f.Locals {
+				locals[name] = r.text(path+".locals."+name, v, literals)
+			}
+			f.Locals = locals
+		}
+	}
+}
+
+// query redacts query text according to the policy's queries setting.
+func (r *redactor) query(field, s string) string {
+	switch {
+	case s == "":
+		return s
+	case r.policy.Queries == redactQueryFull:
+		return r.replace(field, redactQuery, s)
+	case r.policy.Queries == redactQueryLiterals:
+		return r.text(field, s, sqlLiteralPattern)
+	default:
+		return r.text(field, s, nil)
+	}
+}
+
+// memoryContexts returns a copy of a memory-context tree with every ident redacted as a query.
+func (r *redactor) memoryContexts(field string, node *MemoryContextNode) *MemoryContextNode {
+	if node == nil {
+		return nil
+	}
+	redacted := *node
+	redacted.Ident = r.query(field+".ident", node.Ident)
+	redacted.Children = make([]*MemoryContextNode, len(node.Children))
+	for i, child := range node.Children {
+		redacted.Children[i] = r.memoryContexts(fmt.Sprintf("%s.children[%d]", field, i), child)
+	}
+	if node.Children == nil {
+		redacted.Children = nil
+	}
+	return &redacted
+}
+
+// redactAnalysis applies the active redaction policy to an analysis and attaches the redaction
+// report. Analyses that were already redacted are left alone.
+// Parameters:
+// - analysis: The analysis to redact in place.
+func redactAnalysis(analysis *CoreAnalysis) {
+	if redaction == nil || analysis.Redaction != nil {
+		return
+	}
+	r := &redactor{
+		policy:  redaction,
+		report:  &RedactionReport{Policy: redaction.Name, Changes: []RedactionChange{}},
+		changes: make(map[RedactionChange]bool),
+	}
+	p := redaction
+
+	// Collect the sensitive values first so they are replaced consistently in every field
+	session := analysis.SessionInfo
+	for _, title := range []string{analysis.BasicInfo["cmdline"], analysis.FileInfo.FileOutput} {
+		if m := titlePattern.FindStringSubmatch(title); m != nil {
+			if p.Users {
+				r.addKnown(redactUser, m[1])
+			}
+			if p.Databases {
+				r.addKnown(redactDatabase, m[2])
+			}
+			if p.ClientAddresses {
+				r.addKnown(redactClientAddress, m[3])
+			}
+		}
+	}
+	if session != nil {
+		if p.Users {
+			r.addKnown(redactUser, session.User)
+		}
+		if p.Databases {
+			r.addKnown(redactDatabase, session.Database)
+		}
+		if p.ClientAddresses {
+			r.addKnown(redactClientAddress, session.RemoteHost)
+		}
+	}
+	if p.Databases {
+		r.addKnown(redactDatabase, analysis.BasicInfo["database"])
+	}
+	if p.Hostnames {
+		if host, err := os.Hostname(); err == nil {
+			r.addKnown(redactHostname, host)
+		}
+	}
+	sort.SliceStable(r.known, func(i, j int) bool { return len(r.known[i].re.String()) > len(r.known[j].re.String()) })
+
+	analysis.FileInfo.FileOutput = r.text("file_info.file_output", analysis.FileInfo.FileOutput, nil)
+	if len(analysis.BasicInfo) > 0 {
+		info := make(map[string]string, len(analysis.BasicInfo))
+		for key, v := range analysis.BasicInfo {
+			info[key] = r.text("basic_info."+key, v, nil)
+		}
+		analysis.BasicInfo = info
+	}
+	analysis.SignalInfo.SignalDescription = r.text("signal_info.description", analysis.SignalInfo.SignalDescription, nil)
+
+	if session != nil {
+		redacted := *session
+		redacted.User = r.value("session_info.user", redactUser, p.Users, session.User)
+		redacted.Database = r.value("session_info.database", redactDatabase, p.Databases, session.Database)
+		redacted.RemoteHost = r.value("session_info.remote_host", redactClientAddress, p.ClientAddresses, session.RemoteHost)
+		redacted.Query = r.query("session_info.query", session.Query)
+		analysis.SessionInfo = &redacted
+	}
+
+	analysis.StackTrace = append([]StackFrame(nil), analysis.StackTrace...)
+	r.frames("stack_trace", analysis.StackTrace)
+	threads := make([]ThreadInfo, len(analysis.Threads))
+	for i, thread := range analysis.Threads {
+		thread.Backtrace = append([]StackFrame(nil), thread.Backtrace...)
+		r.frames(fmt.Sprintf("threads[%d].backtrace", i), thread.Backtrace)
+		threads[i] = thread
+	}
+	analysis.Threads = threads
+
+	// Memory-context idents hold query text (CachedPlanSource, portals), so they follow the query rules
+	if mc := analysis.MemoryContexts; mc != nil {
+		redacted := *mc
+		redacted.Largest = make([]MemoryContextSummary, len(mc.Largest))
+		for i, c := range mc.Largest {
+			c.Ident = r.query(fmt.Sprintf("memory_contexts.largest[%d].ident", i), c.Ident)
+			redacted.Largest[i] = c
+		}
+		redacted.Root = r.memoryContexts("memory_contexts.tree", mc.Root)
+		analysis.MemoryContexts = &redacted
+	}
+
+	if info := analysis.AbortInfo; info != nil {
+		var literals *regexp.Regexp
+		if p.ErrorMessages {
+			literals = errorLiteralPattern
+		}
+		redacted := *info
+		redacted.Summary = r.text("abort_info.summary", info.Summary, literals)
+		redacted.Errors = make([]ErrorReport, len(info.Errors))
+		for i, e := range info.Errors {
+			field := fmt.Sprintf("abort_info.errors[%d]", i)
+			e.Message = r.text(field+".message", e.Message, literals)
+			e.Detail = r.text(field+".detail", e.Detail, literals)
+			e.Hint = r.text(field+".hint", e.Hint, literals)
+			redacted.Errors[i] = e
+		}
+		analysis.AbortInfo = &redacted
+	}
+
+	sort.Slice(r.report.Changes, func(i, j int) bool {
+		a, b := r.report.Changes[i], r.report.Changes[j]
+		if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+		return a.Replacement < b.Replacement
+	})
+	analysis.Redaction = r.report
+}
+
+// pseudonymizeHostname pseudonymizes a host name when the active policy covers hostnames.
+// Parameters:
+// - host: The host name.
+// Returns:
+// - The pseudonym, or host itself when hostnames are not redacted.
+func pseudonymizeHostname(host string) string {
+	if redaction == nil || !redaction.Hostnames || host == "" {
+		return host
+	}
+	return redaction.pseudonym(redactHostname, host)
+}
@@ -0,0 +1,231 @@
+// File: cmd/core_redact_test.go
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// redactionAnalysis is a backend crash full of values that must not leave the site.
+func redactionAnalysis() CoreAnalysis {
+	title := "postgres: 5432, alice salesdb 10.1.2.3(54321) con12 seg0 cmd3 SELECT"
+	frames := []StackFrame{{
+		FrameNum:  "0",
+		Function:  "exec_simple_query",
+		Arguments: `query_string=0x55d5c0a1b2c3 "select * from payroll where ssn = '123-45-6789'"`,
+		Locals:    map[string]string{"client": `0x55d5c0a1b000 "10.1.2.3"`, "count": "3"},
+	}}
+	analysis := newAnalysis("/var/cores/core.4242", "", frames, crashedThread())
+	analysis.FileInfo = FileInfo{FileOutput: "ELF 64-bit LSB core file, x86-64, from '" + title + "'"}
+	analysis.BasicInfo = map[string]string{"cmdline": title, "client_address": "10.1.2.3", "database": "salesdb", "description": "Client 10.1.2.3 (PID 54321)"}
+	analysis.SessionInfo = &SessionInfo{User: "alice", Database: "salesdb", RemoteHost: "10.1.2.3",
+		Query: "select * from payroll where name = 'bob' and id = 7"}
+	analysis.MemoryContexts = &MemoryContextReport{
+		Largest: []MemoryContextSummary{{Path: "TopMemoryContext/CachedPlanSource", Ident: "select * from payroll where name = 'bob'"}},
+		Root: &MemoryContextNode{Name: "TopMemoryContext", Children: []*MemoryContextNode{
+			{Name: "CachedPlanSource", Ident: "select * from payroll where name = 'bob'"},
+			{Name: "PortalContext", Ident: "<unnamed>"},
+		}},
+	}
+	analysis.AbortInfo = &AbortInfo{Kind: "elog", Summary: `ERROR: relation "payroll_2026" does not exist`,
+		Errors: []ErrorReport{{Level: "ERROR", Message: `relation "payroll_2026" does not exist`}}}
+	return analysis
+}
+
+// useRedaction activates a policy for the duration of a test.
+func useRedaction(t *testing.T, policy string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	oldFlag, oldPolicy := redactFlag, redaction
+	t.Cleanup(func() { redactFlag, redaction = oldFlag, oldPolicy })
+	redactFlag = policy
+	if err := loadRedactionPolicy(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// redactedJSON redacts an analysis and returns it serialized.
+func redactedJSON(t *testing.T, analysis *CoreAnalysis) string {
+	t.Helper()
+	redactAnalysis(analysis)
+	data, err := json.Marshal(analysis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRedactAnalysisBasic(t *testing.T) {
+	useRedaction(t, redactPresetBasic)
+	original := redactionAnalysis()
+	analysis := original
+	out := redactedJSON(t, &analysis)
+
+	for _, secret := range []string{"alice", "salesdb", "10.1.2.3", "'bob'", "123-45-6789"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("redacted analysis still contains %q", secret)
+		}
+	}
+	// Error messages are only redacted by the strict preset
+	if !strings.Contains(analysis.AbortInfo.Summary, "payroll_2026") {
+		t.Errorf("summary = %q", analysis.AbortInfo.Summary)
+	}
+
+	user := redaction.pseudonym(redactUser, "alice")
+	ip := redaction.pseudonym(redactClientAddress, "10.1.2.3")
+	if analysis.SessionInfo.User != user || !strings.Contains(analysis.BasicInfo["cmdline"], user) || !strings.Contains(analysis.FileInfo.FileOutput, user) {
+		t.Errorf("user not pseudonymized consistently: %q / %q", analysis.SessionInfo.User, analysis.BasicInfo["cmdline"])
+	}
+	if want := "postgres: 5432, " + user + " " + redaction.pseudonym(redactDatabase, "salesdb") + " " + ip + "(54321) con12"; !strings.HasPrefix(analysis.BasicInfo["cmdline"], want) {
+		t.Errorf("cmdline = %q, want prefix %q", analysis.BasicInfo["cmdline"], want)
+	}
+	if q := analysis.SessionInfo.Query; !strings.HasPrefix(q, "select * from payroll where name = 'lit-") || !strings.HasSuffix(q, "' and id = 7") {
+		t.Errorf("query = %q", q)
+	}
+	if got := analysis.Threads[0].Backtrace[0].Locals["client"]; got != `0x55d5c0a1b000 "`+redaction.pseudonym(redactLiteral, "10.1.2.3")+`"` {
+		t.Errorf("local = %q", got)
+	}
+	if !strings.HasPrefix(analysis.StackTrace[0].Arguments, "query_string=0x55d5c0a1b2c3 \"lit-") {
+		t.Errorf("args = %q", analysis.StackTrace[0].Arguments)
+	}
+
+	// Memory-context idents hold query text and follow the query rules
+	if ident := analysis.MemoryContexts.Root.Children[0].Ident; !strings.HasPrefix(ident, "select * from payroll where name = 'lit-") || analysis.MemoryContexts.Largest[0].Ident != ident {
+		t.Errorf("memory context idents = %q, %q", ident, analysis.MemoryContexts.Largest[0].Ident)
+	}
+	if ident := analysis.MemoryContexts.Root.Children[1].Ident; ident != "<unnamed>" {
+		t.Errorf("portal ident = %q", ident)
+	}
+
+	// The input is not modified, and the report lists every change without the original values
+	if original.MemoryContexts.Root.Children[0].Ident != "select * from payroll where name = 'bob'" {
+		t.Error("redactAnalysis modified the original memory-context tree")
+	}
+	if original.SessionInfo.User != "alice" || original.StackTrace[0].Locals["client"] == analysis.StackTrace[0].Locals["client"] {
+		t.Error("redactAnalysis modified the original analysis")
+	}
+	report := analysis.Redaction
+	if report == nil || report.Policy != redactPresetBasic {
+		t.Fatalf("report = %+v", report)
+	}
+	found := false
+	for _, c := range report.Changes {
+		if c.Field == "session_info.user" && c.Category == redactUser && c.Replacement == user {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("report does not list session_info.user: %+v", report.Changes)
+	}
+
+	// Redacting again changes nothing
+	again := analysis
+	if redactedJSON(t, &again) != out {
+		t.Error("redaction is not idempotent")
+	}
+}
+
+func TestRedactAnalysisStrict(t *testing.T) {
+	useRedaction(t, redactPresetStrict)
+	analysis := redactionAnalysis()
+	out := redactedJSON(t, &analysis)
+	if strings.Contains(out, "payroll") {
+		t.Errorf("strict redaction kept payroll:\n%s", out)
+	}
+	if q := analysis.SessionInfo.Query; !strings.HasPrefix(q, "query-") {
+		t.Errorf("query = %q", q)
+	}
+}
+
+func TestPseudonymStability(t *testing.T) {
+	policy := redactionPreset(redactPresetBasic)
+	a := policy.pseudonym(redactUser, "alice")
+	if a != policy.pseudonym(redactUser, "alice") || a == policy.pseudonym(redactUser, "bob") || !strings.HasPrefix(a, "user-") {
+		t.Errorf("pseudonym = %q", a)
+	}
+	salted := *policy
+	salted.Salt = "site secret"
+	if salted.pseudonym(redactUser, "alice") == a {
+		t.Error("salt does not change pseudonyms")
+	}
+}
+
+func TestSiteRedactionSalt(t *testing.T) {
+	t.Setenv(redactSaltEnv, "")
+	useRedaction(t, redactPresetBasic)
+	salt := redaction.Salt
+	if len(salt) != 64 {
+		t.Fatalf("generated salt = %q", salt)
+	}
+	home, _ := os.UserHomeDir()
+	info, err := os.Stat(filepath.Join(home, ".cbtoolbox", redactSaltFile))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("salt file: %v, %v", info, err)
+	}
+	// Later runs reuse it, so pseudonyms stay stable
+	if err := loadRedactionPolicy(); err != nil || redaction.Salt != salt {
+		t.Errorf("second run salt = %q, %v", redaction.Salt, err)
+	}
+	t.Setenv(redactSaltEnv, "env secret")
+	if err := loadRedactionPolicy(); err != nil || redaction.Salt != "env secret" {
+		t.Errorf("salt from environment = %q, %v", redaction.Salt, err)
+	}
+
+	// Without a salt and nowhere to keep one, --redact is refused
+	t.Setenv(redactSaltEnv, "")
+	notDir := filepath.Join(t.TempDir(), "home")
+	os.WriteFile(notDir, nil, 0644)
+	t.Setenv("HOME", notDir)
+	if err := loadRedactionPolicy(); err == nil || !strings.Contains(err.Error(), redactSaltEnv) {
+		t.Errorf("loadRedactionPolicy() error = %v", err)
+	}
+}
+
+func TestRedactionPolicyFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	path := write("policy.yaml", `extends: strict
+salt: s3cret
+queries: keep
+error_messages: false
+patterns:
+  - name: email
+    regex: '[\w.+-]+@[\w-]+\.[\w.]+'
+`)
+	useRedaction(t, path)
+	if redaction.Queries != redactQueryKeep || redaction.ErrorMessages || !redaction.Users || redaction.Salt != "s3cret" {
+		t.Fatalf("policy = %+v", redaction)
+	}
+	analysis := redactionAnalysis()
+	analysis.SessionInfo.Query = "select 1 -- from carol@example.com at 10.1.2.3"
+	redactAnalysis(&analysis)
+	if q := analysis.SessionInfo.Query; !strings.HasPrefix(q, "select 1 -- from email-") || strings.Contains(q, "10.1.2.3") {
+		t.Errorf("query = %q", q)
+	}
+
+	for name, content := range map[string]string{
+		"bad.yaml":     "queries: some\n",
+		"preset.yaml":  "extends: lenient\n",
+		"pattern.yaml": "patterns:\n  - name: x\n    regex: '('\n",
+		"field.yaml":   "hostname: true\n",
+	} {
+		redactFlag = write(name, content)
+		if err := loadRedactionPolicy(); err == nil {
+			t.Errorf("%s: loadRedactionPolicy accepted an invalid policy", name)
+		}
+	}
+	redactFlag = "lenient"
+	if err := loadRedactionPolicy(); err == nil {
+		t.Error("loadRedactionPolicy accepted an unknown preset")
+	}
+}
@@ -0,0 +1,624 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_report.go
+// Purpose: Renders core analyses and comparisons as human-readable reports for --format html and
+// --format markdown. The HTML report is a single self-contained file (inline CSS, no scripts) with
+// collapsible threads and the crashed thread highlighted; the Markdown report is laid out to be
+// pasted into a GitHub issue, with secondary details folded into <details> blocks.
+// Dependencies: Uses html/template for escaping and the library categories of core_parser_libraries.go.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// reportThread is a thread prepared for rendering, crashed thread first.
+type reportThread struct {
+	Title   string
+	Crashed bool
+	Rows    []reportFrameRow
+}
+
+// reportFrameRow is a printed backtrace line: a frame, or a collapsed recursion cycle.
+type reportFrameRow struct {
+	Frame *StackFrame
+	Cycle string
+}
+
+// reportLibraryGroup is the libraries of one category.
+type reportLibraryGroup struct {
+	Type        string
+	Description string
+	Libraries   []LibraryInfo
+}
+
+// reportThreads orders the threads for a report and collapses recursion in their backtraces.
+// Parameters:
+// - analysis: The CoreAnalysis object to render.
+// Returns:
+// - The threads, crashed thread first.
+func reportThreads(analysis CoreAnalysis) []reportThread {
+	threads := make([]ThreadInfo, len(analysis.Threads))
+	copy(threads, analysis.Threads)
+	sort.SliceStable(threads, func(i, j int) bool { return threads[i].IsCrashed && !threads[j].IsCrashed })
+
+	var result []reportThread
+	for _, thread := range threads {
+		title := "Thread " + thread.ThreadID
+		if thread.LWPID != "" {
+			title += " [LWP " + thread.LWPID + "]"
+		}
+		if thread.Name != "" {
+			title += " (" + thread.Name + ")"
+		}
+		rt := reportThread{Title: title, Crashed: thread.IsCrashed}
+		collapseRecursion(thread,
+			func(frame StackFrame) { rt.Rows = append(rt.Rows, reportFrameRow{Frame: &frame}) },
+			func(cycle RecursionCycle) {
+				rt.Rows = append(rt.Rows, reportFrameRow{Cycle: fmt.Sprintf("%s (frames %d-%d)", cycle.Summary, cycle.FirstFrame, cycle.LastFrame)})
+			})
+		result = append(result, rt)
+	}
+	return result
+}
+
+// reportLibraryGroups groups libraries in the order of libraryCategories, uncategorized last.
+// Parameters:
+// - libraries: The shared libraries of the analysis.
+// Returns:
+// - The non-empty groups.
+func reportLibraryGroups(libraries []LibraryInfo) []reportLibraryGroup {
+	byType := make(map[string][]LibraryInfo)
+	for _, lib := range libraries {
+		byType[lib.Type] = append(byType[lib.Type], lib)
+	}
+
+	var groups []reportLibraryGroup
+	for _, category := range libraryCategories {
+		if libs := byType[category.Type]; len(libs) > 0 {
+			groups = append(groups, reportLibraryGroup{category.Type, category.Description, libs})
+			delete(byType, category.Type)
+		}
+	}
+	var others []LibraryInfo
+	for _, libs := range byType {
+		others = append(others, libs...)
+	}
+	if len(others) > 0 {
+		sort.Slice(others, func(i, j int) bool { return others[i].Name < others[j].Name })
+		groups = append(groups, reportLibraryGroup{"Other", "Other Libraries", others})
+	}
+	return groups
+}
+
+// reportFrameLocation describes where a frame is: its source line, or the module it belongs to.
+func reportFrameLocation(frame StackFrame) string {
+	switch {
+	case frame.SourceFile != "" && frame.LineNumber > 0:
+		return fmt.Sprintf("%s:%d", frame.SourceFile, frame.LineNumber)
+	case frame.SourceFile != "":
+		return frame.SourceFile
+	case frame.Module != "":
+		return "from " + frame.Module
+	}
+	return ""
+}
+
+// reportFrameLine formats a frame the way gdb prints it in a backtrace.
+func reportFrameLine(frame StackFrame) string {
+	line := fmt.Sprintf("#%-3s %s", frame.FrameNum, frame.Function)
+	if frame.Location != "" {
+		line = fmt.Sprintf("#%-3s %s in %s", frame.FrameNum, frame.Location, frame.Function)
+	}
+	if frame.Arguments != "" {
+		line += " (" + frame.Arguments + ")"
+	}
+	if loc := reportFrameLocation(frame); loc != "" {
+		if !strings.HasPrefix(loc, "from ") {
+			loc = "at " + loc
+		}
+		line += " " + loc
+	}
+	return line
+}
+
+// reportRegisters lists the crashed thread's registers in printRegistersEnhanced order.
+func reportRegisters(registers map[string]string) [][2]string {
+	var result [][2]string
+	seen := make(map[string]bool)
+	for _, reg := range []string{"rax", "rbx", "rcx", "rdx", "rsi", "rdi", "rbp", "rsp",
+		"r8", "r9", "r10", "r11", "r12", "r13", "r14", "r15",
+		"rip", "eflags", "cs", "ss", "ds", "es", "fs", "gs"} {
+		if val, ok := registers[reg]; ok {
+			result = append(result, [2]string{reg, val})
+			seen[reg] = true
+		}
+	}
+	var rest []string
+	for reg := range registers {
+		if !seen[reg] {
+			rest = append(rest, reg)
+		}
+	}
+	sort.Strings(rest)
+	for _, reg := range rest {
+		result = append(result, [2]string{reg, registers[reg]})
+	}
+	return result
+}
+
+// reportTitle names the core a report is about.
+func reportTitle(analysis CoreAnalysis) string {
+	return "Core analysis: " + filepath.Base(analysis.CoreFile)
+}
+
+// reportFuncs are the helpers available to the HTML templates.
+var reportFuncs = template.FuncMap{
+	"base":        filepath.Base,
+	"location":    reportFrameLocation,
+	"registers":   reportRegisters,
+	"libraries":   reportLibraryGroups,
+	"threads":     reportThreads,
+	"title":       reportTitle,
+	"join":        strings.Join,
+	"percent":     func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
+	"missingSyms": missingSymbols,
+	"origin":      signalOriginVerdict,
+	"compression": compressionSummary,
+}
+
+// missingSymbols returns the modules without debug symbols.
+func missingSymbols(modules []ModuleSymbols) []ModuleSymbols {
+	var missing []ModuleSymbols
+	for _, m := range modules {
+		if !m.SymbolsFound {
+			missing = append(missing, m)
+		}
+	}
+	return missing
+}
+
+// reportCSS styles the HTML reports; it is inlined so a report is a single file.
+const reportCSS = `
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+h1 { font-size: 1.5em; } h2 { font-size: 1.2em; border-bottom: 1px solid #d0d7de; padding-bottom: .2em; }
+table { border-collapse: collapse; margin: .5em 0; } th, td { text-align: left; padding: .2em .8em; border: 1px solid #d0d7de; vertical-align: top; }
+th { background: #f6f8fa; } code, pre, .mono { font-family: SFMono-Regular, Consolas, monospace; font-size: .9em; }
+.abort { background: #ffebe9; border: 1px solid #ff8182; padding: .6em 1em; font-weight: bold; }
+details { margin: .4em 0; } summary { cursor: pointer; font-weight: 600; }
+details.crashed { border-left: 4px solid #cf222e; padding-left: .6em; } details.crashed > summary { color: #cf222e; }
+.cycle { font-style: italic; color: #9a6700; } pre.source { background: #f6f8fa; padding: .4em; margin: .2em 0; }
+.muted { color: #656d76; }
+`
+
+// analysisHTMLTemplate renders a single core analysis.
+var analysisHTMLTemplate = template.Must(template.New("analysis").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{title .Analysis}}</title>
+<style>{{.CSS}}</style>
+</head>
+<body>
+{{with .Analysis}}
+<h1>{{title .}}</h1>
+<table>
+<tr><th>Core file</th><td class="mono">{{.CoreFile}}</td></tr>
+{{with compression .FileInfo}}<tr><th>Compression</th><td>{{.}}</td></tr>{{end}}
+{{with index .BasicInfo "description"}}<tr><th>Process</th><td>{{.}}</td></tr>{{end}}
+<tr><th>Analyzed</th><td>{{.Timestamp}}</td></tr>
+<tr><th>PostgreSQL</th><td>{{.PostgresInfo.Version}}</td></tr>
+<tr><th>Cloudberry</th><td>{{.PostgresInfo.GPVersion}}</td></tr>
+{{with .PostgresInfo.BinaryPath}}<tr><th>Binary</th><td class="mono">{{.}}</td></tr>{{end}}
+{{with .AnalysisBackend}}<tr><th>Backend</th><td>{{.}}</td></tr>{{end}}
+{{with .Fingerprint}}<tr><th>Fingerprint</th><td class="mono">{{.}}</td></tr>{{end}}
+{{with .Redaction}}<tr><th>Redaction</th><td>{{len .Changes}} value(s) pseudonymized (policy {{.Policy}})</td></tr>{{end}}
+</table>
+
+{{with .AbortInfo}}<p class="abort">{{.Summary}}</p>
+{{if .Errors}}<table><tr><th>Level</th><th>SQLSTATE</th><th>Message</th><th>Location</th></tr>
+{{range .Errors}}<tr><td>{{.Level}}</td><td>{{.SQLState}}</td><td>{{.Message}}{{with .Detail}}<br><span class="muted">DETAIL: {{.}}</span>{{end}}{{with .Hint}}<br><span class="muted">HINT: {{.}}</span>{{end}}</td><td class="mono">{{.Function}} {{.File}}:{{.Line}}</td></tr>
+{{end}}</table>{{end}}{{end}}
+{{with .OrcaException}}<p class="abort">{{.Summary}}</p>{{end}}
+
+{{with .KnownIssues}}<h2>Known issues</h2>
+<table><tr><th>Issue</th><th>Title</th><th>Workaround</th><th>Fixed in</th></tr>
+{{range .}}<tr><td>{{.ID}}</td><td>{{.Title}}</td><td>{{.Workaround}}</td><td>{{.FixedIn}}</td></tr>
+{{end}}</table>{{end}}
+
+<h2>Signal</h2>
+<table>
+<tr><th>Signal</th><td>{{.SignalInfo.SignalName}} ({{.SignalInfo.SignalNumber}}), code {{.SignalInfo.SignalCode}}</td></tr>
+<tr><th>Description</th><td>{{.SignalInfo.SignalDescription}}</td></tr>
+{{with .SignalInfo.FaultAddress}}<tr><th>Fault address</th><td class="mono">{{.}}</td></tr>{{end}}
+{{with .SignalInfo.FaultClass}}<tr><th>Fault class</th><td>{{.}}</td></tr>{{end}}
+{{with .SignalInfo.FaultVerdict}}<tr><th>Fault</th><td>{{.}}</td></tr>{{end}}
+{{with origin .SignalInfo}}<tr><th>Origin</th><td>{{.}}{{with $.SignalInfo.SignalCodeName}} ({{.}}){{end}}</td></tr>{{else}}{{if .SignalInfo.SenderPID}}<tr><th>Sent by PID</th><td>{{.SignalInfo.SenderPID}}</td></tr>{{end}}{{end}}
+{{with .StackUsage}}<tr><th>Stack</th><td>{{.Verdict}}</td></tr>
+{{range .Recursion}}<tr><th>Recursion</th><td>{{.Summary}}</td></tr>{{end}}{{end}}
+</table>
+
+{{with .SessionInfo}}<h2>Session</h2>
+<table>
+{{with .Role}}<tr><th>Role</th><td>{{.}}</td></tr>{{end}}
+{{if .PID}}<tr><th>PID</th><td>{{.PID}}</td></tr>{{end}}
+{{if .SessionID}}<tr><th>Session</th><td>con{{.SessionID}}, command {{.CommandCount}}</td></tr>{{end}}
+{{with .SegmentID}}<tr><th>Segment</th><td>{{.}}</td></tr>{{end}}
+{{with .SliceID}}<tr><th>Slice</th><td>{{.}}</td></tr>{{end}}
+{{with .Database}}<tr><th>Database</th><td>{{.}}</td></tr>{{end}}
+{{with .User}}<tr><th>User</th><td>{{.}}</td></tr>{{end}}
+{{with .RemoteHost}}<tr><th>Client</th><td>{{.}}</td></tr>{{end}}
+{{with .Query}}<tr><th>Query</th><td><pre>{{.}}</pre></td></tr>{{end}}
+</table>{{end}}
+
+<h2>Threads ({{len .Threads}})</h2>
+{{range threads .}}<details class="thread{{if .Crashed}} crashed{{end}}"{{if .Crashed}} open{{end}}>
+<summary>{{.Title}}{{if .Crashed}} — crashed{{end}}</summary>
+<table><tr><th>#</th><th>Function</th><th>Arguments</th><th>Location</th></tr>
+{{range .Rows}}{{if .Cycle}}<tr><td></td><td colspan="3" class="cycle">… {{.Cycle}} …</td></tr>
+{{else}}{{with .Frame}}<tr><td>{{.FrameNum}}</td><td class="mono">{{.Function}}</td><td class="mono">{{.Arguments}}</td><td class="mono">{{location .}}</td></tr>
+{{with .Source}}<tr><td></td><td colspan="3"><pre class="source">{{range .Lines}}{{if .Current}}=>{{else}}  {{end}} {{printf "%5d" .Number}}  {{.Text}}
+{{end}}</pre></td></tr>{{end}}{{end}}{{end}}{{end}}</table>
+</details>
+{{end}}
+
+{{if .Registers}}<details><summary>Registers</summary>
+<table>{{range registers .Registers}}<tr><th class="mono">{{index . 0}}</th><td class="mono">{{index . 1}}</td></tr>{{end}}</table>
+</details>{{end}}
+
+{{with .MemoryContexts}}<h2>Memory contexts</h2>
+<p>{{.ContextCount}} contexts, {{.TotalBytes}} bytes in total{{if .Truncated}} (walk incomplete){{end}}.</p>
+{{if .Largest}}<table><tr><th>Bytes</th><th>Context</th></tr>
+{{range .Largest}}<tr><td>{{.TotalBytes}}</td><td class="mono">{{.Path}}</td></tr>{{end}}</table>{{end}}{{end}}
+
+{{with missingSyms .Modules}}<h2>Missing debug symbols</h2>
+<table><tr><th>Module</th><th>Build-id</th></tr>
+{{range .}}<tr><td class="mono">{{.Path}}</td><td class="mono">{{.BuildID}}</td></tr>{{end}}</table>{{end}}
+
+<h2>Shared libraries ({{len .Libraries}})</h2>
+{{range libraries .Libraries}}<details{{if or (eq .Type "Core") (eq .Type "Extension")}} open{{end}}>
+<summary>{{.Description}} ({{len .Libraries}})</summary>
+<table><tr><th>Library</th><th>Version</th><th>Address range</th><th>Loaded</th></tr>
+{{range .Libraries}}<tr><td class="mono" title="{{.Name}}">{{base .Name}}</td><td>{{.Version}}</td><td class="mono">{{.StartAddr}}-{{.EndAddr}}</td><td>{{if .IsLoaded}}yes{{else}}no{{end}}</td></tr>
+{{end}}</table>
+</details>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// comparisonHTMLTemplate renders a comparison of several cores.
+var comparisonHTMLTemplate = template.Must(template.New("comparison").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Core comparison</title>
+<style>{{.CSS}}</style>
+</head>
+<body>
+{{with .Comparison}}
+<h1>Core comparison: {{.TotalCores}} cores</h1>
+<p>From {{index .TimeRange "first"}} to {{index .TimeRange "last"}}.</p>
+
+<h2>Signals</h2>
+<table><tr><th>Signal</th><th>Cores</th></tr>
+{{range $signal, $count := .CommonSignals}}<tr><td>{{$signal}}</td><td>{{$count}}</td></tr>{{end}}</table>
+
+<h2>Crash patterns</h2>
+{{if .CrashPatterns}}<table><tr><th>Signal</th><th>Stack</th><th>Cores</th><th>Status</th></tr>
+{{range .CrashPatterns}}<tr><td>{{.Signal}}</td><td class="mono">{{join .StackSignature " ← "}}</td><td>{{.OccurrenceCount}}<br><span class="muted mono">{{join .AffectedCoreFiles ", "}}</span></td><td>{{.Status}}{{with .KnownIssues}}: {{join . ", "}}{{end}}</td></tr>
+{{end}}</table>{{else}}<p class="muted">No core shares its signal and top frames with another.</p>{{end}}
+
+{{if .Clusters}}<h2>Clusters ({{.ClusterMethod}})</h2>
+{{range .Clusters}}<details open>
+<summary>Cluster {{.ID}}: {{.Size}} × {{.Signal}} in {{with .Frames}}{{index . 0}}{{else}}??{{end}}</summary>
+<table>
+<tr><th>Representative</th><td class="mono">{{.Representative}}</td></tr>
+{{with .Status}}<tr><th>Status</th><td>{{.}}</td></tr>{{end}}
+{{with .KnownIssues}}<tr><th>Known issues</th><td>{{join . ", "}}</td></tr>{{end}}
+<tr><th>Stack</th><td class="mono">{{join .Frames " ← "}}</td></tr>
+{{with .VaryingFrames}}<tr><th>Varying frames</th><td class="mono">{{join . ", "}}</td></tr>{{end}}
+<tr><th>Members</th><td>{{range .Members}}<span class="mono">{{.CoreFile}}</span> ({{percent .Similarity}})<br>{{end}}</td></tr>
+</table>
+</details>
+{{end}}{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// renderAnalysisHTML renders a core analysis as a self-contained HTML page.
+// Parameters:
+// - analysis: The CoreAnalysis object to render.
+// Returns:
+// - The HTML document.
+// - An error if rendering fails.
+func renderAnalysisHTML(analysis CoreAnalysis) ([]byte, error) {
+	var buf bytes.Buffer
+	data := struct {
+		Analysis CoreAnalysis
+		CSS      template.CSS
+	}{analysis, template.CSS(reportCSS)}
+	if err := analysisHTMLTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderComparisonHTML renders a core comparison as a self-contained HTML page.
+// Parameters:
+// - comparison: The CoreComparison object to render.
+// Returns:
+// - The HTML document.
+// - An error if rendering fails.
+func renderComparisonHTML(comparison CoreComparison) ([]byte, error) {
+	var buf bytes.Buffer
+	data := struct {
+		Comparison CoreComparison
+		CSS        template.CSS
+	}{comparison, template.CSS(reportCSS)}
+	if err := comparisonHTMLTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// markdownCell escapes a value for a Markdown table cell; GitHub would drop text that looks like a tag.
+func markdownCell(s string) string {
+	s = strings.NewReplacer("|", `\|`, "<", "&lt;", ">", "&gt;").Replace(s)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// writeMarkdownCodeBlock writes lines as a fenced code block, lengthening the fence if a line contains one.
+func writeMarkdownCodeBlock(b *strings.Builder, lang string, lines []string) {
+	fence := "```"
+	for _, line := range lines {
+		for strings.Contains(line, fence) {
+			fence += "`"
+		}
+	}
+	b.WriteString(fence + lang + "\n")
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+	b.WriteString(fence + "\n")
+}
+
+// markdownBacktrace formats a report thread's backtrace as text lines.
+func markdownBacktrace(thread reportThread) []string {
+	var lines []string
+	for _, row := range thread.Rows {
+		if row.Frame == nil {
+			lines = append(lines, "    ... "+row.Cycle+" ...")
+			continue
+		}
+		lines = append(lines, reportFrameLine(*row.Frame))
+		if src := row.Frame.Source; src != nil {
+			for _, l := range src.Lines {
+				marker := "  "
+				if l.Current {
+					marker = "=>"
+				}
+				lines = append(lines, fmt.Sprintf("    %s %5d  %s", marker, l.Number, l.Text))
+			}
+		}
+	}
+	return lines
+}
+
+// renderAnalysisMarkdown renders a core analysis as Markdown suitable for a GitHub issue.
+// Parameters:
+// - analysis: The CoreAnalysis object to render.
+// Returns:
+// - The Markdown document.
+func renderAnalysisMarkdown(analysis CoreAnalysis) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", reportTitle(analysis))
+	if info := analysis.AbortInfo; info != nil {
+		fmt.Fprintf(&b, "> **%s**\n\n", info.Summary)
+	}
+	if exc := analysis.OrcaException; exc != nil {
+		fmt.Fprintf(&b, "> **%s**\n\n", exc.Summary)
+	}
+	for _, issue := range analysis.KnownIssues {
+		fmt.Fprintf(&b, "> Known issue **%s**: %s", markdownCell(issue.ID), markdownCell(issue.Title))
+		if issue.FixedIn != "" {
+			fmt.Fprintf(&b, " (fixed in %s)", markdownCell(issue.FixedIn))
+		}
+		b.WriteString("\n")
+		if issue.Workaround != "" {
+			fmt.Fprintf(&b, ">\n> Workaround: %s\n", markdownCell(issue.Workaround))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("| | |\n|---|---|\n")
+	row := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "| %s | %s |\n", name, markdownCell(value))
+		}
+	}
+	row("Core file", "`"+analysis.CoreFile+"`")
+	row("Compression", compressionSummary(analysis.FileInfo))
+	row("Process", analysis.BasicInfo["description"])
+	row("PostgreSQL", analysis.PostgresInfo.Version)
+	row("Cloudberry", analysis.PostgresInfo.GPVersion)
+	sig := analysis.SignalInfo
+	row("Signal", fmt.Sprintf("%s (%d), code %d: %s", sig.SignalName, sig.SignalNumber, sig.SignalCode, sig.SignalDescription))
+	if sig.FaultAddress != "" {
+		row("Fault address", "`"+sig.FaultAddress+"`")
+	}
+	row("Fault", sig.FaultVerdict)
+	if origin := signalOriginVerdict(sig); origin != "" && sig.SignalCodeName != "" {
+		row("Origin", origin+" ("+sig.SignalCodeName+")")
+	} else {
+		row("Origin", origin)
+	}
+	if usage := analysis.StackUsage; usage != nil {
+		row("Stack", usage.Verdict)
+		for _, cycle := range usage.Recursion {
+			row("Recursion", cycle.Summary)
+		}
+	}
+	if s := analysis.SessionInfo; s != nil {
+		if s.SessionID != 0 {
+			row("Session", fmt.Sprintf("con%d, command %d", s.SessionID, s.CommandCount))
+		}
+		if s.SegmentID != nil {
+			row("Segment", fmt.Sprint(*s.SegmentID))
+		}
+		if s.Database != "" || s.User != "" {
+			row("Database / user", s.Database+" / "+s.User)
+		}
+	}
+	row("Fingerprint", analysis.Fingerprint)
+	if r := analysis.Redaction; r != nil {
+		row("Redaction", fmt.Sprintf("%d value(s) pseudonymized (policy %s)", len(r.Changes), r.Policy))
+	}
+	b.WriteString("\n")
+
+	if info := analysis.AbortInfo; info != nil && len(info.Errors) > 0 {
+		b.WriteString("### Error stack\n\n")
+		var lines []string
+		for i, e := range info.Errors {
+			level := e.Level
+			if e.SQLState != "" {
+				level += " " + e.SQLState
+			}
+			lines = append(lines, fmt.Sprintf("errordata[%d]: %s: %s", i, level, e.Message))
+			if e.Detail != "" {
+				lines = append(lines, "  DETAIL: "+e.Detail)
+			}
+			if e.Hint != "" {
+				lines = append(lines, "  HINT: "+e.Hint)
+			}
+			if e.File != "" {
+				lines = append(lines, fmt.Sprintf("  LOCATION: %s, %s:%d", e.Function, e.File, e.Line))
+			}
+		}
+		writeMarkdownCodeBlock(&b, "text", lines)
+		b.WriteString("\n")
+	}
+
+	if s := analysis.SessionInfo; s != nil && s.Query != "" {
+		b.WriteString("### Query\n\n")
+		writeMarkdownCodeBlock(&b, "sql", strings.Split(s.Query, "\n"))
+		b.WriteString("\n")
+	}
+
+	threads := reportThreads(analysis)
+	for i, thread := range threads {
+		lines := markdownBacktrace(thread)
+		if i == 0 && thread.Crashed {
+			fmt.Fprintf(&b, "### Crashed thread: %s\n\n", thread.Title)
+			writeMarkdownCodeBlock(&b, "text", lines)
+			b.WriteString("\n")
+			continue
+		}
+		fmt.Fprintf(&b, "<details>\n<summary>%s</summary>\n\n", template.HTMLEscapeString(thread.Title))
+		writeMarkdownCodeBlock(&b, "text", lines)
+		b.WriteString("\n</details>\n\n")
+	}
+
+	if len(analysis.Registers) > 0 {
+		b.WriteString("<details>\n<summary>Registers</summary>\n\n")
+		var lines []string
+		for _, reg := range reportRegisters(analysis.Registers) {
+			lines = append(lines, fmt.Sprintf("%-8s %s", reg[0]+":", reg[1]))
+		}
+		writeMarkdownCodeBlock(&b, "text", lines)
+		b.WriteString("\n</details>\n\n")
+	}
+
+	if groups := reportLibraryGroups(analysis.Libraries); len(groups) > 0 {
+		fmt.Fprintf(&b, "<details>\n<summary>Shared libraries (%d)</summary>\n\n", len(analysis.Libraries))
+		b.WriteString("| Category | Library | Version | Loaded |\n|---|---|---|---|\n")
+		for _, group := range groups {
+			for _, lib := range group.Libraries {
+				loaded := "no"
+				if lib.IsLoaded {
+					loaded = "yes"
+				}
+				fmt.Fprintf(&b, "| %s | `%s` | %s | %s |\n", group.Description, markdownCell(filepath.Base(lib.Name)), markdownCell(lib.Version), loaded)
+			}
+		}
+		b.WriteString("\n</details>\n")
+	}
+	return []byte(b.String())
+}
+
+// markdownCrashStatus formats whether a crash pattern or cluster is known, e.g. "known: CBDB-1234".
+func markdownCrashStatus(status string, issues []string) string {
+	if len(issues) == 0 {
+		return status
+	}
+	return markdownCell(status + ": " + strings.Join(issues, ", "))
+}
+
+// renderComparisonMarkdown renders a core comparison as Markdown suitable for a GitHub issue.
+// Parameters:
+// - comparison: The CoreComparison object to render.
+// Returns:
+// - The Markdown document.
+func renderComparisonMarkdown(comparison CoreComparison) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Core comparison: %d cores\n\n", comparison.TotalCores)
+	fmt.Fprintf(&b, "From %s to %s.\n\n", comparison.TimeRange["first"], comparison.TimeRange["last"])
+
+	var signals []string
+	for signal := range comparison.CommonSignals {
+		signals = append(signals, signal)
+	}
+	sort.Strings(signals)
+	b.WriteString("| Signal | Cores |\n|---|---|\n")
+	for _, signal := range signals {
+		fmt.Fprintf(&b, "| %s | %d |\n", markdownCell(signal), comparison.CommonSignals[signal])
+	}
+	b.WriteString("\n### Crash patterns\n\n")
+	if len(comparison.CrashPatterns) == 0 {
+		b.WriteString("No core shares its signal and top frames with another.\n")
+	} else {
+		b.WriteString("| Signal | Stack | Cores | Status |\n|---|---|---|---|\n")
+		for _, p := range comparison.CrashPatterns {
+			fmt.Fprintf(&b, "| %s | `%s` | %d | %s |\n", p.Signal, markdownCell(strings.Join(p.StackSignature, " ← ")), p.OccurrenceCount,
+				markdownCrashStatus(p.Status, p.KnownIssues))
+		}
+	}
+
+	if len(comparison.Clusters) > 0 {
+		fmt.Fprintf(&b, "\n### Clusters (%s)\n\n", comparison.ClusterMethod)
+		b.WriteString("| # | Cores | Signal | Representative | Varying frames | Status |\n|---|---|---|---|---|---|\n")
+		for _, c := range comparison.Clusters {
+			fmt.Fprintf(&b, "| %d | %d | %s | `%s` | %s | %s |\n", c.ID, c.Size, c.Signal,
+				markdownCell(filepath.Base(c.Representative)), markdownCell(strings.Join(c.VaryingFrames, ", ")),
+				markdownCrashStatus(c.Status, c.KnownIssues))
+		}
+		for _, c := range comparison.Clusters {
+			fmt.Fprintf(&b, "\n<details>\n<summary>Cluster %d</summary>\n\n", c.ID)
+			lines := []string{"stack: " + strings.Join(c.Frames, " ← ")}
+			for _, m := range c.Members {
+				lines = append(lines, fmt.Sprintf("%3.0f%%  %s", m.Similarity*100, m.CoreFile))
+			}
+			writeMarkdownCodeBlock(&b, "text", lines)
+			b.WriteString("\n</details>\n")
+		}
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,314 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_report_cmd.go
+// Purpose: Implements `core report`, which loads saved analyses (JSON, YAML or an NDJSON stream) and
+// renders them again in any output format, or compares them, without the original cores or gdb.
+// The passes that need only the analysis itself (recursion detection, source context, known-crash
+// rules and the crash fingerprint) are re-run, so newer thresholds, source trees and rules apply to
+// archived analyses.
+// Dependencies: Uses the output paths of core_printer.go, core_parser_output.go and core_output.go.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// coreReportCmd re-renders saved analyses
+var coreReportCmd = &cobra.Command{
+	Use:   "report <analysis_file_or_directory>...",
+	Short: "Re-render or compare saved core analyses",
+	Long: `Load analyses saved by an earlier run and render them again, without the core files
+or gdb. Files may be JSON or YAML analyses, or NDJSON streams written with --output -;
+directories are searched for core_analysis_* files.
+
+  cbtoolbox core report /var/log/postgres_cores/core_analysis_core.1234_1a2b3c4d.json --gdb-style
+  cbtoolbox core report /var/log/postgres_cores/ --format html --output-dir /tmp/reports
+  cbtoolbox core report archive/*.yaml --compare --cluster edit-distance`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCoreReport(cmd.Context(), args)
+	},
+}
+
+func init() {
+	coreCmd.AddCommand(coreReportCmd)
+	coreReportCmd.Flags().BoolVar(&compareFlag, "compare", false, "Compare the analyses and identify patterns")
+	coreReportCmd.Flags().StringVar(&clusterAlgorithm, "cluster", "", "Cluster compared cores by stack similarity: exact, prefix or edit-distance")
+	coreReportCmd.Flags().Float64Var(&clusterThreshold, "cluster-threshold", 0.7, "Minimum stack similarity (0-1] for cores to share a cluster")
+}
+
+// runCoreReport loads saved analyses and outputs them like a fresh run.
+// Parameters:
+// - ctx: Context for c++filt, which demangles the saved frames.
+// - paths: Analysis files or directories holding them.
+// Returns:
+// - An error if the flags are invalid or no analysis could be loaded.
+func runCoreReport(ctx context.Context, paths []string) error {
+	if err := validateCoreFlags(); err != nil {
+		return err
+	}
+
+	files, err := findAnalysisFiles(paths)
+	if err != nil {
+		return err
+	}
+	inputs := statFiles(files)
+
+	if streamingOutput() {
+		defer startStream()()
+	} else if !gdbStyleOutput {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	manifest := RunManifest{StartedAt: time.Now().Format(time.RFC3339), Summary: make(map[string]int)}
+	var analyses []CoreAnalysis
+	refused := 0
+	for _, file := range files {
+		loaded, err := loadAnalysisFile(file)
+		if err != nil {
+			fmt.Printf("Error loading %s: %v\n", file, err)
+			manifest.Cores = append(manifest.Cores, CoreRunResult{CoreFile: file, Status: coreStatusFailed, Error: err.Error()})
+			continue
+		}
+		for _, analysis := range loaded {
+			reanalyze(ctx, &analysis)
+			analyses = append(analyses, analysis)
+			if err := checkReportDestination(analysis, inputs); err != nil {
+				fmt.Printf("Error outputting analysis for %s: %v\n", analysis.CoreFile, err)
+				manifest.Cores = append(manifest.Cores, CoreRunResult{CoreFile: analysis.CoreFile, Status: coreStatusFailed, Error: err.Error()})
+				refused++
+				continue
+			}
+			if err := saveOrPrintAnalysis(analysis); err != nil {
+				fmt.Printf("Error outputting analysis for %s: %v\n", analysis.CoreFile, err)
+			}
+			manifest.Cores = append(manifest.Cores, CoreRunResult{CoreFile: analysis.CoreFile, Status: coreStatusOK, Backend: analysis.AnalysisBackend})
+		}
+	}
+	for _, result := range manifest.Cores {
+		manifest.Summary[result.Status]++
+	}
+	manifest.FinishedAt = time.Now().Format(time.RFC3339)
+
+	var comparison *CoreComparison
+	if compareFlag && len(analyses) > 1 {
+		result := compareCores(analyses)
+		comparison = &result
+		if !streamingOutput() {
+			if err := saveComparison(result); err != nil {
+				fmt.Printf("Error saving comparison results: %v\n", err)
+			}
+		}
+	}
+
+	if streamingOutput() {
+		if err := streamSummary(manifest, comparison); err != nil {
+			return err
+		}
+	}
+
+	if len(analyses) == 0 {
+		return fmt.Errorf("no saved analyses could be loaded")
+	}
+	if refused > 0 {
+		return fmt.Errorf("%d analyses were not written because they would overwrite the files they were loaded from; use --output-dir to write them elsewhere", refused)
+	}
+	return nil
+}
+
+// statFiles stats the analysis files a report reads, so outputs can be checked against them.
+// Parameters:
+// - files: The analysis files.
+// Returns:
+// - The file infos of the files that could be stat'ed.
+func statFiles(files []string) []os.FileInfo {
+	var infos []os.FileInfo
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// checkReportDestination refuses to save a re-rendered analysis over one of the report's inputs.
+// Analyses are saved under a name derived from the core file, so reporting a directory that is
+// also --output-dir in the same format would replace each archived analysis with the re-analyzed
+// (and possibly redacted) version, losing the original.
+// Parameters:
+// - analysis: The analysis about to be output.
+// - inputs: The file infos of the analysis files being read.
+// Returns:
+// - An error if the analysis would be saved over an input file, or nil otherwise.
+func checkReportDestination(analysis CoreAnalysis, inputs []os.FileInfo) error {
+	if streamingOutput() || gdbStyleOutput {
+		return nil
+	}
+	dest := filepath.Join(outputDir, analysisFileName(analysis.CoreFile, formatFlag))
+	info, err := os.Stat(dest)
+	if err != nil {
+		return nil
+	}
+	for _, input := range inputs {
+		if os.SameFile(info, input) {
+			return fmt.Errorf("refusing to overwrite %s, which this report reads", dest)
+		}
+	}
+	return nil
+}
+
+// findAnalysisFiles expands directories into the analysis files they hold.
+// Parameters:
+// - paths: Analysis files or directories.
+// Returns:
+// - The analysis files, directory contents sorted by name.
+// - An error if a path does not exist or a directory holds no analyses.
+func findAnalysisFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		var found []string
+		for _, ext := range []string{"json", "yaml", "yml"} {
+			matches, _ := filepath.Glob(filepath.Join(path, "core_analysis_*."+ext))
+			found = append(found, matches...)
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("no saved analyses found in %s", path)
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// loadAnalysisFile reads the analyses saved in a file. JSON and YAML files hold one analysis;
+// NDJSON streams hold one per "analysis" record.
+// Parameters:
+// - path: The analysis file.
+// Returns:
+// - The analyses in the file.
+// - An error if the file cannot be read or holds no analysis.
+func loadAnalysisFile(path string) ([]CoreAnalysis, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var analyses []CoreAnalysis
+	switch ext := strings.ToLower(filepath.Ext(path)); {
+	case ext == ".yaml" || ext == ".yml":
+		var analysis CoreAnalysis
+		if err := yaml.Unmarshal(data, &analysis); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML analysis: %w", err)
+		}
+		analyses = append(analyses, analysis)
+	case ext == ".ndjson" || ext == ".jsonl" || isStreamData(data):
+		analyses, err = parseAnalysisStream(data)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		var analysis CoreAnalysis
+		if err := json.Unmarshal(data, &analysis); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON analysis: %w", err)
+		}
+		analyses = append(analyses, analysis)
+	}
+
+	for _, analysis := range analyses {
+		if analysis.CoreFile == "" {
+			return nil, fmt.Errorf("not a core analysis: no core_file recorded")
+		}
+	}
+	if len(analyses) == 0 {
+		return nil, fmt.Errorf("no analysis records found")
+	}
+	return analyses, nil
+}
+
+// isStreamData reports whether JSON data is an NDJSON stream written with --output -.
+func isStreamData(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte(`{"type":`))
+}
+
+// parseAnalysisStream extracts the analysis records of an NDJSON stream.
+// Parameters:
+// - data: The stream contents.
+// Returns:
+// - The streamed analyses; summary records are skipped.
+// - An error if a line is not valid JSON.
+func parseAnalysisStream(data []byte) ([]CoreAnalysis, error) {
+	var analyses []CoreAnalysis
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record streamAnalysisRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("failed to parse stream record on line %d: %w", line, err)
+		}
+		if record.Type == streamRecordAnalysis {
+			analyses = append(analyses, record.CoreAnalysis)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	return analyses, nil
+}
+
+// reanalyze re-runs the analysis passes that need no core file or gdb, so that C++ demangling,
+// the current --recursion-threshold, --source-root, known-crash rules and fingerprint rules apply to saved analyses.
+// With --redact the result is redacted, so analyses archived in full can be scrubbed before sharing.
+// Parameters:
+// - ctx: Context for c++filt.
+// - analysis: The loaded analysis, updated in place.
+func reanalyze(ctx context.Context, analysis *CoreAnalysis) {
+	demangleFrames(ctx, analysis)
+	detectStackRecursion(analysis)
+	detectOrcaException(analysis)
+	if len(sourceRoots) > 0 {
+		attachSourceContext(analysis)
+	}
+	// Saved known issues are kept unless rules are available to match again
+	if len(crashRules) > 0 {
+		attachKnownIssues(analysis)
+	}
+	analysis.Fingerprint, _ = crashFingerprint(*analysis)
+	redactAnalysis(analysis)
+}
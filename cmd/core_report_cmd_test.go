@@ -0,0 +1,129 @@
+// File: cmd/core_report_cmd_test.go
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadAnalysisFile(t *testing.T) {
+	oldDir, oldFormat, oldOutput, oldStream := outputDir, formatFlag, outputFlag, streamOut
+	defer func() { outputDir, formatFlag, outputFlag, streamOut = oldDir, oldFormat, oldOutput, oldStream }()
+	outputDir = t.TempDir()
+
+	analysis := reportAnalysis()
+	for _, format := range []string{formatJSON, formatYAML} {
+		formatFlag = format
+		if err := saveAnalysis(analysis); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := loadAnalysisFile(filepath.Join(outputDir, analysisFileName(analysis.CoreFile, format)))
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(loaded) != 1 || loaded[0].CoreFile != analysis.CoreFile || loaded[0].AbortInfo.Summary != analysis.AbortInfo.Summary {
+			t.Errorf("%s: loaded %+v", format, loaded)
+		}
+		if got := len(loaded[0].Threads[1].Backtrace); got != len(analysis.Threads[1].Backtrace) {
+			t.Errorf("%s: crashed thread has %d frames, want %d", format, got, len(analysis.Threads[1].Backtrace))
+		}
+	}
+
+	// An NDJSON stream holds one analysis per record, followed by a summary
+	stream, err := os.Create(filepath.Join(outputDir, "run.out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputFlag, streamOut = outputStdout, stream
+	saveOrPrintAnalysis(CoreAnalysis{CoreFile: "/cores/core.1"})
+	saveOrPrintAnalysis(CoreAnalysis{CoreFile: "/cores/core.2"})
+	streamSummary(RunManifest{}, nil)
+	stream.Close()
+	loaded, err := loadAnalysisFile(stream.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 2 || loaded[1].CoreFile != "/cores/core.2" {
+		t.Errorf("stream loaded %+v", loaded)
+	}
+
+	notAnalysis := filepath.Join(outputDir, "other.json")
+	os.WriteFile(notAnalysis, []byte(`{"os": "linux"}`), 0644)
+	if _, err := loadAnalysisFile(notAnalysis); err == nil {
+		t.Error("loadAnalysisFile accepted a file without core_file")
+	}
+}
+
+func TestReanalyze(t *testing.T) {
+	oldThreshold := recursionThreshold
+	defer func() { recursionThreshold = oldThreshold }()
+
+	analysis := reportAnalysis()
+	analysis.StackTrace = analysis.Threads[1].Backtrace
+	recursionThreshold = 50
+	reanalyze(context.Background(), &analysis)
+	if len(analysis.Threads[1].Recursion) != 0 {
+		t.Errorf("recursion = %+v, want none above threshold 50", analysis.Threads[1].Recursion)
+	}
+	if want, _ := crashFingerprint(analysis); analysis.Fingerprint != want || want == "" {
+		t.Errorf("fingerprint = %q, want %q", analysis.Fingerprint, want)
+	}
+}
+
+func TestRunCoreReport(t *testing.T) {
+	oldDir, oldFormat, oldCompare := outputDir, formatFlag, compareFlag
+	defer func() { outputDir, formatFlag, compareFlag = oldDir, oldFormat, oldCompare }()
+	archive := t.TempDir()
+	outputDir = archive
+
+	for core, format := range map[string]string{"/cores/core.1": formatJSON, "/cores/core.2": formatYAML} {
+		formatFlag = format
+		analysis := newAnalysis(core, "SIGSEGV", testFrames("heap_fetch", "IndexNext"))
+		if err := saveAnalysis(analysis); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	outputDir = t.TempDir()
+	formatFlag, compareFlag = formatMarkdown, true
+	if err := runCoreReport(context.Background(), []string{archive}); err != nil {
+		t.Fatal(err)
+	}
+	reports, _ := filepath.Glob(filepath.Join(outputDir, "core_analysis_*.md"))
+	comparisons, _ := filepath.Glob(filepath.Join(outputDir, "core_comparison_*.md"))
+	if len(reports) != 2 || len(comparisons) != 1 {
+		t.Fatalf("reports = %v, comparisons = %v", reports, comparisons)
+	}
+	data, _ := os.ReadFile(comparisons[0])
+	if !strings.Contains(string(data), "heap_fetch ← IndexNext") {
+		t.Errorf("comparison does not group the saved analyses:\n%s", data)
+	}
+
+	if err := runCoreReport(context.Background(), []string{t.TempDir()}); err == nil {
+		t.Error("runCoreReport accepted a directory without analyses")
+	}
+
+	// Reporting an archive into itself must not replace the analyses it reads
+	outputDir, formatFlag, compareFlag = archive, formatJSON, false
+	before, _ := filepath.Glob(filepath.Join(archive, "core_analysis_*.json"))
+	original, _ := os.ReadFile(before[0])
+	var err error
+	output := captureOutput(func() { err = runCoreReport(context.Background(), []string{archive}) })
+	if err == nil || !strings.Contains(output, "refusing to overwrite") {
+		t.Errorf("runCoreReport(context.Background(), ) error = %v, output:\n%s", err, output)
+	}
+	if data, _ := os.ReadFile(before[0]); string(data) != string(original) {
+		t.Error("runCoreReport overwrote the analysis it was reading")
+	}
+	// Other formats are written alongside the archive
+	formatFlag = formatMarkdown
+	if err := runCoreReport(context.Background(), []string{archive}); err != nil {
+		t.Fatal(err)
+	}
+	if reports, _ := filepath.Glob(filepath.Join(archive, "core_analysis_*.md")); len(reports) != 2 {
+		t.Errorf("reports = %v", reports)
+	}
+}
@@ -0,0 +1,153 @@
+// File: cmd/core_report_test.go
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// reportAnalysis is a PANIC during deep expression evaluation, with two threads.
+func reportAnalysis() CoreAnalysis {
+	crashed := ThreadInfo{ThreadID: "1", LWPID: "4242", IsCrashed: true, Backtrace: recursiveBacktrace(20)}
+	crashed.Backtrace[0].SourceFile = "detoast.c"
+	crashed.Backtrace[0].LineNumber = 120
+	crashed.Backtrace[0].Source = &SourceContext{File: "src/backend/access/common/detoast.c", Lines: []SourceLine{
+		{Number: 119, Text: "attr = (struct varlena *) DatumGetPointer(d);"},
+		{Number: 120, Text: "if (VARATT_IS_EXTENDED(attr))", Current: true},
+	}}
+	crashed.Recursion = detectRecursion(crashed.Backtrace)
+
+	analysis := newAnalysis("/var/cores/core.4242", "SIGSEGV", nil)
+	analysis.Timestamp = "2026-10-16T10:00:00Z"
+	analysis.BasicInfo = map[string]string{"description": "Query Executor <seg1>"}
+	analysis.PostgresInfo = PostgresInfo{Version: "postgres (Cloudberry Database) 14.4", GPVersion: "1.6.0"}
+	analysis.SignalInfo = SignalInfo{SignalNumber: 11, SignalCode: 1, SignalName: "SIGSEGV", SignalDescription: "Segmentation fault",
+		FaultAddress: "0x18", FaultClass: faultNullDereference, FaultVerdict: "NULL pointer dereference (offset 0x18)"}
+	analysis.AbortInfo = &AbortInfo{Kind: "elog", Summary: "PANIC: could not write to file", Errors: []ErrorReport{{Level: "PANIC", Message: "could not write to file", Detail: "disk | full"}}}
+	analysis.SessionInfo = &SessionInfo{SessionID: 12, CommandCount: 3, Query: "select * from t where a < 1"}
+	analysis.Threads = []ThreadInfo{
+		{ThreadID: "2", Backtrace: []StackFrame{{FrameNum: "0", Function: "epoll_wait", Module: "/lib64/libc.so.6"}}},
+		crashed,
+	}
+	analysis.Registers = map[string]string{"rip": "0x55d5c0912345", "rsp": "0x7ffd5c3a1b20"}
+	analysis.Libraries = []LibraryInfo{
+		{Name: "/lib64/libc.so.6", Type: "System", IsLoaded: true},
+		{Name: "/usr/local/cloudberry/lib/postgresql/gp_fts.so", Type: "Extension", IsLoaded: true},
+		{Name: "/opt/vendor/libfoo.so", Type: "Other", IsLoaded: false},
+	}
+	return analysis
+}
+
+func TestRenderAnalysisHTML(t *testing.T) {
+	data, err := renderAnalysisHTML(reportAnalysis())
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+	for _, want := range []string{
+		"<title>Core analysis: core.4242</title>",
+		`<p class="abort">PANIC: could not write to file</p>`,
+		"NULL pointer dereference (offset 0x18)",
+		`<details class="thread crashed" open>`,
+		"Thread 1 [LWP 4242] — crashed",
+		"ExecInterpExpr → ExecEvalExpr repeated 20 times",
+		"=>   120  if (VARATT_IS_EXTENDED(attr))",
+		"Cloudberry Extensions (1)",
+		"Other Libraries (1)",
+		"select * from t where a &lt; 1",
+		"Query Executor &lt;seg1&gt;",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML report missing %q", want)
+		}
+	}
+	if strings.Index(html, "Thread 1 [LWP 4242]") > strings.Index(html, "Thread 2") {
+		t.Error("crashed thread is not listed first")
+	}
+	if strings.Contains(html, "<script") {
+		t.Error("HTML report is not script-free")
+	}
+}
+
+func TestRenderAnalysisMarkdown(t *testing.T) {
+	md := string(renderAnalysisMarkdown(reportAnalysis()))
+	for _, want := range []string{
+		"## Core analysis: core.4242",
+		"> **PANIC: could not write to file**",
+		"| Fault | NULL pointer dereference (offset 0x18) |",
+		"### Crashed thread: Thread 1 [LWP 4242]",
+		"    ... ExecInterpExpr → ExecEvalExpr repeated 20 times (frames 2-41) ...",
+		"#0   pg_detoast_datum at detoast.c:120",
+		"| Process | Query Executor &lt;seg1&gt; |",
+		"errordata[0]: PANIC: could not write to file",
+		"  DETAIL: disk | full",
+		"```sql\nselect * from t where a < 1\n```",
+		"<summary>Thread 2</summary>",
+		"| Cloudberry Extensions | `gp_fts.so` |  | yes |",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown report missing %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestRenderComparison(t *testing.T) {
+	comparison := CoreComparison{
+		TotalCores:    3,
+		CommonSignals: map[string]int{"SIGSEGV": 3},
+		TimeRange:     map[string]string{"first": "2026-10-15T00:00:00Z", "last": "2026-10-16T00:00:00Z"},
+		CrashPatterns: []CrashPattern{{Signal: "SIGSEGV", StackSignature: []string{"heap_fetch", "IndexNext"}, OccurrenceCount: 2, AffectedCoreFiles: []string{"core.1", "core.2"}}},
+		ClusterMethod: "edit-distance (threshold 0.7)",
+		Clusters: []CrashCluster{{ID: 1, Signal: "SIGSEGV", Representative: "/cores/core.1", Frames: []string{"heap_fetch", "IndexNext"}, Size: 3,
+			Members:       []ClusterMember{{CoreFile: "/cores/core.1", Similarity: 1}, {CoreFile: "/cores/core.3", Similarity: 0.75}},
+			VaryingFrames: []string{"heapam_index_fetch_tuple"}}},
+	}
+
+	data, err := renderComparisonHTML(comparison)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Core comparison: 3 cores", "heap_fetch ← IndexNext", "Cluster 1: 3 × SIGSEGV in heap_fetch", "(75%)", "heapam_index_fetch_tuple"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("HTML comparison missing %q", want)
+		}
+	}
+
+	md := string(renderComparisonMarkdown(comparison))
+	for _, want := range []string{"| SIGSEGV | 3 |", "| SIGSEGV | `heap_fetch ← IndexNext` | 2 |", "| 1 | 3 | SIGSEGV | `core.1` | heapam_index_fetch_tuple |", " 75%  /cores/core.3"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown comparison missing %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestSaveAnalysisReport(t *testing.T) {
+	oldDir, oldFormat := outputDir, formatFlag
+	defer func() { outputDir, formatFlag = oldDir, oldFormat }()
+	outputDir = t.TempDir()
+
+	for format, ext := range map[string]string{formatHTML: ".html", formatMarkdown: ".md", formatJUnit: ".xml"} {
+		formatFlag = format
+		if err := saveAnalysis(reportAnalysis()); err != nil {
+			t.Fatalf("saveAnalysis(%s) error = %v", format, err)
+		}
+		matches, _ := filepath.Glob(filepath.Join(outputDir, "core_analysis_*"+ext))
+		if len(matches) != 1 {
+			t.Fatalf("%s reports = %v, want 1", format, matches)
+		}
+		data, _ := os.ReadFile(matches[0])
+		if !strings.Contains(string(data), "core.4242") {
+			t.Errorf("%s report does not name the core", format)
+		}
+	}
+
+	// Machine-readable companions fall back to JSON
+	if got := dataFormat(); got != formatJSON {
+		t.Errorf("dataFormat() = %q, want json", got)
+	}
+	if err := validateDataFormat(formatMarkdown); err == nil {
+		t.Error("validateDataFormat accepted markdown")
+	}
+}
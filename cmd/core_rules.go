@@ -0,0 +1,456 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_rules.go
+// Purpose: Matches analyses against a knowledge base of known crashes. Rules are YAML files from
+// ~/.cbtoolbox/rules.d or --rules; each rule matches on the signal, frame patterns in the crashed
+// thread, loaded libraries or extensions, a Cloudberry version range and the assert text, and names
+// a known issue with its title, workaround and fixed-in version. Matching rules are attached to each
+// analysis as known_issues, and --compare marks every crash pattern and cluster as known or new.
+// Dependencies: Uses gopkg.in/yaml.v2 and the frame normalization of core_signatures.go.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Crash statuses reported by --compare when rules are loaded.
+const (
+	crashStatusKnown = "known"
+	crashStatusNew   = "new"
+)
+
+var (
+	rulesPaths []string    // --rules files or directories
+	crashRules []crashRule // Rules loaded for this run
+)
+
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)+`)
+
+func init() {
+	coreCmd.PersistentFlags().StringSliceVar(&rulesPaths, "rules", nil, "Known-crash rules file or directory (repeatable; default ~/.cbtoolbox/rules.d)")
+}
+
+// crashRulesFile is the layout of a rules file.
+type crashRulesFile struct {
+	Rules []crashRule `yaml:"rules"`
+}
+
+// crashRule maps a crash signature to a known issue.
+type crashRule struct {
+	ID         string         `yaml:"id"`
+	Title      string         `yaml:"title"`
+	Workaround string         `yaml:"workaround"`
+	FixedIn    string         `yaml:"fixed_in"`
+	Match      crashRuleMatch `yaml:"match"`
+
+	file   string         // Rules file the rule was loaded from
+	assert *regexp.Regexp // Compiled Match.Assert
+}
+
+// crashRuleMatch holds a rule's conditions; every condition that is set must hold.
+type crashRuleMatch struct {
+	Signal    string   `yaml:"signal"`    // e.g. SIGSEGV or SEGV
+	Frames    []string `yaml:"frames"`    // Glob patterns found in this order in the crashed thread, innermost first
+	Libraries []string `yaml:"libraries"` // Glob patterns each matching a loaded library or extension
+	Versions  string   `yaml:"versions"`  // Comma-separated constraints, e.g. ">=1.5.0, <1.6.1"
+	Assert    string   `yaml:"assert"`    // Regular expression matched against the assert condition or abort summary
+}
+
+// loadCrashRules loads the --rules paths, or ~/.cbtoolbox/rules.d when none are given.
+// Rules are loaded before any core is analyzed so that a broken rules file fails the run early.
+// Returns:
+// - An error if a --rules path is missing or a rules file is invalid.
+func loadCrashRules() error {
+	paths := rulesPaths
+	if len(paths) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			crashRules = nil
+			return nil
+		}
+		paths = []string{filepath.Join(home, ".cbtoolbox", "rules.d")}
+		if !dirExists(paths[0]) {
+			crashRules = nil
+			return nil
+		}
+	}
+
+	rules, err := readCrashRules(paths)
+	if err != nil {
+		return err
+	}
+	crashRules = rules
+	return nil
+}
+
+// readCrashRules reads rules from files and directories of *.yaml and *.yml files.
+// Parameters:
+// - paths: Rules files or directories.
+// Returns:
+// - The rules in file order.
+// - An error if a path or rule is invalid, or two rules share an id.
+func readCrashRules(paths []string) ([]crashRule, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("rules not found: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		var found []string
+		for _, ext := range []string{"yaml", "yml"} {
+			matches, _ := filepath.Glob(filepath.Join(p, "*."+ext))
+			found = append(found, matches...)
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+
+	var rules []crashRule
+	seen := make(map[string]string)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rules file: %w", err)
+		}
+		var parsed crashRulesFile
+		if err := yaml.UnmarshalStrict(data, &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse rules file %s: %w", file, err)
+		}
+		for _, rule := range parsed.Rules {
+			if err := rule.compile(); err != nil {
+				return nil, fmt.Errorf("invalid rule in %s: %w", file, err)
+			}
+			if prev, ok := seen[rule.ID]; ok {
+				return nil, fmt.Errorf("duplicate rule %s in %s (first defined in %s)", rule.ID, file, prev)
+			}
+			seen[rule.ID] = file
+			rule.file = file
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// compile validates a rule and prepares its patterns.
+// Returns:
+// - An error if the rule has no id or conditions, or a pattern or version range is malformed.
+func (r *crashRule) compile() error {
+	m := r.Match
+	if r.ID == "" {
+		return fmt.Errorf("rule %q has no id", r.Title)
+	}
+	if m.Signal == "" && len(m.Frames) == 0 && len(m.Libraries) == 0 && m.Versions == "" && m.Assert == "" {
+		return fmt.Errorf("rule %s has no match conditions", r.ID)
+	}
+	for _, pattern := range append(append([]string{}, m.Frames...), m.Libraries...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("rule %s: bad pattern %q: %w", r.ID, pattern, err)
+		}
+	}
+	if _, err := parseVersionRange(m.Versions); err != nil {
+		return fmt.Errorf("rule %s: %w", r.ID, err)
+	}
+	if m.Assert != "" {
+		re, err := regexp.Compile(m.Assert)
+		if err != nil {
+			return fmt.Errorf("rule %s: bad assert pattern: %w", r.ID, err)
+		}
+		r.assert = re
+	}
+	return nil
+}
+
+// versionConstraint is one comparison of a version range, e.g. ">=1.5.0".
+type versionConstraint struct {
+	op      string
+	version []int
+}
+
+// parseVersion extracts the first dotted version number from a version string.
+// Parameters:
+// - s: A version such as "1.6.0" or "postgres (Cloudberry Database) 1.6.0 build 1".
+// Returns:
+// - The version components, or nil if s holds no version.
+func parseVersion(s string) []int {
+	match := versionPattern.FindString(s)
+	if match == "" {
+		return nil
+	}
+	var version []int
+	for _, part := range strings.Split(match, ".") {
+		n, _ := strconv.Atoi(part)
+		version = append(version, n)
+	}
+	return version
+}
+
+// compareVersions compares two versions component by component; missing components are zero.
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// parseVersionRange parses comma-separated version constraints (<, <=, >, >=, =; a bare version means =).
+// Parameters:
+// - s: The range, e.g. ">=1.5.0, <1.6.1"; empty matches every version.
+// Returns:
+// - The constraints.
+// - An error if a constraint has no version.
+func parseVersionRange(s string) ([]versionConstraint, error) {
+	var constraints []versionConstraint
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		op := "="
+		for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+			if strings.HasPrefix(part, candidate) {
+				op = candidate
+				part = strings.TrimSpace(strings.TrimPrefix(part, candidate))
+				break
+			}
+		}
+		version := parseVersion(part)
+		if version == nil {
+			if n, err := strconv.Atoi(part); err == nil {
+				version = []int{n}
+			} else {
+				return nil, fmt.Errorf("bad version constraint %q", part)
+			}
+		}
+		constraints = append(constraints, versionConstraint{op, version})
+	}
+	return constraints, nil
+}
+
+// versionInRange reports whether a version satisfies every constraint of a range.
+func versionInRange(version []int, constraints []versionConstraint) bool {
+	for _, c := range constraints {
+		cmp := compareVersions(version, c.version)
+		var ok bool
+		switch c.op {
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		default:
+			ok = cmp == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// crashedFrames returns the normalized function names of the crashed thread, innermost first.
+func crashedFrames(analysis CoreAnalysis) []string {
+	frames := analysis.StackTrace
+	for _, thread := range analysis.Threads {
+		if thread.IsCrashed {
+			frames = thread.Backtrace
+			break
+		}
+	}
+	names := make([]string, 0, len(frames))
+	for _, frame := range frames {
+		names = append(names, normalizeFrameFunction(frame.Function))
+	}
+	return names
+}
+
+// matchFrameSequence reports whether the patterns match frames in order, not necessarily adjacent.
+func matchFrameSequence(patterns, frames []string) bool {
+	next := 0
+	for _, frame := range frames {
+		if next == len(patterns) {
+			break
+		}
+		if ok, _ := path.Match(patterns[next], frame); ok {
+			next++
+		}
+	}
+	return next == len(patterns)
+}
+
+// matchLibrary reports whether a pattern matches a loaded library by file name ("gp_fts.so")
+// or by extension name ("gp_fts").
+func matchLibrary(pattern string, libraries []LibraryInfo) bool {
+	for _, lib := range libraries {
+		name := filepath.Base(lib.Name)
+		stem := name
+		if i := strings.Index(stem, ".so"); i > 0 {
+			stem = stem[:i]
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, stem); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether a rule matches an analysis.
+// Parameters:
+// - analysis: The analysis to check.
+// Returns:
+// - True if every condition of the rule holds.
+func (r *crashRule) matches(analysis CoreAnalysis) bool {
+	m := r.Match
+	if m.Signal != "" {
+		signal := strings.ToUpper(m.Signal)
+		if !strings.HasPrefix(signal, "SIG") {
+			signal = "SIG" + signal
+		}
+		if signal != analysis.SignalInfo.SignalName {
+			return false
+		}
+	}
+	if len(m.Frames) > 0 && !matchFrameSequence(m.Frames, crashedFrames(analysis)) {
+		return false
+	}
+	for _, pattern := range m.Libraries {
+		if !matchLibrary(pattern, analysis.Libraries) {
+			return false
+		}
+	}
+	if m.Versions != "" {
+		version := parseVersion(analysis.PostgresInfo.GPVersion)
+		constraints, _ := parseVersionRange(m.Versions)
+		if version == nil || !versionInRange(version, constraints) {
+			return false
+		}
+	}
+	if r.assert != nil {
+		info := analysis.AbortInfo
+		if info == nil || !(r.assert.MatchString(info.Condition) || r.assert.MatchString(info.Summary)) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchKnownIssues returns the known issues whose rules match an analysis.
+// Parameters:
+// - analysis: The analysis to check.
+// - rules: The loaded rules.
+// Returns:
+// - The matching known issues, in rule order.
+func matchKnownIssues(analysis CoreAnalysis, rules []crashRule) []KnownIssue {
+	var issues []KnownIssue
+	for i := range rules {
+		rule := &rules[i]
+		if rule.matches(analysis) {
+			issues = append(issues, KnownIssue{
+				ID:         rule.ID,
+				Title:      rule.Title,
+				Workaround: rule.Workaround,
+				FixedIn:    rule.FixedIn,
+				RulesFile:  rule.file,
+			})
+		}
+	}
+	return issues
+}
+
+// attachKnownIssues records the loaded rules that match an analysis under known_issues.
+// Parameters:
+// - analysis: The analysis to update.
+func attachKnownIssues(analysis *CoreAnalysis) {
+	analysis.KnownIssues = matchKnownIssues(*analysis, crashRules)
+}
+
+// knownIssueIDs collects the distinct known issue ids of a group of cores.
+// Parameters:
+// - analyses: The analyses by core file.
+// - cores: The core files of the group.
+// Returns:
+// - The sorted issue ids, and the crash status (known or new), which is empty when no rules are loaded.
+func knownIssueIDs(analyses map[string]CoreAnalysis, cores []string) ([]string, string) {
+	if len(crashRules) == 0 {
+		return nil, ""
+	}
+	seen := make(map[string]bool)
+	var ids []string
+	for _, core := range cores {
+		for _, issue := range analyses[core].KnownIssues {
+			if !seen[issue.ID] {
+				seen[issue.ID] = true
+				ids = append(ids, issue.ID)
+			}
+		}
+	}
+	sort.Strings(ids)
+	if len(ids) == 0 {
+		return nil, crashStatusNew
+	}
+	return ids, crashStatusKnown
+}
+
+// markKnownCrashes sets the known issues and status of every crash pattern and cluster of a comparison.
+// Parameters:
+// - comparison: The comparison to update.
+// - analyses: The compared analyses.
+func markKnownCrashes(comparison *CoreComparison, analyses []CoreAnalysis) {
+	byCore := make(map[string]CoreAnalysis, len(analyses))
+	for _, analysis := range analyses {
+		byCore[analysis.CoreFile] = analysis
+	}
+	for i := range comparison.CrashPatterns {
+		p := &comparison.CrashPatterns[i]
+		p.KnownIssues, p.Status = knownIssueIDs(byCore, p.AffectedCoreFiles)
+	}
+	for i := range comparison.Clusters {
+		c := &comparison.Clusters[i]
+		var cores []string
+		for _, m := range c.Members {
+			cores = append(cores, m.CoreFile)
+		}
+		c.KnownIssues, c.Status = knownIssueIDs(byCore, cores)
+	}
+}
@@ -0,0 +1,163 @@
+// File: cmd/core_rules_test.go
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testRules = `rules:
+  - id: CBDB-101
+    title: heap_fetch on a dropped index
+    workaround: Rebuild the index
+    fixed_in: 1.6.1
+    match:
+      signal: SEGV
+      frames: [heap_fetch, "Index*"]
+      versions: ">=1.5, <1.6.1"
+  - id: CBDB-202
+    title: Snapshot xid assertion
+    match:
+      assert: TransactionIdIsValid\(xid\)
+  - id: CBDB-303
+    title: gp_fts crash
+    match:
+      libraries: [gp_fts]
+`
+
+// writeRules writes rules to a file in a fresh directory.
+func writeRules(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadCrashRules(t *testing.T) {
+	rulesFile := writeRules(t, testRules)
+	rules, err := readCrashRules([]string{filepath.Dir(rulesFile)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 3 || rules[0].ID != "CBDB-101" || rules[0].file != rulesFile {
+		t.Fatalf("rules = %+v", rules)
+	}
+
+	for name, content := range map[string]string{
+		"no id":         "rules:\n  - title: x\n    match: {signal: SIGSEGV}\n",
+		"no conditions": "rules:\n  - id: A\n",
+		"bad version":   "rules:\n  - id: A\n    match: {versions: '>=abc'}\n",
+		"bad assert":    "rules:\n  - id: A\n    match: {assert: '('}\n",
+		"bad pattern":   "rules:\n  - id: A\n    match: {frames: ['[']}\n",
+		"unknown field": "rules:\n  - id: A\n    match: {signals: SIGSEGV}\n",
+		"duplicate id":  "rules:\n  - id: A\n    match: {signal: SIGSEGV}\n  - id: A\n    match: {signal: SIGBUS}\n",
+	} {
+		if _, err := readCrashRules([]string{writeRules(t, content)}); err == nil {
+			t.Errorf("%s: readCrashRules accepted invalid rules", name)
+		}
+	}
+	if _, err := readCrashRules([]string{"/nonexistent/rules.d"}); err == nil {
+		t.Error("readCrashRules accepted a missing path")
+	}
+}
+
+func TestVersionRange(t *testing.T) {
+	tests := []struct {
+		version, constraints string
+		want                 bool
+	}{
+		{"postgres (Cloudberry Database) 1.6.0 build 1", ">=1.5, <1.6.1", true},
+		{"1.6.1", ">=1.5, <1.6.1", false},
+		{"1.4.9", ">=1.5", false},
+		{"2.0.0", "2.0", true},
+		{"2.0.0", "<=2", true},
+		{"1.10.0", ">1.9.9", true},
+	}
+	for _, tt := range tests {
+		constraints, err := parseVersionRange(tt.constraints)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := versionInRange(parseVersion(tt.version), constraints); got != tt.want {
+			t.Errorf("versionInRange(%s, %s) = %v, want %v", tt.version, tt.constraints, got, tt.want)
+		}
+	}
+}
+
+func TestMatchKnownIssues(t *testing.T) {
+	rules, err := readCrashRules([]string{writeRules(t, testRules)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	segv := newAnalysis("core.1", "SIGSEGV", testFrames("heap_fetch.isra.0", "index_getnext", "IndexNext", "ExecScan"))
+	segv.PostgresInfo.GPVersion = "postgres (Cloudberry Database) 1.6.0 build 1"
+	if issues := matchKnownIssues(segv, rules); len(issues) != 1 || issues[0].ID != "CBDB-101" || issues[0].FixedIn != "1.6.1" {
+		t.Errorf("SIGSEGV issues = %+v", issues)
+	}
+
+	// Fixed releases, frames out of order and other signals do not match
+	fixed := segv
+	fixed.PostgresInfo.GPVersion = "1.6.1"
+	reordered := newAnalysis("core.2", "SIGSEGV", testFrames("IndexNext", "heap_fetch"))
+	reordered.PostgresInfo.GPVersion = "1.6.0"
+	bus := segv
+	bus.SignalInfo.SignalName = "SIGBUS"
+	for name, analysis := range map[string]CoreAnalysis{"fixed": fixed, "reordered": reordered, "SIGBUS": bus} {
+		if issues := matchKnownIssues(analysis, rules); len(issues) != 0 {
+			t.Errorf("%s: issues = %+v", name, issues)
+		}
+	}
+
+	abort := newAnalysis("core.3", "SIGABRT", testFrames("ExceptionalCondition", "GetSnapshotData"))
+	abort.AbortInfo = &AbortInfo{Kind: "assert", Condition: "TransactionIdIsValid(xid)"}
+	abort.Libraries = []LibraryInfo{{Name: "/usr/local/cloudberry/lib/postgresql/gp_fts.so"}}
+	issues := matchKnownIssues(abort, rules)
+	if len(issues) != 2 || issues[0].ID != "CBDB-202" || issues[1].ID != "CBDB-303" {
+		t.Errorf("abort issues = %+v", issues)
+	}
+}
+
+func TestCompareKnownCrashes(t *testing.T) {
+	oldRules, oldCluster := crashRules, clusterAlgorithm
+	defer func() { crashRules, clusterAlgorithm = oldRules, oldCluster }()
+	rules, err := readCrashRules([]string{writeRules(t, testRules)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	crashRules, clusterAlgorithm = rules, "exact"
+
+	var analyses []CoreAnalysis
+	for _, core := range []string{"core.1", "core.2"} {
+		a := newAnalysis(core, "SIGSEGV", testFrames("heap_fetch", "IndexNext", "ExecScan"))
+		a.PostgresInfo.GPVersion = "1.6.0"
+		attachKnownIssues(&a)
+		analyses = append(analyses, a)
+	}
+	analyses = append(analyses, newAnalysis("core.3", "SIGBUS", testFrames("memcpy_chk", "CopyFrom")))
+
+	comparison := compareCores(analyses)
+	if p := comparison.CrashPatterns; len(p) != 1 || p[0].Status != crashStatusKnown || strings.Join(p[0].KnownIssues, ",") != "CBDB-101" {
+		t.Errorf("patterns = %+v", p)
+	}
+	statuses := make(map[string]string)
+	for _, c := range comparison.Clusters {
+		statuses[c.Signal] = c.Status
+	}
+	if statuses["SIGSEGV"] != crashStatusKnown || statuses["SIGBUS"] != crashStatusNew {
+		t.Errorf("cluster statuses = %v", statuses)
+	}
+	if md := string(renderComparisonMarkdown(comparison)); !strings.Contains(md, "| known: CBDB-101 |") || !strings.Contains(md, "| new |") {
+		t.Errorf("Markdown comparison does not show crash status:\n%s", md)
+	}
+
+	// Without rules nothing is labelled
+	crashRules = nil
+	if p := compareCores(analyses).CrashPatterns; p[0].Status != "" {
+		t.Errorf("status without rules = %q", p[0].Status)
+	}
+}
@@ -0,0 +1,277 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_runner.go
+// Purpose: Schedules core file analyses on a bounded pool of workers.
+// Each core is analyzed under its own deadline so that a hung gdb is killed rather than stalling
+// the run, and cancelling the run (Ctrl-C) stops in-flight gdb processes. The final status of
+// every core is recorded in a run manifest written next to the analysis results.
+// Dependencies: Uses analyzeCoreFile from core_gdb.go and the shared --format flag.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Final statuses recorded for each core in the run manifest.
+const (
+	coreStatusOK        = "ok"
+	coreStatusTimeout   = "timeout"
+	coreStatusFailed    = "failed"
+	coreStatusCancelled = "cancelled"
+)
+
+// Defaults for the worker pool flags.
+const (
+	defaultJobs        = 4
+	defaultCoreTimeout = 30 * time.Minute
+)
+
+var (
+	jobsFlag    int           // Number of cores analyzed concurrently
+	timeoutFlag time.Duration // Per-core analysis deadline; 0 disables it
+)
+
+func init() {
+	coreCmd.PersistentFlags().IntVar(&jobsFlag, "jobs", defaultJobs, "Number of core files to analyze concurrently")
+	coreCmd.PersistentFlags().DurationVar(&timeoutFlag, "timeout", defaultCoreTimeout, "Maximum time to spend on each core file (0 disables the limit)")
+}
+
+// validateRunFlags checks the worker pool flags.
+// Returns:
+// - An error if --jobs or --timeout is out of range.
+func validateRunFlags() error {
+	if jobsFlag < 1 {
+		return fmt.Errorf("invalid jobs value: %d. Must be at least 1", jobsFlag)
+	}
+	if timeoutFlag < 0 {
+		return fmt.Errorf("invalid timeout: %s. Must not be negative", timeoutFlag)
+	}
+	return nil
+}
+
+// runCores analyzes core files on a pool of --jobs workers.
+// Parameters:
+// - ctx: Context for the whole run; cancelling it stops in-flight and pending analyses.
+// - coreFiles: The core files to analyze.
+// - gphome: Path to the default PostgreSQL/Cloudberry installation; may be empty.
+// - handle: Called with each successful analysis; calls are serialized.
+// Returns:
+// - The successful analyses, in the order of coreFiles.
+// - The run manifest with the status of every core.
+func runCores(ctx context.Context, coreFiles []string, gphome string, handle func(CoreAnalysis)) ([]CoreAnalysis, RunManifest) {
+	manifest := RunManifest{
+		StartedAt: time.Now().Format(time.RFC3339),
+		Jobs:      jobsFlag,
+		Timeout:   timeoutFlag.String(),
+		Summary:   make(map[string]int),
+		Cores:     make([]CoreRunResult, len(coreFiles)),
+	}
+	results := make([]*CoreAnalysis, len(coreFiles))
+
+	work := make(chan int)
+	var handleMu sync.Mutex
+	var wg sync.WaitGroup
+
+	workers := jobsFlag
+	if workers > len(coreFiles) {
+		workers = len(coreFiles)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				analysis, result := runOneCore(ctx, coreFiles[i], gphome)
+				manifest.Cores[i] = result
+				if result.Status != coreStatusOK {
+					fmt.Printf("Error analyzing %s (%s): %s\n", coreFiles[i], result.Status, result.Error)
+					continue
+				}
+				results[i] = &analysis
+
+				handleMu.Lock()
+				handle(analysis)
+				handleMu.Unlock()
+			}
+		}()
+	}
+
+	for i, coreFile := range coreFiles {
+		if ctx.Err() != nil {
+			// Cores that never started are reported as cancelled
+			manifest.Cores[i] = CoreRunResult{CoreFile: coreFile, Status: coreStatusCancelled, Error: ctx.Err().Error()}
+			continue
+		}
+		select {
+		case work <- i:
+		case <-ctx.Done():
+			manifest.Cores[i] = CoreRunResult{CoreFile: coreFile, Status: coreStatusCancelled, Error: ctx.Err().Error()}
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	var analyses []CoreAnalysis
+	for _, analysis := range results {
+		if analysis != nil {
+			analyses = append(analyses, *analysis)
+		}
+	}
+	for _, result := range manifest.Cores {
+		manifest.Summary[result.Status]++
+	}
+	manifest.FinishedAt = time.Now().Format(time.RFC3339)
+	return analyses, manifest
+}
+
+// analyzeAndRecord runs the full analysis pipeline over a set of core files.
+// Each successful analysis is saved (or printed or streamed), the run manifest is written unless
+// results are streamed, and the analyses are recorded in the crash-signature store unless
+// --no-history is set.
+// Parameters:
+// - ctx: Context for the whole run.
+// - coreFiles: The core files to analyze.
+// - gphome: Path to the default PostgreSQL/Cloudberry installation; may be empty.
+// Returns:
+// - The successful analyses, in the order of coreFiles.
+// - The run manifest with the status of every core.
+func analyzeAndRecord(ctx context.Context, coreFiles []string, gphome string) ([]CoreAnalysis, RunManifest) {
+	analyses, manifest := runCores(ctx, coreFiles, gphome, func(analysis CoreAnalysis) {
+		if err := saveOrPrintAnalysis(analysis); err != nil {
+			fmt.Printf("Error outputting analysis for %s: %v\n", analysis.CoreFile, err)
+		}
+	})
+
+	// A streamed run ends with a summary record instead of a manifest file
+	if !streamingOutput() {
+		if err := saveManifest(manifest); err != nil {
+			fmt.Printf("Error saving run manifest: %v\n", err)
+		}
+	}
+
+	// Record every analyzed core in the crash-signature store
+	if !noHistory && len(analyses) > 0 {
+		if err := recordCrashHistory(analyses); err != nil {
+			fmt.Printf("Error updating crash history: %v\n", err)
+		}
+	}
+
+	return analyses, manifest
+}
+
+// runOneCore analyzes a single core under the per-core deadline.
+// Parameters:
+// - ctx: Context for the whole run.
+// - coreFile: The core file to analyze.
+// - gphome: Path to the default PostgreSQL/Cloudberry installation; may be empty.
+// Returns:
+// - The analysis, valid only when the status is ok.
+// - The core's manifest entry.
+func runOneCore(ctx context.Context, coreFile, gphome string) (CoreAnalysis, CoreRunResult) {
+	coreCtx := ctx
+	if timeoutFlag > 0 {
+		var cancel context.CancelFunc
+		coreCtx, cancel = context.WithTimeout(ctx, timeoutFlag)
+		defer cancel()
+	}
+
+	start := time.Now()
+	analysis, err := analyzeCoreFile(coreCtx, coreFile, gphome)
+	if err == nil {
+		analysis.Fingerprint, _ = crashFingerprint(analysis)
+		redactAnalysis(&analysis)
+	}
+	result := CoreRunResult{
+		CoreFile: coreFile,
+		Duration: time.Since(start).Round(time.Millisecond).String(),
+		Backend:  analysis.AnalysisBackend,
+		Status:   coreRunStatus(ctx, coreCtx, err),
+	}
+	switch result.Status {
+	case coreStatusTimeout:
+		result.Error = fmt.Sprintf("analysis exceeded %s", timeoutFlag)
+	case coreStatusCancelled, coreStatusFailed:
+		result.Error = err.Error()
+	}
+	return analysis, result
+}
+
+// coreRunStatus classifies the outcome of a single core analysis.
+// Parameters:
+// - ctx: Context for the whole run.
+// - coreCtx: The core's own context, carrying its deadline.
+// - err: The error returned by the analysis, if any.
+// Returns:
+// - One of the coreStatus constants.
+func coreRunStatus(ctx, coreCtx context.Context, err error) string {
+	switch {
+	case err == nil:
+		return coreStatusOK
+	case ctx.Err() != nil:
+		return coreStatusCancelled
+	case errors.Is(coreCtx.Err(), context.DeadlineExceeded):
+		return coreStatusTimeout
+	default:
+		return coreStatusFailed
+	}
+}
+
+// saveManifest writes the run manifest to the output directory.
+// The file name carries the start time and a random suffix, so runs starting in the same second
+// do not overwrite each other's manifests.
+// Parameters:
+// - manifest: The run manifest to save.
+// Returns:
+// - An error if the manifest cannot be marshaled or written.
+func saveManifest(manifest RunManifest) error {
+	var data []byte
+	var err error
+	if dataFormat() == formatJSON {
+		data, err = json.MarshalIndent(manifest, "", "  ")
+	} else {
+		data, err = yaml.Marshal(manifest)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal run manifest: %w", err)
+	}
+
+	timestamp := time.Now().Format("20060102_150405")
+	f, err := os.CreateTemp(outputDir, fmt.Sprintf("run_manifest_%s_*.%s", timestamp, dataFormat()))
+	if err != nil {
+		return fmt.Errorf("failed to write run manifest: %w", err)
+	}
+	filename := f.Name()
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(filename, 0644)
+	}
+	if err != nil {
+		os.Remove(filename)
+		return fmt.Errorf("failed to write run manifest: %w", err)
+	}
+
+	fmt.Printf("Run manifest saved to: %s\n", filename)
+	return nil
+}
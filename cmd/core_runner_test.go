@@ -0,0 +1,217 @@
+// File: cmd/core_runner_test.go
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingCommander blocks every command until its context is done.
+type blockingCommander struct{}
+
+func (blockingCommander) Execute(ctx context.Context, name string, args ...string) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// countingCommander succeeds after a short delay and records the peak concurrency.
+type countingCommander struct {
+	mu     sync.Mutex
+	active int
+	peak   int
+}
+
+func (c *countingCommander) Execute(ctx context.Context, name string, args ...string) ([]byte, error) {
+	c.mu.Lock()
+	c.active++
+	if c.active > c.peak {
+		c.peak = c.active
+	}
+	c.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	c.mu.Lock()
+	c.active--
+	c.mu.Unlock()
+	return []byte(""), nil
+}
+
+// setupRunnerCores creates n mock core files and a mock GPHOME.
+func setupRunnerCores(t *testing.T, n int) (string, []string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	gphome := filepath.Join(tmpDir, "gphome")
+	if err := os.MkdirAll(filepath.Join(gphome, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(gphome, "bin", "postgres"), []byte("mock binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var cores []string
+	for i := 0; i < n; i++ {
+		core := filepath.Join(tmpDir, "core."+strings.Repeat("1", i+1))
+		if err := os.WriteFile(core, []byte("mock core file"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cores = append(cores, core)
+	}
+	return gphome, cores
+}
+
+// withRunFlags sets the worker pool flags and commander for the duration of a test.
+func withRunFlags(t *testing.T, jobs int, timeout time.Duration, c Commander) {
+	t.Helper()
+	oldJobs, oldTimeout, oldCmd := jobsFlag, timeoutFlag, cmdExecutor
+	jobsFlag, timeoutFlag = jobs, timeout
+	SetCommander(c)
+	t.Cleanup(func() {
+		jobsFlag, timeoutFlag = oldJobs, oldTimeout
+		SetCommander(oldCmd)
+	})
+}
+
+func TestRunCoresBoundedJobs(t *testing.T) {
+	gphome, cores := setupRunnerCores(t, 6)
+	commander := &countingCommander{}
+	withRunFlags(t, 2, time.Minute, commander)
+
+	var handled []string
+	analyses, manifest := runCores(context.Background(), cores, gphome, func(a CoreAnalysis) {
+		handled = append(handled, a.CoreFile)
+	})
+
+	if len(analyses) != len(cores) {
+		t.Fatalf("got %d analyses, want %d", len(analyses), len(cores))
+	}
+	for i, a := range analyses {
+		if a.CoreFile != cores[i] {
+			t.Errorf("analyses[%d] = %s, want %s (input order)", i, a.CoreFile, cores[i])
+		}
+	}
+	if len(handled) != len(cores) {
+		t.Errorf("handler called %d times, want %d", len(handled), len(cores))
+	}
+	if commander.peak > 2 {
+		t.Errorf("peak concurrency = %d, want at most 2", commander.peak)
+	}
+	if manifest.Summary[coreStatusOK] != len(cores) {
+		t.Errorf("Summary = %v, want %d ok", manifest.Summary, len(cores))
+	}
+	if manifest.Jobs != 2 {
+		t.Errorf("Jobs = %d, want 2", manifest.Jobs)
+	}
+}
+
+func TestRunCoresTimeout(t *testing.T) {
+	gphome, cores := setupRunnerCores(t, 3)
+	withRunFlags(t, 2, 50*time.Millisecond, blockingCommander{})
+
+	start := time.Now()
+	analyses, manifest := runCores(context.Background(), cores, gphome, func(CoreAnalysis) {})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("run took %s, timeout not enforced", elapsed)
+	}
+
+	if len(analyses) != 0 {
+		t.Errorf("got %d analyses, want 0", len(analyses))
+	}
+	for _, result := range manifest.Cores {
+		if result.Status != coreStatusTimeout {
+			t.Errorf("%s: Status = %s, want %s", result.CoreFile, result.Status, coreStatusTimeout)
+		}
+		if !strings.Contains(result.Error, "50ms") {
+			t.Errorf("%s: Error = %q, want timeout message", result.CoreFile, result.Error)
+		}
+	}
+}
+
+func TestRunCoresCancelled(t *testing.T) {
+	gphome, cores := setupRunnerCores(t, 4)
+	withRunFlags(t, 1, time.Minute, blockingCommander{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	_, manifest := runCores(ctx, cores, gphome, func(CoreAnalysis) {})
+	if manifest.Summary[coreStatusCancelled] != len(cores) {
+		t.Errorf("Summary = %v, want %d cancelled", manifest.Summary, len(cores))
+	}
+	for i, result := range manifest.Cores {
+		if result.CoreFile != cores[i] {
+			t.Errorf("Cores[%d].CoreFile = %s, want %s", i, result.CoreFile, cores[i])
+		}
+	}
+}
+
+func TestCoreRunStatus(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	background := context.Background()
+	failure := errors.New("boom")
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		coreCtx context.Context
+		err     error
+		want    string
+	}{
+		{"ok", background, background, nil, coreStatusOK},
+		{"failed", background, background, failure, coreStatusFailed},
+		{"timeout", background, expired, failure, coreStatusTimeout},
+		{"cancelled", cancelled, cancelled, failure, coreStatusCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := coreRunStatus(tt.ctx, tt.coreCtx, tt.err); got != tt.want {
+				t.Errorf("coreRunStatus() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveManifestUniqueNames(t *testing.T) {
+	oldOutput, oldFormat := outputDir, formatFlag
+	defer func() { outputDir, formatFlag = oldOutput, oldFormat }()
+	outputDir, formatFlag = t.TempDir(), "json"
+
+	// Runs starting within the same second keep their own manifests
+	for i := 0; i < 3; i++ {
+		if err := saveManifest(RunManifest{Summary: map[string]int{coreStatusOK: i}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	matches, _ := filepath.Glob(filepath.Join(outputDir, "run_manifest_*.json"))
+	if len(matches) != 3 {
+		t.Errorf("manifests = %v, want 3", matches)
+	}
+}
+
+func TestValidateRunFlags(t *testing.T) {
+	oldJobs, oldTimeout := jobsFlag, timeoutFlag
+	defer func() { jobsFlag, timeoutFlag = oldJobs, oldTimeout }()
+
+	jobsFlag, timeoutFlag = 0, time.Minute
+	if err := validateRunFlags(); err == nil {
+		t.Error("expected error for --jobs=0")
+	}
+	jobsFlag, timeoutFlag = 1, -time.Second
+	if err := validateRunFlags(); err == nil {
+		t.Error("expected error for negative --timeout")
+	}
+	jobsFlag, timeoutFlag = 1, 0
+	if err := validateRunFlags(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_session.go
+// Purpose: Reads the running query and session identity from postgres backend globals in the core
+// (debug_query_string, gp_session_id, GpIdentity, Gp_role, MyProcPort, ...). The globals are evaluated
+// by gdb in the same run as the rest of the analysis, for both the CLI and MI backends, and the values
+// replace the guesses made from the process title.
+// Dependencies: Uses the C string decoder in core_gdb_mi.go.
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// sessionGlobal is a backend global read into SessionInfo.
+type sessionGlobal struct {
+	key  string
+	expr string
+}
+
+// sessionGlobals are the expressions evaluated for SessionInfo, in output order.
+var sessionGlobals = []sessionGlobal{
+	{"query", "debug_query_string"},
+	{"pid", "MyProcPid"},
+	{"session_id", "gp_session_id"},
+	{"command_count", "gp_command_count"},
+	{"segment_id", "GpIdentity.segindex"},
+	{"role", "Gp_role"},
+	{"slice_id", "currentSliceId"},
+	{"database", "MyProcPort->database_name"},
+	{"user", "MyProcPort->user_name"},
+	{"remote_host", "MyProcPort->remote_host"},
+	{"max_stack_depth", "max_stack_depth"},
+	{"stack_base", "(unsigned long) stack_base_ptr"},
+}
+
+// sessionMarker prefixes each global's value in gdb CLI output.
+const sessionMarker = "@@cbtoolbox-session "
+
+// sessionMITokenBase is the first MI token used for session globals.
+const sessionMITokenBase = 100
+
+// gpRoles maps GpRoleValue enumerators to role names.
+var gpRoles = map[string]string{
+	"GP_ROLE_DISPATCH":  "dispatch",
+	"GP_ROLE_EXECUTE":   "execute",
+	"GP_ROLE_UTILITY":   "utility",
+	"GP_ROLE_UNDEFINED": "undefined",
+}
+
+// sessionGDBCommands returns the gdb CLI commands that print the session globals.
+// Each value follows a marker line; a global gdb cannot evaluate leaves its value empty.
+func sessionGDBCommands() []string {
+	cmds := []string{"set print elements 0", "set print repeats unlimited"}
+	for _, g := range sessionGlobals {
+		cmds = append(cmds, `echo \n`+sessionMarker+g.key+`=`, "output "+g.expr, `echo \n`)
+	}
+	return cmds
+}
+
+// sessionMICommands adds the MI commands that evaluate the session globals.
+// Parameters:
+// - commands: MI commands keyed by token; updated in place.
+func sessionMICommands(commands map[int]string) {
+	commands[sessionMITokenBase] = "-gdb-set print elements 0"
+	commands[sessionMITokenBase+1] = "-gdb-set print repeats unlimited"
+	for i, g := range sessionGlobals {
+		commands[sessionMITokenBase+10+i] = "-data-evaluate-expression " + g.expr
+	}
+}
+
+// parseSessionOutput extracts session globals from gdb CLI output.
+// Parameters:
+// - output: The raw output from GDB.
+// Returns:
+// - The session information, or nil if no global could be read.
+func parseSessionOutput(output string) *SessionInfo {
+	re := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(sessionMarker) + `(\w+)=(.*)$`)
+	values := make(map[string]string)
+	for _, m := range re.FindAllStringSubmatch(output, -1) {
+		values[m[1]] = strings.TrimSpace(m[2])
+	}
+	return buildSessionInfo(values)
+}
+
+// miSessionInfo extracts session globals from MI result records.
+// Parameters:
+// - records: The result records keyed by token.
+// Returns:
+// - The session information, or nil if no global could be read.
+func miSessionInfo(records map[string]miRecord) *SessionInfo {
+	values := make(map[string]string)
+	for i, g := range sessionGlobals {
+		record, ok := records[strconv.Itoa(sessionMITokenBase+10+i)]
+		if ok && record.Class == "done" {
+			values[g.key] = miString(record.Results, "value")
+		}
+	}
+	return buildSessionInfo(values)
+}
+
+// gdbStringValue decodes a gdb char* value such as `0x55d5c0 "select 1"`.
+// Returns:
+// - The string, and false if the value is not a readable string (e.g. a NULL pointer).
+func gdbStringValue(value string) (string, bool) {
+	i := strings.Index(value, `"`)
+	if i < 0 {
+		return "", false
+	}
+	s, _, err := parseMICString(value[i:])
+	if err != nil {
+		return "", false
+	}
+	return s, true
+}
+
+// gdbIntValue decodes a gdb integer value.
+// Returns:
+// - The integer, and false if the value is not an integer.
+func gdbIntValue(value string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	return n, err == nil
+}
+
+// buildSessionInfo converts raw gdb values of the session globals.
+// Parameters:
+// - values: gdb values keyed by sessionGlobal key.
+// Returns:
+// - The session information, or nil if no value could be decoded.
+func buildSessionInfo(values map[string]string) *SessionInfo {
+	session := &SessionInfo{}
+	found := false
+	str := func(key string, dst *string) {
+		if s, ok := gdbStringValue(values[key]); ok {
+			*dst, found = s, true
+		}
+	}
+	num := func(key string, dst *int) {
+		if n, ok := gdbIntValue(values[key]); ok {
+			*dst, found = n, true
+		}
+	}
+	ptr := func(key string) *int {
+		if n, ok := gdbIntValue(values[key]); ok {
+			found = true
+			return &n
+		}
+		return nil
+	}
+
+	str("query", &session.Query)
+	num("pid", &session.PID)
+	num("session_id", &session.SessionID)
+	num("command_count", &session.CommandCount)
+	session.SegmentID = ptr("segment_id")
+	session.SliceID = ptr("slice_id")
+	if role, ok := gpRoles[strings.TrimSpace(values["role"])]; ok {
+		session.Role, found = role, true
+	}
+	str("database", &session.Database)
+	str("user", &session.User)
+	str("remote_host", &session.RemoteHost)
+	num("max_stack_depth", &session.MaxStackDepthKB)
+	if n, ok := gdbIntValue(values["stack_base"]); ok && n != 0 {
+		session.StackBase, found = fmt.Sprintf("0x%x", n), true
+	}
+
+	if !found {
+		return nil
+	}
+	return session
+}
+
+// sessionProcessType describes the backend's role in the cluster.
+func sessionProcessType(session *SessionInfo) string {
+	switch session.Role {
+	case "dispatch":
+		return "Query Dispatcher"
+	case "execute":
+		return "Query Executor"
+	case "utility":
+		return "Utility Mode Backend"
+	}
+	return ""
+}
+
+// applySessionInfo replaces the process details guessed from the process title with the
+// values read from the backend's globals.
+// Parameters:
+// - analysis: The CoreAnalysis object to update.
+func applySessionInfo(analysis *CoreAnalysis) {
+	session := analysis.SessionInfo
+	if session == nil {
+		return
+	}
+	if analysis.BasicInfo == nil {
+		analysis.BasicInfo = make(map[string]string)
+	}
+	info := analysis.BasicInfo
+
+	if session.SessionID > 0 {
+		info["connection_id"] = strconv.Itoa(session.SessionID)
+	}
+	if session.CommandCount > 0 {
+		info["command_id"] = strconv.Itoa(session.CommandCount)
+	}
+	if session.SegmentID != nil {
+		info["segment_id"] = strconv.Itoa(*session.SegmentID)
+	}
+	if session.SliceID != nil {
+		info["slice_id"] = strconv.Itoa(*session.SliceID)
+	}
+	if session.Database != "" {
+		info["database"] = session.Database
+	}
+	if session.RemoteHost != "" {
+		info["client_address"] = session.RemoteHost
+	}
+	if procType := sessionProcessType(session); procType != "" {
+		info["process_type"] = procType
+	}
+
+	var desc []string
+	if procType := info["process_type"]; procType != "" {
+		desc = append(desc, procType)
+	}
+	if session.Database != "" {
+		desc = append(desc, fmt.Sprintf("Database %s", session.Database))
+	}
+	if session.SegmentID != nil {
+		desc = append(desc, fmt.Sprintf("Segment %d", *session.SegmentID))
+	}
+	if session.SessionID > 0 {
+		desc = append(desc, fmt.Sprintf("Session con%d", session.SessionID))
+	}
+	if session.User != "" && session.RemoteHost != "" {
+		desc = append(desc, fmt.Sprintf("Client %s@%s", session.User, session.RemoteHost))
+	}
+	if len(desc) > 0 {
+		info["description"] = strings.Join(desc, ", ")
+	}
+}
@@ -0,0 +1,110 @@
+// File: cmd/core_session_test.go
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSessionOutput(t *testing.T) {
+	output := strings.Join([]string{
+		"#0  0x00007f1c2b8a2e97 in raise () from /lib64/libc.so.6",
+		"",
+		`@@cbtoolbox-session query=0x55d5c0a1b2c0 "select * from t where a = 'x\"y'\nand b = 1;"`,
+		"@@cbtoolbox-session pid=12345",
+		"@@cbtoolbox-session session_id=42",
+		"@@cbtoolbox-session command_count=7",
+		"@@cbtoolbox-session segment_id=-1",
+		"@@cbtoolbox-session role=GP_ROLE_DISPATCH",
+		"@@cbtoolbox-session slice_id=0",
+		`@@cbtoolbox-session database=0x55d5c0a1c000 "postgres"`,
+		`@@cbtoolbox-session user=0x55d5c0a1c010 "gpadmin"`,
+		"@@cbtoolbox-session remote_host=",
+	}, "\n")
+
+	session := parseSessionOutput(output)
+	if session == nil {
+		t.Fatal("parseSessionOutput() = nil")
+	}
+	if want := "select * from t where a = 'x\"y'\nand b = 1;"; session.Query != want {
+		t.Errorf("Query = %q, want %q", session.Query, want)
+	}
+	if session.PID != 12345 || session.SessionID != 42 || session.CommandCount != 7 {
+		t.Errorf("ids = %d/%d/%d, want 12345/42/7", session.PID, session.SessionID, session.CommandCount)
+	}
+	if session.SegmentID == nil || *session.SegmentID != -1 || session.SliceID == nil || *session.SliceID != 0 {
+		t.Errorf("segment/slice = %v/%v, want -1/0", session.SegmentID, session.SliceID)
+	}
+	if session.Role != "dispatch" || session.Database != "postgres" || session.User != "gpadmin" || session.RemoteHost != "" {
+		t.Errorf("session = %+v", session)
+	}
+
+	if got := parseSessionOutput("no session globals here"); got != nil {
+		t.Errorf("parseSessionOutput(no globals) = %+v, want nil", got)
+	}
+	// Globals gdb could not evaluate (no debug info, NULL MyProcPort) leave empty values
+	if got := parseSessionOutput("@@cbtoolbox-session query=0x0\n@@cbtoolbox-session pid=\n"); got != nil {
+		t.Errorf("parseSessionOutput(unreadable) = %+v, want nil", got)
+	}
+}
+
+func TestMISessionInfo(t *testing.T) {
+	output := strings.Join([]string{
+		`110^done,value="0x55d5c0a1b2c0 \"insert into t values (1)\""`,
+		`111^done,value="2001"`,
+		`112^done,value="9"`,
+		`114^done,value="3"`,
+		`115^done,value="GP_ROLE_EXECUTE"`,
+		`117^error,msg="Cannot access memory at address 0x30"`,
+		"(gdb)",
+	}, "\n")
+
+	session := miSessionInfo(parseMIOutput(output))
+	if session == nil {
+		t.Fatal("miSessionInfo() = nil")
+	}
+	if session.Query != "insert into t values (1)" || session.PID != 2001 || session.SessionID != 9 {
+		t.Errorf("session = %+v", session)
+	}
+	if session.SegmentID == nil || *session.SegmentID != 3 || session.Role != "execute" || session.Database != "" {
+		t.Errorf("session = %+v", session)
+	}
+}
+
+func TestApplySessionInfo(t *testing.T) {
+	seg := 2
+	analysis := CoreAnalysis{
+		BasicInfo: parseBasicInfo("core file from 'postgres:  7000, con1 seg0'"),
+		SessionInfo: &SessionInfo{
+			Query:      "select 1",
+			SessionID:  77,
+			SegmentID:  &seg,
+			Role:       "execute",
+			Database:   "warehouse",
+			User:       "etl",
+			RemoteHost: "10.0.0.5",
+		},
+	}
+	applySessionInfo(&analysis)
+
+	info := analysis.BasicInfo
+	if info["segment_id"] != "2" || info["connection_id"] != "77" || info["client_address"] != "10.0.0.5" {
+		t.Errorf("basic info = %v", info)
+	}
+	if info["process_type"] != "Query Executor" {
+		t.Errorf("process_type = %q, want Query Executor", info["process_type"])
+	}
+	if want := "Query Executor, Database warehouse, Segment 2, Session con77, Client etl@10.0.0.5"; info["description"] != want {
+		t.Errorf("description = %q, want %q", info["description"], want)
+	}
+}
+
+func TestParseBasicInfoDoesNotGuessProcessType(t *testing.T) {
+	info := parseBasicInfo("core file from 'postgres:  7000, con1 seg0'")
+	if _, ok := info["process_type"]; ok {
+		t.Errorf("process_type = %q, want unset", info["process_type"])
+	}
+	if strings.Contains(info["description"], "Coordinator") {
+		t.Errorf("description = %q, should not assume a coordinator", info["description"])
+	}
+}
@@ -0,0 +1,371 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_siginfo.go
+// Purpose: Decodes the siginfo_t of the fatal signal, from gdb's `$_siginfo` or the core's
+// NT_SIGINFO note, and gdb's signal disposition tables. The si_code tells a fault raised by the
+// faulting instruction apart from a signal sent by the kernel, by the process itself (abort(),
+// raise()), by its parent (the postmaster) or by another process such as an administrator's `kill`.
+// A fault that the backend's own handler re-raised is traced back to the original fault through the
+// siginfo_t the handler was called with.
+// Dependencies: Uses the signal tables in core_parser_signal.go and the core reader in core_elf.go.
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Generic si_code values (include/uapi/asm-generic/siginfo.h).
+const (
+	siUser   = 0    // kill()
+	siKernel = 0x80 // Sent by the kernel
+	siTkill  = -6   // tkill() or tgkill(), used by raise() and abort()
+)
+
+// Origins of a signal, reported in SignalInfo.Origin.
+const (
+	signalOriginFault    = "fault"    // Raised by the faulting instruction
+	signalOriginKernel   = "kernel"   // Sent by the kernel (SI_KERNEL and other kernel codes)
+	signalOriginSelf     = "self"     // Sent by the process itself, e.g. abort() after a failed Assert
+	signalOriginParent   = "parent"   // Sent by the parent process, for a backend the postmaster
+	signalOriginProcess  = "process"  // Sent by another process, e.g. `kill -6`
+	signalOriginReraised = "reraised" // A fault re-raised by the process's own signal handler
+)
+
+// faultSignals are the signals the CPU raises for the instruction that caused them; their
+// positive si_codes come with a fault address.
+var faultSignals = map[int]bool{4: true, 5: true, 7: true, 8: true, 11: true}
+
+// reraisedFaultSignals are the faults Cloudberry's StandardHandlerForSigillSigsegvSigbus handles
+// and then raises again, so the core records a signal the process sent itself.
+var reraisedFaultSignals = map[int]bool{4: true, 7: true, 11: true}
+
+// siginfoSize is the size of a siginfo_t on Linux.
+const siginfoSize = 128
+
+// dispositionSignals are the signals whose gdb disposition table is recorded: the ones a
+// backend dumps core on.
+var dispositionSignals = []string{"SIGABRT", "SIGSEGV", "SIGBUS", "SIGILL", "SIGFPE", "SIGTRAP"}
+
+// signalDispositionMIToken is the MI token of the first `info signal` command.
+const signalDispositionMIToken = 300
+
+// siginfoFields are the fields of a siginfo_t the analysis reports.
+type siginfoFields struct {
+	Signo     int
+	Errno     int
+	Code      int
+	Fault     *SignalFault // _sigfault, for faults
+	SenderPID int          // _kill.si_pid, for signals sent by a process
+	SenderUID *int         // _kill.si_uid; nil if unknown
+}
+
+var (
+	siginfoSignoRE   = regexp.MustCompile(`\bsi_signo\s*=\s*(\d+)`)
+	siginfoErrnoRE   = regexp.MustCompile(`\bsi_errno\s*=\s*(-?\d+)`)
+	siginfoCodeRE    = regexp.MustCompile(`\bsi_code\s*=\s*(-?\d+)`)
+	siginfoKillRE    = regexp.MustCompile(`_kill\s*=\s*\{\s*si_pid\s*=\s*(-?\d+),\s*si_uid\s*=\s*(\d+)`)
+	siginfoAddrLsbRE = regexp.MustCompile(`\b_?addr_lsb\s*=\s*(\d+)`)
+	siginfoLowerRE   = regexp.MustCompile(`\b_lower\s*=\s*(?:\([^)]*\)\s*)?(0x[0-9a-fA-F]+)`)
+	siginfoUpperRE   = regexp.MustCompile(`\b_upper\s*=\s*(?:\([^)]*\)\s*)?(0x[0-9a-fA-F]+)`)
+
+	// handlerPointerRE matches the pointer arguments of a frame, candidates for the handler's siginfo_t.
+	handlerPointerRE = regexp.MustCompile(`\b\w+\s*=\s*(?:\([^)]*\)\s*)?(0x[0-9a-fA-F]+)`)
+
+	// dispositionRE matches a row of `info signal`: Signal, Stop, Print, Pass to program, Description.
+	dispositionRE = regexp.MustCompile(`(?m)^(SIG[A-Z0-9]+)\s+(Yes|No)\s+(Yes|No)\s+(Yes|No)\s+(.*?)\s*$`)
+)
+
+// parseSiginfoFields extracts a siginfo_t from gdb's rendering of `$_siginfo`.
+// Parameters:
+// - value: gdb output containing the value; fields are read from the first si_signo on.
+// Returns:
+// - The fields, or false if no si_signo was found.
+func parseSiginfoFields(value string) (siginfoFields, bool) {
+	loc := siginfoSignoRE.FindStringSubmatchIndex(value)
+	if loc == nil {
+		return siginfoFields{}, false
+	}
+	fields := siginfoFields{Signo: parseInt(value[loc[2]:loc[3]])}
+	value = value[loc[0]:]
+	if m := siginfoErrnoRE.FindStringSubmatch(value); m != nil {
+		fields.Errno = parseInt(m[1])
+	}
+	if m := siginfoCodeRE.FindStringSubmatch(value); m != nil {
+		fields.Code = parseInt(m[1])
+	}
+	if m := siginfoKillRE.FindStringSubmatch(value); m != nil {
+		fields.SenderPID = parseInt(m[1])
+		uid := parseInt(m[2])
+		fields.SenderUID = &uid
+	}
+	if fault := parseFaultInfo(value); fault != nil {
+		if m := siginfoAddrLsbRE.FindStringSubmatch(value); m != nil {
+			fault.AddrLsb = parseInt(m[1])
+		}
+		if m := siginfoLowerRE.FindStringSubmatch(value); m != nil {
+			fault.LowerBnd = m[1]
+		}
+		if m := siginfoUpperRE.FindStringSubmatch(value); m != nil {
+			fault.UpperBnd = m[1]
+		}
+		fields.Fault = fault
+	}
+	return fields, true
+}
+
+// signalInfo decodes the fields into a SignalInfo object.
+// Only the union member the si_code selects is used: the fault address of a fault, or the
+// sender of a signal sent by a process.
+// Returns:
+// - The decoded SignalInfo; the origin of a sent signal is refined by attributeSignalSender.
+func (f siginfoFields) signalInfo() SignalInfo {
+	info := SignalInfo{
+		SignalNumber:      f.Signo,
+		SignalCode:        f.Code,
+		SignalErrno:       f.Errno,
+		SignalName:        getSignalName(f.Signo),
+		SignalDescription: getSignalDescription(f.Signo, f.Code),
+		SignalCodeName:    getSignalCodeName(f.Signo, f.Code),
+	}
+
+	switch {
+	case f.Code > 0 && f.Code != siKernel && faultSignals[f.Signo]:
+		info.Origin = signalOriginFault
+		if f.Fault != nil {
+			info.FaultInfo = f.Fault
+			info.FaultAddress = f.Fault.Address
+		}
+	case f.Code > 0:
+		info.Origin = signalOriginKernel
+	default:
+		info.Origin = signalOriginProcess
+		info.SenderPID = f.SenderPID
+		info.SenderUID = f.SenderUID
+	}
+	return info
+}
+
+// attributeSignalSender names the sender of a signal sent by a process: the process itself
+// (abort() or raise()), its parent or another process.
+// Parameters:
+// - analysis: The CoreAnalysis object; its process ID comes from the core notes or the session globals.
+func attributeSignalSender(analysis *CoreAnalysis) {
+	info := &analysis.SignalInfo
+	if info.Origin != signalOriginProcess || info.SenderPID == 0 {
+		return
+	}
+
+	pid, _ := strconv.Atoi(analysis.BasicInfo["pid"])
+	if pid == 0 && analysis.SessionInfo != nil {
+		pid = analysis.SessionInfo.PID
+	}
+	ppid, _ := strconv.Atoi(analysis.BasicInfo["ppid"])
+
+	var sender string
+	switch info.SenderPID {
+	case pid:
+		info.Origin = signalOriginSelf
+		sender = "the process itself"
+		if info.SignalCode == siTkill {
+			sender += " (abort() or raise())"
+		}
+	case ppid:
+		info.Origin = signalOriginParent
+		sender = fmt.Sprintf("its parent, PID %d", ppid)
+		if strings.HasPrefix(analysis.BasicInfo["cmdline"], "postgres:") {
+			sender += " (the postmaster)"
+		}
+	default:
+		sender = fmt.Sprintf("PID %d", info.SenderPID)
+	}
+	if info.SenderUID != nil {
+		sender += fmt.Sprintf(", UID %d", *info.SenderUID)
+	}
+	info.Sender = sender
+}
+
+// recoverReraisedFault recognizes a fault re-raised by the process's own signal handler.
+// Cloudberry's handler for SIGSEGV, SIGBUS and SIGILL logs the crash and raises the signal again,
+// so the core's siginfo shows a signal the process sent itself. When the crashed thread ran a
+// signal handler, the original fault's si_code and si_addr are read from the siginfo_t the handler
+// received.
+// Parameters:
+// - analysis: The CoreAnalysis object, after attributeSignalSender.
+// - notes: The core's notes, used to read the handler's siginfo_t; may be nil.
+func recoverReraisedFault(analysis *CoreAnalysis, notes *coreNotes) {
+	info := &analysis.SignalInfo
+	if info.Origin != signalOriginSelf || !reraisedFaultSignals[info.SignalNumber] {
+		return
+	}
+	handlers := signalHandlerFrames(analysis)
+	if len(handlers) == 0 {
+		return
+	}
+	info.Origin = signalOriginReraised
+	info.Sender = "its signal handler"
+	for _, frame := range handlers {
+		if frame.Function != "<signal handler called>" {
+			info.Sender += " " + frame.Function
+			break
+		}
+	}
+	if notes == nil {
+		return
+	}
+
+	for _, frame := range handlers {
+		for _, m := range handlerPointerRE.FindAllStringSubmatch(frame.Arguments, -1) {
+			fault := readHandlerSiginfo(notes, m[1], info.SignalNumber)
+			if fault == nil {
+				continue
+			}
+			original := fault.fields().signalInfo()
+			info.SignalCode = original.SignalCode
+			info.SignalCodeName = original.SignalCodeName
+			info.SignalDescription = original.SignalDescription
+			info.FaultInfo = original.FaultInfo
+			info.FaultAddress = original.FaultAddress
+			return
+		}
+	}
+}
+
+// readHandlerSiginfo reads a handler argument as a siginfo_t pointer.
+// Parameters:
+// - notes: The core's notes.
+// - pointer: The argument's value.
+// - signo: The re-raised signal; the siginfo_t must describe a fault of the same signal.
+// Returns:
+// - The fault's siginfo, or nil if the argument does not point to one.
+func readHandlerSiginfo(notes *coreNotes, pointer string, signo int) *coreSigInfo {
+	addr, ok := parseHexAddress(pointer)
+	if !ok || addr == 0 {
+		return nil
+	}
+	data, err := notes.readMemory(addr, siginfoSize)
+	if err != nil {
+		return nil
+	}
+	fault := decodeSigInfo(data)
+	if fault == nil || fault.Signo != signo || fault.Code <= 0 || fault.Code == siKernel {
+		return nil
+	}
+	return fault
+}
+
+// signalHandlerFrames returns the signal-handler frames of the crashed thread, innermost first.
+func signalHandlerFrames(analysis *CoreAnalysis) []StackFrame {
+	frames := analysis.StackTrace
+	for _, thread := range analysis.Threads {
+		if thread.IsCrashed {
+			frames = thread.Backtrace
+			break
+		}
+	}
+	var handlers []StackFrame
+	for _, frame := range frames {
+		if isSignalHandlerFrame(frame.Function) {
+			handlers = append(handlers, frame)
+		}
+	}
+	return handlers
+}
+
+// signalOriginVerdict describes where the signal came from in one line.
+// Parameters:
+// - info: The decoded SignalInfo.
+// Returns:
+// - The verdict, or an empty string if the origin is unknown.
+func signalOriginVerdict(info SignalInfo) string {
+	switch info.Origin {
+	case signalOriginFault:
+		return "raised by the faulting instruction"
+	case signalOriginKernel:
+		return "sent by the kernel"
+	case signalOriginReraised:
+		return "raised by the faulting instruction, then re-raised by " + info.Sender
+	case signalOriginSelf, signalOriginParent, signalOriginProcess:
+		if info.Sender == "" {
+			return fmt.Sprintf("sent by PID %d; not a fault", info.SenderPID)
+		}
+		return "sent by " + info.Sender + "; not a fault"
+	}
+	return ""
+}
+
+// signalDispositionCommands returns the gdb CLI commands printing the disposition tables.
+func signalDispositionCommands() []string {
+	commands := make([]string, len(dispositionSignals))
+	for i, signal := range dispositionSignals {
+		commands[i] = "info signal " + signal
+	}
+	return commands
+}
+
+// signalDispositionMICommands adds the disposition commands to a first-pass MI command set.
+// Parameters:
+// - commands: The MI commands keyed by token.
+func signalDispositionMICommands(commands map[int]string) {
+	for i, command := range signalDispositionCommands() {
+		commands[signalDispositionMIToken+i] = fmt.Sprintf("-interpreter-exec console %q", command)
+	}
+}
+
+// parseSignalDispositions extracts the rows of gdb's `info signal` tables.
+// Parameters:
+// - output: gdb output containing the tables.
+// Returns:
+// - One disposition per signal, in order of appearance.
+func parseSignalDispositions(output string) []SignalDisposition {
+	var dispositions []SignalDisposition
+	seen := make(map[string]bool)
+	for _, m := range dispositionRE.FindAllStringSubmatch(output, -1) {
+		if seen[m[1]] {
+			continue
+		}
+		seen[m[1]] = true
+		dispositions = append(dispositions, SignalDisposition{
+			Signal:      m[1],
+			Stop:        m[2] == "Yes",
+			Print:       m[3] == "Yes",
+			Pass:        m[4] == "Yes",
+			Description: m[5],
+		})
+	}
+	return dispositions
+}
+
+// applySignalDispositions records the disposition tables and the fatal signal's handling.
+// Parameters:
+// - info: The SignalInfo object to update.
+// - dispositions: The parsed disposition tables.
+func applySignalDispositions(info *SignalInfo, dispositions []SignalDisposition) {
+	info.Dispositions = dispositions
+	for _, d := range dispositions {
+		if d.Signal == info.SignalName {
+			info.StopSignal, info.PrintSignal, info.PassSignal = d.Stop, d.Print, d.Pass
+		}
+	}
+}
+
+// yesNo renders a disposition flag the way gdb does.
+func yesNo(flag bool) string {
+	if flag {
+		return "Yes"
+	}
+	return "No"
+}
@@ -0,0 +1,197 @@
+// File: cmd/core_siginfo_test.go
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// gdbSiginfo renders $_siginfo the way gdb prints it with `set print pretty on`.
+// The _kill and _sigfault members overlay each other, as in the kernel's union.
+func gdbSiginfo(signo, code int, pid int, addr string) string {
+	return fmt.Sprintf(`$1 = {
+  si_signo = %d,
+  si_errno = 0,
+  si_code = %d,
+  _sifields = {
+    _pad = {%d, 1000, 0 <repeats 26 times>},
+    _kill = {
+      si_pid = %d,
+      si_uid = 1000
+    },
+    _sigfault = {
+      si_addr = %s,
+      _addr_lsb = 3,
+      _addr_bnd = {
+        _lower = 0x0,
+        _upper = 0x0
+      }
+    }
+  }
+}`, signo, code, pid, pid, addr)
+}
+
+func TestParseSignalInfoSiginfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		signo    int
+		code     int
+		codeName string
+		origin   string
+		fault    string
+		sender   bool
+	}{
+		{"SIGSEGV fault", 11, 1, "SEGV_MAPERR", signalOriginFault, "0x18", false},
+		{"SIGSEGV from the kernel", 11, 0x80, "SI_KERNEL", signalOriginKernel, "", false},
+		{"SIGBUS machine check", 7, 4, "BUS_MCEERR_AR", signalOriginFault, "0x18", false},
+		{"SIGILL", 4, 2, "ILL_ILLOPN", signalOriginFault, "0x18", false},
+		{"SIGTRAP", 5, 1, "TRAP_BRKPT", signalOriginFault, "0x18", false},
+		{"SIGFPE", 8, 1, "FPE_INTDIV", signalOriginFault, "0x18", false},
+		{"SIGABRT from abort", 6, -6, "SI_TKILL", signalOriginProcess, "", true},
+		{"SIGSEGV sent by kill", 11, 0, "SI_USER", signalOriginProcess, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := parseSignalInfo(gdbSiginfo(tt.signo, tt.code, 4242, "0x18"))
+			if info.SignalNumber != tt.signo || info.SignalCode != tt.code || info.SignalCodeName != tt.codeName || info.Origin != tt.origin {
+				t.Errorf("info = %+v", info)
+			}
+			if info.FaultAddress != tt.fault {
+				t.Errorf("FaultAddress = %q, want %q", info.FaultAddress, tt.fault)
+			}
+			if tt.fault != "" && info.FaultInfo.AddrLsb != 3 {
+				t.Errorf("FaultInfo = %+v", info.FaultInfo)
+			}
+			if sent := info.SenderPID == 4242 && info.SenderUID != nil && *info.SenderUID == 1000; sent != tt.sender {
+				t.Errorf("sender = %d/%v, want sender %v", info.SenderPID, info.SenderUID, tt.sender)
+			}
+		})
+	}
+}
+
+func TestAttributeSignalSender(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    int
+		pid     int
+		origin  string
+		verdict string
+	}{
+		{"abort", -6, 4242, signalOriginSelf, "sent by the process itself (abort() or raise()), UID 1000; not a fault"},
+		{"postmaster", 0, 100, signalOriginParent, "sent by its parent, PID 100 (the postmaster), UID 1000; not a fault"},
+		{"administrator", 0, 31337, signalOriginProcess, "sent by PID 31337, UID 1000; not a fault"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis := CoreAnalysis{
+				BasicInfo:  map[string]string{"pid": "4242", "ppid": "100", "cmdline": "postgres: 7000, gpadmin postgres [local] con5 cmd3 idle"},
+				SignalInfo: parseSignalInfo(gdbSiginfo(6, tt.code, tt.pid, "0x0")),
+			}
+			attributeSignalSender(&analysis)
+			if analysis.SignalInfo.Origin != tt.origin {
+				t.Errorf("Origin = %q, want %q", analysis.SignalInfo.Origin, tt.origin)
+			}
+			if got := signalOriginVerdict(analysis.SignalInfo); got != tt.verdict {
+				t.Errorf("verdict = %q, want %q", got, tt.verdict)
+			}
+		})
+	}
+
+	// Without the process ID the sender cannot be placed
+	analysis := CoreAnalysis{SignalInfo: parseSignalInfo(gdbSiginfo(6, -6, 4242, "0x0"))}
+	attributeSignalSender(&analysis)
+	if analysis.SignalInfo.Origin != signalOriginProcess {
+		t.Errorf("Origin = %q", analysis.SignalInfo.Origin)
+	}
+}
+
+func TestRecoverReraisedFault(t *testing.T) {
+	// The handler's siginfo_t lives on the stack; the core's own siginfo is the re-raise
+	corePath := filepath.Join(t.TempDir(), "core.4242")
+	writeTestCore(t, corePath, []testNote{siginfoNote(11, siTkill, 4242|1000<<32)}, []testLoad{
+		{vaddr: 0x7ffd0000, data: append([]byte("postgres\x00\x00\x00\x00\x00\x00\x00\x00"), siginfoNote(11, 1, 0x18).desc...)},
+	})
+	notes, err := readCoreNotes(corePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reraised := func(args string) CoreAnalysis {
+		frames := []StackFrame{
+			{FrameNum: "0", Function: "raise"},
+			{FrameNum: "1", Function: "StandardHandlerForSigillSigsegvSigbus_OnMainThread", Arguments: args},
+			{FrameNum: "2", Function: "<signal handler called>"},
+			{FrameNum: "3", Function: "ExecHashJoinImpl", Arguments: "pstate=0x0"},
+		}
+		return CoreAnalysis{
+			BasicInfo:  map[string]string{"pid": "4242"},
+			SignalInfo: notes.SigInfo.fields().signalInfo(),
+			Threads:    []ThreadInfo{{ThreadID: "1", IsCrashed: true, Backtrace: frames}},
+		}
+	}
+
+	analysis := reraised("processName=0x7ffd0000 \"postgres\", processInfo=0x7ffd0010, processContext=0x7ffd0200")
+	attributeSignalSender(&analysis)
+	recoverReraisedFault(&analysis, notes)
+	sig := analysis.SignalInfo
+	if sig.Origin != signalOriginReraised || sig.FaultAddress != "0x18" || sig.SignalCode != 1 || sig.SignalCodeName != "SEGV_MAPERR" {
+		t.Errorf("SignalInfo = %+v", sig)
+	}
+	if want := "raised by the faulting instruction, then re-raised by its signal handler StandardHandlerForSigillSigsegvSigbus_OnMainThread"; signalOriginVerdict(sig) != want {
+		t.Errorf("verdict = %q, want %q", signalOriginVerdict(sig), want)
+	}
+	addFaultAddressContext(&analysis.SignalInfo, &analysis)
+	if analysis.SignalInfo.FaultVerdict != "NULL pointer dereference (offset 0x18)" {
+		t.Errorf("FaultVerdict = %q", analysis.SignalInfo.FaultVerdict)
+	}
+
+	// Without a readable siginfo_t it is still a fault, only without the address
+	analysis = reraised("postgres_signal_arg=11")
+	attributeSignalSender(&analysis)
+	recoverReraisedFault(&analysis, notes)
+	if sig := analysis.SignalInfo; sig.Origin != signalOriginReraised || sig.FaultAddress != "" {
+		t.Errorf("SignalInfo = %+v", sig)
+	}
+
+	// A self-sent SIGSEGV without a handler frame stays a raise()
+	analysis = reraised("")
+	analysis.Threads[0].Backtrace = analysis.Threads[0].Backtrace[:1]
+	attributeSignalSender(&analysis)
+	recoverReraisedFault(&analysis, notes)
+	if analysis.SignalInfo.Origin != signalOriginSelf {
+		t.Errorf("Origin = %q", analysis.SignalInfo.Origin)
+	}
+}
+
+func TestParseSignalDispositions(t *testing.T) {
+	output := gdbSiginfo(11, 1, 0, "0x0") + `
+Signal        Stop	Print	Pass to program	Description
+SIGABRT       Yes	Yes	Yes		Aborted
+Signal        Stop	Print	Pass to program	Description
+SIGSEGV       Yes	Yes	No		Segmentation fault
+Signal        Stop	Print	Pass to program	Description
+SIGTRAP       Yes	No	No		Trace/breakpoint trap
+`
+	info := parseSignalInfo(output)
+	if len(info.Dispositions) != 3 {
+		t.Fatalf("Dispositions = %+v", info.Dispositions)
+	}
+	if d := info.Dispositions[2]; d.Signal != "SIGTRAP" || !d.Stop || d.Print || d.Pass || d.Description != "Trace/breakpoint trap" {
+		t.Errorf("SIGTRAP = %+v", d)
+	}
+	// The fatal signal's own row sets its flags
+	if !info.StopSignal || !info.PrintSignal || info.PassSignal {
+		t.Errorf("flags = %v %v %v", info.StopSignal, info.PrintSignal, info.PassSignal)
+	}
+}
+
+func TestCoreSigInfoFields(t *testing.T) {
+	info := (&coreSigInfo{Signo: 11, Code: 2, Addr: 0x7f0000001000}).fields().signalInfo()
+	if info.Origin != signalOriginFault || info.FaultAddress != "0x7f0000001000" || info.SignalCodeName != "SEGV_ACCERR" {
+		t.Errorf("fault = %+v", info)
+	}
+	info = (&coreSigInfo{Signo: 6, Code: -6, SenderPID: 77, SenderUID: 0}).fields().signalInfo()
+	if info.SenderPID != 77 || info.SenderUID == nil || *info.SenderUID != 0 || info.FaultInfo != nil {
+		t.Errorf("sent = %+v", info)
+	}
+}
@@ -0,0 +1,406 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_signatures.go
+// Purpose: Maintains a persistent crash-signature store under --output-dir.
+// Every analyzed core is reduced to a normalized crash fingerprint (signal plus the top
+// non-system frames, with compiler clone suffixes removed). The store records, per fingerprint,
+// when it was first and last seen, how often, on which hosts and segments, and with which
+// Cloudberry builds, so recurring crashes can be recognized across runs.
+// Dependencies: Uses encoding/json for the store file and lockFile (flock(2) on Unix) to serialize writers.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	crashStoreFile          = "crash_signatures.json" // Store file, relative to --output-dir
+	crashStoreLockFile      = "crash_signatures.lock" // Lock serializing concurrent writers
+	crashStoreVersion       = 1
+	fingerprintFrames       = 5   // Number of frames contributing to a fingerprint
+	maxSignatureOccurrences = 100 // Most recent occurrences kept per signature
+)
+
+var noHistory bool // Skip recording analyses in the crash store
+
+func init() {
+	coreCmd.PersistentFlags().BoolVar(&noHistory, "no-history", false, "Do not record analyzed cores in the crash-signature store")
+}
+
+// cloneSuffixRE matches compiler-generated clone suffixes such as .isra.0 or .constprop.3.
+var cloneSuffixRE = regexp.MustCompile(`(\.(isra|constprop|part|cold|lto_priv|localalias)(\.\d+)?)+$`)
+
+// crashStoreData is the on-disk layout of the crash store.
+type crashStoreData struct {
+	Version    int               `json:"version"`
+	Signatures []*CrashSignature `json:"signatures"`
+}
+
+// crashStore is an in-memory view of the crash store.
+type crashStore struct {
+	path       string
+	signatures map[string]*CrashSignature
+}
+
+// normalizeFrameFunction strips decorations that vary between builds of the same code.
+// Parameters:
+// - name: A function name from a stack frame.
+// Returns:
+// - The normalized function name.
+func normalizeFrameFunction(name string) string {
+	name = strings.TrimSpace(name)
+	name = strings.TrimSuffix(name, "@plt")
+	return cloneSuffixRE.ReplaceAllString(name, "")
+}
+
+// isSignalHandlerFrame reports whether a frame belongs to the crash signal handling machinery.
+func isSignalHandlerFrame(name string) bool {
+	return name == "<signal handler called>" ||
+		strings.Contains(name, "SigillSigsegvSigbus") ||
+		strings.Contains(name, "ProgramErrorHandler")
+}
+
+// crashFingerprint computes the normalized crash fingerprint of an analysis.
+// Parameters:
+// - analysis: The CoreAnalysis object to fingerprint.
+// Returns:
+// - The fingerprint ID (a short hex digest).
+// - The normalized frames contributing to it.
+func crashFingerprint(analysis CoreAnalysis) (string, []string) {
+	var frames []string
+	for _, frame := range analysis.StackTrace {
+		name := normalizeFrameFunction(frame.Function)
+		if name == "" || name == "??" || isSignalHandlerFrame(name) || isSystemFunction(name) {
+			continue
+		}
+		frames = append(frames, name)
+		if len(frames) == fingerprintFrames {
+			break
+		}
+	}
+
+	sum := sha256.Sum256([]byte(analysis.SignalInfo.SignalName + "|" + strings.Join(frames, "|")))
+	return hex.EncodeToString(sum[:8]), frames
+}
+
+// openCrashStore loads the crash store from a directory; a missing store is empty.
+// Parameters:
+// - dir: The directory holding the store (normally --output-dir).
+// Returns:
+// - The loaded crash store.
+// - An error if the store exists but cannot be read.
+func openCrashStore(dir string) (*crashStore, error) {
+	store := &crashStore{
+		path:       filepath.Join(dir, crashStoreFile),
+		signatures: make(map[string]*CrashSignature),
+	}
+
+	data, err := os.ReadFile(store.path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read crash store: %w", err)
+	}
+
+	var stored crashStoreData
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse crash store %s: %w", store.path, err)
+	}
+	for _, sig := range stored.Signatures {
+		// Stores written before RecordedCores existed only know their kept occurrences
+		if len(sig.RecordedCores) == 0 {
+			for _, occ := range sig.Occurrences {
+				sig.markRecorded(coreRecordKey(occ.Host, occ.CoreFile, occ.CoreTime))
+			}
+		}
+		store.signatures[sig.ID] = sig
+	}
+	return store, nil
+}
+
+// save writes the crash store atomically.
+// Returns:
+// - An error if the store cannot be written.
+func (s *crashStore) save() error {
+	data, err := json.MarshalIndent(crashStoreData{Version: crashStoreVersion, Signatures: s.list()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal crash store: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write crash store: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write crash store: %w", err)
+	}
+	return nil
+}
+
+// list returns all signatures, most recently seen first.
+func (s *crashStore) list() []*CrashSignature {
+	sigs := make([]*CrashSignature, 0, len(s.signatures))
+	for _, sig := range s.signatures {
+		sigs = append(sigs, sig)
+	}
+	sort.Slice(sigs, func(i, j int) bool {
+		ti, tj := parseStoreTime(sigs[i].LastSeen), parseStoreTime(sigs[j].LastSeen)
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return sigs[i].ID < sigs[j].ID
+	})
+	return sigs
+}
+
+// lookup finds a signature by ID or unique ID prefix.
+// Parameters:
+// - id: A full signature ID or a unique prefix of one.
+// Returns:
+// - The matching signature.
+// - An error if no signature or more than one matches.
+func (s *crashStore) lookup(id string) (*CrashSignature, error) {
+	if sig, ok := s.signatures[id]; ok {
+		return sig, nil
+	}
+	var matches []*CrashSignature
+	for key, sig := range s.signatures {
+		if strings.HasPrefix(key, id) {
+			matches = append(matches, sig)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no crash signature matches %s", id)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("crash signature prefix %s is ambiguous (%d matches)", id, len(matches))
+	}
+}
+
+// record adds an analyzed core to the store.
+// A core already recorded for this host (same path and creation time) is not counted again, even
+// after its occurrence has been dropped from the most recent occurrences.
+// Parameters:
+// - analysis: The CoreAnalysis object to record.
+// - host: The host the core was analyzed on.
+// Returns:
+// - The signature the core belongs to.
+// - True if the core had not been recorded before.
+func (s *crashStore) record(analysis CoreAnalysis, host string) (*CrashSignature, bool) {
+	id, frames := crashFingerprint(analysis)
+
+	coreTime := analysis.FileInfo.Created
+	if coreTime == "" {
+		coreTime = analysis.Timestamp
+	}
+	version := analysis.PostgresInfo.GPVersion
+	if version == "" {
+		version = analysis.PostgresInfo.Version
+	}
+	if version == "" {
+		version = "unknown"
+	}
+	segment := analysis.BasicInfo["segment_id"]
+	if segment == "N/A" {
+		segment = ""
+	}
+
+	sig, ok := s.signatures[id]
+	if !ok {
+		sig = &CrashSignature{
+			ID:        id,
+			Signal:    analysis.SignalInfo.SignalName,
+			Frames:    frames,
+			FirstSeen: coreTime,
+			LastSeen:  coreTime,
+		}
+		s.signatures[id] = sig
+	}
+
+	if !sig.markRecorded(coreRecordKey(host, analysis.CoreFile, coreTime)) {
+		return sig, false
+	}
+
+	sig.Count++
+	sig.FirstSeen = earlierTime(sig.FirstSeen, coreTime)
+	sig.LastSeen = laterTime(sig.LastSeen, coreTime)
+	sig.Hosts = addUnique(sig.Hosts, host)
+	if segment != "" {
+		sig.Segments = addUnique(sig.Segments, segment)
+	}
+
+	found := false
+	for i := range sig.Versions {
+		v := &sig.Versions[i]
+		if v.Version == version {
+			v.Count++
+			v.FirstSeen = earlierTime(v.FirstSeen, coreTime)
+			v.LastSeen = laterTime(v.LastSeen, coreTime)
+			found = true
+			break
+		}
+	}
+	if !found {
+		sig.Versions = append(sig.Versions, SignatureVersion{
+			Version: version, FirstSeen: coreTime, LastSeen: coreTime, Count: 1,
+		})
+	}
+	sort.Slice(sig.Versions, func(i, j int) bool {
+		return parseStoreTime(sig.Versions[i].FirstSeen).Before(parseStoreTime(sig.Versions[j].FirstSeen))
+	})
+
+	sig.Occurrences = append(sig.Occurrences, SignatureOccurrence{
+		CoreFile:   analysis.CoreFile,
+		CoreTime:   coreTime,
+		Host:       host,
+		Segment:    segment,
+		Version:    version,
+		AnalyzedAt: analysis.Timestamp,
+	})
+	if len(sig.Occurrences) > maxSignatureOccurrences {
+		sig.Occurrences = sig.Occurrences[len(sig.Occurrences)-maxSignatureOccurrences:]
+	}
+
+	return sig, true
+}
+
+// coreRecordKey identifies a core in a signature's RecordedCores.
+// Parameters:
+// - host: The host the core was analyzed on.
+// - coreFile: The core file's path.
+// - coreTime: The core file's creation time.
+// Returns:
+// - A short hex digest of the three.
+func coreRecordKey(host, coreFile, coreTime string) string {
+	sum := sha256.Sum256([]byte(host + "\x00" + coreFile + "\x00" + coreTime))
+	return hex.EncodeToString(sum[:8])
+}
+
+// markRecorded adds a core's key to the signature's recorded cores.
+// Parameters:
+// - key: The key from coreRecordKey.
+// Returns:
+// - False if the core was already recorded.
+func (sig *CrashSignature) markRecorded(key string) bool {
+	i := sort.SearchStrings(sig.RecordedCores, key)
+	if i < len(sig.RecordedCores) && sig.RecordedCores[i] == key {
+		return false
+	}
+	sig.RecordedCores = append(sig.RecordedCores, "")
+	copy(sig.RecordedCores[i+1:], sig.RecordedCores[i:])
+	sig.RecordedCores[i] = key
+	return true
+}
+
+// updateCrashStore applies changes to the crash store under an exclusive lock. Locking is Unix-only;
+// elsewhere concurrent runs sharing an --output-dir can lose each other's updates.
+// Parameters:
+// - dir: The directory holding the store.
+// - update: Called with the loaded store; the store is saved if it returns nil.
+// Returns:
+// - An error if the store cannot be locked, loaded, updated or saved.
+func updateCrashStore(dir string, update func(*crashStore) error) error {
+	lock, err := os.OpenFile(filepath.Join(dir, crashStoreLockFile), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open crash store lock: %w", err)
+	}
+	defer lock.Close()
+	if err := lockFile(lock); err != nil {
+		return fmt.Errorf("failed to lock crash store: %w", err)
+	}
+	defer unlockFile(lock)
+
+	store, err := openCrashStore(dir)
+	if err != nil {
+		return err
+	}
+	if err := update(store); err != nil {
+		return err
+	}
+	return store.save()
+}
+
+// recordCrashHistory records analyses in the crash store and reports whether each crash is known.
+// Parameters:
+// - analyses: The successful analyses of this run.
+// Returns:
+// - An error if the crash store cannot be updated.
+func recordCrashHistory(analyses []CoreAnalysis) error {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	return updateCrashStore(outputDir, func(store *crashStore) error {
+		for _, analysis := range analyses {
+			sig, added := store.record(analysis, host)
+			switch {
+			case !added:
+				fmt.Printf("Crash signature %s: %s already recorded\n", sig.ID, analysis.CoreFile)
+			case sig.Count == 1:
+				fmt.Printf("Crash signature %s: new crash\n", sig.ID)
+			default:
+				fmt.Printf("Crash signature %s: seen %d times since %s (first build: %s)\n",
+					sig.ID, sig.Count, sig.FirstSeen, sig.Versions[0].Version)
+			}
+		}
+		return nil
+	})
+}
+
+// parseStoreTime parses an RFC 3339 timestamp from the store; invalid values sort first.
+func parseStoreTime(value string) time.Time {
+	t, _ := time.Parse(time.RFC3339, value)
+	return t
+}
+
+// earlierTime returns the earlier of two RFC 3339 timestamps, ignoring empty values.
+func earlierTime(a, b string) string {
+	if a == "" || (b != "" && parseStoreTime(b).Before(parseStoreTime(a))) {
+		return b
+	}
+	return a
+}
+
+// laterTime returns the later of two RFC 3339 timestamps, ignoring empty values.
+func laterTime(a, b string) string {
+	if a == "" || (b != "" && parseStoreTime(b).After(parseStoreTime(a))) {
+		return b
+	}
+	return a
+}
+
+// addUnique appends a value to a sorted set of strings.
+func addUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	values = append(values, value)
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,137 @@
+// File: cmd/core_signatures_test.go
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCrashFingerprint(t *testing.T) {
+	a := newAnalysis("core.1", "SIGSEGV", testFrames("raise", "StandardHandlerForSigillSigsegvSigbus_OnMainThread", "<signal handler called>",
+		"ExecHashJoinImpl.isra.0", "ExecProcNode", "??", "ExecutePlan.constprop.3", "standard_ExecutorRun",
+		"PortalRunSelect", "PortalRun", "main"))
+	b := newAnalysis("core.2", "SIGSEGV", testFrames("ExecHashJoinImpl.isra.1", "ExecProcNode", "ExecutePlan", "standard_ExecutorRun",
+		"PortalRunSelect", "exec_simple_query"))
+
+	idA, framesA := crashFingerprint(a)
+	idB, _ := crashFingerprint(b)
+
+	wantFrames := []string{"ExecHashJoinImpl", "ExecProcNode", "ExecutePlan", "standard_ExecutorRun", "PortalRunSelect"}
+	if !reflect.DeepEqual(framesA, wantFrames) {
+		t.Errorf("frames = %v, want %v", framesA, wantFrames)
+	}
+	if idA != idB {
+		t.Errorf("fingerprints differ for equivalent stacks: %s != %s", idA, idB)
+	}
+	if len(idA) != 16 {
+		t.Errorf("fingerprint length = %d, want 16", len(idA))
+	}
+
+	b.SignalInfo.SignalName = "SIGABRT"
+	if idC, _ := crashFingerprint(b); idC == idA {
+		t.Error("fingerprint should depend on the signal")
+	}
+}
+
+func TestCrashStoreRecord(t *testing.T) {
+	store := &crashStore{signatures: make(map[string]*CrashSignature)}
+	frames := []string{"ExecHashJoinImpl", "ExecProcNode"}
+
+	first := newAnalysis("/cores/core.1", "SIGSEGV", testFrames(frames...), coreOrigin("2026-10-01T10:00:00Z", "1.6.0", "0"))
+	second := newAnalysis("/cores/core.2", "SIGSEGV", testFrames(frames...), coreOrigin("2026-10-10T10:00:00Z", "2.0.0", "3"))
+	earlier := newAnalysis("/cores/core.3", "SIGSEGV", testFrames(frames...), coreOrigin("2026-09-01T10:00:00Z", "1.5.0", "N/A"))
+
+	sig, added := store.record(first, "sdw1")
+	if !added || sig.Count != 1 {
+		t.Fatalf("first record: added=%v count=%d", added, sig.Count)
+	}
+	if _, added := store.record(first, "sdw1"); added {
+		t.Error("re-recording the same core should not count it again")
+	}
+	store.record(second, "sdw2")
+	sig, _ = store.record(earlier, "sdw1")
+
+	if sig.Count != 3 {
+		t.Errorf("Count = %d, want 3", sig.Count)
+	}
+	if sig.FirstSeen != "2026-09-01T10:00:00Z" || sig.LastSeen != "2026-10-10T10:00:00Z" {
+		t.Errorf("FirstSeen/LastSeen = %s/%s", sig.FirstSeen, sig.LastSeen)
+	}
+	if !reflect.DeepEqual(sig.Hosts, []string{"sdw1", "sdw2"}) {
+		t.Errorf("Hosts = %v", sig.Hosts)
+	}
+	if !reflect.DeepEqual(sig.Segments, []string{"0", "3"}) {
+		t.Errorf("Segments = %v", sig.Segments)
+	}
+	var versions []string
+	for _, v := range sig.Versions {
+		versions = append(versions, v.Version)
+	}
+	if !reflect.DeepEqual(versions, []string{"1.5.0", "1.6.0", "2.0.0"}) {
+		t.Errorf("Versions = %v, want ordered by first seen", versions)
+	}
+}
+
+func TestCrashStoreRecordOlderCore(t *testing.T) {
+	store := &crashStore{signatures: make(map[string]*CrashSignature)}
+	oldest := newAnalysis("/cores/core.0", "SIGSEGV", testFrames("ExecHashJoinImpl"), coreOrigin("2026-01-01T00:00:00Z", "1.6.0", "0"))
+	store.record(oldest, "sdw1")
+	for i := 1; i <= maxSignatureOccurrences; i++ {
+		store.record(newAnalysis(fmt.Sprintf("/cores/core.%d", i), "SIGSEGV", testFrames("ExecHashJoinImpl"), coreOrigin("2026-10-01T10:00:00Z", "1.6.0", "0")), "sdw1")
+	}
+
+	// The oldest core has dropped out of the kept occurrences but is still known
+	sig, added := store.record(oldest, "sdw1")
+	if added || sig.Count != maxSignatureOccurrences+1 || len(sig.Occurrences) != maxSignatureOccurrences {
+		t.Errorf("re-recording an older core: added=%v count=%d occurrences=%d", added, sig.Count, len(sig.Occurrences))
+	}
+}
+
+func TestUpdateCrashStore(t *testing.T) {
+	dir := t.TempDir()
+	analysis := newAnalysis("/cores/core.1", "SIGSEGV", testFrames("ExecHashJoinImpl"), coreOrigin("2026-10-01T10:00:00Z", "1.6.0", "0"))
+
+	for i := 0; i < 2; i++ {
+		err := updateCrashStore(dir, func(store *crashStore) error {
+			store.record(analysis, "sdw1")
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("updateCrashStore() error = %v", err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, crashStoreFile)); err != nil {
+		t.Fatalf("store file not written: %v", err)
+	}
+	store, err := openCrashStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigs := store.list()
+	if len(sigs) != 1 || sigs[0].Count != 1 {
+		t.Fatalf("store = %+v, want one signature seen once", sigs)
+	}
+
+	// A store without recorded core keys still recognizes its kept occurrences
+	sigs[0].RecordedCores = nil
+	if err := store.save(); err != nil {
+		t.Fatal(err)
+	}
+	if store, err = openCrashStore(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, added := store.record(analysis, "sdw1"); added {
+		t.Error("a core from a store without recorded core keys was counted again")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, crashStoreFile), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openCrashStore(dir); err == nil {
+		t.Error("expected error for corrupt store")
+	}
+}
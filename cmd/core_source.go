@@ -0,0 +1,223 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_source.go
+// Purpose: Attaches source-code context to the crashed thread's top frames. Source files named by gdb
+// are resolved against --source-root trees (and the installation's ../src directory), and a window of
+// lines around each frame's line is embedded in the analysis with the faulting line marked.
+// Dependencies: Operates on the StackFrame data produced by the gdb parsers.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	sourceRoots        []string // Source trees used to resolve frame source files
+	sourceContextLines int      // Lines shown before and after each frame's line
+	sourceFrames       int      // Number of crashed-thread frames given source context
+)
+
+func init() {
+	coreCmd.PersistentFlags().StringSliceVar(&sourceRoots, "source-root", nil, "Source tree used to show code around crashing frames (repeatable)")
+	coreCmd.PersistentFlags().IntVar(&sourceContextLines, "source-context", 5, "Source lines shown before and after each crashing frame's line")
+	coreCmd.PersistentFlags().IntVar(&sourceFrames, "source-frames", 5, "Number of crashed-thread frames to show source for")
+}
+
+// sourceIndexes caches, per source root, the files found under it by base name.
+var (
+	sourceIndexMu sync.Mutex
+	sourceIndexes = make(map[string]map[string][]string)
+)
+
+// sourceIndex returns the files under a source root, keyed by base name.
+// Parameters:
+// - root: The source root.
+// Returns:
+// - A map from base name to the paths with that name.
+func sourceIndex(root string) map[string][]string {
+	sourceIndexMu.Lock()
+	defer sourceIndexMu.Unlock()
+	if index, ok := sourceIndexes[root]; ok {
+		return index
+	}
+
+	index := make(map[string][]string)
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if name := d.Name(); path != root && strings.HasPrefix(name, ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		index[d.Name()] = append(index[d.Name()], path)
+		return nil
+	})
+	sourceIndexes[root] = index
+	return index
+}
+
+// sharedSuffix counts the trailing path components two paths have in common.
+func sharedSuffix(a, b string) int {
+	pa := strings.Split(filepath.ToSlash(a), "/")
+	pb := strings.Split(filepath.ToSlash(b), "/")
+	n := 0
+	for n < len(pa) && n < len(pb) && pa[len(pa)-1-n] == pb[len(pb)-1-n] {
+		n++
+	}
+	return n
+}
+
+// resolveSourceFile finds a frame's source file on this machine.
+// Parameters:
+// - file: The source file as reported by gdb; absolute build paths and bare names are both common.
+// - roots: The source roots to search.
+// Returns:
+// - The path of the source file, or "" if it cannot be found.
+func resolveSourceFile(file string, roots []string) string {
+	if file == "" {
+		return ""
+	}
+	if filepath.IsAbs(file) && fileExists(file) {
+		return file
+	}
+
+	// Try the path relative to each root, dropping leading build directories one at a time
+	parts := strings.Split(strings.TrimPrefix(filepath.ToSlash(file), "/"), "/")
+	for _, root := range roots {
+		for i := range parts {
+			candidate := filepath.Join(append([]string{root}, parts[i:]...)...)
+			if fileExists(candidate) {
+				return candidate
+			}
+		}
+	}
+
+	// Fall back to a unique best match by name anywhere in the roots
+	best, bestScore, ambiguous := "", 0, false
+	for _, root := range roots {
+		for _, candidate := range sourceIndex(root)[filepath.Base(file)] {
+			switch score := sharedSuffix(file, candidate); {
+			case score > bestScore:
+				best, bestScore, ambiguous = candidate, score, false
+			case score == bestScore:
+				ambiguous = true
+			}
+		}
+	}
+	if ambiguous {
+		return ""
+	}
+	return best
+}
+
+// fileExists reports whether a regular file exists at path.
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// readSourceContext reads the lines around a line of a source file.
+// Parameters:
+// - path: The source file.
+// - line: The 1-based line to center on.
+// - context: Lines to include before and after it.
+// Returns:
+// - The source context.
+// - An error if the file cannot be read or is shorter than line.
+func readSourceContext(path string, line, context int) (*SourceContext, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	src := &SourceContext{File: path}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan() && n <= line+context; n++ {
+		if n < line-context {
+			continue
+		}
+		src.Lines = append(src.Lines, SourceLine{
+			Number:  n,
+			Text:    strings.TrimRight(scanner.Text(), "\r"),
+			Current: n == line,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(src.Lines) == 0 || src.Lines[len(src.Lines)-1].Number < line {
+		return nil, fmt.Errorf("%s has fewer than %d lines", path, line)
+	}
+	return src, nil
+}
+
+// analysisSourceRoots returns the source roots for an analysis: --source-root followed by the
+// installation's ../src directory, which gdb is also pointed at.
+func analysisSourceRoots(analysis CoreAnalysis) []string {
+	roots := append([]string{}, sourceRoots...)
+	if binary := analysis.PostgresInfo.BinaryPath; binary != "" {
+		if srcDir := filepath.Join(filepath.Dir(binary), "../src"); dirExists(srcDir) {
+			roots = append(roots, srcDir)
+		}
+	}
+	return roots
+}
+
+// attachSourceContext adds source context to the crashed thread's top frames that have line information.
+// Parameters:
+// - analysis: The CoreAnalysis object to update.
+func attachSourceContext(analysis *CoreAnalysis) {
+	roots := analysisSourceRoots(*analysis)
+	if len(roots) == 0 || sourceFrames <= 0 {
+		return
+	}
+
+	frames := analysis.StackTrace
+	for i := range analysis.Threads {
+		if analysis.Threads[i].IsCrashed {
+			frames = analysis.Threads[i].Backtrace
+			break
+		}
+	}
+
+	attached := 0
+	for i := range frames {
+		if attached == sourceFrames {
+			break
+		}
+		frame := &frames[i]
+		if frame.SourceFile == "" || frame.LineNumber <= 0 {
+			continue
+		}
+		path := resolveSourceFile(frame.SourceFile, roots)
+		if path == "" {
+			continue
+		}
+		if src, err := readSourceContext(path, frame.LineNumber, sourceContextLines); err == nil {
+			frame.Source = src
+			attached++
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// File: cmd/core_source_test.go
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSourceFile writes a source file with numbered lines under root.
+func writeSourceFile(t *testing.T, root, rel string, lines int) string {
+	t.Helper()
+	path := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	for i := 1; i <= lines; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolveSourceFile(t *testing.T) {
+	root := t.TempDir()
+	execMain := writeSourceFile(t, root, "src/backend/executor/execMain.c", 10)
+	writeSourceFile(t, root, "src/backend/utils/misc/guc.c", 10)
+	writeSourceFile(t, root, "contrib/a/util.c", 10)
+	writeSourceFile(t, root, "contrib/b/util.c", 10)
+
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{"absolute path on this machine", execMain, execMain},
+		{"build path", "/home/build/cloudberry/src/backend/executor/execMain.c", execMain},
+		{"relative path", "executor/execMain.c", execMain},
+		{"bare name", "guc.c", filepath.Join(root, "src/backend/utils/misc/guc.c")},
+		{"ambiguous bare name", "util.c", ""},
+		{"disambiguated by directory", "/build/contrib/b/util.c", filepath.Join(root, "contrib/b/util.c")},
+		{"missing", "nosuch.c", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveSourceFile(tt.file, []string{root}); got != tt.want {
+				t.Errorf("resolveSourceFile(%q) = %q, want %q", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSourceContext(t *testing.T) {
+	path := writeSourceFile(t, t.TempDir(), "foo.c", 20)
+
+	src, err := readSourceContext(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(src.Lines) != 5 || src.Lines[0].Number != 8 || src.Lines[4].Number != 12 {
+		t.Fatalf("lines = %+v, want 8-12", src.Lines)
+	}
+	if !src.Lines[2].Current || src.Lines[2].Text != "line 10" || src.Lines[1].Current {
+		t.Errorf("current line not marked: %+v", src.Lines)
+	}
+
+	// The window is clipped at the start and end of the file
+	if src, err := readSourceContext(path, 1, 3); err != nil || len(src.Lines) != 4 {
+		t.Errorf("readSourceContext(line 1) = %+v, %v", src, err)
+	}
+	if src, err := readSourceContext(path, 20, 3); err != nil || len(src.Lines) != 4 {
+		t.Errorf("readSourceContext(line 20) = %+v, %v", src, err)
+	}
+	if _, err := readSourceContext(path, 25, 3); err == nil {
+		t.Error("expected error for a line past the end of the file")
+	}
+}
+
+func TestAttachSourceContext(t *testing.T) {
+	root := t.TempDir()
+	writeSourceFile(t, root, "src/backend/executor/execMain.c", 50)
+
+	oldRoots, oldLines, oldFrames := sourceRoots, sourceContextLines, sourceFrames
+	defer func() { sourceRoots, sourceContextLines, sourceFrames = oldRoots, oldLines, oldFrames }()
+	sourceRoots, sourceContextLines, sourceFrames = []string{root}, 1, 1
+
+	analysis := CoreAnalysis{
+		Threads: []ThreadInfo{
+			{ThreadID: "2", Backtrace: []StackFrame{
+				{FrameNum: "0", Function: "other", SourceFile: "execMain.c", LineNumber: 5},
+			}},
+			{ThreadID: "1", IsCrashed: true, Backtrace: []StackFrame{
+				{FrameNum: "0", Function: "raise"},
+				{FrameNum: "1", Function: "ExecutorRun", SourceFile: "/build/src/backend/executor/execMain.c", LineNumber: 30},
+				{FrameNum: "2", Function: "PortalRun", SourceFile: "execMain.c", LineNumber: 40},
+			}},
+		},
+	}
+	attachSourceContext(&analysis)
+
+	crashed := analysis.Threads[1].Backtrace
+	if crashed[0].Source != nil {
+		t.Error("frame without line information has source")
+	}
+	src := crashed[1].Source
+	if src == nil || len(src.Lines) != 3 || !src.Lines[1].Current || src.Lines[1].Number != 30 {
+		t.Fatalf("source = %+v, want lines 29-31 with 30 marked", src)
+	}
+	if crashed[2].Source != nil {
+		t.Error("source attached beyond --source-frames")
+	}
+	if analysis.Threads[0].Backtrace[0].Source != nil {
+		t.Error("source attached to a thread that did not crash")
+	}
+}
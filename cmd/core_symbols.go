@@ -0,0 +1,256 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_symbols.go
+// Purpose: Resolves separate debug symbols for the executable and every library in a core by GNU build-id.
+// Debug files are looked up in --debug-dir trees using the standard .build-id/xx/yyyy.debug layout and,
+// when configured, fetched from debuginfod-protocol servers into a local cache. The directories are
+// passed to gdb as its debug-file-directory, and whether symbols were found is recorded per module.
+// Dependencies: Builds on the build-id readers in core_binary.go.
+
+package cmd
+
+import (
+	"context"
+	"debug/elf"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Where a module's symbols were found.
+const (
+	symbolSourceDebugDir   = "debug-dir"
+	symbolSourceDebuginfod = "debuginfod"
+	symbolSourceEmbedded   = "embedded"
+)
+
+const debuginfodCacheDir = "debuginfod-cache" // Cache of fetched debug files, relative to --output-dir
+
+var (
+	debugDirs         []string      // Debug file directories with a .build-id tree
+	debuginfodURLFlag []string      // debuginfod servers; defaults to $DEBUGINFOD_URLS
+	debuginfodTimeout time.Duration // Timeout for a single debuginfod request
+
+	// debuginfodMisses remembers build-ids no server could provide during this run.
+	debuginfodMisses sync.Map
+)
+
+func init() {
+	coreCmd.PersistentFlags().StringSliceVar(&debugDirs, "debug-dir", []string{"/usr/lib/debug"}, "Debug file directories containing a .build-id tree (repeatable)")
+	coreCmd.PersistentFlags().StringSliceVar(&debuginfodURLFlag, "debuginfod-url", nil, "debuginfod server to fetch missing debug files from (repeatable; default $DEBUGINFOD_URLS)")
+	coreCmd.PersistentFlags().DurationVar(&debuginfodTimeout, "debuginfod-timeout", 30*time.Second, "Timeout for each debuginfod request")
+}
+
+// debuginfodURLs returns the configured debuginfod servers.
+func debuginfodURLs() []string {
+	if len(debuginfodURLFlag) > 0 {
+		return debuginfodURLFlag
+	}
+	return strings.Fields(os.Getenv("DEBUGINFOD_URLS"))
+}
+
+// debugFileDirectories returns the directories gdb should search for separate debug files.
+func debugFileDirectories() []string {
+	dirs := append([]string{}, debugDirs...)
+	if len(debuginfodURLs()) > 0 {
+		dirs = append(dirs, filepath.Join(outputDir, debuginfodCacheDir))
+	}
+	return dirs
+}
+
+// gdbDebugArgs returns the gdb arguments that set the debug file directories before the
+// executable and core are loaded.
+func gdbDebugArgs() []string {
+	dirs := debugFileDirectories()
+	if len(dirs) == 0 {
+		return nil
+	}
+	return []string{"-iex", "set debug-file-directory " + strings.Join(dirs, string(os.PathListSeparator))}
+}
+
+// buildIDDebugPath returns the path of a debug file in a .build-id tree.
+// Parameters:
+// - dir: The debug file directory.
+// - buildID: The build-id as a hex string.
+// Returns:
+// - The path <dir>/.build-id/xx/yyyy.debug.
+func buildIDDebugPath(dir, buildID string) string {
+	return filepath.Join(dir, ".build-id", buildID[:2], buildID[2:]+".debug")
+}
+
+// hasDebugInfo reports whether an ELF file carries DWARF debug information.
+func hasDebugInfo(path string) bool {
+	f, err := elf.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	return f.Section(".debug_info") != nil || f.Section(".zdebug_info") != nil
+}
+
+// fetchDebuginfod downloads the debug file for a build-id into the debuginfod cache.
+// Parameters:
+// - ctx: Context bounding the requests.
+// - buildID: The build-id as a hex string.
+// Returns:
+// - The path of the cached debug file.
+// - An error if no server provided it.
+func fetchDebuginfod(ctx context.Context, buildID string) (string, error) {
+	cacheDir := filepath.Join(outputDir, debuginfodCacheDir)
+	dest := buildIDDebugPath(cacheDir, buildID)
+	if _, err := os.Stat(dest); err == nil {
+		return dest, nil
+	}
+	if _, missed := debuginfodMisses.Load(buildID); missed {
+		return "", fmt.Errorf("build-id %s not available from debuginfod", buildID)
+	}
+
+	var lastErr error
+	for _, server := range debuginfodURLs() {
+		url := strings.TrimRight(server, "/") + "/buildid/" + buildID + "/debuginfo"
+		if err := downloadFile(ctx, url, dest); err != nil {
+			lastErr = err
+			continue
+		}
+		return dest, nil
+	}
+
+	debuginfodMisses.Store(buildID, true)
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no debuginfod servers configured")
+	}
+	return "", lastErr
+}
+
+// downloadFile fetches a URL into a file, replacing it atomically.
+// Parameters:
+// - ctx: Context bounding the request.
+// - url: The URL to fetch.
+// - dest: The destination file.
+// Returns:
+// - An error if the request fails or does not return 200 OK.
+func downloadFile(ctx context.Context, url, dest string) error {
+	reqCtx, cancel := context.WithTimeout(ctx, debuginfodTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("%s: %w", url, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+// resolveModuleSymbols finds debug symbols for a single module.
+// Parameters:
+// - ctx: Context bounding debuginfod requests.
+// - module: The module, with Path and BuildID set; updated in place.
+func resolveModuleSymbols(ctx context.Context, module *ModuleSymbols) {
+	if module.BuildID != "" && len(module.BuildID) > 2 {
+		for _, dir := range debugDirs {
+			if candidate := buildIDDebugPath(dir, module.BuildID); hasDebugInfo(candidate) {
+				module.SymbolsFound, module.SymbolSource, module.DebugFile = true, symbolSourceDebugDir, candidate
+				return
+			}
+		}
+	}
+
+	// Unstripped builds carry their own debug information
+	if module.Path != "" && hasDebugInfo(module.Path) && readBuildIDFromFile(module.Path) == module.BuildID {
+		module.SymbolsFound, module.SymbolSource = true, symbolSourceEmbedded
+		return
+	}
+
+	if module.BuildID != "" && len(module.BuildID) > 2 && len(debuginfodURLs()) > 0 {
+		if path, err := fetchDebuginfod(ctx, module.BuildID); err == nil && hasDebugInfo(path) {
+			module.SymbolsFound, module.SymbolSource, module.DebugFile = true, symbolSourceDebuginfod, path
+		}
+	}
+}
+
+// resolveDebugSymbols identifies every module in a core and resolves its debug symbols.
+// Parameters:
+// - ctx: Context bounding debuginfod requests.
+// - notes: The decoded core notes, or nil if the core could not be read natively.
+// - selection: The postgres binary chosen for the core.
+// Returns:
+// - One entry per module, the executable first.
+func resolveDebugSymbols(ctx context.Context, notes *coreNotes, selection binarySelection) []ModuleSymbols {
+	var modules []ModuleSymbols
+
+	if selection.Path != "" {
+		exe := ModuleSymbols{Path: selection.Path, BuildID: selection.CoreBuildID, BuildIDSource: "core"}
+		if exe.BuildID == "" {
+			exe.BuildID, exe.BuildIDSource = selection.BuildID, "file"
+		}
+		if exe.BuildID == "" {
+			exe.BuildIDSource = ""
+		}
+		modules = append(modules, exe)
+	}
+
+	if notes != nil {
+		seen := map[string]bool{selection.CoreExecutable: true, selection.Path: true}
+		for _, f := range notes.Files {
+			if f.Offset != 0 || seen[f.Path] || !strings.Contains(filepath.Base(f.Path), ".so") {
+				continue
+			}
+			seen[f.Path] = true
+
+			module := ModuleSymbols{Path: f.Path, BuildID: readBuildIDFromCore(notes, f.Start), BuildIDSource: "core"}
+			if module.BuildID == "" {
+				module.BuildID, module.BuildIDSource = readBuildIDFromFile(f.Path), "file"
+			}
+			if module.BuildID == "" {
+				module.BuildIDSource = ""
+			}
+			modules = append(modules, module)
+		}
+	}
+
+	for i := range modules {
+		if ctx.Err() != nil {
+			break
+		}
+		resolveModuleSymbols(ctx, &modules[i])
+	}
+	return modules
+}
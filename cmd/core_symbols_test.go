@@ -0,0 +1,199 @@
+// File: cmd/core_symbols_test.go
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"debug/elf"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeDebugFile writes a minimal ELF file carrying a .debug_info section.
+func writeDebugFile(t *testing.T, path string) []byte {
+	t.Helper()
+	le := binary.LittleEndian
+	shstrtab := []byte("\x00.shstrtab\x00.debug_info\x00")
+	debugInfo := []byte("dwarf")
+	shoff := uint64(64 + len(shstrtab) + len(debugInfo))
+	shoff = (shoff + 7) &^ 7
+
+	var buf bytes.Buffer
+	ident := [16]byte{0x7f, 'E', 'L', 'F', 2, 1, 1}
+	buf.Write(ident[:])
+	binary.Write(&buf, le, uint16(elf.ET_DYN))
+	binary.Write(&buf, le, uint16(elf.EM_X86_64))
+	binary.Write(&buf, le, uint32(1))
+	binary.Write(&buf, le, uint64(0))  // e_entry
+	binary.Write(&buf, le, uint64(0))  // e_phoff
+	binary.Write(&buf, le, shoff)      // e_shoff
+	binary.Write(&buf, le, uint32(0))  // e_flags
+	binary.Write(&buf, le, uint16(64)) // e_ehsize
+	binary.Write(&buf, le, uint16(56)) // e_phentsize
+	binary.Write(&buf, le, uint16(0))  // e_phnum
+	binary.Write(&buf, le, uint16(64)) // e_shentsize
+	binary.Write(&buf, le, uint16(3))  // e_shnum
+	binary.Write(&buf, le, uint16(1))  // e_shstrndx
+	buf.Write(shstrtab)
+	buf.Write(debugInfo)
+	buf.Write(make([]byte, int(shoff)-buf.Len()))
+
+	section := func(name uint32, typ elf.SectionType, offset, size uint64) {
+		binary.Write(&buf, le, name)
+		binary.Write(&buf, le, uint32(typ))
+		binary.Write(&buf, le, uint64(0)) // sh_flags
+		binary.Write(&buf, le, uint64(0)) // sh_addr
+		binary.Write(&buf, le, offset)
+		binary.Write(&buf, le, size)
+		binary.Write(&buf, le, uint32(0)) // sh_link
+		binary.Write(&buf, le, uint32(0)) // sh_info
+		binary.Write(&buf, le, uint64(1)) // sh_addralign
+		binary.Write(&buf, le, uint64(0)) // sh_entsize
+	}
+	section(0, elf.SHT_NULL, 0, 0)
+	section(1, elf.SHT_STRTAB, 64, uint64(len(shstrtab)))
+	section(11, elf.SHT_PROGBITS, 64+uint64(len(shstrtab)), uint64(len(debugInfo)))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withSymbolFlags sets the debug symbol flags for the duration of a test.
+func withSymbolFlags(t *testing.T, dirs, urls []string) {
+	t.Helper()
+	oldDirs, oldURLs, oldTimeout, oldOutput := debugDirs, debuginfodURLFlag, debuginfodTimeout, outputDir
+	debugDirs, debuginfodURLFlag, debuginfodTimeout = dirs, urls, 5*time.Second
+	outputDir = t.TempDir()
+	debuginfodMisses = sync.Map{}
+	t.Setenv("DEBUGINFOD_URLS", "")
+	t.Cleanup(func() {
+		debugDirs, debuginfodURLFlag, debuginfodTimeout, outputDir = oldDirs, oldURLs, oldTimeout, oldOutput
+	})
+}
+
+func TestBuildIDDebugPath(t *testing.T) {
+	got := buildIDDebugPath("/usr/lib/debug", "0123456789abcdef")
+	want := "/usr/lib/debug/.build-id/01/23456789abcdef.debug"
+	if got != want {
+		t.Errorf("buildIDDebugPath() = %q, want %q", got, want)
+	}
+}
+
+func TestGDBDebugArgs(t *testing.T) {
+	withSymbolFlags(t, []string{"/a", "/b"}, nil)
+	args := gdbDebugArgs()
+	if len(args) != 2 || args[0] != "-iex" || args[1] != "set debug-file-directory /a:/b" {
+		t.Errorf("gdbDebugArgs() = %v", args)
+	}
+
+	debuginfodURLFlag = []string{"http://debuginfod.example"}
+	args = gdbDebugArgs()
+	if !strings.HasSuffix(args[1], ":"+filepath.Join(outputDir, debuginfodCacheDir)) {
+		t.Errorf("gdbDebugArgs() with debuginfod = %v, want cache directory", args)
+	}
+}
+
+func TestResolveModuleSymbolsDebugDir(t *testing.T) {
+	id := "aabbccddeeff00112233445566778899aabbccdd"
+	debugDir := t.TempDir()
+	withSymbolFlags(t, []string{debugDir}, nil)
+
+	module := ModuleSymbols{Path: "/lib64/libc.so.6", BuildID: id}
+	resolveModuleSymbols(context.Background(), &module)
+	if module.SymbolsFound {
+		t.Fatalf("symbols found without a debug file: %+v", module)
+	}
+
+	debugFile := buildIDDebugPath(debugDir, id)
+	writeDebugFile(t, debugFile)
+	resolveModuleSymbols(context.Background(), &module)
+	if !module.SymbolsFound || module.SymbolSource != symbolSourceDebugDir || module.DebugFile != debugFile {
+		t.Errorf("module = %+v, want symbols from %s", module, debugFile)
+	}
+}
+
+func TestResolveModuleSymbolsDebuginfod(t *testing.T) {
+	id := "00112233445566778899aabbccddeeff00112233"
+	image := writeDebugFile(t, filepath.Join(t.TempDir(), "debug"))
+
+	var requests int
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		if r.URL.Path != "/buildid/"+id+"/debuginfo" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(image)
+	}))
+	defer server.Close()
+	withSymbolFlags(t, []string{t.TempDir()}, []string{server.URL})
+
+	module := ModuleSymbols{Path: "/usr/local/cloudberry/bin/postgres", BuildID: id}
+	resolveModuleSymbols(context.Background(), &module)
+	if !module.SymbolsFound || module.SymbolSource != symbolSourceDebuginfod {
+		t.Fatalf("module = %+v, want symbols from debuginfod", module)
+	}
+	if want := buildIDDebugPath(filepath.Join(outputDir, debuginfodCacheDir), id); module.DebugFile != want {
+		t.Errorf("DebugFile = %q, want %q", module.DebugFile, want)
+	}
+
+	// Cached files and known misses are not requested again
+	missing := ModuleSymbols{Path: "/lib64/libm.so.6", BuildID: "ffffffffffffffffffffffffffffffffffffffff"}
+	resolveModuleSymbols(context.Background(), &missing)
+	resolveModuleSymbols(context.Background(), &missing)
+	resolveModuleSymbols(context.Background(), &module)
+	if missing.SymbolsFound {
+		t.Errorf("missing module reported symbols: %+v", missing)
+	}
+	if requests != 2 {
+		t.Errorf("debuginfod requests = %d, want 2", requests)
+	}
+}
+
+func TestResolveDebugSymbols(t *testing.T) {
+	id := "0123456789abcdef0123456789abcdef01234567"
+	dir := t.TempDir()
+	exePath := filepath.Join(dir, "gphome", "bin", "postgres")
+	corePath := filepath.Join(dir, "core.1")
+	writeCoreForExecutable(t, corePath, exePath, writeTestExecutable(t, exePath, id))
+
+	debugDir := filepath.Join(dir, "debug")
+	writeDebugFile(t, buildIDDebugPath(debugDir, id))
+	withSymbolFlags(t, []string{debugDir}, nil)
+
+	notes, err := readCoreNotes(corePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	selection, err := selectPostgresBinary(notes, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modules := resolveDebugSymbols(context.Background(), notes, selection)
+	if len(modules) != 2 {
+		t.Fatalf("modules = %+v, want executable and libc", modules)
+	}
+	exe := modules[0]
+	if exe.Path != exePath || exe.BuildID != id || exe.BuildIDSource != "core" || !exe.SymbolsFound {
+		t.Errorf("executable = %+v", exe)
+	}
+	if modules[1].Path != "/lib64/libc.so.6" {
+		t.Errorf("library = %+v, want /lib64/libc.so.6", modules[1])
+	}
+}
@@ -0,0 +1,48 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_sys_other.go
+// Purpose: Fallbacks for platforms without flock(2), statfs(2) or kill(2). Concurrent writers of
+// the crash store and analysis index are not serialized, the scratch area's free space is not
+// checked, and scratch directories of other runs are never swept.
+
+//go:build !unix
+
+package cmd
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile does nothing; file locking is Unix-only.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile does nothing.
+func unlockFile(f *os.File) {}
+
+// diskFreeSpace reports that free space cannot be determined on this platform.
+func diskFreeSpace(dir string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
+
+// processAlive reports whether a process can be found; where that is unknown it is assumed alive.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -2,12 +2,57 @@
 package cmd
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"testing"
 )
 
+// analysisOption sets fields of an analysis built by newAnalysis.
+type analysisOption func(*CoreAnalysis)
+
+// newAnalysis builds an analysis for tests: a core that received a signal, with a stack trace of
+// frames, innermost first. Options set anything else.
+func newAnalysis(core, signal string, frames []StackFrame, options ...analysisOption) CoreAnalysis {
+	analysis := CoreAnalysis{
+		Timestamp:  "2026-10-16T12:00:00Z",
+		CoreFile:   core,
+		SignalInfo: SignalInfo{SignalName: signal},
+		StackTrace: frames,
+	}
+	for _, option := range options {
+		option(&analysis)
+	}
+	return analysis
+}
+
+// testFrames makes numbered frames calling functions, innermost first.
+func testFrames(functions ...string) []StackFrame {
+	frames := make([]StackFrame, len(functions))
+	for i, function := range functions {
+		frames[i] = StackFrame{FrameNum: strconv.Itoa(i), Function: function}
+	}
+	return frames
+}
+
+// crashedThread gives the analysis a crashed thread whose backtrace is a copy of the stack trace.
+func crashedThread() analysisOption {
+	return func(analysis *CoreAnalysis) {
+		analysis.Threads = []ThreadInfo{{ThreadID: "1", IsCrashed: true, Backtrace: append([]StackFrame(nil), analysis.StackTrace...)}}
+	}
+}
+
+// coreOrigin records when the core was created, the build that crashed and the segment it ran on.
+func coreOrigin(created, version, segment string) analysisOption {
+	return func(analysis *CoreAnalysis) {
+		analysis.FileInfo.Created = created
+		analysis.PostgresInfo.GPVersion = version
+		analysis.BasicInfo = map[string]string{"segment_id": segment}
+	}
+}
+
 func TestFindCoreFiles(t *testing.T) {
 	// Create temporary test directory
 	tmpDir, err := os.MkdirTemp("", "core_test_*")
@@ -39,9 +84,7 @@ func TestFindCoreFiles(t *testing.T) {
 				t.Fatal(err)
 			}
 		}
-		if err := os.WriteFile(path, []byte("test core file"), 0644); err != nil {
-			t.Fatal(err)
-		}
+		writeTestCore(t, path, nil, nil)
 	}
 
 	// Create some non-core files
@@ -89,7 +132,7 @@ func TestFindCoreFiles(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			files, err := findCoreFiles(tt.path)
+			files, err := discoverCoreFiles(tt.path)
 
 			// Check error condition
 			if tt.expectError {
@@ -274,11 +317,9 @@ func TestRunCoreAnalysis(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// Create test core file
+	// Create test core file; directory searches only pick up ELF core files
 	coreFile := filepath.Join(tmpDir, "core.12345")
-	if err := os.WriteFile(coreFile, []byte("test core file"), 0644); err != nil {
-		t.Fatal(err)
-	}
+	writeTestCore(t, coreFile, nil, nil)
 
 	// Set up test environment
 	oldGPHOME := os.Getenv("GPHOME")
@@ -351,7 +392,7 @@ func TestRunCoreAnalysis(t *testing.T) {
 			compareFlag = tt.compareFlag
 
 			// Run analysis
-			err := runCoreAnalysis(tt.path)
+			err := runCoreAnalysis(context.Background(), tt.path)
 
 			// Check error condition
 			if tt.expectError {
@@ -32,50 +32,240 @@ type CoreAnalysis struct {
     Libraries          []LibraryInfo     `json:"shared_libraries" yaml:"shared_libraries"`
     PostgresInfo       PostgresInfo      `json:"postgres_info" yaml:"postgres_info"`
     CurrentInstruction string            `json:"current_instruction,omitempty" yaml:"current_instruction,omitempty"`
+    AnalysisBackend    string            `json:"analysis_backend,omitempty" yaml:"analysis_backend,omitempty"`
+    Fingerprint        string            `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+    Modules            []ModuleSymbols   `json:"modules,omitempty" yaml:"modules,omitempty"`
+    SessionInfo        *SessionInfo      `json:"session_info,omitempty" yaml:"session_info,omitempty"`
+    MemoryContexts     *MemoryContextReport `json:"memory_contexts,omitempty" yaml:"memory_contexts,omitempty"`
+    AbortInfo          *AbortInfo        `json:"abort_info,omitempty" yaml:"abort_info,omitempty"`
+    OrcaException      *OrcaException    `json:"orca_exception,omitempty" yaml:"orca_exception,omitempty"`
+    MemoryMap          []MemoryMapping   `json:"memory_map,omitempty" yaml:"memory_map,omitempty"`
+    StackUsage         *StackUsage       `json:"stack_usage,omitempty" yaml:"stack_usage,omitempty"`
+    KnownIssues        []KnownIssue      `json:"known_issues,omitempty" yaml:"known_issues,omitempty"`
+    Redaction          *RedactionReport  `json:"redaction,omitempty" yaml:"redaction,omitempty"`
+}
+
+// RedactionReport lists what --redact replaced in an analysis. Original values are not recorded.
+type RedactionReport struct {
+    Policy  string            `json:"policy" yaml:"policy"`
+    Changes []RedactionChange `json:"changes" yaml:"changes"`
+}
+
+// RedactionChange is one pseudonym written into one field of the analysis.
+type RedactionChange struct {
+    Field       string `json:"field" yaml:"field"`
+    Category    string `json:"category" yaml:"category"`
+    Replacement string `json:"replacement" yaml:"replacement"`
+}
+
+// KnownIssue is a known crash from the rules knowledge base that matched the analysis.
+type KnownIssue struct {
+    ID         string `json:"id" yaml:"id"`
+    Title      string `json:"title" yaml:"title"`
+    Workaround string `json:"workaround,omitempty" yaml:"workaround,omitempty"`
+    FixedIn    string `json:"fixed_in,omitempty" yaml:"fixed_in,omitempty"`
+    RulesFile  string `json:"rules_file,omitempty" yaml:"rules_file,omitempty"`
+}
+
+// MemoryMapping is one region of the process address space.
+type MemoryMapping struct {
+    Start  string `json:"start" yaml:"start"`
+    End    string `json:"end" yaml:"end"`
+    Size   uint64 `json:"size" yaml:"size"`
+    Offset string `json:"offset,omitempty" yaml:"offset,omitempty"`
+    Perms  string `json:"perms,omitempty" yaml:"perms,omitempty"`
+    Kind   string `json:"kind,omitempty" yaml:"kind,omitempty"`
+    Path   string `json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+// AbortInfo explains an abort: a failed Assert, or the elog/ereport errors being reported.
+type AbortInfo struct {
+    Kind      string        `json:"kind" yaml:"kind"`
+    Summary   string        `json:"summary" yaml:"summary"`
+    Condition string        `json:"condition,omitempty" yaml:"condition,omitempty"`
+    ErrorType string        `json:"error_type,omitempty" yaml:"error_type,omitempty"`
+    File      string        `json:"file,omitempty" yaml:"file,omitempty"`
+    Line      int           `json:"line,omitempty" yaml:"line,omitempty"`
+    Errors    []ErrorReport `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// OrcaException is the gpos::CException a GPORCA crash was raised with.
+type OrcaException struct {
+    Major     int    `json:"major" yaml:"major"`
+    Minor     int    `json:"minor" yaml:"minor"`
+    MajorName string `json:"major_name,omitempty" yaml:"major_name,omitempty"`
+    MinorName string `json:"minor_name,omitempty" yaml:"minor_name,omitempty"`
+    File      string `json:"file,omitempty" yaml:"file,omitempty"`
+    Line      int    `json:"line,omitempty" yaml:"line,omitempty"`
+    RaisedIn  string `json:"raised_in,omitempty" yaml:"raised_in,omitempty"`
+    Summary   string `json:"summary" yaml:"summary"`
+}
+
+// ErrorReport is one entry of the elog error stack (errordata).
+type ErrorReport struct {
+    Level    string `json:"level,omitempty" yaml:"level,omitempty"`
+    SQLState string `json:"sqlstate,omitempty" yaml:"sqlstate,omitempty"`
+    Message  string `json:"message,omitempty" yaml:"message,omitempty"`
+    Detail   string `json:"detail,omitempty" yaml:"detail,omitempty"`
+    Hint     string `json:"hint,omitempty" yaml:"hint,omitempty"`
+    File     string `json:"file,omitempty" yaml:"file,omitempty"`
+    Line     int    `json:"line,omitempty" yaml:"line,omitempty"`
+    Function string `json:"function,omitempty" yaml:"function,omitempty"`
+}
+
+// MemoryContextReport holds the crashed backend's memory-context tree.
+type MemoryContextReport struct {
+    ContextCount int                    `json:"context_count" yaml:"context_count"`
+    TotalBytes   uint64                 `json:"total_bytes" yaml:"total_bytes"`
+    FreeBytes    uint64                 `json:"free_bytes" yaml:"free_bytes"`
+    UsedBytes    uint64                 `json:"used_bytes" yaml:"used_bytes"`
+    Blocks       uint64                 `json:"blocks" yaml:"blocks"`
+    FreeChunks   uint64                 `json:"free_chunks" yaml:"free_chunks"`
+    Truncated    bool                   `json:"truncated,omitempty" yaml:"truncated,omitempty"`
+    Largest      []MemoryContextSummary `json:"largest,omitempty" yaml:"largest,omitempty"`
+    Root         *MemoryContextNode     `json:"tree" yaml:"tree"`
+}
+
+// MemoryContextNode is a single memory context and its children.
+type MemoryContextNode struct {
+    Name              string               `json:"name" yaml:"name"`
+    Ident             string               `json:"ident,omitempty" yaml:"ident,omitempty"`
+    Address           string               `json:"address" yaml:"address"`
+    Type              string               `json:"type,omitempty" yaml:"type,omitempty"`
+    TotalBytes        uint64               `json:"total_bytes" yaml:"total_bytes"`
+    FreeBytes         uint64               `json:"free_bytes" yaml:"free_bytes"`
+    UsedBytes         uint64               `json:"used_bytes" yaml:"used_bytes"`
+    Blocks            uint64               `json:"blocks" yaml:"blocks"`
+    FreeChunks        uint64               `json:"free_chunks" yaml:"free_chunks"`
+    SubtreeTotalBytes uint64               `json:"subtree_total_bytes" yaml:"subtree_total_bytes"`
+    Children          []*MemoryContextNode `json:"children,omitempty" yaml:"children,omitempty"`
+}
+
+// MemoryContextSummary identifies one of the largest memory contexts by its path from TopMemoryContext.
+type MemoryContextSummary struct {
+    Path       string `json:"path" yaml:"path"`
+    Ident      string `json:"ident,omitempty" yaml:"ident,omitempty"`
+    TotalBytes uint64 `json:"total_bytes" yaml:"total_bytes"`
+    UsedBytes  uint64 `json:"used_bytes" yaml:"used_bytes"`
+}
+
+// SessionInfo holds the running query and session identity read from backend globals.
+type SessionInfo struct {
+    Query           string `json:"query,omitempty" yaml:"query,omitempty"`
+    PID             int    `json:"pid,omitempty" yaml:"pid,omitempty"`
+    SessionID       int    `json:"session_id,omitempty" yaml:"session_id,omitempty"`
+    CommandCount    int    `json:"command_count,omitempty" yaml:"command_count,omitempty"`
+    SegmentID       *int   `json:"segment_id,omitempty" yaml:"segment_id,omitempty"`
+    SliceID         *int   `json:"slice_id,omitempty" yaml:"slice_id,omitempty"`
+    Role            string `json:"role,omitempty" yaml:"role,omitempty"`
+    Database        string `json:"database,omitempty" yaml:"database,omitempty"`
+    User            string `json:"user,omitempty" yaml:"user,omitempty"`
+    RemoteHost      string `json:"remote_host,omitempty" yaml:"remote_host,omitempty"`
+    MaxStackDepthKB int    `json:"max_stack_depth_kb,omitempty" yaml:"max_stack_depth_kb,omitempty"`
+    StackBase       string `json:"stack_base,omitempty" yaml:"stack_base,omitempty"`
 }
 
 // FileInfo contains metadata about the core file.
 type FileInfo struct {
-    FileOutput string `json:"file_output" yaml:"file_output"`
-    Size       int64  `json:"size" yaml:"size"`
-    Created    string `json:"created" yaml:"created"`
+    FileOutput     string `json:"file_output" yaml:"file_output"`
+    Size           int64  `json:"size" yaml:"size"`
+    Created        string `json:"created" yaml:"created"`
+    Compression    string `json:"compression,omitempty" yaml:"compression,omitempty"` // gzip, zstd, lz4 or xz; empty for an uncompressed core
+    CompressedSize int64  `json:"compressed_size,omitempty" yaml:"compressed_size,omitempty"` // Size of the compressed file; Size is then the uncompressed size
 }
 
 // StackFrame represents a single frame in a stack trace.
 type StackFrame struct {
-    FrameNum    string            `json:"frame_num" yaml:"frame_num"`
-    Location    string            `json:"location" yaml:"location"`
-    Function    string            `json:"function" yaml:"function"`
-    Arguments   string            `json:"args" yaml:"args"`
-    SourceFile  string            `json:"source_file,omitempty" yaml:"source_file,omitempty"`
-    LineNumber  int               `json:"line_number,omitempty" yaml:"line_number,omitempty"`
-    Module      string            `json:"module,omitempty" yaml:"module,omitempty"`
-    Locals      map[string]string `json:"locals,omitempty" yaml:"locals,omitempty"`
+    FrameNum      string            `json:"frame_num" yaml:"frame_num"`
+    Location      string            `json:"location" yaml:"location"`
+    Function      string            `json:"function" yaml:"function"`
+    MangledName   string            `json:"mangled_name,omitempty" yaml:"mangled_name,omitempty"`
+    DemangledName string            `json:"demangled_name,omitempty" yaml:"demangled_name,omitempty"`
+    Component     string            `json:"component,omitempty" yaml:"component,omitempty"`
+    Arguments     string            `json:"args" yaml:"args"`
+    SourceFile    string            `json:"source_file,omitempty" yaml:"source_file,omitempty"`
+    LineNumber    int               `json:"line_number,omitempty" yaml:"line_number,omitempty"`
+    Module        string            `json:"module,omitempty" yaml:"module,omitempty"`
+    Locals        map[string]string `json:"locals,omitempty" yaml:"locals,omitempty"`
+    Source        *SourceContext    `json:"source,omitempty" yaml:"source,omitempty"`
+}
+
+// SourceContext holds the source lines around a stack frame's line.
+type SourceContext struct {
+    File  string       `json:"file" yaml:"file"`
+    Lines []SourceLine `json:"lines" yaml:"lines"`
+}
+
+// SourceLine is a single line of source code; Current marks the frame's own line.
+type SourceLine struct {
+    Number  int    `json:"number" yaml:"number"`
+    Text    string `json:"text" yaml:"text"`
+    Current bool   `json:"current,omitempty" yaml:"current,omitempty"`
 }
 
 // ThreadInfo contains details about a thread in the core file.
 type ThreadInfo struct {
-    ThreadID    string       `json:"thread_id" yaml:"thread_id"`
-    ThreadAddr  string       `json:"thread_addr,omitempty" yaml:"thread_addr,omitempty"`
-    LWPID       string       `json:"lwp_id" yaml:"lwp_id"`
-    Name        string       `json:"name,omitempty" yaml:"name,omitempty"`
-    State       string       `json:"state,omitempty" yaml:"state,omitempty"`
-    IsCrashed   bool         `json:"is_crashed,omitempty" yaml:"is_crashed,omitempty"`
-    Backtrace   []StackFrame `json:"backtrace" yaml:"backtrace"`
+    ThreadID   string           `json:"thread_id" yaml:"thread_id"`
+    ThreadAddr string           `json:"thread_addr,omitempty" yaml:"thread_addr,omitempty"`
+    LWPID      string           `json:"lwp_id" yaml:"lwp_id"`
+    Name       string           `json:"name,omitempty" yaml:"name,omitempty"`
+    State      string           `json:"state,omitempty" yaml:"state,omitempty"`
+    IsCrashed  bool             `json:"is_crashed,omitempty" yaml:"is_crashed,omitempty"`
+    Backtrace  []StackFrame     `json:"backtrace" yaml:"backtrace"`
+    Recursion  []RecursionCycle `json:"recursion,omitempty" yaml:"recursion,omitempty"`
+}
+
+// RecursionCycle is a sequence of frames repeated back to back in a backtrace.
+// FirstFrame and LastFrame index the backtrace, innermost frame first.
+type RecursionCycle struct {
+    Functions  []string `json:"functions" yaml:"functions"`
+    Repeats    int      `json:"repeats" yaml:"repeats"`
+    FirstFrame int      `json:"first_frame" yaml:"first_frame"`
+    LastFrame  int      `json:"last_frame" yaml:"last_frame"`
+    Summary    string   `json:"summary" yaml:"summary"`
+}
+
+// StackUsage describes how much stack the crashed thread used.
+type StackUsage struct {
+    Frames             int              `json:"frames" yaml:"frames"`
+    DepthBytes         uint64           `json:"depth_bytes,omitempty" yaml:"depth_bytes,omitempty"`
+    StackMappingBytes  uint64           `json:"stack_mapping_bytes,omitempty" yaml:"stack_mapping_bytes,omitempty"`
+    MaxStackDepthBytes uint64           `json:"max_stack_depth_bytes,omitempty" yaml:"max_stack_depth_bytes,omitempty"`
+    Exhausted          bool             `json:"exhausted" yaml:"exhausted"`
+    Verdict            string           `json:"verdict" yaml:"verdict"`
+    Recursion          []RecursionCycle `json:"recursion,omitempty" yaml:"recursion,omitempty"`
 }
 
 // SignalInfo contains details about the signal that caused the core dump.
 type SignalInfo struct {
-    SignalNumber      int         `json:"signal_number" yaml:"signal_number"`
-    SignalCode        int         `json:"signal_code" yaml:"signal_code"`
-    SignalName        string      `json:"signal_name" yaml:"signal_name"`
-    SignalDescription string      `json:"signal_description" yaml:"signal_description"`
-    FaultAddress      string      `json:"fault_address,omitempty" yaml:"fault_address,omitempty"`
-    FaultInfo         *SignalFault `json:"fault_info,omitempty" yaml:"fault_info,omitempty"`
-    FrameInfo         *FrameInfo   `json:"frame_info,omitempty" yaml:"frame_info,omitempty"`
-    StopSignal        bool         `json:"stop_signal" yaml:"stop_signal"`
-    PrintSignal       bool         `json:"print_signal" yaml:"print_signal"`
-    PassSignal        bool         `json:"pass_signal" yaml:"pass_signal"`
+    SignalNumber      int                 `json:"signal_number" yaml:"signal_number"`
+    SignalCode        int                 `json:"signal_code" yaml:"signal_code"`
+    SignalName        string              `json:"signal_name" yaml:"signal_name"`
+    SignalDescription string              `json:"signal_description" yaml:"signal_description"`
+    FaultAddress      string              `json:"fault_address,omitempty" yaml:"fault_address,omitempty"`
+    FaultInfo         *SignalFault        `json:"fault_info,omitempty" yaml:"fault_info,omitempty"`
+    FaultClass        string              `json:"fault_class,omitempty" yaml:"fault_class,omitempty"`
+    FaultVerdict      string              `json:"fault_verdict,omitempty" yaml:"fault_verdict,omitempty"`
+    FrameInfo         *FrameInfo          `json:"frame_info,omitempty" yaml:"frame_info,omitempty"`
+    SignalCodeName    string              `json:"signal_code_name,omitempty" yaml:"signal_code_name,omitempty"`
+    SignalErrno       int                 `json:"signal_errno,omitempty" yaml:"signal_errno,omitempty"`
+    Origin            string              `json:"origin,omitempty" yaml:"origin,omitempty"`
+    SenderPID         int                 `json:"sender_pid,omitempty" yaml:"sender_pid,omitempty"`
+    SenderUID         *int                `json:"sender_uid,omitempty" yaml:"sender_uid,omitempty"`
+    Sender            string              `json:"sender,omitempty" yaml:"sender,omitempty"`
+    StopSignal        bool                `json:"stop_signal" yaml:"stop_signal"`
+    PrintSignal       bool                `json:"print_signal" yaml:"print_signal"`
+    PassSignal        bool                `json:"pass_signal" yaml:"pass_signal"`
+    Dispositions      []SignalDisposition `json:"dispositions,omitempty" yaml:"dispositions,omitempty"`
+}
+
+// SignalDisposition is a row of gdb's `info signal` table: how gdb handles a signal.
+type SignalDisposition struct {
+    Signal      string `json:"signal" yaml:"signal"`
+    Stop        bool   `json:"stop" yaml:"stop"`
+    Print       bool   `json:"print" yaml:"print"`
+    Pass        bool   `json:"pass" yaml:"pass"`
+    Description string `json:"description" yaml:"description"`
 }
 
 // LibraryInfo contains details about a shared library in the core file.
@@ -90,12 +280,27 @@ type LibraryInfo struct {
     TextEnd   string `json:"text_end,omitempty" yaml:"text_end,omitempty"`
 }
 
+// ModuleSymbols records the build-id of a loaded module and whether debug symbols were found for it.
+type ModuleSymbols struct {
+    Path          string `json:"path" yaml:"path"`
+    BuildID       string `json:"build_id,omitempty" yaml:"build_id,omitempty"`
+    BuildIDSource string `json:"build_id_source,omitempty" yaml:"build_id_source,omitempty"`
+    SymbolsFound  bool   `json:"symbols_found" yaml:"symbols_found"`
+    SymbolSource  string `json:"symbol_source,omitempty" yaml:"symbol_source,omitempty"`
+    DebugFile     string `json:"debug_file,omitempty" yaml:"debug_file,omitempty"`
+}
+
 // PostgresInfo contains PostgreSQL-specific information.
 type PostgresInfo struct {
-    BinaryPath   string   `json:"binary_path" yaml:"binary_path"`
-    Version      string   `json:"version" yaml:"version"`
-    GPVersion    string   `json:"gp_version" yaml:"gp_version"`
-    BuildOptions []string `json:"build_options" yaml:"build_options"`
+    BinaryPath      string   `json:"binary_path" yaml:"binary_path"`
+    Version         string   `json:"version" yaml:"version"`
+    GPVersion       string   `json:"gp_version" yaml:"gp_version"`
+    BuildOptions    []string `json:"build_options" yaml:"build_options"`
+    GPHOME          string   `json:"gphome,omitempty" yaml:"gphome,omitempty"`
+    BinarySelection string   `json:"binary_selection,omitempty" yaml:"binary_selection,omitempty"`
+    CoreExecutable  string   `json:"core_executable,omitempty" yaml:"core_executable,omitempty"`
+    CoreBuildID     string   `json:"core_build_id,omitempty" yaml:"core_build_id,omitempty"`
+    BuildID         string   `json:"build_id,omitempty" yaml:"build_id,omitempty"`
 }
 
 // CrashPattern represents a common crash pattern across core files.
@@ -104,6 +309,8 @@ type CrashPattern struct {
     StackSignature    []string `json:"stack_signature" yaml:"stack_signature"`
     OccurrenceCount   int      `json:"occurrence_count" yaml:"occurrence_count"`
     AffectedCoreFiles []string `json:"core_files" yaml:"core_files"`
+    KnownIssues       []string `json:"known_issues,omitempty" yaml:"known_issues,omitempty"`
+    Status            string   `json:"status,omitempty" yaml:"status,omitempty"` // known or new; empty without rules
 }
 
 // CoreComparison represents the comparison results between multiple core files.
@@ -113,6 +320,119 @@ type CoreComparison struct {
     CommonFunctions map[string]int    `json:"function_distribution" yaml:"function_distribution"`
     CrashPatterns   []CrashPattern    `json:"crash_patterns" yaml:"crash_patterns"`
     TimeRange       map[string]string `json:"time_range" yaml:"time_range"`
+    ClusterMethod   string            `json:"cluster_method,omitempty" yaml:"cluster_method,omitempty"`
+    Clusters        []CrashCluster    `json:"clusters,omitempty" yaml:"clusters,omitempty"`
+
+    analyses []CoreAnalysis // The compared analyses, for reports that show each core
+}
+
+// CrashCluster is a group of cores whose stacks are similar enough to be the same bug.
+type CrashCluster struct {
+    ID             int             `json:"id" yaml:"id"`
+    Signal         string          `json:"signal" yaml:"signal"`
+    Representative string          `json:"representative" yaml:"representative"`
+    Frames         []string        `json:"frames" yaml:"frames"`
+    Size           int             `json:"size" yaml:"size"`
+    Members        []ClusterMember `json:"members" yaml:"members"`
+    VaryingFrames  []string        `json:"varying_frames,omitempty" yaml:"varying_frames,omitempty"`
+    KnownIssues    []string        `json:"known_issues,omitempty" yaml:"known_issues,omitempty"`
+    Status         string          `json:"status,omitempty" yaml:"status,omitempty"` // known or new; empty without rules
+}
+
+// ClusterMember is a core in a crash cluster and its similarity to the representative.
+type ClusterMember struct {
+    CoreFile   string  `json:"core_file" yaml:"core_file"`
+    Similarity float64 `json:"similarity" yaml:"similarity"`
+}
+
+// RunManifest records the outcome of one `core` run across all core files.
+type RunManifest struct {
+    StartedAt  string          `json:"started_at" yaml:"started_at"`
+    FinishedAt string          `json:"finished_at" yaml:"finished_at"`
+    Jobs       int             `json:"jobs" yaml:"jobs"`
+    Timeout    string          `json:"timeout" yaml:"timeout"`
+    Summary    map[string]int  `json:"summary" yaml:"summary"`
+    Cores      []CoreRunResult `json:"cores" yaml:"cores"`
+}
+
+// CoreRunResult is the final status of a single core file within a run.
+type CoreRunResult struct {
+    CoreFile string `json:"core_file" yaml:"core_file"`
+    Status   string `json:"status" yaml:"status"`
+    Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+    Duration string `json:"duration" yaml:"duration"`
+    Backend  string `json:"analysis_backend,omitempty" yaml:"analysis_backend,omitempty"`
+}
+
+// AnalysisIndex links analyzed core files to their analysis files in --output-dir (index.json).
+type AnalysisIndex struct {
+    Updated string               `json:"updated" yaml:"updated"`
+    Cores   []AnalysisIndexEntry `json:"cores" yaml:"cores"` // Sorted by core file
+}
+
+// AnalysisIndexEntry lists the analysis files written for one core file.
+type AnalysisIndexEntry struct {
+    CoreFile    string            `json:"core_file" yaml:"core_file"` // Absolute path of the core file
+    Fingerprint string            `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+    AnalyzedAt  string            `json:"analyzed_at" yaml:"analyzed_at"`
+    Files       map[string]string `json:"files" yaml:"files"` // Analysis file by output format, relative to --output-dir
+}
+
+// CrashSignature is a crash fingerprint tracked across runs in the crash store.
+type CrashSignature struct {
+    ID            string                `json:"id" yaml:"id"`
+    Signal        string                `json:"signal" yaml:"signal"`
+    Frames        []string              `json:"frames" yaml:"frames"`
+    FirstSeen     string                `json:"first_seen" yaml:"first_seen"`
+    LastSeen      string                `json:"last_seen" yaml:"last_seen"`
+    Count         int                   `json:"count" yaml:"count"`
+    Hosts         []string              `json:"hosts" yaml:"hosts"`
+    Segments      []string              `json:"segments,omitempty" yaml:"segments,omitempty"`
+    Versions      []SignatureVersion    `json:"versions" yaml:"versions"`
+    Occurrences   []SignatureOccurrence `json:"occurrences" yaml:"occurrences"`
+    RecordedCores []string              `json:"recorded_cores,omitempty" yaml:"recorded_cores,omitempty"` // Sorted keys of every core counted, including those dropped from Occurrences
+}
+
+// SignatureVersion tracks when a crash signature was seen with a given Cloudberry build.
+type SignatureVersion struct {
+    Version   string `json:"version" yaml:"version"`
+    FirstSeen string `json:"first_seen" yaml:"first_seen"`
+    LastSeen  string `json:"last_seen" yaml:"last_seen"`
+    Count     int    `json:"count" yaml:"count"`
+}
+
+// SignatureOccurrence is a single core file that matched a crash signature.
+type SignatureOccurrence struct {
+    CoreFile   string `json:"core_file" yaml:"core_file"`
+    CoreTime   string `json:"core_time" yaml:"core_time"`
+    Host       string `json:"host" yaml:"host"`
+    Segment    string `json:"segment,omitempty" yaml:"segment,omitempty"`
+    Version    string `json:"version" yaml:"version"`
+    AnalyzedAt string `json:"analyzed_at" yaml:"analyzed_at"`
+}
+
+// BundleManifest describes the contents of a crash bundle created by `core package`.
+type BundleManifest struct {
+    CreatedAt   string       `json:"created_at" yaml:"created_at"`
+    Host        string       `json:"host" yaml:"host"`
+    CoreFile    string       `json:"core_file" yaml:"core_file"`
+    Binary      string       `json:"binary" yaml:"binary"`
+    BuildID     string       `json:"build_id,omitempty" yaml:"build_id,omitempty"`
+    CoreBuildID string       `json:"core_build_id,omitempty" yaml:"core_build_id,omitempty"`
+    Compression string       `json:"compression,omitempty" yaml:"compression,omitempty"` // How CoreFile was compressed; the bundled core is decompressed
+    GDBCommand  string       `json:"gdb_command" yaml:"gdb_command"`
+    Files       []BundleFile `json:"files" yaml:"files"`
+    Missing     []string     `json:"missing,omitempty" yaml:"missing,omitempty"`
+    Notes       []string     `json:"notes,omitempty" yaml:"notes,omitempty"`
+}
+
+// BundleFile is a single file stored in a crash bundle.
+type BundleFile struct {
+    Path   string `json:"path" yaml:"path"`
+    Source string `json:"source,omitempty" yaml:"source,omitempty"`
+    Role   string `json:"role" yaml:"role"`
+    Size   int64  `json:"size" yaml:"size"`
+    SHA256 string `json:"sha256" yaml:"sha256"`
 }
 
 // SignalFault contains additional details about the fault caused by a signal.
@@ -0,0 +1,409 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_watch.go
+// Purpose: Implements `core watch`, a long-running mode that analyzes new core files as they land.
+// Core directories are monitored with inotify where available and rescanned periodically as a
+// fallback. A core is analyzed once it has finished being written (closed, or its size has been
+// stable for --settle), using the same discovery and pipeline as `core`. A state file under --output-dir
+// records analyzed cores so restarts do not analyze them again. SIGTERM and SIGINT stop the
+// watcher gracefully, cancelling any in-flight gdb runs.
+// Dependencies: Uses the analysis pipeline in core_runner.go and the inotify watcher in
+// core_watch_inotify_*.go.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	watchStateFile     = "watch_state.json" // Default state file, relative to --output-dir
+	watchStatusSkipped = "skipped"          // State of a file that is not a core or is filtered out
+)
+
+var (
+	watchPollInterval time.Duration // Interval between directory rescans
+	watchSettle       time.Duration // How long a core's size must be stable before analysis
+	watchStatePath    string        // State file recording analyzed cores
+	watchNoInotify    bool          // Rely on polling only
+)
+
+// coreFileNamePatterns are the file name patterns recognized as core files.
+var coreFileNamePatterns = []string{"core.*", "*.core", "core", "core-*"}
+
+// coreWatchCmd represents the core watch command
+var coreWatchCmd = &cobra.Command{
+	Use:   "watch <directory>...",
+	Short: "Analyze new core files as they are written",
+	Long: `Monitor core directories and analyze each new core file once it has been
+completely written. Results are written to --output-dir as with 'core'.
+Directories are searched as by 'core': --pattern, --exclude, --since, --until
+and --min-size apply, and files that are not ELF core files or compressed are
+skipped.
+
+Analyzed cores are recorded in a state file, so the watcher can be restarted
+without analyzing the same cores again. SIGTERM stops it gracefully, which makes
+it suitable for running under systemd on every segment host:
+  cbtoolbox core watch /var/lib/postgres/cores --output-dir /var/log/postgres_cores`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return runCoreWatch(ctx, args)
+	},
+}
+
+func init() {
+	coreCmd.AddCommand(coreWatchCmd)
+	coreWatchCmd.Flags().DurationVar(&watchPollInterval, "poll-interval", 30*time.Second, "Interval between directory rescans")
+	coreWatchCmd.Flags().DurationVar(&watchSettle, "settle", 10*time.Second, "Time a core's size must stay unchanged before it is analyzed")
+	coreWatchCmd.Flags().StringVar(&watchStatePath, "state-file", "", "State file recording analyzed cores (default <output-dir>/"+watchStateFile+")")
+	coreWatchCmd.Flags().BoolVar(&watchNoInotify, "no-inotify", false, "Detect new cores by polling only")
+}
+
+// watchedCore is the state recorded for a core file that has been analyzed.
+type watchedCore struct {
+	Size        int64  `json:"size"`
+	ModTime     string `json:"mod_time"`
+	Status      string `json:"status"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	AnalyzedAt  string `json:"analyzed_at"`
+}
+
+// pendingCore tracks a core file that has not been analyzed yet.
+type pendingCore struct {
+	size        int64
+	modTime     time.Time
+	stableSince time.Time
+	closed      bool
+}
+
+// coreWatcher holds the state of a running `core watch`.
+type coreWatcher struct {
+	dirs      []string
+	gphome    string
+	statePath string
+	settle    time.Duration
+	analyzed  map[string]watchedCore
+	pending   map[string]*pendingCore
+}
+
+// newCoreWatcher creates a watcher and loads its state file.
+// Parameters:
+// - dirs: The directories to monitor.
+// - statePath: Path of the state file.
+// Returns:
+// - The watcher.
+// - An error if the state file exists but cannot be read.
+func newCoreWatcher(dirs []string, statePath string) (*coreWatcher, error) {
+	w := &coreWatcher{
+		dirs:      dirs,
+		gphome:    os.Getenv("GPHOME"),
+		statePath: statePath,
+		settle:    watchSettle,
+		analyzed:  make(map[string]watchedCore),
+		pending:   make(map[string]*pendingCore),
+	}
+
+	data, err := os.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return w, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watch state: %w", err)
+	}
+	if err := json.Unmarshal(data, &w.analyzed); err != nil {
+		return nil, fmt.Errorf("failed to parse watch state %s: %w", statePath, err)
+	}
+	return w, nil
+}
+
+// saveState writes the watch state atomically.
+// Returns:
+// - An error if the state file cannot be written.
+func (w *coreWatcher) saveState() error {
+	data, err := json.MarshalIndent(w.analyzed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal watch state: %w", err)
+	}
+	tmp := w.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write watch state: %w", err)
+	}
+	if err := os.Rename(tmp, w.statePath); err != nil {
+		return fmt.Errorf("failed to write watch state: %w", err)
+	}
+	return nil
+}
+
+// isCoreFileName reports whether a file name matches the core file name patterns and no --exclude pattern.
+func isCoreFileName(name string) bool {
+	return matchesAny(coreNamePatterns(), name, name) && !matchesAny(excludeFlags, name, name)
+}
+
+// observe records the current size of a candidate core file.
+// Parameters:
+// - path: The core file.
+// - now: The observation time.
+// - closed: True if the writer is known to have closed the file.
+func (w *coreWatcher) observe(path string, now time.Time, closed bool) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() == 0 {
+		delete(w.pending, path)
+		return
+	}
+
+	// Skip cores already analyzed; a different size or mtime means a new core reused the name
+	if done, ok := w.analyzed[path]; ok && done.Size == info.Size() && done.ModTime == info.ModTime().Format(time.RFC3339Nano) {
+		return
+	}
+
+	p, ok := w.pending[path]
+	if !ok || p.size != info.Size() || !p.modTime.Equal(info.ModTime()) {
+		p = &pendingCore{size: info.Size(), modTime: info.ModTime(), stableSince: now}
+		w.pending[path] = p
+	}
+	if closed {
+		p.closed = true
+	}
+}
+
+// scan rescans every watched directory for candidate core files.
+// Parameters:
+// - now: The observation time.
+func (w *coreWatcher) scan(now time.Time) {
+	// Forget cores that have been removed, so the state file does not grow without bound
+	for path := range w.analyzed {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			delete(w.analyzed, path)
+		}
+	}
+
+	for _, dir := range w.dirs {
+		if _, err := os.Stat(dir); err != nil {
+			fmt.Printf("Error scanning %s: %v\n", dir, err)
+			continue
+		}
+		// A core whose header has not been written yet is watched until it settles
+		found := searchCoreFiles(dir)
+		for _, coreFile := range append(found.cores, found.incomplete...) {
+			w.observe(coreFile, now, false)
+		}
+	}
+}
+
+// ready returns the pending cores that have finished being written.
+// Parameters:
+// - now: The current time.
+// Returns:
+// - The ready core files, sorted by path.
+func (w *coreWatcher) ready(now time.Time) []string {
+	var ready []string
+	for path, p := range w.pending {
+		if p.closed || now.Sub(p.stableSince) >= w.settle {
+			ready = append(ready, path)
+		}
+	}
+	sort.Strings(ready)
+	return ready
+}
+
+// skipReason checks a settled core against the discovery filters and the ELF core check.
+// Parameters:
+// - path: The core file.
+// - p: Its pending state.
+// Returns:
+// - Why the file is not analyzed, or "" if it is.
+func (w *coreWatcher) skipReason(path string, p *pendingCore) string {
+	if !matchesDiscoveryFilters(coreCandidate{path: path, modTime: p.modTime, size: p.size}) {
+		return "outside the --since, --until or --min-size filters"
+	}
+	if p.size < elfHeaderSize || !isCoreOrCompressed(path) {
+		return "neither an ELF core file nor compressed"
+	}
+	return ""
+}
+
+// analyzeReady analyzes every ready core and records the outcome in the state file.
+// Cores whose analysis was cancelled are left pending so they are retried after a restart.
+// Parameters:
+// - ctx: Context for the watcher; cancelling it stops in-flight analyses.
+// - now: The current time.
+func (w *coreWatcher) analyzeReady(ctx context.Context, now time.Time) {
+	ready := w.ready(now)
+	if len(ready) == 0 {
+		return
+	}
+
+	// Re-check sizes right before analysis; anything still growing waits for the next round.
+	// Files reported by inotify have not been through discovery, and a file that settled
+	// before its header was complete is no core, so both are checked here.
+	var stable []string
+	skipped := false
+	for _, path := range ready {
+		p := w.pending[path]
+		if info, err := os.Stat(path); err != nil || info.Size() != p.size || !info.ModTime().Equal(p.modTime) {
+			w.observe(path, now, false)
+			continue
+		}
+		if reason := w.skipReason(path, p); reason != "" {
+			fmt.Printf("Skipping %s: %s\n", path, reason)
+			w.analyzed[path] = watchedCore{
+				Size:       p.size,
+				ModTime:    p.modTime.Format(time.RFC3339Nano),
+				Status:     watchStatusSkipped,
+				AnalyzedAt: time.Now().Format(time.RFC3339),
+			}
+			delete(w.pending, path)
+			skipped = true
+			continue
+		}
+		stable = append(stable, path)
+	}
+	if len(stable) == 0 {
+		if skipped {
+			if err := w.saveState(); err != nil {
+				fmt.Printf("Error saving watch state: %v\n", err)
+			}
+		}
+		return
+	}
+
+	fmt.Printf("Analyzing %d new core file(s)\n", len(stable))
+	analyses, manifest := analyzeAndRecord(ctx, stable, w.gphome)
+	if streamingOutput() {
+		if err := streamSummary(manifest, nil); err != nil {
+			fmt.Printf("Error streaming batch summary: %v\n", err)
+		}
+	}
+
+	fingerprints := make(map[string]string)
+	for _, analysis := range analyses {
+		fingerprints[analysis.CoreFile] = analysis.Fingerprint
+	}
+	for _, result := range manifest.Cores {
+		if result.Status == coreStatusCancelled {
+			continue
+		}
+		p := w.pending[result.CoreFile]
+		w.analyzed[result.CoreFile] = watchedCore{
+			Size:        p.size,
+			ModTime:     p.modTime.Format(time.RFC3339Nano),
+			Status:      result.Status,
+			Fingerprint: fingerprints[result.CoreFile],
+			AnalyzedAt:  time.Now().Format(time.RFC3339),
+		}
+		delete(w.pending, result.CoreFile)
+	}
+
+	if err := w.saveState(); err != nil {
+		fmt.Printf("Error saving watch state: %v\n", err)
+	}
+}
+
+// runCoreWatch monitors core directories until ctx is cancelled.
+// Parameters:
+// - ctx: Context for the watcher; cancelled on SIGTERM or SIGINT.
+// - dirs: The directories to monitor.
+// Returns:
+// - An error if the watcher cannot be started.
+func runCoreWatch(ctx context.Context, dirs []string) error {
+	if err := validateCoreFlags(); err != nil {
+		return err
+	}
+	if watchPollInterval <= 0 {
+		return fmt.Errorf("invalid poll interval: %s. Must be positive", watchPollInterval)
+	}
+	for _, dir := range dirs {
+		if !dirExists(dir) {
+			return fmt.Errorf("core directory not found: %s", dir)
+		}
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if streamingOutput() {
+		defer startStream()()
+	}
+
+	statePath := watchStatePath
+	if statePath == "" {
+		statePath = filepath.Join(outputDir, watchStateFile)
+	}
+	w, err := newCoreWatcher(dirs, statePath)
+	if err != nil {
+		return err
+	}
+
+	var events <-chan watchEvent
+	if !watchNoInotify {
+		dw, err := newDirWatcher(dirs)
+		if err != nil {
+			fmt.Printf("inotify unavailable (%v); polling every %s\n", err, watchPollInterval)
+		} else {
+			defer dw.Close()
+			events = dw.Events()
+		}
+	}
+
+	fmt.Printf("Watching %d director(ies) for new core files\n", len(dirs))
+	w.scan(time.Now())
+
+	poll := time.NewTicker(watchPollInterval)
+	defer poll.Stop()
+	check := time.NewTicker(watchCheckInterval(w.settle, watchPollInterval))
+	defer check.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("Stopping core watch")
+			return w.saveState()
+		case ev, ok := <-events:
+			if !ok {
+				fmt.Printf("inotify stopped; polling every %s\n", watchPollInterval)
+				events = nil
+				continue
+			}
+			if isCoreFileName(filepath.Base(ev.Path)) {
+				w.observe(ev.Path, time.Now(), ev.Closed)
+			}
+		case <-poll.C:
+			w.scan(time.Now())
+		case <-check.C:
+		}
+		w.analyzeReady(ctx, time.Now())
+	}
+}
+
+// watchCheckInterval chooses how often pending cores are checked for readiness.
+func watchCheckInterval(settle, poll time.Duration) time.Duration {
+	interval := settle / 2
+	if interval > poll {
+		interval = poll
+	}
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+	return interval
+}
@@ -0,0 +1,111 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_watch_inotify_linux.go
+// Purpose: inotify-based directory watcher used by `core watch` on Linux.
+
+//go:build linux
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask selects the events that signal a new or completed core file.
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO
+
+// watchEvent reports activity on a file in a watched directory.
+type watchEvent struct {
+	Path   string // The file the event refers to
+	Closed bool   // True if the writer closed the file or it was moved into place
+}
+
+// inotifyWatcher delivers inotify events for a set of directories.
+type inotifyWatcher struct {
+	file   *os.File
+	dirs   map[int32]string
+	events chan watchEvent
+}
+
+// newDirWatcher starts watching the given directories with inotify.
+// Parameters:
+// - dirs: The directories to watch.
+// Returns:
+// - The watcher; its Events channel is closed when watching stops.
+// - An error if inotify cannot be initialized.
+func newDirWatcher(dirs []string) (*inotifyWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify_init1: %w", err)
+	}
+
+	w := &inotifyWatcher{
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		dirs:   make(map[int32]string),
+		events: make(chan watchEvent, 64),
+	}
+	for _, dir := range dirs {
+		wd, err := syscall.InotifyAddWatch(fd, dir, inotifyMask)
+		if err != nil {
+			w.file.Close()
+			return nil, fmt.Errorf("inotify_add_watch %s: %w", dir, err)
+		}
+		w.dirs[int32(wd)] = dir
+	}
+
+	go w.read()
+	return w, nil
+}
+
+// Events returns the channel of file events.
+func (w *inotifyWatcher) Events() <-chan watchEvent {
+	return w.events
+}
+
+// Close stops watching; the Events channel is closed once the reader exits.
+func (w *inotifyWatcher) Close() error {
+	return w.file.Close()
+}
+
+// read decodes inotify events until the watcher is closed.
+func (w *inotifyWatcher) read() {
+	defer close(w.events)
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameStart := off + syscall.SizeofInotifyEvent
+			nameEnd := nameStart + int(ev.Len)
+			if nameEnd > n {
+				break
+			}
+			if dir, ok := w.dirs[ev.Wd]; ok && ev.Len > 0 {
+				name := cString(buf[nameStart:nameEnd])
+				w.events <- watchEvent{
+					Path:   filepath.Join(dir, name),
+					Closed: ev.Mask&(syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO) != 0,
+				}
+			}
+			off = nameEnd
+		}
+	}
+}
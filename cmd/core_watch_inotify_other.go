@@ -0,0 +1,44 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// File: cmd/core_watch_inotify_other.go
+// Purpose: Stub directory watcher for platforms without inotify; `core watch` falls back to polling.
+
+//go:build !linux
+
+package cmd
+
+import "errors"
+
+// watchEvent reports activity on a file in a watched directory.
+type watchEvent struct {
+	Path   string // The file the event refers to
+	Closed bool   // True if the writer closed the file or it was moved into place
+}
+
+// inotifyWatcher is unavailable on this platform.
+type inotifyWatcher struct{}
+
+// newDirWatcher reports that inotify is not supported.
+func newDirWatcher(dirs []string) (*inotifyWatcher, error) {
+	return nil, errors.New("inotify is not supported on this platform")
+}
+
+// Events returns a nil channel.
+func (w *inotifyWatcher) Events() <-chan watchEvent {
+	return nil
+}
+
+// Close does nothing.
+func (w *inotifyWatcher) Close() error {
+	return nil
+}
@@ -0,0 +1,231 @@
+// File: cmd/core_watch_test.go
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setupWatchEnv prepares a core directory, output directory and mock GPHOME for watch tests.
+func setupWatchEnv(t *testing.T) (string, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	coreDir := filepath.Join(tmpDir, "cores")
+	gphome := filepath.Join(tmpDir, "gphome")
+	for _, dir := range []string{coreDir, filepath.Join(gphome, "bin")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(gphome, "bin", "postgres"), []byte("mock binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	oldOutput, oldGPHOME, oldFormat, oldSettle := outputDir, os.Getenv("GPHOME"), formatFlag, watchSettle
+	outputDir = filepath.Join(tmpDir, "output")
+	formatFlag = "json"
+	os.Setenv("GPHOME", gphome)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	withRunFlags(t, 1, time.Minute, &countingCommander{})
+	t.Cleanup(func() {
+		outputDir, formatFlag, watchSettle = oldOutput, oldFormat, oldSettle
+		os.Setenv("GPHOME", oldGPHOME)
+	})
+	return coreDir, filepath.Join(outputDir, watchStateFile)
+}
+
+func TestIsCoreFileName(t *testing.T) {
+	tests := map[string]bool{
+		"core":                true,
+		"core.1234":           true,
+		"core-postgres-11-42": true,
+		"postgres.core":       true,
+		"core_analysis.json":  false,
+		"notes.txt":           false,
+	}
+	for name, want := range tests {
+		if got := isCoreFileName(name); got != want {
+			t.Errorf("isCoreFileName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestCoreWatcherReadiness(t *testing.T) {
+	coreDir, statePath := setupWatchEnv(t)
+	watchSettle = time.Minute
+	w, err := newCoreWatcher([]string{coreDir}, statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corePath := filepath.Join(coreDir, "core.100")
+	if err := os.WriteFile(corePath, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	w.scan(now)
+
+	if ready := w.ready(now); len(ready) != 0 {
+		t.Errorf("core ready before settling: %v", ready)
+	}
+	if ready := w.ready(now.Add(time.Minute)); len(ready) != 1 {
+		t.Errorf("core not ready after settling: %v", ready)
+	}
+
+	// A growing core restarts the settle period
+	if err := os.WriteFile(corePath, []byte("partial core, still being written"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.observe(corePath, now.Add(time.Minute), false)
+	if ready := w.ready(now.Add(time.Minute)); len(ready) != 0 {
+		t.Errorf("growing core reported ready: %v", ready)
+	}
+
+	// A closed core is ready immediately
+	w.observe(corePath, now.Add(time.Minute), true)
+	if ready := w.ready(now.Add(time.Minute)); len(ready) != 1 {
+		t.Errorf("closed core not ready: %v", ready)
+	}
+
+	// Empty files are ignored
+	emptyPath := filepath.Join(coreDir, "core.200")
+	if err := os.WriteFile(emptyPath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.observe(emptyPath, now, true)
+	if _, ok := w.pending[emptyPath]; ok {
+		t.Error("empty core should not be pending")
+	}
+}
+
+func TestCoreWatcherStatePersists(t *testing.T) {
+	coreDir, statePath := setupWatchEnv(t)
+	watchSettle = 0
+
+	corePath := filepath.Join(coreDir, "core.300")
+	writeTestCore(t, corePath, nil, nil)
+
+	w, err := newCoreWatcher([]string{coreDir}, statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.scan(time.Now())
+	w.analyzeReady(context.Background(), time.Now())
+
+	if got := w.analyzed[corePath].Status; got != coreStatusOK {
+		t.Fatalf("status = %q, want %q", got, coreStatusOK)
+	}
+	if len(w.pending) != 0 {
+		t.Errorf("pending = %v, want empty", w.pending)
+	}
+
+	// A restarted watcher does not pick the core up again
+	restarted, err := newCoreWatcher([]string{coreDir}, statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted.scan(time.Now())
+	if len(restarted.pending) != 0 {
+		t.Errorf("analyzed core pending again after restart")
+	}
+
+	// A new core reusing the name is analyzed again
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(corePath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	restarted.scan(time.Now())
+	if _, ok := restarted.pending[corePath]; !ok {
+		t.Error("replaced core not pending")
+	}
+}
+
+func TestCoreWatcherSkipsNonCores(t *testing.T) {
+	coreDir, statePath := setupWatchEnv(t)
+	watchSettle = time.Minute
+	w, err := newCoreWatcher([]string{coreDir}, statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shortPath := filepath.Join(coreDir, "core.400")
+	textPath := filepath.Join(coreDir, "core.notes")
+	if err := os.WriteFile(shortPath, []byte("\x7fELF"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(textPath, []byte("notes about the last crash"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Discovery leaves out the text file but waits for the short one to be written
+	now := time.Now()
+	w.scan(now)
+	if _, ok := w.pending[textPath]; ok {
+		t.Error("text file pending after a scan")
+	}
+	if _, ok := w.pending[shortPath]; !ok {
+		t.Fatal("file with an incomplete header not pending")
+	}
+
+	// Once settled, neither is analyzed; inotify reports the text file too
+	w.observe(textPath, now, true)
+	w.analyzeReady(context.Background(), now.Add(time.Minute))
+	for _, path := range []string{shortPath, textPath} {
+		if got := w.analyzed[path].Status; got != watchStatusSkipped {
+			t.Errorf("%s status = %q, want %q", filepath.Base(path), got, watchStatusSkipped)
+		}
+	}
+	if len(w.pending) != 0 {
+		t.Errorf("pending = %v, want empty", w.pending)
+	}
+	if entries, _ := os.ReadDir(outputDir); len(entries) != 1 {
+		t.Errorf("output directory holds %d entries, want only the state file", len(entries))
+	}
+}
+
+func TestRunCoreWatch(t *testing.T) {
+	coreDir, statePath := setupWatchEnv(t)
+
+	oldPoll, oldNoInotify := watchPollInterval, watchNoInotify
+	defer func() { watchPollInterval, watchNoInotify = oldPoll, oldNoInotify }()
+	watchPollInterval, watchSettle = 20*time.Millisecond, 50*time.Millisecond
+
+	for _, noInotify := range []bool{false, true} {
+		watchNoInotify = noInotify
+		corePath := filepath.Join(coreDir, "core.watch")
+		os.Remove(corePath)
+		os.Remove(statePath)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- runCoreWatch(ctx, []string{coreDir}) }()
+
+		time.Sleep(50 * time.Millisecond)
+		writeTestCore(t, corePath, nil, nil)
+
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if w, err := newCoreWatcher(nil, statePath); err == nil && w.analyzed[corePath].Status == coreStatusOK {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		cancel()
+
+		if err := <-done; err != nil {
+			t.Fatalf("runCoreWatch(no-inotify=%v) error = %v", noInotify, err)
+		}
+		w, err := newCoreWatcher(nil, statePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.analyzed[corePath].Status != coreStatusOK {
+			t.Errorf("no-inotify=%v: core not analyzed, state = %v", noInotify, w.analyzed)
+		}
+	}
+}
@@ -12,7 +12,8 @@
 
 // File: cmd/flags.go
 // Purpose: Defines shared command flags and their initialization logic for the Cloudberry Database CLI.
-// Includes functionality to validate and set global flags such as output format (yaml/json).
+// Includes functionality to validate and set global flags such as output format (yaml/json, or the
+// html/markdown/junit reports written for core analyses).
 
 package cmd
 
@@ -22,24 +23,72 @@ import (
 
 // Shared command flags
 var (
-	formatFlag string // Common flag for output format (yaml/json)
+	formatFlag string // Common flag for output format (yaml/json/html/markdown/junit)
 )
 
-// validateFormat checks if the provided format is either "json" or "yaml".
+// Output formats accepted by --format. HTML and Markdown are human-readable reports;
+// JUnit XML lets CI servers report cores as failed tests.
+const (
+	formatJSON     = "json"
+	formatYAML     = "yaml"
+	formatHTML     = "html"
+	formatMarkdown = "markdown"
+	formatJUnit    = "junit"
+)
+
+// validateFormat checks if the provided format is "json", "yaml", "html", "markdown" or "junit".
 // Parameters:
 // - format: A string representing the desired output format.
 // Returns:
 // - An error if the format is invalid, or nil if the format is valid.
 func validateFormat(format string) error {
-	if format != "json" && format != "yaml" {
+	switch format {
+	case formatJSON, formatYAML, formatHTML, formatMarkdown, formatJUnit:
+		return nil
+	}
+	return fmt.Errorf("invalid format: %s. Valid options are 'json', 'yaml', 'html', 'markdown' or 'junit'", format)
+}
+
+// validateDataFormat checks the format of commands that only output raw data.
+// Parameters:
+// - format: A string representing the desired output format.
+// Returns:
+// - An error unless the format is "json" or "yaml".
+func validateDataFormat(format string) error {
+	if format != formatJSON && format != formatYAML {
 		return fmt.Errorf("invalid format: %s. Valid options are 'json' or 'yaml'", format)
 	}
 	return nil
 }
 
+// isReportFormat reports whether a format is a report rather than a data dump.
+func isReportFormat(format string) bool {
+	return format == formatHTML || format == formatMarkdown || format == formatJUnit
+}
+
+// dataFormat returns the format for machine-readable files such as run manifests:
+// the --format flag, or JSON when a report format was requested.
+func dataFormat() string {
+	if isReportFormat(formatFlag) {
+		return formatJSON
+	}
+	return formatFlag
+}
+
+// formatExtension returns the file extension for an output format.
+func formatExtension(format string) string {
+	switch format {
+	case formatMarkdown:
+		return "md"
+	case formatJUnit:
+		return "xml"
+	}
+	return format
+}
+
 // initSharedFlags initializes flags that are shared across multiple commands.
 // This includes setting up the --format flag for specifying output format.
 func initSharedFlags() {
 	// Add format flag to root command so it's available to all subcommands.
-	rootCmd.PersistentFlags().StringVar(&formatFlag, "format", "yaml", "Output format: yaml or json")
+	rootCmd.PersistentFlags().StringVar(&formatFlag, "format", "yaml", "Output format: yaml, json, html, markdown or junit")
 }
@@ -351,34 +351,12 @@ func gatherGPHOMEInfo() (string, []string, string, string, []error) {
     return gphome, pgConfig, postgresVersion, gpVersion, errs
 }
 
-// RunSysInfo gathers and displays system and database information.
-// Performs concurrent collection of system information and sequential collection
-// of database information if GPHOME is properly configured.
-//
-// System information collected:
-// - Operating system and version
-// - System architecture
-// - Hostname
-// - Kernel version
-// - CPU count
-// - Memory statistics
-//
-// Database information collected (when GPHOME is set):
-// - PostgreSQL build configuration
-// - PostgreSQL server version
-// - Cloudberry Database version
-//
-// The output format is determined by the global formatFlag ("yaml" or "json").
-// Any errors encountered during collection are displayed in a summary before
-// the output. Returns an error if:
-// - The format is invalid
-// - Required system information cannot be collected
-// - Database information cannot be collected when GPHOME is set
-func RunSysInfo(cmd *cobra.Command, args []string) error {
-    if err := validateFormat(formatFlag); err != nil {
-        return err
-    }
-
+// gatherSysInfo collects system and database information.
+// Returns:
+// - SysInfo: The collected information
+// - []error: Errors from system information collection
+// - []error: Errors from GPHOME/database information collection
+func gatherSysInfo() (SysInfo, []error, []error) {
     var wg sync.WaitGroup
     var mu sync.Mutex
 
@@ -418,6 +396,39 @@ func RunSysInfo(cmd *cobra.Command, args []string) error {
     
     wg.Wait()
 
+    return info, errs, gphomeErrs
+}
+
+// RunSysInfo gathers and displays system and database information.
+// Performs concurrent collection of system information and sequential collection
+// of database information if GPHOME is properly configured.
+//
+// System information collected:
+// - Operating system and version
+// - System architecture
+// - Hostname
+// - Kernel version
+// - CPU count
+// - Memory statistics
+//
+// Database information collected (when GPHOME is set):
+// - PostgreSQL build configuration
+// - PostgreSQL server version
+// - Cloudberry Database version
+//
+// The output format is determined by the global formatFlag ("yaml" or "json").
+// Any errors encountered during collection are displayed in a summary before
+// the output. Returns an error if:
+// - The format is invalid
+// - Required system information cannot be collected
+// - Database information cannot be collected when GPHOME is set
+func RunSysInfo(cmd *cobra.Command, args []string) error {
+    if err := validateDataFormat(formatFlag); err != nil {
+        return err
+    }
+
+    info, errs, gphomeErrs := gatherSysInfo()
+
     // Log errors but don't fail if they're only from optional components
     if len(errs) > 0 || len(gphomeErrs) > 0 {
         fmt.Println("\nSummary of errors:")